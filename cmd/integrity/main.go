@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"olra-v1/internal/database"
+	"olra-v1/services"
+
+	_ "github.com/joho/godotenv/autoload"
+)
+
+// integrity runs the ledger integrity checks once, saves the report and exits
+// non-zero when anything was found, so it can gate a deploy or a cron job
+func main() {
+	days := flag.Int("days", 0, "only check journals and transfers from the last N days; 0 checks all history")
+	flag.Parse()
+
+	database.New()
+	var since *time.Time
+	if *days > 0 {
+		from := time.Now().AddDate(0, 0, -*days)
+		since = &from
+	}
+	report, err := services.CheckLedgerIntegrity(since)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "integrity check could not run:", err)
+		os.Exit(2)
+	}
+
+	fmt.Printf("Integrity report %d: %s\n", report.ID, report.Status)
+	fmt.Printf("  journals checked:     %d, unbalanced: %d\n", report.JournalsChecked, report.UnbalancedJournals)
+	fmt.Printf("  transfers checked:    %d, unmatched: %d\n", report.TransfersChecked, report.UnmatchedTransfers)
+	fmt.Printf("  accounts checked:     %d, drifted: %d, negative: %d\n", report.AccountsChecked, report.BalanceDrifts, report.NegativeBalances)
+	fmt.Printf("  customer funds:       %.2f\n", report.CustomerFunds)
+	fmt.Printf("  omnibus (%s): %.2f, difference: %.2f\n", report.OmnibusSource, report.OmnibusBalance, report.OmnibusDifference)
+	for _, finding := range report.Findings {
+		fmt.Printf("  %-18s %-30s expected %.2f, found %.2f: %s\n",
+			finding.Check, finding.Subject, finding.Expected, finding.Actual, finding.Detail)
+	}
+	if report.Status == services.IntegrityFailed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,426 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/structs"
+	"olra-v1/middleware"
+	"olra-v1/services"
+)
+
+func ExportData(c *gin.Context) {
+	var _, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         true,
+			"response code": 401,
+			"message":       "User not found",
+			"data":          "",
+		})
+		return
+	}
+	userStruct, ok := user.(middleware.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "User not a valid struct",
+			"data":          "",
+		})
+		return
+	}
+	export, err := services.BuildDataExport(*userStruct.UserId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Could not build data export",
+			"data":          "",
+		})
+		return
+	}
+	c.Header(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=olra-data-export-%d.json", *userStruct.UserId),
+	)
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Data export generated successfully",
+		"data":          export,
+	})
+}
+
+func CloseAccount(c *gin.Context) {
+	var _, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         true,
+			"response code": 401,
+			"message":       "User not found",
+			"data":          "",
+		})
+		return
+	}
+	userStruct, ok := user.(middleware.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "User not a valid struct",
+			"data":          "",
+		})
+		return
+	}
+	var (
+		closeAccountRequest structs.CloseAccountRequest
+		existingUser        database.User
+	)
+	if err := c.BindJSON(&closeAccountRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(closeAccountRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err := database.DB.Where(
+		"user_id = ?", userStruct.UserId,
+	).First(&existingUser).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "User does not exist.",
+			"data":          "",
+		})
+		return
+	}
+	// Closure is irreversible so the passcode is asked for again
+	if err := bcrypt.CompareHashAndPassword(
+		[]byte(existingUser.PasswordHash), []byte(closeAccountRequest.Passcode),
+	); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         true,
+			"response code": 401,
+			"message":       "Invaid passcode",
+			"data":          "",
+		})
+		return
+	}
+	if err := services.CloseAccount(existingUser.UserID, closeAccountRequest.Reason); err != nil {
+		if errors.Is(err, services.ErrWalletNotEmpty) ||
+			errors.Is(err, services.ErrFundsHeld) ||
+			errors.Is(err, services.ErrTransfersPending) ||
+			errors.Is(err, services.ErrGroupHasFunds) ||
+			errors.Is(err, services.ErrAccountClosed) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":         true,
+				"response code": 400,
+				"message":       err.Error(),
+				"data":          "",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to close account",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Account closed successfully",
+		"data":          "",
+	})
+}
+
+func UpdateLanguage(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var languageRequest structs.UpdateLanguageRequest
+	if err := c.BindJSON(&languageRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(languageRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err := database.DB.Model(&database.User{}).
+		Where("user_id = ?", userID).
+		Update("language", languageRequest.Language).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to update language",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Language updated successfully",
+		"data":          languageRequest.Language,
+	})
+}
+
+func GetWalletBalance(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	balance, err := services.GetWalletBalance(userID)
+	if errors.Is(err, services.ErrWalletNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "User virtual account does not exist.",
+			"data":          "",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Error retrieving balance",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Balance retrieved successfully",
+		"data":          balance,
+	})
+}
+
+func TransactionHistory(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	page, limit := getPagination(c)
+	transactions, err := services.TransactionHistory(userID, page, limit)
+	if errors.Is(err, services.ErrAccountNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "User does not exist.",
+			"data":          "",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Error retrieving transactions",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Transactions retrieved successfully",
+		"data":          transactions,
+	})
+}
+
+func GetWalletStatus(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	provisioning, err := services.WalletStatus(services.OwnerUser, userID)
+	if errors.Is(err, services.ErrProvisioningNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "No wallet has been requested for this account",
+			"data":          "",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Error retrieving wallet status",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Wallet status retrieved successfully",
+		"data":          provisioning,
+	})
+}
+
+// ProvisionWallet requests the wallet again after a failed attempt
+func ProvisionWallet(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var user database.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "User not found",
+			"data":          "",
+		})
+		return
+	}
+	if user.Tag == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Create a tag before requesting a wallet",
+			"data":          "",
+		})
+		return
+	}
+	provisioning, err := services.ProvisionUserWallet(&user)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":         true,
+			"response code": 502,
+			"message":       "The bank could not open the wallet, try again later",
+			"data":          provisioning,
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"error":         false,
+		"response code": 202,
+		"message":       "Wallet requested successfully",
+		"data":          provisioning,
+	})
+}
+
+func SetTransactionPin(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var pinRequest structs.SetTransactionPinRequest
+	if err := c.BindJSON(&pinRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(pinRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	err := services.SetTransactionPin(userID, pinRequest.Passcode, pinRequest.Pin)
+	if errors.Is(err, services.ErrIncorrectPasscode) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         true,
+			"response code": 401,
+			"message":       "Invaid passcode",
+			"data":          "",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to set transaction PIN",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Transaction PIN set successfully",
+		"data":          "",
+	})
+}
+
+func AccountRoutes(rg *gin.RouterGroup) {
+	accountRoute := rg.Group("/account")
+	accountRoute.GET(
+		"/export",
+		middleware.AuthMiddleware,
+		ExportData,
+	)
+	accountRoute.POST(
+		"/close",
+		middleware.AuthMiddleware,
+		CloseAccount,
+	)
+	accountRoute.PUT(
+		"/language",
+		middleware.AuthMiddleware,
+		UpdateLanguage,
+	)
+	accountRoute.GET(
+		"/wallet",
+		middleware.AuthMiddleware,
+		GetWalletStatus,
+	)
+	accountRoute.GET(
+		"/balance",
+		middleware.AuthMiddleware,
+		GetWalletBalance,
+	)
+	accountRoute.GET(
+		"/transactions",
+		middleware.AuthMiddleware,
+		TransactionHistory,
+	)
+	accountRoute.POST(
+		"/wallet",
+		middleware.AuthMiddleware,
+		ProvisionWallet,
+	)
+	accountRoute.PUT(
+		"/pin",
+		middleware.AuthMiddleware,
+		SetTransactionPin,
+	)
+}
@@ -0,0 +1,988 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/structs"
+	"olra-v1/internal/templates"
+	"olra-v1/middleware"
+	"olra-v1/services"
+)
+
+func InviteWaitlist(c *gin.Context) {
+	var inviteRequest structs.InviteWaitlistRequest
+	if err := c.BindJSON(&inviteRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(inviteRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	invites, err := services.InviteWaitlist(inviteRequest.WaitlistIDs, inviteRequest.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to invite waitlist entries",
+			"data":          invites,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Waitlist invites sent successfully",
+		"data":          invites,
+	})
+}
+
+func WaitlistReport(c *gin.Context) {
+	report, err := services.GetWaitlistReport()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to build waitlist report",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Waitlist report retrieved successfully",
+		"data":          report,
+	})
+}
+
+func ListTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Templates retrieved successfully",
+		"data":          templates.Events(),
+	})
+}
+
+// PreviewTemplate renders an event with sample data. Pass format=html to view
+// the email as it would arrive.
+func PreviewTemplate(c *gin.Context) {
+	event := c.Query("event")
+	rendered, err := templates.Render(event, c.Query("locale"), templates.SampleData(event))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, templates.ErrUnknownEvent) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error":         true,
+			"response code": status,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if c.Query("format") == "html" {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(rendered.HTML))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Template rendered successfully",
+		"data":          rendered,
+	})
+}
+
+func ListOutbox(c *gin.Context) {
+	page, limit := getPagination(c)
+	status := c.DefaultQuery("status", services.OutboxDead)
+	var messages []database.OutboxMessage
+	if err := database.DB.Where("status = ?", status).
+		Order("created_at desc").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&messages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to retrieve outbox messages",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Outbox messages retrieved successfully",
+		"data":          messages,
+	})
+}
+
+func RetryOutboxMessage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid outbox message id",
+			"data":          "",
+		})
+		return
+	}
+	message, err := services.RetryOutboxMessage(uint(id))
+	if errors.Is(err, services.ErrOutboxMessageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrOutboxMessageSent) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         true,
+			"response code": 409,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to requeue outbox message",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Outbox message requeued successfully",
+		"data":          message,
+	})
+}
+
+func ListSuspenseCredits(c *gin.Context) {
+	page, limit := getPagination(c)
+	status := c.DefaultQuery("status", services.InboundSuspense)
+	var credits []database.InboundCredit
+	if err := database.DB.Where("status = ?", status).
+		Order("created_at desc").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&credits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to retrieve inbound credits",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Inbound credits retrieved successfully",
+		"data":          credits,
+	})
+}
+
+func ResolveSuspenseCredit(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid inbound credit id",
+			"data":          "",
+		})
+		return
+	}
+	var resolveRequest structs.ResolveSuspenseRequest
+	if err := c.BindJSON(&resolveRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(resolveRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	credit, err := services.ResolveSuspenseCredit(uint(id), resolveRequest.AccountNumber)
+	switch {
+	case errors.Is(err, services.ErrInboundCreditNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	case errors.Is(err, services.ErrInboundCreditNotHeld), errors.Is(err, services.ErrAccountNumberNotFound),
+		errors.Is(err, services.ErrRecipientClosed):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to resolve inbound credit",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Inbound credit resolved successfully",
+		"data":          credit,
+	})
+}
+
+func ListPricingRules(c *gin.Context) {
+	rules, err := services.ListPricingRules(c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to retrieve pricing rules",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Pricing rules retrieved successfully",
+		"data":          rules,
+	})
+}
+
+// bindPricingRule reads and validates a pricing rule from the body. When it
+// returns false the error response has already been written.
+func bindPricingRule(c *gin.Context) (*database.PricingRule, bool) {
+	var ruleRequest structs.PricingRuleRequest
+	if err := c.BindJSON(&ruleRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return nil, false
+	}
+	validationErr := Validate.Struct(ruleRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return nil, false
+	}
+	rule := &database.PricingRule{
+		Name:            ruleRequest.Name,
+		TransactionType: ruleRequest.TransactionType,
+		Tier:            ruleRequest.Tier,
+		Channel:         ruleRequest.Channel,
+		MinAmount:       ruleRequest.MinAmount,
+		MaxAmount:       ruleRequest.MaxAmount,
+		FeeType:         ruleRequest.FeeType,
+		FlatFee:         ruleRequest.FlatFee,
+		Percentage:      ruleRequest.Percentage,
+		Cap:             ruleRequest.Cap,
+		FreePerMonth:    ruleRequest.FreePerMonth,
+		Priority:        ruleRequest.Priority,
+		EffectiveTo:     ruleRequest.EffectiveTo,
+	}
+	if ruleRequest.EffectiveFrom != nil {
+		rule.EffectiveFrom = *ruleRequest.EffectiveFrom
+	}
+	return rule, true
+}
+
+func CreatePricingRule(c *gin.Context) {
+	rule, ok := bindPricingRule(c)
+	if !ok {
+		return
+	}
+	err := services.CreatePricingRule(rule)
+	if errors.Is(err, services.ErrInvalidPricingRule) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to create pricing rule",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"error":         false,
+		"response code": 201,
+		"message":       "Pricing rule created successfully",
+		"data":          rule,
+	})
+}
+
+func UpdatePricingRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid pricing rule id",
+			"data":          "",
+		})
+		return
+	}
+	changes, ok := bindPricingRule(c)
+	if !ok {
+		return
+	}
+	rule, err := services.UpdatePricingRule(uint(id), changes)
+	switch {
+	case errors.Is(err, services.ErrPricingRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	case errors.Is(err, services.ErrInvalidPricingRule):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to update pricing rule",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Pricing rule updated successfully",
+		"data":          rule,
+	})
+}
+
+func EndPricingRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid pricing rule id",
+			"data":          "",
+		})
+		return
+	}
+	rule, err := services.EndPricingRule(uint(id))
+	if errors.Is(err, services.ErrPricingRuleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to end pricing rule",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Pricing rule ended successfully",
+		"data":          rule,
+	})
+}
+
+// ReverseTransaction refunds all of a transaction, or the amount given.
+// Reversals above the approval threshold come back pending a second approver.
+func ReverseTransaction(c *gin.Context) {
+	staffID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var reverseRequest structs.ReverseTransactionRequest
+	if err := c.BindJSON(&reverseRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(reverseRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	reversal, err := services.RequestReversal(c.Param("reference"), reverseRequest.Amount, reverseRequest.Reason, staffID)
+	if reversalFailed(c, err) {
+		return
+	}
+	if reversal.Status == services.ReversalPendingApproval {
+		c.JSON(http.StatusAccepted, gin.H{
+			"error":         false,
+			"response code": 202,
+			"message":       "Reversal is awaiting a second approver",
+			"data":          reversal,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Transaction reversed successfully",
+		"data":          reversal,
+	})
+}
+
+func ListReversals(c *gin.Context) {
+	page, limit := getPagination(c)
+	reversals, err := services.ListReversals(c.Query("status"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to retrieve reversals",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Reversals retrieved successfully",
+		"data":          reversals,
+	})
+}
+
+func ApproveReversal(c *gin.Context) {
+	reviewReversal(c, services.ApproveReversal, "Reversal approved successfully")
+}
+
+func RejectReversal(c *gin.Context) {
+	reviewReversal(c, services.RejectReversal, "Reversal rejected successfully")
+}
+
+func reviewReversal(c *gin.Context, review func(id, staffID uint) (*database.Reversal, error), message string) {
+	staffID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid reversal id",
+			"data":          "",
+		})
+		return
+	}
+	reversal, err := review(uint(id), staffID)
+	if reversalFailed(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       message,
+		"data":          reversal,
+	})
+}
+
+// reversalFailed writes the response for a reversal error and reports
+// whether there was one
+func reversalFailed(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrTransactionNotFound), errors.Is(err, services.ErrReversalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	case errors.Is(err, services.ErrAlreadyReversed), errors.Is(err, services.ErrReversalNotPending):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         true,
+			"response code": 409,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	case errors.Is(err, services.ErrSameApprover):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":         true,
+			"response code": 403,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	case errors.Is(err, services.ErrNotReversible),
+		errors.Is(err, services.ErrReversalExceedsAmount),
+		errors.Is(err, services.ErrInsufficientBalance):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to reverse transaction",
+			"data":          "",
+		})
+	}
+	return true
+}
+
+// UploadStatement reconciles a statement file sent as a multipart form with
+// the file, the date it covers and its format, csv or json, which defaults to
+// the file's extension
+func UploadStatement(c *gin.Context) {
+	staffID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	day, err := time.Parse("2006-01-02", c.PostForm("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "date must be given as YYYY-MM-DD",
+			"data":          "",
+		})
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "A statement file is required",
+			"data":          "",
+		})
+		return
+	}
+	format := c.PostForm("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Could not read the uploaded file",
+			"data":          "",
+		})
+		return
+	}
+	defer file.Close()
+	lines, err := services.ParseStatement(format, file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+	provider := c.PostForm("provider")
+	if provider == "" {
+		provider = services.Banking().Name()
+	}
+	report, err := services.Reconcile(provider, format, day, lines, &staffID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to reconcile statement",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Statement reconciled successfully",
+		"data":          report,
+	})
+}
+
+// ReconcileFromProvider fetches the statement for a day from the banking
+// provider's API and reconciles it
+func ReconcileFromProvider(c *gin.Context) {
+	staffID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var reconcileRequest structs.ReconcileProviderRequest
+	if err := c.BindJSON(&reconcileRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(reconcileRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	day, _ := time.Parse("2006-01-02", reconcileRequest.Date)
+	report, err := services.ReconcileFromProvider(day, &staffID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":         true,
+			"response code": 502,
+			"message":       "Failed to reconcile the provider's statement",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Statement reconciled successfully",
+		"data":          report,
+	})
+}
+
+func ListReconciliationReports(c *gin.Context) {
+	page, limit := getPagination(c)
+	reports, err := services.ListReconciliationReports(page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to retrieve reconciliation reports",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Reconciliation reports retrieved successfully",
+		"data":          reports,
+	})
+}
+
+// GetReconciliationReport returns a report and its items. Pass status to see
+// only matched items or one kind of exception.
+func GetReconciliationReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid reconciliation report id",
+			"data":          "",
+		})
+		return
+	}
+	report, err := services.GetReconciliationReport(uint(id), c.Query("status"))
+	if reconciliationFailed(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Reconciliation report retrieved successfully",
+		"data":          report,
+	})
+}
+
+func ResolveReconciliationItem(c *gin.Context) {
+	staffID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	itemID, itemErr := strconv.ParseUint(c.Param("itemId"), 10, 64)
+	if err != nil || itemErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid reconciliation item id",
+			"data":          "",
+		})
+		return
+	}
+	var resolveRequest structs.ResolveReconciliationItemRequest
+	if err := c.BindJSON(&resolveRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(resolveRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	item, err := services.ResolveReconciliationItem(uint(id), uint(itemID), staffID, resolveRequest.Note)
+	if reconciliationFailed(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Reconciliation item resolved successfully",
+		"data":          item,
+	})
+}
+
+func ReviewReconciliationReport(c *gin.Context) {
+	staffID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid reconciliation report id",
+			"data":          "",
+		})
+		return
+	}
+	report, err := services.ReviewReconciliationReport(uint(id), staffID)
+	if reconciliationFailed(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Reconciliation report reviewed successfully",
+		"data":          report,
+	})
+}
+
+// reconciliationFailed writes the response for a reconciliation review error
+// and reports whether there was one
+func reconciliationFailed(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrReconciliationNotFound), errors.Is(err, services.ErrReconciliationItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	case errors.Is(err, services.ErrReconciliationReviewed):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         true,
+			"response code": 409,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	case errors.Is(err, services.ErrItemAlreadyMatched), errors.Is(err, services.ErrUnresolvedItems):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to update reconciliation report",
+			"data":          "",
+		})
+	}
+	return true
+}
+
+// RunIntegrityCheck runs the ledger integrity checks now, over the last days
+// of journals and transfers when days is given
+func RunIntegrityCheck(c *gin.Context) {
+	var since *time.Time
+	if days, err := strconv.Atoi(c.Query("days")); err == nil && days > 0 {
+		from := time.Now().AddDate(0, 0, -days)
+		since = &from
+	}
+	report, err := services.CheckLedgerIntegrity(since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to check ledger integrity",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Ledger integrity checked successfully",
+		"data":          report,
+	})
+}
+
+func ListIntegrityReports(c *gin.Context) {
+	page, limit := getPagination(c)
+	reports, err := services.ListIntegrityReports(page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to retrieve integrity reports",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Integrity reports retrieved successfully",
+		"data":          reports,
+	})
+}
+
+func GetIntegrityReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid integrity report id",
+			"data":          "",
+		})
+		return
+	}
+	report, err := services.GetIntegrityReport(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "Integrity report not found",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Integrity report retrieved successfully",
+		"data":          report,
+	})
+}
+
+// IntegrityMetrics serves the latest integrity report for Prometheus. Scrapers
+// authenticate with METRICS_TOKEN as a bearer token; without one set the
+// endpoint is off.
+func IntegrityMetrics(c *gin.Context) {
+	token := os.Getenv("METRICS_TOKEN")
+	if token == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	metrics, err := services.IntegrityMetrics()
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4", []byte(metrics))
+}
+
+func AdminRoutes(rg *gin.RouterGroup) {
+	adminRoute := rg.Group("/admin", middleware.AuthMiddleware, middleware.AdminMiddleware)
+	adminRoute.POST("/waitlist/invite", InviteWaitlist)
+	adminRoute.GET("/waitlist/report", WaitlistReport)
+	adminRoute.GET("/templates", ListTemplates)
+	adminRoute.GET("/templates/preview", PreviewTemplate)
+	adminRoute.GET("/outbox", ListOutbox)
+	adminRoute.POST("/outbox/:id/retry", RetryOutboxMessage)
+	adminRoute.GET("/suspense", ListSuspenseCredits)
+	adminRoute.POST("/suspense/:id/resolve", ResolveSuspenseCredit)
+	adminRoute.GET("/pricing-rules", ListPricingRules)
+	adminRoute.POST("/pricing-rules", CreatePricingRule)
+	adminRoute.PUT("/pricing-rules/:id", UpdatePricingRule)
+	adminRoute.POST("/pricing-rules/:id/end", EndPricingRule)
+
+	financeRoute := rg.Group("/admin", middleware.AuthMiddleware, middleware.RequireRole("admin", "finance"))
+	financeRoute.POST("/transactions/:reference/reverse", ReverseTransaction)
+	financeRoute.GET("/reversals", ListReversals)
+	financeRoute.POST("/reversals/:id/approve", ApproveReversal)
+	financeRoute.POST("/reversals/:id/reject", RejectReversal)
+	financeRoute.GET("/reconciliations", ListReconciliationReports)
+	financeRoute.POST("/reconciliations", UploadStatement)
+	financeRoute.POST("/reconciliations/provider", ReconcileFromProvider)
+	financeRoute.GET("/reconciliations/:id", GetReconciliationReport)
+	financeRoute.POST("/reconciliations/:id/items/:itemId/resolve", ResolveReconciliationItem)
+	financeRoute.POST("/reconciliations/:id/review", ReviewReconciliationReport)
+	financeRoute.GET("/integrity-reports", ListIntegrityReports)
+	financeRoute.POST("/integrity-reports", RunIntegrityCheck)
+	financeRoute.GET("/integrity-reports/:id", GetIntegrityReport)
+
+	rg.GET("/metrics", IntegrityMetrics)
+}
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"olra-v1/internal/structs"
+	"olra-v1/middleware"
+	"olra-v1/services"
+)
+
+func ListBanks(c *gin.Context) {
+	banks, err := services.SearchBanks(c.Query("q"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to retrieve banks",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Banks retrieved successfully",
+		"data":          banks,
+	})
+}
+
+func ListBeneficiaries(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	beneficiaries, err := services.ListBeneficiaries(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to retrieve beneficiaries",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Beneficiaries retrieved successfully",
+		"data":          beneficiaries,
+	})
+}
+
+func AddBeneficiary(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var beneficiaryRequest structs.AddBeneficiaryRequest
+	if err := c.BindJSON(&beneficiaryRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(beneficiaryRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	beneficiary, err := services.AddBeneficiary(
+		userID,
+		beneficiaryRequest.AccountNumber,
+		beneficiaryRequest.BankCode,
+		beneficiaryRequest.Nickname,
+	)
+	switch {
+	case errors.Is(err, services.ErrUnknownBank),
+		errors.Is(err, services.ErrInvalidAccountNumber):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	case errors.Is(err, services.ErrNameEnquiryFailed):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	case errors.Is(err, services.ErrBeneficiaryExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         true,
+			"response code": 409,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to save beneficiary",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"error":         false,
+		"response code": 201,
+		"message":       "Beneficiary saved successfully",
+		"data":          beneficiary,
+	})
+}
+
+func UpdateBeneficiary(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid beneficiary id",
+			"data":          "",
+		})
+		return
+	}
+	var beneficiaryRequest structs.UpdateBeneficiaryRequest
+	if err := c.BindJSON(&beneficiaryRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(beneficiaryRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	beneficiary, err := services.UpdateBeneficiary(userID, uint(id), beneficiaryRequest.Nickname)
+	if errors.Is(err, services.ErrBeneficiaryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to update beneficiary",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Beneficiary updated successfully",
+		"data":          beneficiary,
+	})
+}
+
+func DeleteBeneficiary(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid beneficiary id",
+			"data":          "",
+		})
+		return
+	}
+	err = services.DeleteBeneficiary(userID, uint(id))
+	if errors.Is(err, services.ErrBeneficiaryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to delete beneficiary",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Beneficiary deleted successfully",
+		"data":          "",
+	})
+}
+
+func RecentRecipients(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit < 1 || limit > 50 {
+		limit = 10
+	}
+	recipients, err := services.RecentRecipients(userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to retrieve recent recipients",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Recent recipients retrieved successfully",
+		"data":          recipients,
+	})
+}
+
+func BeneficiaryRoutes(rg *gin.RouterGroup) {
+	rg.GET("/banks", ListBanks)
+
+	beneficiaryRoute := rg.Group("/beneficiaries", middleware.AuthMiddleware)
+	beneficiaryRoute.GET("", ListBeneficiaries)
+	beneficiaryRoute.POST("", AddBeneficiary)
+	beneficiaryRoute.GET("/recent", RecentRecipients)
+	beneficiaryRoute.PUT("/:id", UpdateBeneficiary)
+	beneficiaryRoute.DELETE("/:id", DeleteBeneficiary)
+}
@@ -0,0 +1,382 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"olra-v1/internal/structs"
+	"olra-v1/middleware"
+	"olra-v1/services"
+)
+
+func OpenDispute(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var disputeRequest structs.OpenDisputeRequest
+	if err := c.BindJSON(&disputeRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(disputeRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	dispute, err := services.OpenDispute(
+		userID,
+		disputeRequest.TransactionReference,
+		disputeRequest.Reason,
+		disputeRequest.Description,
+		disputeRequest.Amount,
+	)
+	if disputeFailed(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Dispute opened successfully",
+		"data":          dispute,
+	})
+}
+
+func ListMyDisputes(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	page, limit := getPagination(c)
+	disputes, err := services.ListMyDisputes(userID, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Error retrieving disputes",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Disputes retrieved successfully",
+		"data":          disputes,
+	})
+}
+
+func GetMyDispute(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := getDisputeID(c, "id")
+	if !ok {
+		return
+	}
+	dispute, err := services.GetMyDispute(id, userID)
+	if disputeFailed(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Dispute retrieved successfully",
+		"data":          dispute,
+	})
+}
+
+// AddMyDisputeEvidence takes a multipart form with a note, a file or both.
+// The counterparty answers a dispute this way.
+func AddMyDisputeEvidence(c *gin.Context) {
+	addDisputeEvidence(c, false)
+}
+
+func GetMyDisputeEvidence(c *gin.Context) {
+	downloadDisputeEvidence(c, false)
+}
+
+func ListDisputes(c *gin.Context) {
+	page, limit := getPagination(c)
+	disputes, err := services.ListDisputes(c.Query("status"), c.Query("overdue") == "true", page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Error retrieving disputes",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Disputes retrieved successfully",
+		"data":          disputes,
+	})
+}
+
+func GetDispute(c *gin.Context) {
+	id, ok := getDisputeID(c, "id")
+	if !ok {
+		return
+	}
+	dispute, err := services.GetDispute(id)
+	if disputeFailed(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Dispute retrieved successfully",
+		"data":          dispute,
+	})
+}
+
+func UpdateDispute(c *gin.Context) {
+	id, ok := getDisputeID(c, "id")
+	if !ok {
+		return
+	}
+	var updateRequest structs.UpdateDisputeRequest
+	if err := c.BindJSON(&updateRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(updateRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	dispute, err := services.UpdateDisputeStatus(id, updateRequest.Status)
+	if disputeFailed(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Dispute updated successfully",
+		"data":          dispute,
+	})
+}
+
+func AddDisputeEvidence(c *gin.Context) {
+	addDisputeEvidence(c, true)
+}
+
+func GetDisputeEvidence(c *gin.Context) {
+	downloadDisputeEvidence(c, true)
+}
+
+// ResolveDispute decides a dispute. Deciding for the opener reverses the
+// transaction, which above the approval threshold waits for finance.
+func ResolveDispute(c *gin.Context) {
+	staffID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := getDisputeID(c, "id")
+	if !ok {
+		return
+	}
+	var resolveRequest structs.ResolveDisputeRequest
+	if err := c.BindJSON(&resolveRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(resolveRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	dispute, err := services.ResolveDispute(id, staffID, resolveRequest.Outcome, resolveRequest.Amount, resolveRequest.Resolution)
+	if disputeFailed(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Dispute resolved successfully",
+		"data":          dispute,
+	})
+}
+
+func addDisputeEvidence(c *gin.Context, staff bool) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := getDisputeID(c, "id")
+	if !ok {
+		return
+	}
+	var upload *services.EvidenceUpload
+	fileHeader, err := c.FormFile("file")
+	switch {
+	case err == nil:
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":         true,
+				"response code": 400,
+				"message":       "Could not read the uploaded file",
+				"data":          "",
+			})
+			return
+		}
+		defer file.Close()
+		upload = &services.EvidenceUpload{Name: fileHeader.Filename, Size: fileHeader.Size, Content: file}
+	case !errors.Is(err, http.ErrMissingFile):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Evidence must be sent as a multipart form",
+			"data":          "",
+		})
+		return
+	}
+	evidence, err := services.AddDisputeEvidence(id, userID, staff, c.PostForm("note"), upload)
+	if disputeFailed(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Evidence added successfully",
+		"data":          evidence,
+	})
+}
+
+func downloadDisputeEvidence(c *gin.Context, staff bool) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := getDisputeID(c, "id")
+	if !ok {
+		return
+	}
+	evidenceID, ok := getDisputeID(c, "evidenceId")
+	if !ok {
+		return
+	}
+	evidence, path, err := services.EvidenceFile(id, evidenceID, userID, staff)
+	if disputeFailed(c, err) {
+		return
+	}
+	c.Header("Content-Type", evidence.ContentType)
+	c.FileAttachment(path, evidence.FileName)
+}
+
+func getDisputeID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid " + param,
+			"data":          "",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// disputeFailed writes the response for a dispute error and reports whether
+// there was one. Errors from the reversal a resolution posts are answered as
+// reversals are.
+func disputeFailed(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrDisputeNotFound), errors.Is(err, services.ErrEvidenceFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	case errors.Is(err, services.ErrDisputeExists), errors.Is(err, services.ErrDisputeResolved):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         true,
+			"response code": 409,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	case errors.Is(err, services.ErrEvidenceTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":         true,
+			"response code": 413,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	case errors.Is(err, services.ErrNotDisputable),
+		errors.Is(err, services.ErrDisputeWindowClosed),
+		errors.Is(err, services.ErrDisputeExceedsAmount),
+		errors.Is(err, services.ErrInvalidDisputeStatus),
+		errors.Is(err, services.ErrInvalidDisputeOutcome),
+		errors.Is(err, services.ErrNoCounterparty),
+		errors.Is(err, services.ErrEmptyEvidence),
+		errors.Is(err, services.ErrEvidenceType):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	case errors.Is(err, services.ErrTransactionNotFound),
+		errors.Is(err, services.ErrAlreadyReversed),
+		errors.Is(err, services.ErrNotReversible),
+		errors.Is(err, services.ErrReversalExceedsAmount),
+		errors.Is(err, services.ErrInsufficientBalance):
+		return reversalFailed(c, err)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to process dispute",
+			"data":          "",
+		})
+	}
+	return true
+}
+
+func DisputeRoutes(rg *gin.RouterGroup) {
+	disputeRoute := rg.Group("/disputes", middleware.AuthMiddleware)
+	disputeRoute.POST("", OpenDispute)
+	disputeRoute.GET("", ListMyDisputes)
+	disputeRoute.GET("/:id", GetMyDispute)
+	disputeRoute.POST("/:id/evidence", AddMyDisputeEvidence)
+	disputeRoute.GET("/:id/evidence/:evidenceId", GetMyDisputeEvidence)
+
+	staffRoute := rg.Group(
+		"/support/staff/disputes",
+		middleware.AuthMiddleware,
+		middleware.RequireRole("admin", "support"),
+	)
+	staffRoute.GET("", ListDisputes)
+	staffRoute.GET("/:id", GetDispute)
+	staffRoute.PATCH("/:id", UpdateDispute)
+	staffRoute.POST("/:id/evidence", AddDisputeEvidence)
+	staffRoute.GET("/:id/evidence/:evidenceId", GetDisputeEvidence)
+	staffRoute.POST("/:id/resolve", ResolveDispute)
+}
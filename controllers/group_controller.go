@@ -2,9 +2,10 @@ package controllers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -142,27 +143,6 @@ func CreateGroup(c *gin.Context) {
 		return
 	}
 
-	// Generate a random account number for the group's virtual wallet
-	generatedAccountNumber := helpers.GenerateRandomAccountNumber()
-
-	// Create a virtual wallet for the group
-	groupVirtualAccount := database.GroupVirtualAccount{
-		GroupVirtualAccountBank:   "Guaranty Trust Bank",
-		GroupVirtualAccountNumber: generatedAccountNumber,
-		GroupVirtualAccountName:   groupRequest.GroupName,
-		GroupID:                   group.GroupID,
-	}
-	if err := database.DB.Create(&groupVirtualAccount).Error; err != nil {
-		database.DB.Delete(&group)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":         true,
-			"response code": 500,
-			"message":       "Failed to create virtual account",
-			"data":          "",
-		})
-		return
-	}
-
 	// Iterate over group members and create group members
 	for _, friendTag := range groupRequest.GroupMembers {
 		var friend database.User
@@ -173,7 +153,6 @@ func CreateGroup(c *gin.Context) {
 			JoinedAt: time.Now(),
 		}
 		if err := database.DB.Create(&groupMember).Error; err != nil {
-			database.DB.Delete(&groupVirtualAccount)
 			database.DB.Delete(&group)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":         true,
@@ -185,6 +164,13 @@ func CreateGroup(c *gin.Context) {
 		}
 	}
 
+	var admin database.User
+	if err := database.DB.First(&admin, *userStruct.UserId).Error; err == nil {
+		if _, err := services.ProvisionGroupWallet(&group, &admin); err != nil {
+			log.Println("Error provisioning group wallet:", err)
+		}
+	}
+
 	// Respond with success message and created group data
 	c.JSON(http.StatusOK, gin.H{
 		"error":         false,
@@ -225,7 +211,6 @@ func SendGroupFunds(c *gin.Context) {
 		group                 database.Group
 		userAccount           database.VirtualAccount
 		// groupMember           database.GroupMember
-		fundsResponse structs.FundsResponse
 	)
 	if err := c.BindJSON(&sendGroupFundsRequest); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err})
@@ -300,7 +285,16 @@ func SendGroupFunds(c *gin.Context) {
 	// 	})
 	// 	return
 	// }
-	if userAccount.Balance < sendGroupFundsRequest.Amount {
+	transaction, err := services.TransferToGroup(
+		&existingUser,
+		&userAccount,
+		&group,
+		&groupAccount,
+		sendGroupFundsRequest.Amount,
+		sendGroupFundsRequest.Description,
+		getChannel(c),
+	)
+	if errors.Is(err, services.ErrInsufficientBalance) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
 			"response code": 400,
@@ -309,76 +303,86 @@ func SendGroupFunds(c *gin.Context) {
 		})
 		return
 	}
-	sentGroupBalance := sendGroupFundsRequest.Amount + groupAccount.Balance
-	if err := database.DB.Model(&groupAccount).Update(
-		"balance", sentGroupBalance,
-	).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	if errors.Is(err, services.ErrInvalidAmount) {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
-			"response code": 500,
-			"message":       "Failed to update group account balance",
+			"response code": 400,
+			"message":       err.Error(),
 			"data":          "",
 		})
 		return
 	}
-	debitedBalance := userAccount.Balance - sendGroupFundsRequest.Amount
-	if err := database.DB.Model(&userAccount).Update(
-		"balance", debitedBalance,
-	).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":         true,
 			"response code": 500,
-			"message":       "Failed to update user account balance",
+			"message":       "Failed to complete group transfer",
 			"data":          "",
 		})
 		return
 	}
+	services.EvaluateReferral(*userStruct.UserId)
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Group transaction successful",
+		"data":          transaction,
+	})
+}
 
-	transaction := database.Transaction{
-		UserID:             *userStruct.UserId,
-		TransactionEnviron: "withinOlra",
-		TransactionType:    "group-payment",
-		Amount:             sendGroupFundsRequest.Amount,
-		Description:        sendGroupFundsRequest.Description,
-		Receiver:           group.GroupTag,
-		Sender:             existingUser.Tag,
-		Status:             "completed",
-		TransactionDate:    time.Now(),
+func GetGroupWalletStatus(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
 	}
-	if err := database.DB.Create(&transaction).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	groupID, err := strconv.ParseUint(c.Query("groupId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
-			"response code": 500,
-			"message":       "Failed to create transaction instance",
+			"response code": 400,
+			"message":       "Invalid group id",
 			"data":          "",
 		})
 		return
 	}
-	smsFundsResponse, errr := services.DebitGroupFundsSMS(
-		existingUser.PhoneNumber,
-		group.GroupName,
-		sendGroupFundsRequest.Amount,
-		userAccount.Balance,
-	)
-	if errr != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
+	var group database.Group
+	if err := database.DB.First(&group, groupID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
 			"error":         true,
-			"response code": 400,
-			"message":       "Could not send debit funds sms",
+			"response code": 404,
+			"message":       "Group not found",
+			"data":          "",
+		})
+		return
+	}
+	var membership int64
+	database.DB.Model(&database.GroupMember{}).
+		Where("group_id = ? AND user_id = ?", group.GroupID, userID).
+		Count(&membership)
+	if group.AdminID != userID && group.CreatedBy != userID && membership == 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":         true,
+			"response code": 403,
+			"message":       "You are not a member of this group",
 			"data":          "",
 		})
 		return
 	}
-	ee := json.Unmarshal([]byte(smsFundsResponse), &fundsResponse)
-	if ee != nil {
-		log.Println("Error:", ee)
+	provisioning, err := services.WalletStatus(services.OwnerGroup, group.GroupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "No wallet has been requested for this group",
+			"data":          "",
+		})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"error":         false,
 		"response code": 200,
-		"message":       "Group transaction successful",
-		"data":          fundsResponse,
+		"message":       "Group wallet status retrieved successfully",
+		"data":          provisioning,
 	})
 }
 
@@ -394,5 +398,15 @@ func GroupRoutes(rg *gin.RouterGroup) {
 		middleware.AuthMiddleware,
 		SendGroupFunds,
 	)
+	grouproute.GET(
+		"/wallet",
+		middleware.AuthMiddleware,
+		GetGroupWalletStatus,
+	)
+	grouproute.GET(
+		"/wallet/statement",
+		middleware.AuthMiddleware,
+		GetGroupWalletStatement,
+	)
 
 }
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"olra-v1/middleware"
+	"olra-v1/services"
+)
+
+// getAuthUserID reads the user set by AuthMiddleware. When it returns false the
+// error response has already been written.
+func getAuthUserID(c *gin.Context) (uint, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         true,
+			"response code": 401,
+			"message":       "User not found",
+			"data":          "",
+		})
+		return 0, false
+	}
+	userStruct, ok := user.(middleware.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "User not a valid struct",
+			"data":          "",
+		})
+		return 0, false
+	}
+	return *userStruct.UserId, true
+}
+
+// getPagination reads the page and limit query parameters, defaulting to the
+// first page of 20 and capping the limit at 100
+func getPagination(c *gin.Context) (page, limit int) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err = strconv.Atoi(c.Query("limit"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return page, limit
+}
+
+// channelKey is where withChannel leaves the channel a route serves
+const channelKey = "channel"
+
+// withChannel marks the routes it is mounted on as serving channel. Each
+// entry point is mounted separately, so the channel fees are priced for is
+// decided by the server rather than anything the client sends.
+func withChannel(channel string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(channelKey, channel)
+		c.Next()
+	}
+}
+
+// getChannel is the channel the route was mounted for, the app unless
+// withChannel says otherwise
+func getChannel(c *gin.Context) string {
+	if channel := c.GetString(channelKey); channel != "" {
+		return channel
+	}
+	return services.ChannelApp
+}
+
+// otpErrorMessage keeps provider failures out of the response while passing
+// the OTP engine's own errors through
+func otpErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, services.ErrOTPNotFound),
+		errors.Is(err, services.ErrOTPExpired),
+		errors.Is(err, services.ErrOTPAttemptsExceeded),
+		errors.Is(err, services.ErrInvalidOTP):
+		return err.Error()
+	default:
+		return "Something went wrong. Please try again"
+	}
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"olra-v1/services"
+)
+
+func TestGetChannel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	respond := func(c *gin.Context) { c.String(http.StatusOK, getChannel(c)) }
+	router.GET("/app", respond)
+	router.GET("/web", withChannel(services.ChannelWeb), respond)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/app", services.ChannelApp},
+		{"/web", services.ChannelWeb},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			// The client cannot choose a channel for itself
+			req.Header.Set("X-Channel", services.ChannelUSSD)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("getChannel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"olra-v1/internal/structs"
+	"olra-v1/middleware"
+	"olra-v1/services"
+)
+
+func ListNotifications(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	page, limit := getPagination(c)
+	notifications, total, err := services.ListNotifications(userID, c.Query("unread") == "true", page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Error retrieving notifications",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Notifications retrieved successfully",
+		"data": gin.H{
+			"notifications": notifications,
+			"page":          page,
+			"limit":         limit,
+			"total":         total,
+		},
+	})
+}
+
+func UnreadNotificationCount(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	count, err := services.UnreadNotificationCount(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Error counting notifications",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Unread count retrieved successfully",
+		"data":          gin.H{"unread": count},
+	})
+}
+
+func MarkNotificationRead(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid notification id",
+			"data":          "",
+		})
+		return
+	}
+	err = services.MarkNotificationRead(userID, uint(id))
+	if errors.Is(err, services.ErrNotificationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "Notification does not exist.",
+			"data":          "",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to mark notification as read",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Notification marked as read",
+		"data":          "",
+	})
+}
+
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	marked, err := services.MarkAllNotificationsRead(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to mark notifications as read",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Notifications marked as read",
+		"data":          gin.H{"marked": marked},
+	})
+}
+
+func RegisterPushToken(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         true,
+			"response code": 401,
+			"message":       "User not found",
+			"data":          "",
+		})
+		return
+	}
+	userStruct, ok := user.(middleware.User)
+	if !ok || userStruct.DeviceId == nil || *userStruct.DeviceId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Session has no device",
+			"data":          "",
+		})
+		return
+	}
+	var pushTokenRequest structs.RegisterPushTokenRequest
+	if err := c.BindJSON(&pushTokenRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(pushTokenRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	pushToken, err := services.RegisterPushToken(
+		*userStruct.UserId,
+		*userStruct.DeviceId,
+		pushTokenRequest.Platform,
+		pushTokenRequest.Token,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to register push token",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Push token registered successfully",
+		"data":          pushToken,
+	})
+}
+
+func UnregisterPushToken(c *gin.Context) {
+	user, _ := c.Get("user")
+	userStruct, ok := user.(middleware.User)
+	if !ok || userStruct.DeviceId == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Session has no device",
+			"data":          "",
+		})
+		return
+	}
+	if err := services.UnregisterPushTokens(*userStruct.DeviceId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to remove push token",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Push token removed successfully",
+		"data":          "",
+	})
+}
+
+func GetNotificationPreferences(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	settings, err := services.GetNotificationSettings(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Error retrieving notification preferences",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Notification preferences retrieved successfully",
+		"data":          settings,
+	})
+}
+
+func UpdateNotificationPreferences(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var preferencesRequest structs.UpdateNotificationPreferencesRequest
+	if err := c.BindJSON(&preferencesRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(preferencesRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	settings, err := services.UpdateNotificationSettings(
+		userID,
+		preferencesRequest.Preferences,
+		preferencesRequest.QuietHoursStart,
+		preferencesRequest.QuietHoursEnd,
+		preferencesRequest.LowBalanceThreshold,
+	)
+	if errors.Is(err, services.ErrMandatoryNotification) ||
+		errors.Is(err, services.ErrUnknownNotification) ||
+		errors.Is(err, services.ErrInvalidQuietHours) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to update notification preferences",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Notification preferences updated successfully",
+		"data":          settings,
+	})
+}
+
+func NotificationRoutes(rg *gin.RouterGroup) {
+	notificationRoute := rg.Group("/notifications", middleware.AuthMiddleware)
+	notificationRoute.GET("", ListNotifications)
+	notificationRoute.GET("/unread-count", UnreadNotificationCount)
+	notificationRoute.POST("/read-all", MarkAllNotificationsRead)
+	notificationRoute.POST("/:id/read", MarkNotificationRead)
+	notificationRoute.POST("/devices", RegisterPushToken)
+	notificationRoute.DELETE("/devices", UnregisterPushToken)
+	notificationRoute.GET("/preferences", GetNotificationPreferences)
+	notificationRoute.PUT("/preferences", UpdateNotificationPreferences)
+}
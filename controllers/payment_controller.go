@@ -2,18 +2,21 @@ package controllers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
 	"olra-v1/internal/database"
 	"olra-v1/internal/structs"
+	"olra-v1/internal/templates"
 	"olra-v1/middleware"
 	"olra-v1/services"
+	helpers "olra-v1/utils"
 )
 
 func RequestFunds(c *gin.Context) {
@@ -42,7 +45,6 @@ func RequestFunds(c *gin.Context) {
 	}
 	var (
 		requestFundsRequest structs.RequestFundsRequest
-		fundsResponse       structs.FundsResponse
 	)
 	if err := c.BindJSON(&requestFundsRequest); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err})
@@ -72,6 +74,7 @@ func RequestFunds(c *gin.Context) {
 	}
 	transaction := database.Transaction{
 		UserID:             *userStruct.UserId,
+		Reference:          helpers.GenerateTransactionReference(),
 		TransactionEnviron: "withinOlra",
 		TransactionType:    "request",
 		Amount:             requestFundsRequest.Amount,
@@ -80,41 +83,47 @@ func RequestFunds(c *gin.Context) {
 		Status:             "completed",
 		TransactionDate:    time.Now(),
 	}
-	if err := database.DB.Create(&transaction).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	var requester database.User
+	if err := database.DB.Where("user_id = ?", userStruct.UserId).First(&requester).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
 			"error":         true,
-			"response code": 500,
-			"message":       "Failed to create transaction instance",
+			"response code": 404,
+			"message":       "User does not exist.",
 			"data":          "",
 		})
 		return
 	}
-	smsFundsResponse, err := services.SendRequestFundsSMS(
-		existingUser.PhoneNumber,
-		existingUser.FirstName,
-		existingUser.LastName,
-		existingUser.Tag,
-		requestFundsRequest.Amount,
-	)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+		return services.Dispatch(tx, &existingUser, services.Notice{
+			Type:         services.NotificationRequest,
+			Event:        templates.EventRequestFunds,
+			ResourceType: services.ResourceRequest,
+			ResourceID:   transaction.Reference,
+			Data: templates.Data{
+				"FirstName": existingUser.FirstName,
+				"LastName":  existingUser.LastName,
+				"Tag":       requester.Tag,
+				"Amount":    requestFundsRequest.Amount,
+			},
+		})
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
+		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":         true,
-			"response code": 400,
-			"message":       "Could not send funds sms request",
+			"response code": 500,
+			"message":       "Failed to create transaction instance",
 			"data":          "",
 		})
 		return
 	}
-	e := json.Unmarshal([]byte(smsFundsResponse), &fundsResponse)
-	if e != nil {
-		log.Println("Error:", e)
-		return
-	}
 	c.JSON(http.StatusOK, gin.H{
 		"error":         false,
 		"response code": 200,
 		"message":       "Successfully sent request",
-		"data":          fundsResponse,
+		"data":          transaction,
 	})
 
 }
@@ -148,7 +157,6 @@ func SendFundsOlra(c *gin.Context) {
 		receiverAccount  database.VirtualAccount
 		existingUser     database.User
 		userAccount      database.VirtualAccount
-		fundsResponse    structs.FundsResponse
 	)
 	if err := c.BindJSON(&sendFundsRequest); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err})
@@ -213,7 +221,16 @@ func SendFundsOlra(c *gin.Context) {
 		})
 		return
 	}
-	if userAccount.Balance < sendFundsRequest.Amount {
+	transaction, err := services.TransferToUser(
+		&existingUser,
+		&receivingUser,
+		&userAccount,
+		&receiverAccount,
+		sendFundsRequest.Amount,
+		sendFundsRequest.Description,
+		getChannel(c),
+	)
+	if errors.Is(err, services.ErrInsufficientBalance) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
 			"response code": 400,
@@ -222,99 +239,278 @@ func SendFundsOlra(c *gin.Context) {
 		})
 		return
 	}
-	sentBalance := sendFundsRequest.Amount + receiverAccount.Balance
-	if err := database.DB.Model(&receiverAccount).Update(
-		"balance", sentBalance,
-	).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	if errors.Is(err, services.ErrInvalidAmount) || errors.Is(err, services.ErrRecipientClosed) {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
-			"response code": 500,
-			"message":       "Failed to update receiver account balance",
+			"response code": 400,
+			"message":       err.Error(),
 			"data":          "",
 		})
 		return
 	}
-	debitedBalance := userAccount.Balance - sendFundsRequest.Amount
-	if err := database.DB.Model(&userAccount).Update(
-		"balance", debitedBalance,
-	).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":         true,
 			"response code": 500,
-			"message":       "Failed to update user account balance",
+			"message":       "Failed to complete transfer",
 			"data":          "",
 		})
 		return
 	}
-	transaction := database.Transaction{
-		UserID:             *userStruct.UserId,
-		TransactionEnviron: "withinOlra",
-		TransactionType:    "olraTransfer-out",
-		Amount:             sendFundsRequest.Amount,
-		Description:        sendFundsRequest.Description,
-		Receiver:           sendFundsRequest.Receiver,
-		Sender:             existingUser.Tag,
-		Status:             "completed",
-		TransactionDate:    time.Now(),
+	services.EvaluateReferral(*userStruct.UserId)
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Transaction successful",
+		"data":          transaction,
+	})
+}
+
+func NameEnquiry(c *gin.Context) {
+	if _, ok := getAuthUserID(c); !ok {
+		return
+	}
+	var nameEnquiryRequest structs.NameEnquiryRequest
+	if err := c.BindJSON(&nameEnquiryRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
 	}
-	if err := database.DB.Create(&transaction).Error; err != nil {
+	validationErr := Validate.Struct(nameEnquiryRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	account, err := services.NameEnquiry(nameEnquiryRequest.AccountNumber, nameEnquiryRequest.BankCode)
+	if errors.Is(err, services.ErrUnknownBank) || errors.Is(err, services.ErrInvalidAccountNumber) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrNameEnquiryFailed) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":         true,
+			"response code": 502,
+			"message":       "Account lookup is unavailable, try again later",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Account resolved successfully",
+		"data":          account,
+	})
+}
+
+// QuoteFee prices a payment of ?type= for the signed in user before they
+// confirm it
+func QuoteFee(c *gin.Context) {
+	quoteFee(c, c.Query("type"))
+}
+
+func QuoteBankTransfer(c *gin.Context) {
+	quoteFee(c, services.PricedBankTransfer)
+}
+
+func quoteFee(c *gin.Context, transactionType string) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	amount, err := strconv.ParseFloat(c.Query("amount"), 64)
+	if err != nil || amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid amount",
+			"data":          "",
+		})
+		return
+	}
+	known := false
+	for _, priced := range services.PricedTransactionTypes {
+		known = known || priced == transactionType
+	}
+	if !known {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid transaction type",
+			"data":          "",
+		})
+		return
+	}
+	var existingUser database.User
+	if err := database.DB.Where("user_id = ?", userID).First(&existingUser).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "User does not exist.",
+			"data":          "",
+		})
+		return
+	}
+	quote, err := services.QuoteFee(database.DB, &existingUser, transactionType, getChannel(c), amount)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":         true,
 			"response code": 500,
-			"message":       "Failed to create transaction instance",
+			"message":       "Failed to retrieve fee",
 			"data":          "",
 		})
 		return
 	}
-	smsFundsResponse, err := services.CreditFundsSMS(
-		receivingUser.PhoneNumber,
-		existingUser.Tag,
-		sendFundsRequest.Amount,
-		receiverAccount.Balance,
-	)
-	if err != nil {
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Fee retrieved successfully",
+		"data":          quote,
+	})
+}
+
+func SendFundsBank(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var bankTransferRequest structs.BankTransferRequest
+	if err := c.BindJSON(&bankTransferRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(bankTransferRequest)
+	if validationErr != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
 			"response code": 400,
-			"message":       "Could not send credit funds sms",
+			"message":       validationErr.Error(),
 			"data":          "",
 		})
 		return
 	}
-	e := json.Unmarshal([]byte(smsFundsResponse), &fundsResponse)
-	if e != nil {
-		log.Println("Error:", e)
+	var (
+		existingUser database.User
+		userAccount  database.VirtualAccount
+	)
+	if err := database.DB.Where("user_id = ?", userID).First(&existingUser).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "User does not exist.",
+			"data":          "",
+		})
 		return
 	}
-	smsFundsResponse, errr := services.DebitFundsSMS(
-		existingUser.PhoneNumber,
-		sendFundsRequest.Amount,
-		userAccount.Balance,
+	if err := database.DB.Where("user_id = ?", userID).First(&userAccount).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "User virtual account does not exist.",
+			"data":          "",
+		})
+		return
+	}
+	transfer, err := services.SendBankTransfer(
+		&existingUser,
+		&userAccount,
+		bankTransferRequest.AccountNumber,
+		bankTransferRequest.BankCode,
+		bankTransferRequest.Amount,
+		bankTransferRequest.Narration,
+		bankTransferRequest.Pin,
+		getChannel(c),
 	)
-	if errr != nil {
+	switch {
+	case errors.Is(err, services.ErrIncorrectPin), errors.Is(err, services.ErrPinLocked):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         true,
+			"response code": 401,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	case errors.Is(err, services.ErrPinNotSet),
+		errors.Is(err, services.ErrUnknownBank),
+		errors.Is(err, services.ErrInvalidAccountNumber),
+		errors.Is(err, services.ErrNameEnquiryFailed),
+		errors.Is(err, services.ErrInsufficientBalance),
+		errors.Is(err, services.ErrTransferBelowMinimum),
+		errors.Is(err, services.ErrTransferAboveMaximum),
+		errors.Is(err, services.ErrDailyLimitExceeded):
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
 			"response code": 400,
-			"message":       "Could not send debit funds sms",
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to send transfer",
 			"data":          "",
 		})
 		return
 	}
-	ee := json.Unmarshal([]byte(smsFundsResponse), &fundsResponse)
-	if ee != nil {
-		log.Println("Error:", ee)
+	c.JSON(http.StatusAccepted, gin.H{
+		"error":         false,
+		"response code": 202,
+		"message":       "Transfer submitted",
+		"data":          transfer,
+	})
+}
+
+func GetBankTransfer(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	transfer, err := services.GetBankTransfer(userID, c.Param("reference"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"error":         false,
 		"response code": 200,
-		"message":       "Transaction successful",
-		"data":          fundsResponse,
+		"message":       "Transfer retrieved successfully",
+		"data":          transfer,
 	})
 }
 
+// PaymentRoutes mounts the payment endpoints for the app under /payment and
+// for the website under /web/payment, so each is priced for its channel
 func PaymentRoutes(rg *gin.RouterGroup) {
-	paymentroute := rg.Group("/payment")
+	paymentEndpoints(rg.Group("/payment", withChannel(services.ChannelApp)))
+	paymentEndpoints(rg.Group("/web/payment", withChannel(services.ChannelWeb)))
+}
+
+func paymentEndpoints(paymentroute *gin.RouterGroup) {
 	paymentroute.POST(
 		"/request-funds",
 		middleware.AuthMiddleware,
@@ -325,4 +521,29 @@ func PaymentRoutes(rg *gin.RouterGroup) {
 		middleware.AuthMiddleware,
 		SendFundsOlra,
 	)
+	paymentroute.POST(
+		"/name-enquiry",
+		middleware.AuthMiddleware,
+		NameEnquiry,
+	)
+	paymentroute.GET(
+		"/fee",
+		middleware.AuthMiddleware,
+		QuoteFee,
+	)
+	paymentroute.GET(
+		"/bank-transfer/fee",
+		middleware.AuthMiddleware,
+		QuoteBankTransfer,
+	)
+	paymentroute.POST(
+		"/send-funds-bank",
+		middleware.AuthMiddleware,
+		SendFundsBank,
+	)
+	paymentroute.GET(
+		"/bank-transfer/:reference",
+		middleware.AuthMiddleware,
+		GetBankTransfer,
+	)
 }
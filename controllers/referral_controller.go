@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"olra-v1/middleware"
+	"olra-v1/services"
+)
+
+func ReferralDashboard(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         true,
+			"response code": 401,
+			"message":       "User not found",
+			"data":          "",
+		})
+		return
+	}
+	userStruct, ok := user.(middleware.User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "User not a valid struct",
+			"data":          "",
+		})
+		return
+	}
+	dashboard, err := services.GetReferralDashboard(*userStruct.UserId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Referrals retrieved successfully",
+		"data":          dashboard,
+	})
+}
+
+func ReferralRoutes(rg *gin.RouterGroup) {
+	referralRoute := rg.Group("/referral")
+	referralRoute.GET(
+		"/dashboard",
+		middleware.AuthMiddleware,
+		ReferralDashboard,
+	)
+}
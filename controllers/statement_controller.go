@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"olra-v1/middleware"
+	"olra-v1/services"
+)
+
+// GetWalletStatement downloads a statement of the user's wallet. It takes from
+// and to as YYYY-MM-DD and a format of csv or pdf.
+func GetWalletStatement(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	start, end, err := services.ParseStatementPeriod(c.Query("from"), c.Query("to"))
+	if statementFailed(c, err) {
+		return
+	}
+	statement, err := services.WalletStatement(userID, start, end, c.DefaultQuery("format", services.AccountStatementPDF))
+	if statementFailed(c, err) {
+		return
+	}
+	writeStatement(c, statement)
+}
+
+// GetGroupWalletStatement is GetWalletStatement for the group wallet of the
+// groupId query parameter, open to the group's admin only
+func GetGroupWalletStatement(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	groupID, err := strconv.ParseUint(c.Query("groupId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid group id",
+			"data":          "",
+		})
+		return
+	}
+	start, end, err := services.ParseStatementPeriod(c.Query("from"), c.Query("to"))
+	if statementFailed(c, err) {
+		return
+	}
+	statement, err := services.GroupWalletStatement(
+		userID,
+		uint(groupID),
+		start,
+		end,
+		c.DefaultQuery("format", services.AccountStatementPDF),
+	)
+	if statementFailed(c, err) {
+		return
+	}
+	writeStatement(c, statement)
+}
+
+// VerifyStatement is public so that whoever a statement is shown to can
+// confirm we issued it. Passing the hash printed on it checks that too.
+func VerifyStatement(c *gin.Context) {
+	verification, err := services.VerifyStatement(c.Param("code"), c.Query("hash"))
+	if statementFailed(c, err) {
+		return
+	}
+	message := "Statement found"
+	if verification.HashMatches != nil {
+		if *verification.HashMatches {
+			message = "Statement is genuine"
+		} else {
+			message = "Hash does not match this statement"
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       message,
+		"data":          verification,
+	})
+}
+
+func writeStatement(c *gin.Context, statement *services.AccountStatement) {
+	contentType := "application/pdf"
+	write := services.WriteStatementPDF
+	if statement.Record.Format == services.AccountStatementCSV {
+		contentType = "text/csv; charset=utf-8"
+		write = services.WriteStatementCSV
+	}
+	// Rendered in full first so a failure can still be answered with an error
+	var body bytes.Buffer
+	if err := write(statement, &body); statementFailed(c, err) {
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", services.StatementFileName(statement)))
+	c.Header("X-Statement-Code", statement.Record.Code)
+	c.Header("X-Statement-Hash", statement.Record.Hash)
+	c.Data(http.StatusOK, contentType, body.Bytes())
+}
+
+// statementFailed writes the response for a statement error and reports
+// whether there was one
+func statementFailed(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, services.ErrAccountStatementNotFound),
+		errors.Is(err, services.ErrGroupNotFound),
+		errors.Is(err, services.ErrWalletNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	case errors.Is(err, services.ErrNotGroupAdmin):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":         true,
+			"response code": 403,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	case errors.Is(err, services.ErrAccountStatementFormat),
+		errors.Is(err, services.ErrStatementPeriod),
+		errors.Is(err, services.ErrStatementFutureDate),
+		errors.Is(err, services.ErrStatementPeriodTooLong):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       err.Error(),
+			"data":          "",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Could not generate statement",
+			"data":          "",
+		})
+	}
+	return true
+}
+
+func StatementRoutes(rg *gin.RouterGroup) {
+	rg.GET(
+		"/wallet/statement",
+		middleware.AuthMiddleware,
+		GetWalletStatement,
+	)
+	statementRoute := rg.Group("/statements")
+	statementRoute.GET("/verify/:code", VerifyStatement)
+}
@@ -0,0 +1,409 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/structs"
+	"olra-v1/internal/templates"
+	"olra-v1/middleware"
+	"olra-v1/services"
+)
+
+func CreateTicket(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var (
+		ticketRequest structs.CreateTicketRequest
+		existingUser  database.User
+	)
+	if err := c.BindJSON(&ticketRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(ticketRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err := database.DB.Where("user_id = ?", userID).First(&existingUser).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "User does not exist.",
+			"data":          "",
+		})
+		return
+	}
+	// Users may only raise tickets about transactions they were part of
+	if ticketRequest.TransactionReference != "" {
+		var transaction database.Transaction
+		if err := database.DB.Where(
+			"reference = ? AND (user_id = ? OR receiver = ? OR requestee = ?)",
+			ticketRequest.TransactionReference, userID, existingUser.Tag, existingUser.Tag,
+		).First(&transaction).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":         true,
+				"response code": 404,
+				"message":       "Transaction with this reference does not exist.",
+				"data":          "",
+			})
+			return
+		}
+	}
+	ticket := database.Contact{
+		FirstName:            existingUser.FirstName,
+		LastName:             existingUser.LastName,
+		Email:                existingUser.Email,
+		Phone:                existingUser.PhoneNumber,
+		Message:              ticketRequest.Message,
+		UserID:               &userID,
+		TransactionReference: ticketRequest.TransactionReference,
+	}
+	if err := database.DB.Create(&ticket).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to create ticket",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Ticket created successfully",
+		"data":          ticket,
+	})
+}
+
+func ListMyTickets(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	page, limit := getPagination(c)
+	var tickets []database.Contact
+	if err := database.DB.Where("user_id = ?", userID).
+		Order("updated_at desc").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&tickets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Error retrieving tickets",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Tickets retrieved successfully",
+		"data":          tickets,
+	})
+}
+
+func GetMyTicket(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var ticket database.Contact
+	if err := database.DB.Preload("Messages", "internal = ?", false).
+		Where("id = ? AND user_id = ?", c.Param("id"), userID).
+		First(&ticket).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "Ticket does not exist.",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Ticket retrieved successfully",
+		"data":          ticket,
+	})
+}
+
+func CustomerTicketReply(c *gin.Context) {
+	userID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var (
+		replyRequest structs.TicketReplyRequest
+		ticket       database.Contact
+	)
+	if err := c.BindJSON(&replyRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(replyRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&ticket).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "Ticket does not exist.",
+			"data":          "",
+		})
+		return
+	}
+	message := database.TicketMessage{
+		ContactID: ticket.ID,
+		AuthorID:  &userID,
+		Author:    "customer",
+		Body:      replyRequest.Body,
+	}
+	if err := database.DB.Create(&message).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to add reply",
+			"data":          "",
+		})
+		return
+	}
+	// A customer reply always puts the ticket back in the staff queue
+	database.DB.Model(&ticket).Update("status", "open")
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Reply added successfully",
+		"data":          message,
+	})
+}
+
+func ListTickets(c *gin.Context) {
+	page, limit := getPagination(c)
+	query := database.DB.Model(&database.Contact{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if assignee := c.Query("assignee"); assignee != "" {
+		query = query.Where("assignee_id = ?", assignee)
+	}
+	var tickets []database.Contact
+	if err := query.Order("updated_at desc").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&tickets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Error retrieving tickets",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Tickets retrieved successfully",
+		"data":          tickets,
+	})
+}
+
+func GetTicket(c *gin.Context) {
+	var ticket database.Contact
+	if err := database.DB.Preload("Messages").Where("id = ?", c.Param("id")).First(&ticket).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "Ticket does not exist.",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Ticket retrieved successfully",
+		"data":          ticket,
+	})
+}
+
+func UpdateTicket(c *gin.Context) {
+	var (
+		updateRequest structs.UpdateTicketRequest
+		ticket        database.Contact
+	)
+	if err := c.BindJSON(&updateRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(updateRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&ticket).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "Ticket does not exist.",
+			"data":          "",
+		})
+		return
+	}
+	updates := map[string]interface{}{}
+	if updateRequest.Status != "" {
+		updates["status"] = updateRequest.Status
+	}
+	if updateRequest.AssigneeID != nil {
+		var assignee database.User
+		if err := database.DB.Where(
+			"user_id = ? AND role IN ?", *updateRequest.AssigneeID, []string{"admin", "support"},
+		).First(&assignee).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":         true,
+				"response code": 400,
+				"message":       "Assignee must be a support staff member",
+				"data":          "",
+			})
+			return
+		}
+		updates["assignee_id"] = assignee.UserID
+	}
+	if len(updates) > 0 {
+		if err := database.DB.Model(&ticket).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":         true,
+				"response code": 500,
+				"message":       "Failed to update ticket",
+				"data":          "",
+			})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Ticket updated successfully",
+		"data":          ticket,
+	})
+}
+
+func AddTicketNote(c *gin.Context) {
+	addStaffMessage(c, true)
+}
+
+func StaffTicketReply(c *gin.Context) {
+	addStaffMessage(c, false)
+}
+
+// addStaffMessage stores an internal note, or a reply that is also emailed to
+// the customer and leaves the ticket pending on them
+func addStaffMessage(c *gin.Context, internal bool) {
+	staffID, ok := getAuthUserID(c)
+	if !ok {
+		return
+	}
+	var (
+		replyRequest structs.TicketReplyRequest
+		ticket       database.Contact
+	)
+	if err := c.BindJSON(&replyRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return
+	}
+	validationErr := Validate.Struct(replyRequest)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       validationErr.Error(),
+			"data":          "",
+		})
+		return
+	}
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&ticket).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       "Ticket does not exist.",
+			"data":          "",
+		})
+		return
+	}
+	message := database.TicketMessage{
+		ContactID: ticket.ID,
+		AuthorID:  &staffID,
+		Author:    "staff",
+		Body:      replyRequest.Body,
+		Internal:  internal,
+	}
+	if err := database.DB.Create(&message).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to add message",
+			"data":          "",
+		})
+		return
+	}
+	if !internal {
+		locale := templates.DefaultLocale
+		if ticket.UserID != nil {
+			var customer database.User
+			if err := database.DB.Select("language").First(&customer, *ticket.UserID).Error; err == nil {
+				locale = customer.Language
+			}
+		}
+		if err := services.SendTicketReplyEmail(locale, ticket.FirstName, ticket.Email, ticket.ID, replyRequest.Body); err != nil {
+			log.Println("Error sending ticket reply email:", err)
+		}
+		database.DB.Model(&ticket).Update("status", "pending")
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Message added successfully",
+		"data":          message,
+	})
+}
+
+func SupportRoutes(rg *gin.RouterGroup) {
+	supportRoute := rg.Group("/support")
+	supportRoute.POST("/tickets", middleware.AuthMiddleware, CreateTicket)
+	supportRoute.GET("/tickets", middleware.AuthMiddleware, ListMyTickets)
+	supportRoute.GET("/tickets/:id", middleware.AuthMiddleware, GetMyTicket)
+	supportRoute.POST("/tickets/:id/reply", middleware.AuthMiddleware, CustomerTicketReply)
+
+	staffRoute := supportRoute.Group(
+		"/staff",
+		middleware.AuthMiddleware,
+		middleware.RequireRole("admin", "support"),
+	)
+	staffRoute.GET("/tickets", ListTickets)
+	staffRoute.GET("/tickets/:id", GetTicket)
+	staffRoute.PATCH("/tickets/:id", UpdateTicket)
+	staffRoute.POST("/tickets/:id/notes", AddTicketNote)
+	staffRoute.POST("/tickets/:id/reply", StaffTicketReply)
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+	"olra-v1/middleware"
+	"olra-v1/services"
+)
+
+// supportRouter mounts the ticket handlers behind a stand-in for the auth
+// middleware that signs in whoever the X-Test-User header names
+func supportRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		var userID uint
+		switch c.GetHeader("X-Test-User") {
+		case "customer":
+			userID = 1
+		case "other":
+			userID = 2
+		case "staff":
+			userID = 3
+		}
+		c.Set("user", middleware.User{UserId: &userID})
+	})
+	router.GET("/tickets/:id", GetMyTicket)
+	router.POST("/tickets/:id/reply", CustomerTicketReply)
+	router.POST("/staff/tickets/:id/notes", AddTicketNote)
+	router.POST("/staff/tickets/:id/reply", StaffTicketReply)
+	return router
+}
+
+func serveSupport(router *gin.Engine, method, path, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", user)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSupportTickets(t *testing.T) {
+	db := dbtest.Open(t, &database.User{}, &database.Contact{}, &database.TicketMessage{}, &database.EmailLog{})
+	previous := services.Mail()
+	mailer := services.NewMemoryMailer()
+	services.SetMailer(mailer)
+	t.Cleanup(func() { services.SetMailer(previous) })
+
+	customerID := uint(1)
+	ticket := database.Contact{FirstName: "Ada", Email: "ada@example.com", Message: "Where is my money?", UserID: &customerID}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatal(err)
+	}
+	router := supportRouter()
+	path := "/tickets/1"
+
+	if rec := serveSupport(router, http.MethodGet, path, "other", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("another user reading the ticket got %d, want 404", rec.Code)
+	}
+	if rec := serveSupport(router, http.MethodPost, path+"/reply", "other", `{"body":"hi"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("another user replying got %d, want 404", rec.Code)
+	}
+
+	if rec := serveSupport(router, http.MethodPost, "/staff"+path+"/notes", "staff", `{"body":"checking with the bank"}`); rec.Code != http.StatusOK {
+		t.Fatalf("adding a note got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serveSupport(router, http.MethodPost, "/staff"+path+"/reply", "staff", `{"body":"it has been refunded"}`); rec.Code != http.StatusOK {
+		t.Fatalf("staff reply got %d: %s", rec.Code, rec.Body)
+	}
+	db.First(&ticket, ticket.ID)
+	if ticket.Status != "pending" {
+		t.Errorf("status after a staff reply = %q, want pending", ticket.Status)
+	}
+	if emails := mailer.Emails(); len(emails) != 1 || !strings.Contains(emails[0].Text, "it has been refunded") {
+		t.Errorf("emails = %+v, want the staff reply and not the note", emails)
+	}
+
+	rec := serveSupport(router, http.MethodGet, path, "customer", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("customer reading the ticket got %d", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "checking with the bank") || !strings.Contains(body, "it has been refunded") {
+		t.Errorf("customer view = %s, want the reply without the internal note", body)
+	}
+
+	if rec := serveSupport(router, http.MethodPost, path+"/reply", "customer", `{"body":"thanks, still missing 50"}`); rec.Code != http.StatusOK {
+		t.Fatalf("customer reply got %d", rec.Code)
+	}
+	db.First(&ticket, ticket.ID)
+	if ticket.Status != "open" {
+		t.Errorf("status after a customer reply = %q, want open", ticket.Status)
+	}
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,9 +13,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 
 	"olra-v1/internal/database"
 	"olra-v1/internal/structs"
+	"olra-v1/internal/templates"
 	"olra-v1/middleware"
 	"olra-v1/services"
 	helpers "olra-v1/utils"
@@ -28,7 +31,6 @@ func RequestPhoneOTP(c *gin.Context) {
 
 	var (
 		phoneOTPRequestBody structs.PhoneOTPRequestBody
-		phoneOTPResponse    structs.PhoneOTPResponse
 	)
 	if err := c.BindJSON(&phoneOTPRequestBody); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err})
@@ -58,7 +60,25 @@ func RequestPhoneOTP(c *gin.Context) {
 		return
 	}
 
-	phoneOTP, err := services.SendPhoneWelcomeOTP(phoneOTPRequestBody.Mobile)
+	if services.InviteOnlyMode() {
+		if _, err := services.ValidateInviteCode(phoneOTPRequestBody.InviteCode); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":         true,
+				"response code": 400,
+				"message":       err.Error(),
+				"data":          "",
+			})
+			return
+		}
+	}
+
+	phoneOTP, err := services.IssueOTP(
+		services.OTPPurposePhoneSignup,
+		phoneOTPRequestBody.Channel,
+		phoneOTPRequestBody.Mobile,
+		c.GetHeader("Accept-Language"),
+		0,
+	)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
@@ -69,16 +89,11 @@ func RequestPhoneOTP(c *gin.Context) {
 		return
 	}
 
-	e := json.Unmarshal([]byte(phoneOTP), &phoneOTPResponse)
-	if e != nil {
-		log.Println("Error:", e)
-		return
-	}
 	c.JSON(http.StatusOK, gin.H{
 		"error":         false,
 		"response code": 200,
 		"message":       "OTP sent successfully",
-		"data":          phoneOTPResponse,
+		"data":          phoneOTP,
 	})
 
 }
@@ -89,7 +104,6 @@ func VerifyPhoneOTP(c *gin.Context) {
 
 	var (
 		verifyPhoneOTPRequestBody structs.VerifyPhoneOTPRequestBody
-		verifyPhoneOTPBody        structs.VerifyPhoneOTPBody
 	)
 	if err := c.BindJSON(&verifyPhoneOTPRequestBody); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err})
@@ -107,63 +121,87 @@ func VerifyPhoneOTP(c *gin.Context) {
 		return
 	}
 
-	verifyPhoneOTP, err := services.VerifyOTP(verifyPhoneOTPRequestBody.PinId, verifyPhoneOTPRequestBody.Pin)
-	if err != nil {
+	if err := services.VerifyOTPCode(
+		services.OTPPurposePhoneSignup,
+		verifyPhoneOTPRequestBody.Phone,
+		verifyPhoneOTPRequestBody.Pin,
+	); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
 			"response code": 400,
-			"message":       "Somehting went wrong. Please try again",
+			"message":       otpErrorMessage(err),
 			"data":          "",
 		})
 		return
 	}
-	fmt.Println("verifyPhoneOTP", verifyPhoneOTP)
-
-	e := json.Unmarshal([]byte(verifyPhoneOTP), &verifyPhoneOTPBody)
-	if e != nil {
-		log.Println("Error:", e)
-		return
+	if services.InviteOnlyMode() {
+		if _, err := services.ValidateInviteCode(verifyPhoneOTPRequestBody.InviteCode); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":         true,
+				"response code": 400,
+				"message":       err.Error(),
+				"data":          "",
+			})
+			return
+		}
 	}
-	if verifyPhoneOTPBody.Status == 400 {
-		c.JSON(http.StatusBadRequest, gin.H{
+	refCode, err := services.NewReferralCode()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":         true,
-			"response code": 400,
-			"message":       "Token expired.",
+			"response code": 500,
+			"message":       "Couldn't add user details",
 			"data":          "",
 		})
 		return
 	}
-	fmt.Println("verifyPhoneOTPBody", verifyPhoneOTPBody)
-
-	if !verifyPhoneOTPBody.Verified {
+	user := database.User{
+		PhoneNumber:   verifyPhoneOTPRequestBody.Phone,
+		PhoneVerified: true,
+		RefCode:       refCode,
+	}
+	// The invite is redeemed with the user so a code cannot be spent twice
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if verifyPhoneOTPRequestBody.InviteCode == "" {
+			return nil
+		}
+		err := services.RedeemInvite(tx, verifyPhoneOTPRequestBody.InviteCode, user.UserID)
+		if err != nil && !services.InviteOnlyMode() {
+			// Outside invite-only mode a stale code does not block signup
+			log.Println("Error redeeming invite:", err)
+			return nil
+		}
+		return err
+	})
+	switch {
+	case errors.Is(err, services.ErrInvalidInvite), errors.Is(err, services.ErrInviteExpired):
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
 			"response code": 400,
-			"message":       "Invalid token",
+			"message":       err.Error(),
 			"data":          "",
 		})
 		return
-	}
-	user := database.User{
-		PhoneNumber:   verifyPhoneOTPRequestBody.Phone,
-		PhoneVerified: true,
-	}
-	result := database.DB.Create(&user)
-	if result.Error != nil {
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":         true,
 			"response code": 500,
 			"message":       "Couldn't add user details",
-			"data":          result.Error,
+			"data":          "",
 		})
 		return
 	}
-	fmt.Println("result", result)
+	if verifyPhoneOTPRequestBody.InviteCode == "" {
+		services.MarkWaitlistConverted("", user.PhoneNumber, user.UserID)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"error":         false,
 		"response code": 200,
 		"message":       "OTP verified successfully",
-		"data":          verifyPhoneOTPBody,
+		"data":          "",
 	})
 
 }
@@ -219,6 +257,31 @@ func AddUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err})
 		return
 	}
+	// Users created before referral codes were assigned at phone verification
+	refCode := existingUser.RefCode
+	if refCode == "" {
+		refCode, err = services.NewReferralCode()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":         true,
+				"response code": 500,
+				"message":       "Failed to update user",
+				"data":          "",
+			})
+			return
+		}
+	}
+	if userRequestBody.ReferralCode != "" {
+		if err := services.LinkReferral(&existingUser, userRequestBody.ReferralCode); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":         true,
+				"response code": 400,
+				"message":       err.Error(),
+				"data":          "",
+			})
+			return
+		}
+	}
 	// Update existing user with provided details
 	if err := database.DB.Model(&existingUser).Updates(database.User{
 		FirstName: userRequestBody.FirstName,
@@ -226,7 +289,7 @@ func AddUser(c *gin.Context) {
 		Email:     userRequestBody.Email,
 		Role:      "user",
 		Tag:       userName,
-		RefCode:   helpers.GenerateVerificationCode(),
+		RefCode:   refCode,
 	}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":         true,
@@ -236,6 +299,7 @@ func AddUser(c *gin.Context) {
 		})
 		return
 	}
+	services.MarkWaitlistConverted(userRequestBody.Email, existingUser.PhoneNumber, existingUser.UserID)
 	returnedOTP, err := services.CreateEmailOtp(&existingUser, userRequestBody.Email)
 	defer cancel()
 	if err != nil {
@@ -289,24 +353,16 @@ func EmailVerification(c *gin.Context) {
 		return
 	}
 
-	// Find OTP by token
-	var otp database.Otp
-	if err := database.DB.Where("token = ?", emailVerificationCodeRequest.Code).First(&otp).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":         true,
-			"response code": 400,
-			"message":       "OTP not valid",
-			"data":          "",
-		})
-		return
-	}
-
-	// Check if OTP has expired
-	if time.Now().After(otp.ExpiresAt) {
+	// Check the OTP issued to this email address
+	if err := services.VerifyOTPCode(
+		services.OTPPurposeEmailVerification,
+		emailVerificationCodeRequest.Email,
+		emailVerificationCodeRequest.Code,
+	); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
 			"response code": 400,
-			"message":       "OTP has expired",
+			"message":       otpErrorMessage(err),
 			"data":          "",
 		})
 		return
@@ -406,6 +462,8 @@ func VerifyBVN(c *gin.Context) {
 		return
 	}
 
+	services.EvaluateReferral(user.UserID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"error":         false,
 		"response code": 200,
@@ -414,84 +472,6 @@ func VerifyBVN(c *gin.Context) {
 	})
 }
 
-func CallBack(c *gin.Context) {
-	var callbackData structs.CallbackData
-	// if err := c.BindJSON(&callbackData); err != nil {
-	// 	log.Println("Error binding callback data:", err)
-	// 	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid callback data"})
-	// 	return
-	// }
-
-	response, err := services.Callback()
-
-	err = json.Unmarshal([]byte(response), &callbackData)
-	if err != nil {
-		log.Println("Error:", err)
-		return
-	}
-
-	// Process the callback data
-	fmt.Println("Received callback:", callbackData)
-	fmt.Println("Title:", callbackData.Title)
-	fmt.Println("Message:", callbackData.Message)
-	fmt.Println("NUBAN Name:", callbackData.Data.NUBANName)
-	fmt.Println("NUBAN:", callbackData.Data.NUBAN)
-	fmt.Println("NUBAN Status:", callbackData.Data.NUBANStatus)
-	fmt.Println("NUBAN Type:", callbackData.Data.NUBANType)
-	fmt.Println("Request:", callbackData.Data.Request)
-
-	// Handle different types of requests
-	switch callbackData.Data.Request {
-	case 1:
-		fmt.Println("Request type: Wallet Creation")
-		// Handle wallet creation request
-		// Update your system with the newly generated wallet information
-	case 2:
-		fmt.Println("Request type: Account Creation")
-		// Handle account creation request
-	case 3:
-		fmt.Println("Request type: Pin Validation")
-		// Handle pin validation request
-	case 4:
-		fmt.Println("Request type: Payment Response")
-		// Handle payment response request
-	default:
-		fmt.Println("Unknown request type")
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Callback processed successfully"})
-}
-
-func GenerateWallet(c *gin.Context) {
-
-	var (
-		walletData       structs.WalletRequest
-		generateResponse structs.GenerateWalletResponse
-	)
-	if err := c.BindJSON(&walletData); err != nil {
-		log.Println("Error binding callback data:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid callback data"})
-		return
-	}
-
-	response, err := services.GenerateWallet(
-		walletData.Gender,
-		walletData.Email,
-		walletData.FirstName,
-		walletData.LastName,
-		walletData.Dob,
-		walletData.PhoneNumber,
-	)
-
-	err = json.Unmarshal([]byte(response), &generateResponse)
-	if err != nil {
-		log.Println("Error:", err)
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"data": generateResponse})
-}
-
 func CreateTag(c *gin.Context) {
 	var (
 		tagRequest structs.TagRequest
@@ -568,24 +548,6 @@ func CreateTag(c *gin.Context) {
 		})
 		return
 	}
-	generatedAccountNumber := helpers.GenerateRandomAccountNumber()
-	// Create a wallet for the user in the VirtualAccount table
-	virtualAccount := database.VirtualAccount{
-		VirtualAccountBank:    "Guaranty Trust Bank",
-		VirtualAccountAccount: generatedAccountNumber,
-		VirtualAccountName:    user.FirstName + " " + user.LastName,
-		UserID:                user.UserID,
-		Balance:               0, // Default balance
-	}
-	if err := database.DB.Create(&virtualAccount).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":         true,
-			"response code": 500,
-			"message":       "Failed to create virtual account",
-			"data":          "",
-		})
-		return
-	}
 	// Update the user's records in the user table
 	user.Tag = tagRequest.Tag
 	user.SignupLevel = 4
@@ -600,12 +562,19 @@ func CreateTag(c *gin.Context) {
 		return
 	}
 
+	// The account number arrives on the provider's callback; the app polls
+	// the wallet status until it does
+	provisioning, err := services.ProvisionUserWallet(&user)
+	if err != nil {
+		log.Println("Error provisioning wallet:", err)
+	}
+
 	// Respond with success message
 	c.JSON(http.StatusOK, gin.H{
 		"error":         false,
 		"response code": 200,
 		"message":       "Tag created successfully",
-		"data":          "",
+		"data":          gin.H{"wallet": provisioning},
 	})
 }
 
@@ -700,9 +669,8 @@ func CreatePasscode(c *gin.Context) {
 
 func LoginRequest(c *gin.Context) {
 	var (
-		user             database.User
-		loginRequest     structs.LoginRequestBody
-		phoneOTPResponse structs.PhoneOTPResponse
+		user         database.User
+		loginRequest structs.LoginRequestBody
 	)
 	if err := c.BindJSON(&loginRequest); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -733,7 +701,13 @@ func LoginRequest(c *gin.Context) {
 		})
 		return
 	}
-	phoneOTP, err := services.SendPhoneOTP(loginRequest.PhoneNumber)
+	phoneOTP, err := services.IssueOTP(
+		services.OTPPurposeLogin,
+		loginRequest.Channel,
+		loginRequest.PhoneNumber,
+		user.Language,
+		user.UserID,
+	)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
@@ -743,16 +717,11 @@ func LoginRequest(c *gin.Context) {
 		})
 		return
 	}
-	e := json.Unmarshal([]byte(phoneOTP), &phoneOTPResponse)
-	if e != nil {
-		log.Println("Error:", e)
-		return
-	}
 	c.JSON(http.StatusOK, gin.H{
 		"error":         false,
 		"response code": 200,
 		"message":       "OTP sent successfully",
-		"data":          phoneOTPResponse,
+		"data":          phoneOTP,
 	})
 
 }
@@ -762,7 +731,6 @@ func VerifyLoginRequest(c *gin.Context) {
 	var (
 		user                      database.User
 		verifyPhoneOTPRequestBody structs.VerifyPhoneOTPRequestBody
-		verifyPhoneOTPBody        structs.VerifyPhoneOTPBody
 	)
 	if err := c.BindJSON(&verifyPhoneOTPRequestBody); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err})
@@ -779,40 +747,15 @@ func VerifyLoginRequest(c *gin.Context) {
 		})
 		return
 	}
-	verifyPhoneOTP, err := services.VerifyOTP(
-		verifyPhoneOTPRequestBody.PinId,
+	if err := services.VerifyOTPCode(
+		services.OTPPurposeLogin,
+		verifyPhoneOTPRequestBody.Phone,
 		verifyPhoneOTPRequestBody.Pin,
-	)
-	if err != nil {
+	); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":         true,
 			"response code": 400,
-			"message":       "Somehting went wrong. Please try again",
-			"data":          "",
-		})
-		return
-	}
-	fmt.Println("verifyPhoneOTP", verifyPhoneOTP)
-	e := json.Unmarshal([]byte(verifyPhoneOTP), &verifyPhoneOTPBody)
-	if e != nil {
-		log.Println("Error:", e)
-		return
-	}
-	if verifyPhoneOTPBody.Status == 400 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":         true,
-			"response code": 400,
-			"message":       "Token expired.",
-			"data":          "",
-		})
-		return
-	}
-	fmt.Println("verifyPhoneOTPBody", verifyPhoneOTPBody)
-	if !verifyPhoneOTPBody.Verified {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":         true,
-			"response code": 400,
-			"message":       "Invalid token",
+			"message":       otpErrorMessage(err),
 			"data":          "",
 		})
 		return
@@ -876,6 +819,13 @@ func VerifyLoginRequest(c *gin.Context) {
 		Token:    tokenString,
 	}
 	database.DB.Create(&deviceTokenMapping)
+	if err := services.Dispatch(database.DB, &user, services.Notice{
+		Type:  services.NotificationSecurity,
+		Event: templates.EventNewLogin,
+		Data:  templates.Data{"Date": time.Now()},
+	}); err != nil {
+		log.Println("Error adding sign-in notification:", err)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"error":         false,
 		"response code": 200,
@@ -935,7 +885,4 @@ func UserRoutes(rg *gin.RouterGroup) {
 	userRoute.POST("/verify-login", VerifyLoginRequest)
 	userRoute.POST("/search", Search)
 
-	userRoute.POST("/callback", CallBack)
-	userRoute.POST("/generate-wallet", GenerateWallet)
-
 }
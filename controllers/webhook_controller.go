@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"olra-v1/internal/database"
+	"olra-v1/middleware"
+	"olra-v1/services"
+)
+
+// Bodies larger than this are not callbacks we know about
+const maxWebhookBody = 1 << 20
+
+func WemaWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Could not read callback body",
+			"data":          "",
+		})
+		return
+	}
+	headers := services.WebhookHeaders{
+		Signature: c.GetHeader("X-Signature"),
+		Secret:    c.GetHeader("X-Webhook-Secret"),
+		Timestamp: c.GetHeader("X-Timestamp"),
+		EventID:   c.GetHeader("X-Event-Id"),
+	}
+	if err := services.VerifyWebhook(body, headers, time.Now()); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         true,
+			"response code": 401,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	}
+
+	event, err := services.ReceiveWebhook("wema", body, headers.EventID)
+	if errors.Is(err, services.ErrWebhookDuplicate) {
+		c.JSON(http.StatusOK, gin.H{
+			"error":         false,
+			"response code": 200,
+			"message":       "Callback already processed",
+			"data":          gin.H{"eventId": event.EventID},
+		})
+		return
+	}
+	if errors.Is(err, services.ErrWebhookInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid callback data",
+			"data":          gin.H{"eventId": event.EventID},
+		})
+		return
+	}
+	if event == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Could not store callback",
+			"data":          "",
+		})
+		return
+	}
+	// The event is stored whatever happened, so a handler failure is
+	// acknowledged and left for replay rather than making the bank retry
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Callback received",
+		"data":          gin.H{"eventId": event.EventID, "status": event.Status},
+	})
+}
+
+func ListWebhookEvents(c *gin.Context) {
+	page, limit := getPagination(c)
+	status := c.DefaultQuery("status", services.WebhookFailed)
+	var events []database.WebhookEvent
+	if err := database.DB.Where("status = ?", status).
+		Order("received_at desc").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         true,
+			"response code": 500,
+			"message":       "Failed to retrieve webhook events",
+			"data":          "",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Webhook events retrieved successfully",
+		"data":          events,
+	})
+}
+
+func ReplayWebhookEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "Invalid webhook event id",
+			"data":          "",
+		})
+		return
+	}
+	event, err := services.ReplayWebhook(uint(id))
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":         true,
+			"response code": 404,
+			"message":       err.Error(),
+			"data":          "",
+		})
+		return
+	case errors.Is(err, services.ErrWebhookDuplicate):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         true,
+			"response code": 409,
+			"message":       err.Error(),
+			"data":          event,
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":         true,
+			"response code": 422,
+			"message":       err.Error(),
+			"data":          event,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"error":         false,
+		"response code": 200,
+		"message":       "Webhook event replayed successfully",
+		"data":          event,
+	})
+}
+
+func WebhookRoutes(rg *gin.RouterGroup) {
+	rg.POST("/webhooks/wema", WemaWebhook)
+	// The callback URL already registered with Wema
+	rg.POST("/user/callback", WemaWebhook)
+
+	adminRoute := rg.Group("/admin/webhooks", middleware.AuthMiddleware, middleware.AdminMiddleware)
+	adminRoute.GET("", ListWebhookEvents)
+	adminRoute.POST("/:id/replay", ReplayWebhookEvent)
+}
@@ -51,6 +51,17 @@ type User struct {
 	Budgets        []Budget
 	DeviceID       string  `gorm:"null"`
 	GroupsAdmin    []Group `gorm:"foreignKey:AdminID"` // Groups where the user is admin
+	Status         string  `gorm:"default:active"`     //active, closed
+	ClosedAt       *time.Time
+	Language       string `gorm:"default:en"` //en, yo, ha, ig, pcm
+	// Quiet hours are "HH:MM" in Lagos time; both empty means none
+	QuietHoursStart     string
+	QuietHoursEnd       string
+	LowBalanceThreshold float64 // 0 turns the alert off
+	// The transaction PIN authorises money leaving the wallet
+	TransactionPinHash string
+	PinAttempts        int
+	PinLockedUntil     *time.Time
 }
 
 // Group struct represents group-related data
@@ -81,14 +92,21 @@ type GroupMember struct {
 type Transaction struct {
 	TransactionID      uint `gorm:"primaryKey"`
 	UserID             uint
+	Reference          string  `gorm:"index"`
 	TransactionEnviron string  `gorm:"not null"` //withinOlra, outsideOlra
-	TransactionType    string  `gorm:"not null"` //request, olraTransfer-out, olraTransfer-in, bankTransfer-out, bankTransfer-in, group-payment
+	TransactionType    string  `gorm:"not null"` //request, olraTransfer-out, olraTransfer-in, bankTransfer-out, bankTransfer-in, group-payment, reversal
 	Amount             float64 `gorm:"not null"`
 	Description        string
 	Requestee          string
 	Receiver           string
 	Sender             string
-	Status             string    `gorm:"default:pending"` //pending, completed
+	SenderBank         string
+	ReceiverAccount    string
+	ReceiverBank       string
+	Fee                float64
+	OriginalReference  string    `gorm:"index"` // the transaction a reversal undoes
+	ReversedAmount     float64   `gorm:"default:0"`
+	Status             string    `gorm:"default:pending"` //pending, completed, reversed
 	TransactionDate    time.Time `gorm:"not null"`
 }
 
@@ -99,16 +117,28 @@ type VirtualAccount struct {
 	VirtualAccountAccount string `gorm:"not null;unique"`
 	VirtualAccountName    string `gorm:"not null"`
 	UserID                uint
-	Balance               float64 `gorm:"default:1000"`
+	Balance               float64 `gorm:"default:0"` // cache of the ledger balance, which starts at zero
 }
 
-// Bank represents the banks table
+// Bank represents the banks table, the accounts at other banks a user has
+// saved as beneficiaries. Different users may save the same account.
 type Bank struct {
 	BankID        uint   `gorm:"primaryKey"`
 	BankName      string `gorm:"not null"`
-	AccountNumber string `gorm:"not null;unique"`
+	BankCode      string `gorm:"not null;uniqueIndex:idx_beneficiary"`
+	AccountNumber string `gorm:"not null;uniqueIndex:idx_beneficiary"`
 	AccountName   string `gorm:"not null"`
-	UserID        uint
+	Nickname      string
+	UserID        uint      `gorm:"uniqueIndex:idx_beneficiary"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
+
+// BankDirectory lists the banks transfers can be sent to, keyed by the code
+// used for name enquiry and NIP
+type BankDirectory struct {
+	Code string `gorm:"primaryKey"`
+	Name string `gorm:"not null;index"`
+	Type string // commercial, merchant, non-interest, microfinance, fintech
 }
 
 // GroupVirtualAccount represents the group_virtual_accounts table
@@ -133,11 +163,21 @@ type Budget struct {
 	EndDate    time.Time `gorm:"not null"`
 }
 
+// Otp is a one-time code scoped to a purpose and recipient. Only a hash of the
+// code is kept; codes issued through a provider's pin API keep its pin ID instead.
 type Otp struct {
-	ID        uint `gorm:"primaryKey"`
-	UserID    uint
-	Token     string    `gorm:"not null"`
-	ExpiresAt time.Time `gorm:"not null"`
+	ID            uint `gorm:"primaryKey"`
+	UserID        uint
+	Purpose       string `gorm:"not null;index:idx_otp_lookup"` //phone-signup, login, email-verification
+	Recipient     string `gorm:"not null;index:idx_otp_lookup"`
+	Channel       string `gorm:"not null"` //sms, whatsapp, email
+	CodeHash      string
+	ProviderPinID string
+	Attempts      int
+	MaxAttempts   int       `gorm:"not null"`
+	ExpiresAt     time.Time `gorm:"not null"`
+	UsedAt        *time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
 }
 
 type DeviceTokenMapping struct {
@@ -145,20 +185,429 @@ type DeviceTokenMapping struct {
 	Token    string
 }
 
+// PushToken is the FCM or APNs token of a signed-in device. Unlike
+// DeviceTokenMapping, which holds the session JWT, it is used to reach the device.
+type PushToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	DeviceID  string    `gorm:"not null;index"`
+	Platform  string    `gorm:"not null"` //android, ios
+	Token     string    `gorm:"not null;unique"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
 type Waitlist struct {
-	ID       uint
-	FullName string
-	Email    string
-	Phone    string
+	ID              uint
+	FullName        string
+	Email           string
+	Phone           string
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+	InvitedAt       *time.Time
+	ConvertedUserID *uint
+	ConvertedAt     *time.Time
+}
+
+// Invite is a single-use code that lets a waitlisted person sign up
+type Invite struct {
+	ID           uint   `gorm:"primaryKey"`
+	Code         string `gorm:"not null;unique"`
+	WaitlistID   uint
+	Email        string
+	Phone        string
+	ExpiresAt    time.Time `gorm:"not null"`
+	UsedAt       *time.Time
+	UsedByUserID *uint
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
 }
 
+// Contact is a message from the contact form or the app, handled as a support ticket
 type Contact struct {
-	ID        uint
-	FirstName string
-	LastName  string
-	Email     string
-	Phone     string
-	Message   string
+	ID                   uint
+	FirstName            string
+	LastName             string
+	Email                string
+	Phone                string
+	Message              string
+	UserID               *uint
+	TransactionReference string
+	Status               string `gorm:"default:open"` //open, pending, resolved
+	AssigneeID           *uint
+	CreatedAt            time.Time `gorm:"autoCreateTime"`
+	UpdatedAt            time.Time `gorm:"autoUpdateTime"`
+	Messages             []TicketMessage
+}
+
+// TicketMessage is a reply or an internal note on a support ticket
+type TicketMessage struct {
+	ID        uint `gorm:"primaryKey"`
+	ContactID uint `gorm:"not null;index"`
+	AuthorID  *uint
+	Author    string `gorm:"not null"` //customer, staff
+	Body      string `gorm:"not null"`
+	Internal  bool
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// LedgerEntry is one leg of a journal. The legs sharing a Reference always sum to zero.
+type LedgerEntry struct {
+	EntryID     uint   `gorm:"primaryKey"`
+	Reference   string `gorm:"not null;index"`
+	Account     string `gorm:"not null;index"` // wallet:<id>, group:<id>, system:<name>
+	Amount      int64  `gorm:"not null"`       // kobo, credits are positive and debits negative
+	Description string
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+// Referral links a referrer to the user who signed up with their code
+type Referral struct {
+	ReferralID        uint `gorm:"primaryKey"`
+	ReferrerID        uint `gorm:"not null;index"`
+	RefereeID         uint `gorm:"not null;unique"`
+	Code              string
+	Status            string `gorm:"default:pending"` //pending, rewarded, capped, rejected
+	ReferrerReward    float64
+	RefereeReward     float64
+	Reference         string    // journal paying the referee
+	ReferrerReference string    // journal paying the referrer; empty when capped
+	CreatedAt         time.Time `gorm:"autoCreateTime"`
+	RewardedAt        *time.Time
+}
+
+// DataRequest records a data-subject request made under the NDPR
+type DataRequest struct {
+	ID          uint `gorm:"primaryKey"`
+	UserID      uint
+	Type        string `gorm:"not null"`          //export, deletion
+	Status      string `gorm:"default:completed"` //completed, rejected
+	Reason      string
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	CompletedAt *time.Time
+}
+
+// OutboxMessage is a notification written in the same transaction as the
+// change it reports on and delivered afterwards by the dispatcher
+type OutboxMessage struct {
+	ID            uint   `gorm:"primaryKey"`
+	Channel       string `gorm:"not null"` //sms, email
+	Event         string `gorm:"not null"`
+	Locale        string
+	Recipient     string `gorm:"not null"`
+	Payload       string // JSON template data
+	Reference     string `gorm:"index"`
+	Status        string `gorm:"default:pending;index"` //pending, sent, dead
+	Attempts      int
+	MaxAttempts   int
+	NextAttemptAt time.Time `gorm:"index"`
+	LastError     string
+	SentAt        *time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+// Notification is an entry in a user's in-app inbox. ResourceType and
+// ResourceID tell the app what to open when it is tapped.
+type Notification struct {
+	ID           uint   `gorm:"primaryKey"`
+	UserID       uint   `gorm:"not null;index"`
+	Type         string `gorm:"not null"` //credit, debit, payment-request, group, security
+	Event        string
+	Title        string
+	Body         string
+	ResourceType string //transaction, request, group
+	ResourceID   string // transaction reference or group id
+	ReadAt       *time.Time
+	CreatedAt    time.Time `gorm:"autoCreateTime;index"`
+}
+
+// NotificationPreference turns one channel on or off for one type of
+// notification. Types and channels without a row use the defaults.
+type NotificationPreference struct {
+	ID      uint   `gorm:"primaryKey"`
+	UserID  uint   `gorm:"not null;uniqueIndex:idx_preference"`
+	Type    string `gorm:"not null;uniqueIndex:idx_preference"` //credit, debit, request, group, security, marketing
+	Channel string `gorm:"not null;uniqueIndex:idx_preference"` //sms, email, push, in-app
+	Enabled bool
+}
+
+// EmailLog records every attempt to send an email
+type EmailLog struct {
+	ID        uint   `gorm:"primaryKey"`
+	Recipient string `gorm:"not null;index"`
+	Subject   string
+	Provider  string
+	Status    string `gorm:"not null"` //sent, failed, bounced, rate-limited
+	Error     string
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+// WebhookEvent is the raw body of a callback from a banking partner, kept
+// whether or not it could be processed
+type WebhookEvent struct {
+	ID          uint   `gorm:"primaryKey"`
+	Provider    string `gorm:"not null;uniqueIndex:idx_webhook_event"`
+	EventID     string `gorm:"not null;uniqueIndex:idx_webhook_event"`
+	Request     int
+	Payload     string `gorm:"not null"`
+	Status      string `gorm:"default:received;index"` //received, processed, failed
+	Error       string
+	ReceivedAt  time.Time `gorm:"autoCreateTime"`
+	ProcessedAt *time.Time
+}
+
+// InboundCredit is a transfer from another bank into an Olra account number.
+// Credits that match no wallet stay in suspense until ops resolve them.
+type InboundCredit struct {
+	ID                   uint    `gorm:"primaryKey"`
+	Provider             string  `gorm:"not null;uniqueIndex:idx_inbound_session"`
+	SessionID            string  `gorm:"not null;uniqueIndex:idx_inbound_session"`
+	AccountNumber        string  `gorm:"not null;index"`
+	Amount               float64 `gorm:"not null"`
+	SenderName           string
+	SenderBank           string
+	SenderAccount        string
+	Narration            string
+	Status               string `gorm:"default:pending;index"` //pending, credited, suspense, resolved
+	Reference            string // ledger journal that moved the funds
+	TransactionReference string
+	CreatedAt            time.Time `gorm:"autoCreateTime"`
+	UpdatedAt            time.Time `gorm:"autoUpdateTime"`
+}
+
+// AccountProvisioning tracks a request to the banking provider for a wallet
+// account number. The wallet row is only created once the provider confirms.
+type AccountProvisioning struct {
+	ID            uint   `gorm:"primaryKey"`
+	Provider      string `gorm:"not null"`
+	Reference     string `gorm:"not null;unique"`
+	OwnerType     string `gorm:"not null;index:idx_provisioning_owner"` //user, group
+	OwnerID       uint   `gorm:"not null;index:idx_provisioning_owner"`
+	AccountName   string `gorm:"not null"`
+	PhoneNumber   string `gorm:"index"`
+	Email         string
+	Status        string `gorm:"default:pending;index"` //pending, active, failed, closed
+	AccountNumber string
+	Bank          string
+	Error         string
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+// BankTransfer is a withdrawal to an account at another bank. The amount and
+// fee are held on the wallet until the provider reports the outcome.
+type BankTransfer struct {
+	ID                uint   `gorm:"primaryKey"`
+	UserID            uint   `gorm:"not null;index"`
+	Reference         string `gorm:"not null;unique"` // also the Transaction reference
+	Provider          string `gorm:"not null"`
+	ProviderReference string
+	AccountNumber     string  `gorm:"not null"`
+	BankCode          string  `gorm:"not null"`
+	AccountName       string  `gorm:"not null"`
+	Amount            float64 `gorm:"not null"`
+	Fee               float64
+	Narration         string
+	Status            string `gorm:"default:pending;index"` //pending, successful, reversed
+	Error             string
+	CompletedAt       *time.Time
+	CreatedAt         time.Time `gorm:"autoCreateTime"`
+	UpdatedAt         time.Time `gorm:"autoUpdateTime"`
+}
+
+// NubanSequence hands out account number serials for a bank code in order, so
+// numbers we issue are never picked at random and never repeat
+type NubanSequence struct {
+	BankCode   string `gorm:"primaryKey"`
+	LastSerial uint64 `gorm:"not null;default:0"`
+}
+
+// PricingRule is one way of charging for a transaction type. A rule can be
+// narrowed to a KYC tier, a channel and an amount band, and only applies
+// between its effective dates.
+type PricingRule struct {
+	ID              uint      `gorm:"primaryKey"`
+	Name            string    `gorm:"not null"`
+	TransactionType string    `gorm:"not null;index"` // olraTransfer-out, group-payment, bankTransfer-out
+	Tier            int       `gorm:"default:0"`      // 0 for every tier
+	Channel         string    // app, web, ussd; empty for every channel
+	MinAmount       float64   `gorm:"default:0"`
+	MaxAmount       float64   `gorm:"default:0"` // 0 for no upper bound
+	FeeType         string    `gorm:"not null"`  // flat, percentage
+	FlatFee         float64   `gorm:"default:0"`
+	Percentage      float64   `gorm:"default:0"`
+	Cap             float64   `gorm:"default:0"` // 0 for uncapped
+	FreePerMonth    int       `gorm:"default:0"` // transactions each month before the fee applies
+	Priority        int       `gorm:"default:0"`
+	EffectiveFrom   time.Time `gorm:"not null;index"`
+	EffectiveTo     *time.Time
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime"`
+}
+
+// Hold reserves part of a wallet's balance without moving it. Amounts are in
+// kobo; Captured grows as the hold is taken in parts.
+type Hold struct {
+	ID               uint       `gorm:"primaryKey"`
+	VirtualAccountID uint       `gorm:"not null;index"`
+	Reference        string     `gorm:"not null;unique"`
+	Reason           string     `gorm:"not null"` // bank-withdrawal, dispute
+	Amount           int64      `gorm:"not null"`
+	Captured         int64      `gorm:"default:0"`
+	Status           string     `gorm:"not null;index"` // active, captured, released, expired
+	ExpiresAt        *time.Time `gorm:"index"`
+	CreatedAt        time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt        time.Time  `gorm:"autoUpdateTime"`
+}
+
+// Reversal undoes all or part of a completed transaction with compensating
+// ledger entries. Above the approval threshold it waits for a second member of
+// staff before anything is posted.
+type Reversal struct {
+	ID                uint    `gorm:"primaryKey"`
+	Reference         string  `gorm:"not null;unique"` // journal and Transaction reference of the reversal
+	OriginalReference string  `gorm:"not null;index"`
+	Amount            float64 `gorm:"not null"`
+	FeeRefunded       float64 `gorm:"default:0"`
+	Reason            string  `gorm:"not null"`
+	Status            string  `gorm:"not null;index"` // pending-approval, completed, rejected
+	RequestedBy       uint    `gorm:"not null"`
+	ReviewedBy        *uint
+	CreatedAt         time.Time `gorm:"autoCreateTime"`
+	CompletedAt       *time.Time
+}
+
+// Dispute is a user's claim that a transaction they sent went wrong. The
+// counterparty is the owner of the wallet that received the money, when it
+// went to one.
+type Dispute struct {
+	ID                   uint    `gorm:"primaryKey"`
+	TransactionReference string  `gorm:"not null;index"`
+	OpenedBy             uint    `gorm:"not null;index"`
+	CounterpartyID       *uint   `gorm:"index"`
+	Amount               float64 `gorm:"not null"`
+	Reason               string  `gorm:"not null"` // wrong-recipient, not-received, unauthorised, other
+	Description          string
+	Status               string `gorm:"not null;index"` // open, under-review, awaiting-counterparty, resolved-opener, resolved-counterparty
+	HoldReference        string // hold on the counterparty's wallet while the dispute is open
+	ReversalID           *uint
+	Resolution           string
+	ResolvedBy           *uint
+	RespondBy            *time.Time // when the counterparty's answer is due
+	ResolveBy            time.Time  `gorm:"not null"`
+	EscalatedAt          *time.Time // when it was flagged for not being decided by ResolveBy
+	CreatedAt            time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt            time.Time  `gorm:"autoUpdateTime"`
+	ResolvedAt           *time.Time
+	Evidence             []DisputeEvidence
+}
+
+// DisputeEvidence is a statement or file either party or staff added to a
+// dispute. Files are kept under DISPUTE_EVIDENCE_DIR.
+type DisputeEvidence struct {
+	ID          uint   `gorm:"primaryKey"`
+	DisputeID   uint   `gorm:"not null;index"`
+	UploadedBy  uint   `gorm:"not null"`
+	Party       string `gorm:"not null"` // opener, counterparty, staff
+	Note        string
+	FileName    string
+	ContentType string
+	Size        int64
+	Path        string
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+// ReconciliationReport is one run matching the banking provider's statement
+// for a day against the bank settlement account in the ledger
+type ReconciliationReport struct {
+	ID             uint      `gorm:"primaryKey"`
+	Provider       string    `gorm:"not null;index"`
+	StatementDate  time.Time `gorm:"not null;index"` // start of the Lagos day reconciled
+	Source         string    `gorm:"not null"`       // csv, json, api
+	Matched        int
+	MissingOurs    int     // on the statement but not in our ledger
+	MissingTheirs  int     // in our ledger but not on the statement
+	AmountMismatch int     // on both with different amounts or directions
+	StatementNet   float64 // credits less debits
+	LedgerNet      float64
+	Status         string `gorm:"not null;index"` // balanced, open, reviewed
+	CreatedBy      *uint  // nil when the daily job ran it
+	ReviewedBy     *uint
+	ReviewedAt     *time.Time
+	CreatedAt      time.Time            `gorm:"autoCreateTime"`
+	Items          []ReconciliationItem `gorm:"foreignKey:ReportID"`
+}
+
+// ReconciliationItem is a statement line, a ledger entry or a pair of them
+type ReconciliationItem struct {
+	ID              uint   `gorm:"primaryKey"`
+	ReportID        uint   `gorm:"not null;index"`
+	Status          string `gorm:"not null;index"` // matched, missing-ours, missing-theirs, amount-mismatch
+	Reference       string `gorm:"index"`          // as the bank knows it
+	LedgerReference string
+	Direction       string // credit, debit, as the bank sees it
+	StatementAmount float64
+	LedgerAmount    float64
+	Narration       string
+	Note            string
+	ResolvedBy      *uint
+	ResolvedAt      *time.Time
+}
+
+// IntegrityReport is one run of the ledger integrity checks. Counts cover
+// every finding; only the first findings of a run are kept as rows.
+type IntegrityReport struct {
+	ID                 uint       `gorm:"primaryKey"`
+	Since              *time.Time // journals and transfers before this were not checked
+	Status             string     `gorm:"not null;index"` // passed, failed
+	JournalsChecked    int
+	TransfersChecked   int
+	AccountsChecked    int
+	UnbalancedJournals int
+	UnmatchedTransfers int
+	NegativeBalances   int
+	BalanceDrifts      int // wallets whose cached balance differs from their ledger
+	CustomerFunds      float64
+	OmnibusBalance     float64
+	OmnibusSource      string // provider, ledger
+	OmnibusDifference  float64
+	OmnibusMismatch    bool               // the difference is beyond INTEGRITY_OMNIBUS_TOLERANCE
+	StartedAt          time.Time          `gorm:"not null"`
+	CompletedAt        time.Time          `gorm:"not null;index"`
+	Findings           []IntegrityFinding `gorm:"foreignKey:ReportID"`
+}
+
+type IntegrityFinding struct {
+	ID       uint   `gorm:"primaryKey"`
+	ReportID uint   `gorm:"not null;index"`
+	Check    string `gorm:"not null;index"` // unbalanced-journal, unmatched-transfer, negative-balance, balance-drift, omnibus-mismatch
+	Subject  string // journal reference or ledger account
+	Expected float64
+	Actual   float64
+	Detail   string
+}
+
+// AccountStatement records a statement issued for a wallet or group wallet so
+// that a third party holding the document can check it against us
+type AccountStatement struct {
+	ID             uint   `gorm:"primaryKey"`
+	Code           string `gorm:"not null;unique"` // printed on the statement for verification
+	OwnerType      string `gorm:"not null;index"`  // user, group
+	OwnerID        uint   `gorm:"not null;index"`
+	Account        string `gorm:"not null"` // ledger account
+	AccountNumber  string `gorm:"not null"`
+	AccountName    string `gorm:"not null"`
+	Bank           string
+	RequestedBy    uint      `gorm:"not null"`
+	Format         string    `gorm:"not null"` // csv, pdf
+	PeriodStart    time.Time `gorm:"not null"`
+	PeriodEnd      time.Time `gorm:"not null"`
+	OpeningBalance float64
+	ClosingBalance float64
+	TotalCredits   float64
+	TotalDebits    float64
+	Entries        int
+	Hash           string    `gorm:"not null"` // HMAC-SHA256 of the statement contents
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
 }
 
 // type DeviceSession struct {
@@ -184,6 +633,10 @@ func New() Service {
 	// Uncomment the line below if you want to log SQL statements
 	// db = db.Debug()
 
+	if err := Migrate(DB, migrations...); err != nil {
+		log.Fatal(err)
+	}
+
 	// Automatically create or modify tables based on the struct definitions
 	// DB.AutoMigrate(
 	// 	&User{},
@@ -198,6 +651,32 @@ func New() Service {
 	// 	&DeviceTokenMapping{},
 	// 	&Waitlist{},
 	// 	&Contact{},
+	// 	&DataRequest{},
+	// 	&LedgerEntry{},
+	// 	&Referral{},
+	// 	&Invite{},
+	// 	&TicketMessage{},
+	// 	&OutboxMessage{},
+	// 	&Notification{},
+	// 	&PushToken{},
+	// 	&NotificationPreference{},
+	// 	&EmailLog{},
+	// 	&WebhookEvent{},
+	// 	&InboundCredit{},
+	// 	&AccountProvisioning{},
+	// 	&BankTransfer{},
+	// 	&BankDirectory{},
+	// 	&NubanSequence{},
+	// 	&PricingRule{},
+	// 	&Hold{},
+	// 	&Reversal{},
+	// 	&Dispute{},
+	// 	&DisputeEvidence{},
+	// 	&ReconciliationReport{},
+	// 	&ReconciliationItem{},
+	// 	&IntegrityReport{},
+	// 	&IntegrityFinding{},
+	// 	&AccountStatement{},
 	// )
 
 	s := &service{db: DB}
@@ -0,0 +1,6 @@
+package database
+
+// Migrations exposes the migrations New applies to the external tests
+func Migrations() []Migration {
+	return migrations
+}
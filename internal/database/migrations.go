@@ -0,0 +1,82 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SchemaMigration records a migration that has been applied
+type SchemaMigration struct {
+	Name      string    `gorm:"primaryKey"`
+	AppliedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Migration changes existing tables or data once. Run is given the
+// transaction that records it, so a migration that fails is tried again on the
+// next start.
+type Migration struct {
+	Name string
+	Run  func(tx *gorm.DB) error
+}
+
+// migrations are applied by New, oldest first. Never edit one that has
+// shipped; add another.
+var migrations = []Migration{
+	{Name: "beneficiary-index", Run: migrateBeneficiaries},
+}
+
+// Migrate applies the migrations that have not been applied yet. Instances
+// starting together wait on each other's record, so each migration runs once.
+func Migrate(db *gorm.DB, migrations ...Migration) error {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&SchemaMigration{Name: migration.Name})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			return migration.Run(tx)
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// migrateBeneficiaries lets different users save the same account: the unique
+// account number on banks becomes unique per user and bank code
+func migrateBeneficiaries(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if !migrator.HasTable(&Bank{}) {
+		return nil
+	}
+	// Saved before bank codes were recorded; they are filled in as the
+	// accounts are saved again
+	if !migrator.HasColumn(&Bank{}, "BankCode") {
+		if err := tx.Exec("ALTER TABLE banks ADD COLUMN bank_code text NOT NULL DEFAULT ''").Error; err != nil {
+			return err
+		}
+	}
+	for _, field := range []string{"Nickname", "CreatedAt"} {
+		if !migrator.HasColumn(&Bank{}, field) {
+			if err := migrator.AddColumn(&Bank{}, field); err != nil {
+				return err
+			}
+		}
+	}
+	if migrator.HasConstraint(&Bank{}, "banks_account_number_key") {
+		if err := migrator.DropConstraint(&Bank{}, "banks_account_number_key"); err != nil {
+			return err
+		}
+	}
+	if !migrator.HasIndex(&Bank{}, "idx_beneficiary") {
+		return migrator.CreateIndex(&Bank{}, "idx_beneficiary")
+	}
+	return nil
+}
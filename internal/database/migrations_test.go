@@ -0,0 +1,57 @@
+package database_test
+
+import (
+	"testing"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+func TestMigrateBeneficiaries(t *testing.T) {
+	db := dbtest.Open(t)
+	// The banks table as it was before beneficiaries, one account per number
+	if err := db.Exec(`CREATE TABLE banks (
+		bank_id integer PRIMARY KEY,
+		bank_name text NOT NULL,
+		account_number text NOT NULL,
+		account_name text NOT NULL,
+		user_id integer,
+		CONSTRAINT banks_account_number_key UNIQUE (account_number)
+	)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Migrator().DropTable("banks", &database.SchemaMigration{}) })
+	if err := db.Exec(`INSERT INTO banks (bank_name, account_number, account_name, user_id)
+		VALUES ('GTBank', '0123456789', 'Ada Obi', 1)`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := database.Migrate(db, database.Migrations()...); err != nil {
+			t.Fatalf("Migrate() run %d error = %v", i+1, err)
+		}
+	}
+	migrator := db.Migrator()
+	if migrator.HasConstraint(&database.Bank{}, "banks_account_number_key") {
+		t.Errorf("banks_account_number_key was not dropped")
+	}
+	if !migrator.HasIndex(&database.Bank{}, "idx_beneficiary") {
+		t.Errorf("idx_beneficiary was not created")
+	}
+
+	save := func(userID uint) error {
+		return db.Create(&database.Bank{BankName: "GTBank", BankCode: "058", AccountNumber: "0123456789",
+			AccountName: "Ada Obi", UserID: userID}).Error
+	}
+	if err := save(2); err != nil {
+		t.Errorf("another user saving the account error = %v", err)
+	}
+	if err := save(2); err == nil {
+		t.Errorf("the same user saved the account twice")
+	}
+	var applied int64
+	db.Model(&database.SchemaMigration{}).Count(&applied)
+	if applied != int64(len(database.Migrations())) {
+		t.Errorf("%d migrations recorded, want %d", applied, len(database.Migrations()))
+	}
+}
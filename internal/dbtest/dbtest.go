@@ -0,0 +1,71 @@
+// Package dbtest gives tests a database to run against: the Postgres database
+// in TEST_DATABASE_URL when it is set, otherwise a private in-memory SQLite
+// database, so the tests need no setup. Only the tables a test asks for are
+// created, and database.DB points at it until the test ends.
+package dbtest
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"olra-v1/internal/database"
+)
+
+// Open creates the tables for models and makes the database the one the
+// services use for the rest of the test
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	config := &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+		// Tests create only the tables they use, so a foreign key may have
+		// nothing to point at
+		DisableForeignKeyConstraintWhenMigrating: true,
+	}
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	var (
+		db  *gorm.DB
+		err error
+	)
+	if dsn != "" {
+		db, err = gorm.Open(postgres.Open(dsn), config)
+	} else {
+		name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+		db, err = gorm.Open(sqlite.Open("file:"+name+"?mode=memory"), config)
+	}
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	if dsn == "" {
+		// Every connection to an in-memory database gets its own, so there
+		// must only ever be the one
+		sqlDB.SetMaxOpenConns(1)
+	}
+	// Tables left behind by an earlier run against Postgres are recreated
+	if err := db.Migrator().DropTable(models...); err != nil {
+		t.Fatalf("dropping test tables: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("creating test tables: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		if dsn != "" {
+			db.Migrator().DropTable(models...)
+		}
+		sqlDB.Close()
+	})
+	return db
+}
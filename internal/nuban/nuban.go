@@ -0,0 +1,110 @@
+// Package nuban computes and checks Nigeria Uniform Bank Account Numbers.
+//
+// A NUBAN is a nine digit serial followed by a check digit. The check digit is
+// worked out over the six digit institution code and the serial, weighting the
+// digits 3, 7, 3 in turn and taking ten minus the sum modulo ten. Deposit
+// money banks have three digit CBN codes, padded to six with leading zeros;
+// other financial institutions have five digit codes, prefixed with a 9.
+package nuban
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	SerialLength = 9
+	Length       = SerialLength + 1
+	// MaxSerial is the largest serial that fits in nine digits
+	MaxSerial = 999999999
+)
+
+var (
+	ErrInvalidBankCode = errors.New("bank code must be three, five or six digits")
+	ErrInvalidLength   = errors.New("account number must be ten digits")
+	ErrNotNumeric      = errors.New("account number must only contain digits")
+	ErrCheckDigit      = errors.New("account number is not valid for this bank")
+	ErrSerialRange     = errors.New("serial does not fit in nine digits")
+)
+
+var weights = [3]int{3, 7, 3}
+
+// InstitutionCode returns the six digit code the check digit is computed over
+func InstitutionCode(bankCode string) (string, error) {
+	if !numeric(bankCode) {
+		return "", ErrInvalidBankCode
+	}
+	switch len(bankCode) {
+	case 3:
+		return "000" + bankCode, nil
+	case 5:
+		return "9" + bankCode, nil
+	case 6:
+		return bankCode, nil
+	}
+	return "", ErrInvalidBankCode
+}
+
+// CheckDigit returns the check digit for a nine digit serial at a bank
+func CheckDigit(bankCode, serial string) (byte, error) {
+	institution, err := InstitutionCode(bankCode)
+	if err != nil {
+		return 0, err
+	}
+	if !numeric(serial) {
+		return 0, ErrNotNumeric
+	}
+	if len(serial) != SerialLength {
+		return 0, ErrSerialRange
+	}
+	sum := 0
+	for i, digit := range institution + serial {
+		sum += int(digit-'0') * weights[i%3]
+	}
+	check := (10 - sum%10) % 10
+	return byte('0' + check), nil
+}
+
+// Generate builds the account number for a serial at a bank
+func Generate(bankCode string, serial uint64) (string, error) {
+	if serial > MaxSerial {
+		return "", ErrSerialRange
+	}
+	padded := fmt.Sprintf("%09d", serial)
+	check, err := CheckDigit(bankCode, padded)
+	if err != nil {
+		return "", err
+	}
+	return padded + string(check), nil
+}
+
+// Validate reports whether the account number could have been issued by the
+// bank. It says nothing about whether the account exists.
+func Validate(bankCode, accountNumber string) error {
+	if len(accountNumber) != Length {
+		return ErrInvalidLength
+	}
+	if !numeric(accountNumber) {
+		return ErrNotNumeric
+	}
+	check, err := CheckDigit(bankCode, accountNumber[:SerialLength])
+	if err != nil {
+		return err
+	}
+	if accountNumber[SerialLength] != check {
+		return ErrCheckDigit
+	}
+	return nil
+}
+
+func numeric(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package nuban
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckDigit(t *testing.T) {
+	tests := []struct {
+		name     string
+		bankCode string
+		serial   string
+		want     byte
+		err      error
+	}{
+		{"three digit bank code", "011", "000001457", '9', nil},
+		{"three digit bank code, mixed serial", "058", "012345678", '5', nil},
+		{"ascending serial", "035", "123456789", '9', nil},
+		{"zero serial", "057", "000000000", '4', nil},
+		{"five digit bank code", "50211", "000000001", '9', nil},
+		{"six digit institution code", "999992", "812345678", '7', nil},
+		{"two digit bank code", "11", "000001457", 0, ErrInvalidBankCode},
+		{"four digit bank code", "0110", "000001457", 0, ErrInvalidBankCode},
+		{"non numeric bank code", "0a1", "000001457", 0, ErrInvalidBankCode},
+		{"non numeric serial", "011", "00000145x", 0, ErrNotNumeric},
+		{"short serial", "011", "00001457", 0, ErrSerialRange},
+		{"long serial", "011", "0000001457", 0, ErrSerialRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckDigit(tt.bankCode, tt.serial)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("CheckDigit(%q, %q) error = %v, want %v", tt.bankCode, tt.serial, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("CheckDigit(%q, %q) = %q, want %q", tt.bankCode, tt.serial, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		bankCode      string
+		accountNumber string
+		err           error
+	}{
+		{"valid", "011", "0000014579", nil},
+		{"valid five digit bank code", "50211", "0000000019", nil},
+		{"valid six digit institution code", "999992", "8123456787", nil},
+		{"wrong check digit", "011", "0000014578", ErrCheckDigit},
+		{"valid at another bank only", "058", "0000014579", ErrCheckDigit},
+		{"too short", "011", "000001457", ErrInvalidLength},
+		{"too long", "011", "00000145790", ErrInvalidLength},
+		{"empty", "011", "", ErrInvalidLength},
+		{"non numeric", "011", "000001457a", ErrNotNumeric},
+		{"bad bank code", "0110", "0000014579", ErrInvalidBankCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.bankCode, tt.accountNumber); !errors.Is(err, tt.err) {
+				t.Errorf("Validate(%q, %q) = %v, want %v", tt.bankCode, tt.accountNumber, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		bankCode string
+		serial   uint64
+		want     string
+		err      error
+	}{
+		{"pads the serial", "011", 1457, "0000014579", nil},
+		{"full serial", "035", 123456789, "1234567899", nil},
+		{"zero serial", "057", 0, "0000000004", nil},
+		{"largest serial", "011", MaxSerial, "", nil},
+		{"serial too large", "011", MaxSerial + 1, "", ErrSerialRange},
+		{"bad bank code", "1", 1457, "", ErrInvalidBankCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Generate(tt.bankCode, tt.serial)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Generate(%q, %d) error = %v, want %v", tt.bankCode, tt.serial, err, tt.err)
+			}
+			if tt.want != "" && got != tt.want {
+				t.Errorf("Generate(%q, %d) = %q, want %q", tt.bankCode, tt.serial, got, tt.want)
+			}
+			if err == nil {
+				// Whatever is generated must validate at the same bank
+				if err := Validate(tt.bankCode, got); err != nil {
+					t.Errorf("Validate(%q, %q) = %v", tt.bankCode, got, err)
+				}
+			}
+		})
+	}
+}
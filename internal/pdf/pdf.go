@@ -0,0 +1,229 @@
+// Package pdf writes simple PDF documents: text in the standard Helvetica
+// faces, lines and filled rectangles on A4 pages.
+//
+// The standard fonts are built into every reader, so nothing is embedded and
+// text is limited to the Latin-1 characters of WinAnsiEncoding. Anything
+// outside it is written as a question mark. Coordinates are in points from
+// the top left corner of the page, which is turned into the PDF's bottom left
+// origin on output.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Font is one of the standard faces the package can write
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+// Color is an RGB colour with components from 0 to 1
+type Color struct {
+	R, G, B float64
+}
+
+// RGB builds a colour from 0-255 components
+func RGB(r, g, b uint8) Color {
+	return Color{float64(r) / 255, float64(g) / 255, float64(b) / 255}
+}
+
+var Black = Color{}
+
+// Document is a PDF being built up page by page
+type Document struct {
+	Title    string
+	Author   string
+	Subject  string
+	Keywords string
+	Created  time.Time
+	pages    []*Page
+}
+
+// Page holds the drawing operators for one page
+type Page struct {
+	content bytes.Buffer
+}
+
+func New() *Document {
+	return &Document{Created: time.Now()}
+}
+
+// AddPage starts a new page and returns it for drawing
+func (d *Document) AddPage() *Page {
+	page := &Page{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Text writes s with its baseline at y
+func (p *Page) Text(x, y float64, font Font, size float64, color Color, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s rg %s %s Td (%s) Tj ET\n",
+		font+1, num(size), rgb(color), num(x), num(PageHeight-y), escape(s))
+}
+
+// TextRight writes s so that it ends at x
+func (p *Page) TextRight(x, y float64, font Font, size float64, color Color, s string) {
+	p.Text(x-TextWidth(font, size, s), y, font, size, color, s)
+}
+
+// Rect fills a rectangle whose top left corner is at x, y
+func (p *Page) Rect(x, y, w, h float64, color Color) {
+	fmt.Fprintf(&p.content, "%s rg %s %s %s %s re f\n",
+		rgb(color), num(x), num(PageHeight-y-h), num(w), num(h))
+}
+
+// Line strokes a straight line
+func (p *Page) Line(x1, y1, x2, y2, width float64, color Color) {
+	fmt.Fprintf(&p.content, "%s RG %s w %s %s m %s %s l S\n",
+		rgb(color), num(width), num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+// TextWidth measures s in points
+func TextWidth(font Font, size float64, s string) float64 {
+	widths := &helveticaWidths
+	if font == HelveticaBold {
+		widths = &helveticaBoldWidths
+	}
+	var units int
+	for _, r := range s {
+		if r >= 32 && r <= 126 {
+			units += widths[r-32]
+		} else {
+			units += 556
+		}
+	}
+	return float64(units) * size / 1000
+}
+
+// Fit shortens s with an ellipsis until it is no wider than width
+func Fit(font Font, size, width float64, s string) string {
+	if TextWidth(font, size, s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && TextWidth(font, size, string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimRight(string(runes), " ") + "..."
+}
+
+// WriteTo writes the finished document
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	stream := func(data []byte) error {
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(data); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n",
+			len(offsets), compressed.Len())
+		out.Write(compressed.Bytes())
+		out.WriteString("\nendstream\nendobj\n")
+		return nil
+	}
+
+	pages := d.pages
+	if len(pages) == 0 {
+		pages = []*Page{{}}
+	}
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// 1 catalog, 2 page tree, 3 and 4 fonts, 5 info, then a page and its
+	// content stream for each page
+	const firstPage = 6
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Author (%s) /Subject (%s) /Keywords (%s) /Producer (olra) /CreationDate (%s) >>",
+		escape(d.Title), escape(d.Author), escape(d.Subject), escape(d.Keywords), d.Created.Format("D:20060102150405Z")))
+	for i, page := range pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(PageWidth), num(PageHeight), firstPage+2*i+1,
+		))
+		if err := stream(page.content.Bytes()); err != nil {
+			return 0, err
+		}
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.WriteTo(w)
+}
+
+// escape turns s into the body of a PDF literal string in WinAnsiEncoding
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r <= 126:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func num(f float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", f), "0"), ".")
+}
+
+func rgb(c Color) string {
+	return num(c.R) + " " + num(c.G) + " " + num(c.B)
+}
+
+// Advance widths of the printable ASCII characters, from the Adobe font metrics
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
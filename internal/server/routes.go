@@ -13,10 +13,19 @@ func (s *Server) RegisterRoutes() http.Handler {
 	server = gin.Default()
 	basepath := server.Group("/api/v1")
 	// server.GET()
-	// server.GET("https://wema-alatdev-apimgt.azure-api.net/callback-url/callbackURL", controllers.CallBack)
 
 	controllers.UserRoutes(basepath)
 	controllers.PaymentRoutes(basepath)
 	controllers.GroupRoutes(basepath)
+	controllers.AccountRoutes(basepath)
+	controllers.ReferralRoutes(basepath)
+	controllers.WebRoutes(basepath)
+	controllers.AdminRoutes(basepath)
+	controllers.SupportRoutes(basepath)
+	controllers.NotificationRoutes(basepath)
+	controllers.WebhookRoutes(basepath)
+	controllers.BeneficiaryRoutes(basepath)
+	controllers.DisputeRoutes(basepath)
+	controllers.StatementRoutes(basepath)
 	return server
 }
@@ -2,12 +2,14 @@ package server
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"time"
 
 	"olra-v1/internal/database"
+	"olra-v1/services"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -23,6 +25,30 @@ func NewServer() *http.Server {
 		port: port,
 		db:   database.New(),
 	}
+	// Debits are checked against the ledger, so the money wallets held before
+	// it has to be posted before any request is served
+	if err := database.Migrate(database.DB, database.Migration{
+		Name: "opening-balances",
+		Run:  services.PostOpeningBalances,
+	}); err != nil {
+		log.Fatal(err)
+	}
+	if err := services.SeedBankDirectory(); err != nil {
+		log.Println("Error seeding bank directory:", err)
+	}
+	if err := services.SeedPricingRules(); err != nil {
+		log.Println("Error seeding pricing rules:", err)
+	}
+	if err := services.CheckMailer(); err != nil {
+		log.Fatal(err)
+	}
+	services.StartOutboxDispatcher()
+	services.StartNotificationCleanup()
+	services.StartBankTransferPoller()
+	services.StartHoldExpiry()
+	services.StartDisputeDeadlines()
+	services.StartDailyReconciliation()
+	services.StartIntegrityChecks()
 
 	// Declare Server config
 	server := &http.Server{
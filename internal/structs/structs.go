@@ -1,9 +1,11 @@
 package structs
 
+import "time"
+
 type VerifyPhoneOTPRequestBody struct {
-	PinId string `json:"pinId" validate:"required"`
-	Pin   string `json:"pin" validate:"required"`
-	Phone string `json:"phone" validate:"required"`
+	Pin        string `json:"pin" validate:"required"`
+	Phone      string `json:"phone" validate:"required"`
+	InviteCode string `json:"inviteCode"`
 }
 
 type VerifyPhoneOTPBody struct {
@@ -15,7 +17,9 @@ type VerifyPhoneOTPBody struct {
 }
 
 type PhoneOTPRequestBody struct {
-	Mobile string `json:"mobile" validate:"required"`
+	Mobile     string `json:"mobile" validate:"required"`
+	InviteCode string `json:"inviteCode"`
+	Channel    string `json:"channel" validate:"omitempty,oneof=sms whatsapp"`
 }
 
 type PhoneOTPResponse struct {
@@ -26,10 +30,11 @@ type PhoneOTPResponse struct {
 }
 
 type UserRequestBody struct {
-	FirstName   string `json:"firstName" validate:"required"`
-	LastName    string `json:"lastName" validate:"required"`
-	Email       string `json:"email" validate:"required"`
-	PhoneNumber string `json:"phoneNumber" validate:"required"`
+	FirstName    string `json:"firstName" validate:"required"`
+	LastName     string `json:"lastName" validate:"required"`
+	Email        string `json:"email" validate:"required"`
+	PhoneNumber  string `json:"phoneNumber" validate:"required"`
+	ReferralCode string `json:"referralCode"`
 }
 
 type EmailVerificationCodeRequest struct {
@@ -86,26 +91,24 @@ type Data struct {
 	NUBANStatus string `json:"NUBANStatus"`
 	NUBANType   int    `json:"NUBANType"`
 	Request     int    `json:"Request"`
-}
-
-type WalletRequest struct {
-	Gender      string `json:"gender"`
-	Email       string `json:"email"`
-	FirstName   string `json:"firstName"`
-	LastName    string `json:"lastName"`
-	Dob         string `json:"dob"`
-	PhoneNumber string `json:"phoneNumber"`
-}
-
-type GenerateWalletResponse struct {
-	Successful bool   `json:"Successful"`
-	Message    string `json:"Message"`
+	PhoneNumber string `json:"PhoneNumber"`
+	Email       string `json:"Email"`
+	Reference   string `json:"Reference"`
+	Status      string `json:"Status"`
+	// Inbound transfer notifications
+	SessionID           string  `json:"SessionID"`
+	Amount              float64 `json:"Amount"`
+	SenderName          string  `json:"SenderName"`
+	SenderBank          string  `json:"SenderBank"`
+	SenderAccountNumber string  `json:"SenderAccountNumber"`
+	Narration           string  `json:"Narration"`
 }
 
 type LoginRequestBody struct {
 	PhoneNumber string `json:"phoneNumber"`
 	Passcode    string `json:"passcode"`
 	DeviceID    string `json:"deviceId"`
+	Channel     string `json:"channel" validate:"omitempty,oneof=sms whatsapp"`
 }
 
 type RequestFundsRequest struct {
@@ -115,7 +118,7 @@ type RequestFundsRequest struct {
 }
 
 type SendFundsRequest struct {
-	Amount      float64 `json:"amount"`
+	Amount      float64 `json:"amount" validate:"required,gt=0"`
 	Description string  `json:"description"`
 	Receiver    string  `json:"receiver"`
 }
@@ -161,7 +164,7 @@ type GroupFriendsRequest struct {
 }
 
 type SendGroupFundsRequest struct {
-	Amount      float64 `json:"amount"`
+	Amount      float64 `json:"amount" validate:"required,gt=0"`
 	Description string  `json:"description"`
 	Group       string  `json:"group"`
 }
@@ -179,3 +182,191 @@ type ContactRequest struct {
 	Phone     string `json:"phone"`
 	Message   string `json:"message"`
 }
+
+type CloseAccountRequest struct {
+	Passcode string `json:"passcode" validate:"required"`
+	Reason   string `json:"reason"`
+}
+
+type DataExport struct {
+	GeneratedAt    time.Time             `json:"generatedAt"`
+	Profile        ExportProfile         `json:"profile"`
+	VirtualAccount *ExportVirtualAccount `json:"virtualAccount"`
+	Transactions   []ExportTransaction   `json:"transactions"`
+	Groups         []ExportGroup         `json:"groups"`
+	Budgets        []ExportBudget        `json:"budgets"`
+	Sessions       []ExportSession       `json:"sessions"`
+}
+
+type ExportProfile struct {
+	UserID        uint      `json:"userId"`
+	FirstName     string    `json:"firstName"`
+	LastName      string    `json:"lastName"`
+	Email         string    `json:"email"`
+	PhoneNumber   string    `json:"phoneNumber"`
+	Tag           string    `json:"tag"`
+	RefCode       string    `json:"refCode"`
+	EmailVerified bool      `json:"emailVerified"`
+	PhoneVerified bool      `json:"phoneVerified"`
+	BvnVerified   bool      `json:"bvnVerified"`
+	KycStatus     bool      `json:"kycStatus"`
+	SignupLevel   int       `json:"signupLevel"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type ExportVirtualAccount struct {
+	Bank          string  `json:"bank"`
+	AccountNumber string  `json:"accountNumber"`
+	AccountName   string  `json:"accountName"`
+	Balance       float64 `json:"balance"`
+}
+
+type ExportTransaction struct {
+	TransactionID      uint      `json:"transactionId"`
+	TransactionEnviron string    `json:"transactionEnviron"`
+	TransactionType    string    `json:"transactionType"`
+	Amount             float64   `json:"amount"`
+	Description        string    `json:"description"`
+	Requestee          string    `json:"requestee"`
+	Receiver           string    `json:"receiver"`
+	Sender             string    `json:"sender"`
+	Status             string    `json:"status"`
+	TransactionDate    time.Time `json:"transactionDate"`
+}
+
+type ExportGroup struct {
+	GroupID   uint      `json:"groupId"`
+	GroupName string    `json:"groupName"`
+	GroupTag  string    `json:"groupTag"`
+	Role      string    `json:"role"` //admin, member
+	JoinedAt  time.Time `json:"joinedAt"`
+}
+
+type ExportBudget struct {
+	BudgetName string    `json:"budgetName"`
+	Category   string    `json:"category"`
+	Type       string    `json:"type"`
+	Amount     float64   `json:"amount"`
+	StartDate  time.Time `json:"startDate"`
+	EndDate    time.Time `json:"endDate"`
+}
+
+type ExportSession struct {
+	DeviceID string `json:"deviceId"`
+	Active   bool   `json:"active"`
+}
+
+type InviteWaitlistRequest struct {
+	WaitlistIDs []uint `json:"waitlistIds"`
+	Limit       int    `json:"limit" validate:"omitempty,min=1,max=500"`
+}
+
+type CreateTicketRequest struct {
+	Message              string `json:"message" validate:"required"`
+	TransactionReference string `json:"transactionReference"`
+}
+
+type TicketReplyRequest struct {
+	Body string `json:"body" validate:"required"`
+}
+
+type UpdateTicketRequest struct {
+	Status     string `json:"status" validate:"omitempty,oneof=open pending resolved"`
+	AssigneeID *uint  `json:"assigneeId"`
+}
+
+type UpdateLanguageRequest struct {
+	Language string `json:"language" validate:"required,oneof=en yo ha ig pcm"`
+}
+
+type RegisterPushTokenRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Platform string `json:"platform" validate:"required,oneof=android ios"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences         map[string]map[string]bool `json:"preferences"`
+	QuietHoursStart     *string                    `json:"quietHoursStart"`
+	QuietHoursEnd       *string                    `json:"quietHoursEnd"`
+	LowBalanceThreshold *float64                   `json:"lowBalanceThreshold" validate:"omitempty,min=0"`
+}
+
+type ResolveSuspenseRequest struct {
+	AccountNumber string `json:"accountNumber" validate:"required,numeric,len=10"`
+}
+
+type SetTransactionPinRequest struct {
+	Passcode   string `json:"passcode" validate:"required"`
+	Pin        string `json:"pin" validate:"required,numeric,len=4"`
+	ConfirmPin string `json:"confirmPin" validate:"required,eqfield=Pin"`
+}
+
+type NameEnquiryRequest struct {
+	AccountNumber string `json:"accountNumber" validate:"required,numeric,len=10"`
+	BankCode      string `json:"bankCode" validate:"required,numeric"`
+}
+
+type BankTransferRequest struct {
+	AccountNumber string  `json:"accountNumber" validate:"required,numeric,len=10"`
+	BankCode      string  `json:"bankCode" validate:"required,numeric"`
+	Amount        float64 `json:"amount" validate:"required,gt=0"`
+	Narration     string  `json:"narration" validate:"max=100"`
+	Pin           string  `json:"pin" validate:"required,numeric,len=4"`
+}
+
+type AddBeneficiaryRequest struct {
+	AccountNumber string `json:"accountNumber" validate:"required,numeric,len=10"`
+	BankCode      string `json:"bankCode" validate:"required,numeric"`
+	Nickname      string `json:"nickname" validate:"max=50"`
+}
+
+type UpdateBeneficiaryRequest struct {
+	Nickname string `json:"nickname" validate:"max=50"`
+}
+
+type PricingRuleRequest struct {
+	Name            string     `json:"name" validate:"required,max=100"`
+	TransactionType string     `json:"transactionType" validate:"required,oneof=olraTransfer-out group-payment bankTransfer-out"`
+	Tier            int        `json:"tier" validate:"min=0,max=3"`
+	Channel         string     `json:"channel" validate:"omitempty,oneof=app web ussd"`
+	MinAmount       float64    `json:"minAmount" validate:"min=0"`
+	MaxAmount       float64    `json:"maxAmount" validate:"min=0"`
+	FeeType         string     `json:"feeType" validate:"required,oneof=flat percentage"`
+	FlatFee         float64    `json:"flatFee" validate:"min=0"`
+	Percentage      float64    `json:"percentage" validate:"min=0,max=100"`
+	Cap             float64    `json:"cap" validate:"min=0"`
+	FreePerMonth    int        `json:"freePerMonth" validate:"min=0"`
+	Priority        int        `json:"priority"`
+	EffectiveFrom   *time.Time `json:"effectiveFrom"`
+	EffectiveTo     *time.Time `json:"effectiveTo"`
+}
+
+type ReverseTransactionRequest struct {
+	Amount float64 `json:"amount" validate:"omitempty,gt=0"`
+	Reason string  `json:"reason" validate:"required,max=255"`
+}
+
+type OpenDisputeRequest struct {
+	TransactionReference string  `json:"transactionReference" validate:"required"`
+	Reason               string  `json:"reason" validate:"required,oneof=wrong-recipient not-received unauthorised other"`
+	Description          string  `json:"description" validate:"max=1000"`
+	Amount               float64 `json:"amount" validate:"omitempty,gt=0"`
+}
+
+type UpdateDisputeRequest struct {
+	Status string `json:"status" validate:"required,oneof=open under-review awaiting-counterparty"`
+}
+
+type ResolveDisputeRequest struct {
+	Outcome    string  `json:"outcome" validate:"required,oneof=opener counterparty"`
+	Amount     float64 `json:"amount" validate:"omitempty,gt=0"`
+	Resolution string  `json:"resolution" validate:"required,max=255"`
+}
+
+type ReconcileProviderRequest struct {
+	Date string `json:"date" validate:"required,datetime=2006-01-02"`
+}
+
+type ResolveReconciliationItemRequest struct {
+	Note string `json:"note" validate:"required,max=500"`
+}
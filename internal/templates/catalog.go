@@ -0,0 +1,202 @@
+package templates
+
+import "time"
+
+const (
+	EventOTPPhoneSignup       = "otp-phone-signup"
+	EventOTPLogin             = "otp-login"
+	EventOTPEmailVerification = "otp-email-verification"
+	EventRequestFunds         = "request-funds"
+	EventCredit               = "credit"
+	EventDebit                = "debit"
+	EventGroupDebit           = "group-debit"
+	EventInvite               = "invite"
+	EventTicketReply          = "ticket-reply"
+	EventGroupContribution    = "group-contribution"
+	EventNewLogin             = "new-login"
+	EventLowBalance           = "low-balance"
+	EventTransferReversed     = "transfer-reversed"
+	EventRefund               = "refund"
+	EventReversalDebit        = "reversal-debit"
+	EventDisputeOpened        = "dispute-opened"
+	EventDisputeRaised        = "dispute-raised"
+	EventDisputeResolved      = "dispute-resolved"
+	EventDisputeNoResponse    = "dispute-no-response"
+	EventDisputeDelayed       = "dispute-delayed"
+)
+
+var catalog = map[string]map[string]Message{
+	EventOTPPhoneSignup: {
+		"en":  {Subject: "Verify your phone number", Body: "Welcome to Olra! We are extremely glad to have you on our platform. Kindly use the confirmation code {{.Code}} to verify your phone number. Valid for 10 minutes, one-time use only"},
+		"yo":  {Subject: "Jẹ́rìísí nọ́ńbà fóònù rẹ", Body: "Ẹ kú àbọ̀ sí Olra! Inú wa dùn láti rí ọ. Lo kóòdù ìjẹ́rìísí {{.Code}} láti jẹ́rìísí nọ́ńbà fóònù rẹ. Ó wúlò fún ìṣẹ́jú mẹ́wàá, ẹ̀ẹ̀kan ṣoṣo."},
+		"ha":  {Subject: "Tabbatar da lambar wayarka", Body: "Barka da zuwa Olra! Muna farin cikin kasancewarka tare da mu. Yi amfani da lambar tabbatarwa {{.Code}} don tabbatar da lambar wayarka. Tana aiki na mintuna 10, sau ɗaya kawai."},
+		"ig":  {Subject: "Kwado nọmba ekwentị gị", Body: "Nnọọ na Olra! Obi dị anyị ụtọ inwe gị. Jiri koodu nkwenye {{.Code}} kwado nọmba ekwentị gị. Ọ dị mma maka nkeji iri, naanị otu ugboro."},
+		"pcm": {Subject: "Confirm your phone number", Body: "Welcome to Olra! We happy well well say you join us. Use dis code {{.Code}} confirm your phone number. E go work for 10 minutes, only one time."},
+	},
+	EventOTPLogin: {
+		"en":  {Subject: "Your Olra confirmation code", Body: "Your Olra confirmation code is {{.Code}}. Do not disclose this code with anyone. Valid for 10 minutes, one-time use only"},
+		"yo":  {Subject: "Kóòdù ìjẹ́rìísí Olra rẹ", Body: "Kóòdù ìjẹ́rìísí Olra rẹ ni {{.Code}}. Má ṣe fi kóòdù yìí han ẹnikẹ́ni. Ó wúlò fún ìṣẹ́jú mẹ́wàá, ẹ̀ẹ̀kan ṣoṣo."},
+		"ha":  {Subject: "Lambar tabbatarwa ta Olra", Body: "Lambar tabbatarwa ta Olra ita ce {{.Code}}. Kada ka bayyana wannan lambar ga kowa. Tana aiki na mintuna 10, sau ɗaya kawai."},
+		"ig":  {Subject: "Koodu nkwenye Olra gị", Body: "Koodu nkwenye Olra gị bụ {{.Code}}. Egosila onye ọbụla koodu a. Ọ dị mma maka nkeji iri, naanị otu ugboro."},
+		"pcm": {Subject: "Your Olra code", Body: "Your Olra code na {{.Code}}. No show anybody dis code. E go work for 10 minutes, only one time."},
+	},
+	EventOTPEmailVerification: {
+		"en":  {Subject: "OTP Code", Body: "Hello {{.FirstName}},\n\nYour otp code is: {{.Code}}, valid for 30mins."},
+		"yo":  {Subject: "Kóòdù OTP", Body: "Ẹ n lẹ́ {{.FirstName}},\n\nKóòdù OTP rẹ ni {{.Code}}, ó wúlò fún ìṣẹ́jú ọgbọ̀n."},
+		"ha":  {Subject: "Lambar OTP", Body: "Sannu {{.FirstName}},\n\nLambar OTP ɗinka ita ce {{.Code}}, tana aiki na mintuna 30."},
+		"ig":  {Subject: "Koodu OTP", Body: "Ndewo {{.FirstName}},\n\nKoodu OTP gị bụ {{.Code}}, ọ dị mma maka nkeji iri atọ."},
+		"pcm": {Subject: "OTP Code", Body: "How far {{.FirstName}},\n\nYour OTP code na {{.Code}}, e go work for 30 minutes."},
+	},
+	EventRequestFunds: {
+		"en":  {Subject: "New payment request", Body: "Hello {{.FirstName}} {{.LastName}}, you have received a request from {{.Tag}} to send the amount of {{amount .Amount}} to them. Kindly log into your Olra account to do so."},
+		"yo":  {Subject: "Ìbéèrè owó tuntun", Body: "Ẹ n lẹ́ {{.FirstName}} {{.LastName}}, {{.Tag}} ti béèrè pé kí o fi {{amount .Amount}} ránṣẹ́ sí wọn. Jọ̀wọ́ wọlé sí àkáǹtì Olra rẹ láti ṣe bẹ́ẹ̀."},
+		"ha":  {Subject: "Sabuwar buƙatar kuɗi", Body: "Sannu {{.FirstName}} {{.LastName}}, {{.Tag}} ya nemi ka tura masa {{amount .Amount}}. Da fatan ka shiga asusun Olra ɗinka don yin hakan."},
+		"ig":  {Subject: "Arịrịọ ego ọhụrụ", Body: "Ndewo {{.FirstName}} {{.LastName}}, {{.Tag}} rịọrọ ka i zitere ha {{amount .Amount}}. Biko banye n'akaụntụ Olra gị ime ya."},
+		"pcm": {Subject: "Person dey ask you for money", Body: "How far {{.FirstName}} {{.LastName}}, {{.Tag}} dey ask make you send {{amount .Amount}} give dem. Abeg enter your Olra account to do am."},
+	},
+	EventCredit: {
+		"en":  {Subject: "Credit alert", Body: "Your Olra account has been credited with {{amount .Amount}} by {{.Tag}} on {{date .Date}}. Your new account balance is {{amount .Balance}}"},
+		"yo":  {Subject: "Ìkìlọ̀ owó wọlé", Body: "{{.Tag}} ti fi {{amount .Amount}} sí àkáǹtì Olra rẹ ní {{date .Date}}. Iye owó tó wà nínú àkáǹtì rẹ báyìí jẹ́ {{amount .Balance}}"},
+		"ha":  {Subject: "Sanarwar shigar kuɗi", Body: "An saka {{amount .Amount}} a asusun Olra ɗinka daga {{.Tag}} a ranar {{date .Date}}. Sabon ma'aunin asusunka shine {{amount .Balance}}"},
+		"ig":  {Subject: "Ọkwa ego batara", Body: "{{.Tag}} etinyela {{amount .Amount}} n'akaụntụ Olra gị na {{date .Date}}. Ego dị n'akaụntụ gị ugbu a bụ {{amount .Balance}}"},
+		"pcm": {Subject: "Money don land", Body: "{{.Tag}} don send {{amount .Amount}} enter your Olra account for {{date .Date}}. Your new balance na {{amount .Balance}}"},
+	},
+	EventDebit: {
+		"en":  {Subject: "Debit alert", Body: "Your transfer of {{amount .Amount}} on {{date .Date}} is confirmed and processing. Your available Olra account balance is {{amount .Balance}}"},
+		"yo":  {Subject: "Ìkìlọ̀ owó jáde", Body: "Ìfiránṣẹ́ {{amount .Amount}} rẹ ní {{date .Date}} ti jẹ́ ìfọwọ́sí, ó sì ń lọ lọ́wọ́. Iye owó tó wà nínú àkáǹtì Olra rẹ jẹ́ {{amount .Balance}}"},
+		"ha":  {Subject: "Sanarwar fitar kuɗi", Body: "An tabbatar da turawarka ta {{amount .Amount}} a ranar {{date .Date}} kuma ana kan aiwatarwa. Kuɗin da ke asusun Olra ɗinka shine {{amount .Balance}}"},
+		"ig":  {Subject: "Ọkwa ego pụrụ", Body: "Akwadola izipu {{amount .Amount}} gị na {{date .Date}}, ọ na-aga n'ihu. Ego dị n'akaụntụ Olra gị bụ {{amount .Balance}}"},
+		"pcm": {Subject: "Money don comot", Body: "Your transfer of {{amount .Amount}} for {{date .Date}} don confirm and e dey process. Your Olra balance wey remain na {{amount .Balance}}"},
+	},
+	EventGroupDebit: {
+		"en":  {Subject: "Group transfer", Body: "Your transfer of {{amount .Amount}} to the {{.Group}} group is confirmed and processing. Your available Olra account balance is {{amount .Balance}}"},
+		"yo":  {Subject: "Ìfiránṣẹ́ sí ẹgbẹ́", Body: "Ìfiránṣẹ́ {{amount .Amount}} rẹ sí ẹgbẹ́ {{.Group}} ti jẹ́ ìfọwọ́sí, ó sì ń lọ lọ́wọ́. Iye owó tó wà nínú àkáǹtì Olra rẹ jẹ́ {{amount .Balance}}"},
+		"ha":  {Subject: "Turawa zuwa ƙungiya", Body: "An tabbatar da turawarka ta {{amount .Amount}} zuwa ƙungiyar {{.Group}} kuma ana kan aiwatarwa. Kuɗin da ke asusun Olra ɗinka shine {{amount .Balance}}"},
+		"ig":  {Subject: "Izipu n'otu", Body: "Akwadola izipu {{amount .Amount}} gị n'otu {{.Group}}, ọ na-aga n'ihu. Ego dị n'akaụntụ Olra gị bụ {{amount .Balance}}"},
+		"pcm": {Subject: "Group transfer", Body: "Your transfer of {{amount .Amount}} go {{.Group}} group don confirm and e dey process. Your Olra balance wey remain na {{amount .Balance}}"},
+	},
+	EventGroupContribution: {
+		"en":  {Subject: "Group activity", Body: "{{.Tag}} sent {{amount .Amount}} to the {{.Group}} group."},
+		"yo":  {Subject: "Ìṣẹ̀lẹ̀ nínú ẹgbẹ́", Body: "{{.Tag}} ti fi {{amount .Amount}} ránṣẹ́ sí ẹgbẹ́ {{.Group}}."},
+		"ha":  {Subject: "Ayyukan ƙungiya", Body: "{{.Tag}} ya tura {{amount .Amount}} zuwa ƙungiyar {{.Group}}."},
+		"ig":  {Subject: "Ihe mere n'otu", Body: "{{.Tag}} ezitela {{amount .Amount}} n'otu {{.Group}}."},
+		"pcm": {Subject: "Group gist", Body: "{{.Tag}} don send {{amount .Amount}} enter {{.Group}} group."},
+	},
+	EventNewLogin: {
+		"en":  {Subject: "New sign-in to your account", Body: "Your Olra account was signed in to on {{date .Date}}. If this was not you, contact support immediately."},
+		"yo":  {Subject: "Ìwọlé tuntun sí àkáǹtì rẹ", Body: "Ẹnìkan wọlé sí àkáǹtì Olra rẹ ní {{date .Date}}. Bí kì í bá ṣe ìwọ, kàn sí ìrànlọ́wọ́ lẹ́sẹ̀kẹsẹ̀."},
+		"ha":  {Subject: "Sabon shiga asusunka", Body: "An shiga asusun Olra ɗinka a ranar {{date .Date}}. Idan ba kai ba ne, tuntuɓi tallafi nan take."},
+		"ig":  {Subject: "Mbanye ọhụrụ n'akaụntụ gị", Body: "Abanyere n'akaụntụ Olra gị na {{date .Date}}. Ọ bụrụ na ọ bụghị gị, kpọtụrụ enyemaka ozugbo."},
+		"pcm": {Subject: "Person don enter your account", Body: "Somebody enter your Olra account on {{date .Date}}. If no be you, contact support sharp sharp."},
+	},
+	EventLowBalance: {
+		"en":  {Subject: "Low balance", Body: "Your Olra account balance is {{amount .Balance}}, below the {{amount .Threshold}} alert you set."},
+		"yo":  {Subject: "Owó ti kéré", Body: "Iye owó tó wà nínú àkáǹtì Olra rẹ jẹ́ {{amount .Balance}}, ó kéré sí {{amount .Threshold}} tí o yàn fún ìkìlọ̀."},
+		"ha":  {Subject: "Kuɗi sun yi ƙasa", Body: "Kuɗin da ke asusun Olra ɗinka {{amount .Balance}} ne, ƙasa da {{amount .Threshold}} da ka saita don faɗakarwa."},
+		"ig":  {Subject: "Ego fọdụrụ dị ala", Body: "Ego dị n'akaụntụ Olra gị bụ {{amount .Balance}}, ọ dị ala karịa {{amount .Threshold}} i setịrị maka ọkwa."},
+		"pcm": {Subject: "Your money don low", Body: "Your Olra balance na {{amount .Balance}}, e don pass under the {{amount .Threshold}} wey you set for alert."},
+	},
+	EventTransferReversed: {
+		"en":  {Subject: "Transfer reversed", Body: "Your transfer of {{amount .Amount}} to {{.AccountName}} could not be completed. {{amount .Total}} has been returned to your Olra account and your balance is {{amount .Balance}}."},
+		"yo":  {Subject: "Ìfiránṣẹ́ ti padà", Body: "Ìfiránṣẹ́ {{amount .Amount}} rẹ sí {{.AccountName}} kò ṣeé parí. A ti dá {{amount .Total}} padà sí àkáǹtì Olra rẹ, iye owó tó wà nínú rẹ̀ báyìí jẹ́ {{amount .Balance}}."},
+		"ha":  {Subject: "An mayar da tura kuɗi", Body: "Tura kuɗin {{amount .Amount}} zuwa {{.AccountName}} bai yiwu ba. An mayar da {{amount .Total}} zuwa asusun Olra ɗinka kuma ma'aunin asusunka shine {{amount .Balance}}."},
+		"ig":  {Subject: "Ezigharị ego alaghachila", Body: "Ezigharị {{amount .Amount}} gị nye {{.AccountName}} agaghị nke ọma. Eweghachila {{amount .Total}} n'akaụntụ Olra gị, ego dị na ya ugbu a bụ {{amount .Balance}}."},
+		"pcm": {Subject: "Transfer don reverse", Body: "Your transfer of {{amount .Amount}} to {{.AccountName}} no go through. We don return {{amount .Total}} to your Olra account and your balance na {{amount .Balance}}."},
+	},
+	EventRefund: {
+		"en":  {Subject: "Refund received", Body: "{{amount .Amount}} from transaction {{.Reference}} has been refunded to your Olra account. Your balance is {{amount .Balance}}."},
+		"yo":  {Subject: "Owó ti padà", Body: "A ti dá {{amount .Amount}} láti inú ìṣòwò {{.Reference}} padà sí àkáǹtì Olra rẹ. Iye owó tó wà nínú rẹ̀ báyìí jẹ́ {{amount .Balance}}."},
+		"ha":  {Subject: "An mayar maka da kuɗi", Body: "An mayar da {{amount .Amount}} daga ma'amala {{.Reference}} zuwa asusun Olra ɗinka. Ma'aunin asusunka shine {{amount .Balance}}."},
+		"ig":  {Subject: "Enwetala nkwụghachi", Body: "Eweghachila {{amount .Amount}} site na azụmahịa {{.Reference}} n'akaụntụ Olra gị. Ego dị na ya ugbu a bụ {{amount .Balance}}."},
+		"pcm": {Subject: "Refund don land", Body: "We don refund {{amount .Amount}} from transaction {{.Reference}} enter your Olra account. Your balance na {{amount .Balance}}."},
+	},
+	EventReversalDebit: {
+		"en":  {Subject: "Transaction reversed", Body: "Transaction {{.Reference}} has been reversed and {{amount .Amount}} has been taken back from your Olra account. Your balance is {{amount .Balance}}."},
+		"yo":  {Subject: "Ìṣòwò ti padà", Body: "A ti yí ìṣòwò {{.Reference}} padà, a sì ti gba {{amount .Amount}} padà láti inú àkáǹtì Olra rẹ. Iye owó tó wà nínú rẹ̀ báyìí jẹ́ {{amount .Balance}}."},
+		"ha":  {Subject: "An soke ma'amala", Body: "An soke ma'amala {{.Reference}} kuma an cire {{amount .Amount}} daga asusun Olra ɗinka. Ma'aunin asusunka shine {{amount .Balance}}."},
+		"ig":  {Subject: "Azụmahịa alaghachila", Body: "Alaghachila azụmahịa {{.Reference}}, ewepụla {{amount .Amount}} n'akaụntụ Olra gị. Ego dị na ya ugbu a bụ {{amount .Balance}}."},
+		"pcm": {Subject: "Transaction don reverse", Body: "Transaction {{.Reference}} don reverse and we don collect {{amount .Amount}} back from your Olra account. Your balance na {{amount .Balance}}."},
+	},
+	EventDisputeOpened: {
+		"en":  {Subject: "Dispute received", Body: "We have received your dispute #{{.DisputeID}} on transaction {{.Reference}} for {{amount .Amount}}. We will resolve it by {{date .ResolveBy}}."},
+		"yo":  {Subject: "A ti gba ẹ̀sùn rẹ", Body: "A ti gba ẹ̀sùn #{{.DisputeID}} rẹ lórí ìṣòwò {{.Reference}} fún {{amount .Amount}}. A ó yanjú rẹ̀ kí ó tó di {{date .ResolveBy}}."},
+		"ha":  {Subject: "Mun karɓi ƙorafinka", Body: "Mun karɓi ƙorafinka #{{.DisputeID}} kan ma'amala {{.Reference}} ta {{amount .Amount}}. Za mu warware shi kafin {{date .ResolveBy}}."},
+		"ig":  {Subject: "Anatala mkpesa gị", Body: "Anatala mkpesa gị #{{.DisputeID}} gbasara azụmahịa {{.Reference}} nke {{amount .Amount}}. Anyị ga-edozi ya tupu {{date .ResolveBy}}."},
+		"pcm": {Subject: "We don receive your dispute", Body: "We don receive your dispute #{{.DisputeID}} on transaction {{.Reference}} for {{amount .Amount}}. We go settle am before {{date .ResolveBy}}."},
+	},
+	EventDisputeRaised: {
+		"en":  {Subject: "A payment you received is disputed", Body: "Transaction {{.Reference}} that you received has been disputed (#{{.DisputeID}}). {{if .Held}}{{amount .Held}} in your Olra account is on hold until the dispute is resolved. {{end}}Add your response in the Olra app by {{date .RespondBy}}."},
+		"yo":  {Subject: "Wọ́n ń ṣàròyé lórí owó tí o gbà", Body: "Wọ́n ń ṣàròyé lórí ìṣòwò {{.Reference}} tí o gbà (#{{.DisputeID}}). {{if .Held}}A ti dá {{amount .Held}} dúró nínú àkáǹtì Olra rẹ títí a ó fi yanjú rẹ̀. {{end}}Fi èsì rẹ ránṣẹ́ nínú áàpù Olra kí ó tó di {{date .RespondBy}}."},
+		"ha":  {Subject: "An yi ƙorafi kan kuɗin da ka karɓa", Body: "An yi ƙorafi kan ma'amala {{.Reference}} da ka karɓa (#{{.DisputeID}}). {{if .Held}}An riƙe {{amount .Held}} a asusun Olra ɗinka har sai an warware ƙorafin. {{end}}Ka aika da amsarka a manhajar Olra kafin {{date .RespondBy}}."},
+		"ig":  {Subject: "Enwere mkpesa gbasara ego i natara", Body: "Enwere mkpesa gbasara azụmahịa {{.Reference}} i natara (#{{.DisputeID}}). {{if .Held}}Ejidere {{amount .Held}} n'akaụntụ Olra gị ruo mgbe edoziri mkpesa a. {{end}}Zaghachi na ngwa Olra tupu {{date .RespondBy}}."},
+		"pcm": {Subject: "Person dey dispute money wey you receive", Body: "Person dey dispute transaction {{.Reference}} wey you receive (#{{.DisputeID}}). {{if .Held}}We don hold {{amount .Held}} for your Olra account until we settle the matter. {{end}}Send your side for Olra app before {{date .RespondBy}}."},
+	},
+	EventDisputeResolved: {
+		"en":  {Subject: "Dispute resolved", Body: "Dispute #{{.DisputeID}} on transaction {{.Reference}} has been resolved. {{if .Refunded}}{{amount .Amount}} is being returned to the sender.{{else}}The transaction stands and any funds on hold have been released.{{end}}"},
+		"yo":  {Subject: "A ti yanjú ẹ̀sùn", Body: "A ti yanjú ẹ̀sùn #{{.DisputeID}} lórí ìṣòwò {{.Reference}}. {{if .Refunded}}A ń dá {{amount .Amount}} padà fún ẹni tó fi ránṣẹ́.{{else}}Ìṣòwò náà dúró bẹ́ẹ̀, a sì ti tú owó tí a dá dúró sílẹ̀.{{end}}"},
+		"ha":  {Subject: "An warware ƙorafi", Body: "An warware ƙorafi #{{.DisputeID}} kan ma'amala {{.Reference}}. {{if .Refunded}}Ana mayar da {{amount .Amount}} ga wanda ya tura.{{else}}Ma'amalar ta tsaya kuma an saki duk kuɗin da aka riƙe.{{end}}"},
+		"ig":  {Subject: "Edozila mkpesa", Body: "Edozila mkpesa #{{.DisputeID}} gbasara azụmahịa {{.Reference}}. {{if .Refunded}}A na-eweghachi {{amount .Amount}} nye onye zitere ya.{{else}}Azụmahịa ahụ ka dị, ewepụkwala ego ọ bụla ejidere.{{end}}"},
+		"pcm": {Subject: "Dispute don settle", Body: "Dispute #{{.DisputeID}} on transaction {{.Reference}} don settle. {{if .Refunded}}We dey return {{amount .Amount}} give the person wey send am.{{else}}The transaction stand as e be and we don release any money wey we hold.{{end}}"},
+	},
+	EventDisputeNoResponse: {
+		"en":  {Subject: "Dispute under review", Body: "No response was received on dispute #{{.DisputeID}} about transaction {{.Reference}} by {{date .RespondBy}}. Our team is now reviewing it with the evidence we have."},
+		"yo":  {Subject: "A ń ṣàyẹ̀wò ẹ̀sùn", Body: "A kò rí èsì gbà lórí ẹ̀sùn #{{.DisputeID}} nípa ìṣòwò {{.Reference}} kí ó tó di {{date .RespondBy}}. Àwọn òṣìṣẹ́ wa ti ń ṣàyẹ̀wò rẹ̀ báyìí pẹ̀lú ẹ̀rí tí a ní."},
+		"ha":  {Subject: "Ana duba ƙorafi", Body: "Ba a samu amsa kan ƙorafi #{{.DisputeID}} game da ma'amala {{.Reference}} kafin {{date .RespondBy}} ba. Ma'aikatanmu suna duba shi yanzu da shaidar da muke da ita."},
+		"ig":  {Subject: "A na-enyocha mkpesa", Body: "Anataghị nzaghachi na mkpesa #{{.DisputeID}} gbasara azụmahịa {{.Reference}} tupu {{date .RespondBy}}. Ndị ọrụ anyị na-enyocha ya ugbu a site na ihe akaebe anyị nwere."},
+		"pcm": {Subject: "We dey review the dispute", Body: "Nobody answer dispute #{{.DisputeID}} on transaction {{.Reference}} before {{date .RespondBy}}. Our team don start to review am with the evidence wey we get."},
+	},
+	EventDisputeDelayed: {
+		"en":  {Subject: "Your dispute is taking longer", Body: "Dispute #{{.DisputeID}} on transaction {{.Reference}} was not resolved by {{date .ResolveBy}} as we promised. It has been escalated and we will let you know as soon as it is decided."},
+		"yo":  {Subject: "Ẹ̀sùn rẹ ń pẹ́ díẹ̀", Body: "A kò yanjú ẹ̀sùn #{{.DisputeID}} lórí ìṣòwò {{.Reference}} kí ó tó di {{date .ResolveBy}} bí a ti ṣèlérí. A ti gbé e lọ sókè, a ó sì jẹ́ kí o mọ̀ ní kété tí a bá ti pinnu rẹ̀."},
+		"ha":  {Subject: "Ƙorafinka yana ɗaukar lokaci", Body: "Ba a warware ƙorafi #{{.DisputeID}} kan ma'amala {{.Reference}} kafin {{date .ResolveBy}} kamar yadda muka alkawarta ba. An ɗaga shi zuwa gaba kuma za mu sanar da kai da zarar an yanke hukunci."},
+		"ig":  {Subject: "Mkpesa gị na-ewe oge", Body: "Edozighị mkpesa #{{.DisputeID}} gbasara azụmahịa {{.Reference}} tupu {{date .ResolveBy}} dịka anyị kwere nkwa. Ebugoro ya elu, anyị ga-agwa gị ozugbo e kpebiri ya."},
+		"pcm": {Subject: "Your dispute dey take time", Body: "We no fit settle dispute #{{.DisputeID}} on transaction {{.Reference}} before {{date .ResolveBy}} as we promise. We don push am go up and we go tell you once dem decide am."},
+	},
+	EventInvite: {
+		"en":  {Subject: "You're invited to Olra", Body: "Hello {{.FullName}},\n\nYour spot on Olra is ready. Use the invite code {{.Code}} when you sign up. The code can only be used once and expires in 14 days."},
+		"yo":  {Subject: "A pè ọ́ sí Olra", Body: "Ẹ n lẹ́ {{.FullName}},\n\nÀyè rẹ lórí Olra ti ṣetán. Lo kóòdù ìpè {{.Code}} nígbà tí o bá ń forúkọsílẹ̀. Ẹ̀ẹ̀kan ṣoṣo ni o lè lò ó, yóò sì parí ní ọjọ́ mẹ́rìnlá."},
+		"ha":  {Subject: "An gayyace ka zuwa Olra", Body: "Sannu {{.FullName}},\n\nWurinka a Olra ya shirya. Yi amfani da lambar gayyata {{.Code}} lokacin rajista. Sau ɗaya kawai za a iya amfani da ita, kuma za ta ƙare cikin kwanaki 14."},
+		"ig":  {Subject: "A kpọrọ gị òkù na Olra", Body: "Ndewo {{.FullName}},\n\nOhere gị na Olra adịla njikere. Jiri koodu òkù {{.Code}} mgbe ị na-edebanye aha. Naanị otu ugboro ka ị ga-eji ya, ọ ga-agwụ n'ime ụbọchị 14."},
+		"pcm": {Subject: "Olra don invite you", Body: "How far {{.FullName}},\n\nYour space for Olra don ready. Use invite code {{.Code}} when you dey sign up. Na one time you fit use am, e go expire after 14 days."},
+	},
+	EventTicketReply: {
+		"en":  {Subject: "Re: Your Olra support request #{{.TicketID}}", Body: "Hello {{.FirstName}},\n\n{{.Reply}}\n\nReply to this ticket from the Olra app or reference ticket #{{.TicketID}} when contacting us.\n\nOlra Support"},
+		"yo":  {Subject: "Èsì: Ìbéèrè ìrànlọ́wọ́ Olra rẹ #{{.TicketID}}", Body: "Ẹ n lẹ́ {{.FirstName}},\n\n{{.Reply}}\n\nFèsì sí tíkẹ́ẹ̀tì yìí láti inú áàpù Olra tàbí mẹ́nu ba tíkẹ́ẹ̀tì #{{.TicketID}} nígbà tí o bá ń kàn sí wa.\n\nÌrànlọ́wọ́ Olra"},
+		"ha":  {Subject: "Amsa: Buƙatar tallafin Olra #{{.TicketID}}", Body: "Sannu {{.FirstName}},\n\n{{.Reply}}\n\nKa amsa wannan tikitin daga manhajar Olra ko ka ambaci tikiti #{{.TicketID}} lokacin tuntuɓar mu.\n\nTallafin Olra"},
+		"ig":  {Subject: "Nzaghachi: Arịrịọ enyemaka Olra gị #{{.TicketID}}", Body: "Ndewo {{.FirstName}},\n\n{{.Reply}}\n\nZaghachi tiketi a site na ngwa Olra ma ọ bụ kwuo tiketi #{{.TicketID}} mgbe ị na-akpọtụrụ anyị.\n\nEnyemaka Olra"},
+		"pcm": {Subject: "Re: Your Olra support request #{{.TicketID}}", Body: "How far {{.FirstName}},\n\n{{.Reply}}\n\nReply dis ticket for Olra app or mention ticket #{{.TicketID}} when you dey contact us.\n\nOlra Support"},
+	},
+}
+
+var footers = map[string]string{
+	"en":  "You are receiving this because you have an Olra account.",
+	"yo":  "O ń gba èyí nítorí pé o ní àkáǹtì Olra.",
+	"ha":  "Kana karɓar wannan saboda kana da asusun Olra.",
+	"ig":  "Ị na-anata nke a n'ihi na ị nwere akaụntụ Olra.",
+	"pcm": "You dey get dis message because you get Olra account.",
+}
+
+var sampleDate = time.Date(2024, time.March, 14, 10, 30, 0, 0, time.Local)
+
+var samples = map[string]Data{
+	EventOTPPhoneSignup:       {"Code": "482913"},
+	EventOTPLogin:             {"Code": "482913"},
+	EventOTPEmailVerification: {"Code": "482913", "FirstName": "Ada"},
+	EventRequestFunds:         {"FirstName": "Ada", "LastName": "Obi", "Tag": "tunde", "Amount": 5000.0},
+	EventCredit:               {"Tag": "tunde", "Amount": 12500.5, "Balance": 40250.75, "Date": sampleDate},
+	EventDebit:                {"Amount": 12500.5, "Balance": 27750.25, "Date": sampleDate},
+	EventGroupDebit:           {"Group": "Lekki Flatmates", "Amount": 15000.0, "Balance": 12750.25},
+	EventInvite:               {"FullName": "Ada Obi", "Code": "K7M2QX9P"},
+	EventGroupContribution:    {"Tag": "tunde", "Group": "Lekki Flatmates", "Amount": 15000.0},
+	EventNewLogin:             {"Date": sampleDate},
+	EventLowBalance:           {"Balance": 1850.0, "Threshold": 5000.0},
+	EventTransferReversed:     {"Amount": 20000.0, "Total": 20025.0, "AccountName": "Ada Obi", "Balance": 47775.25},
+	EventRefund:               {"Amount": 5000.0, "Reference": "20240314103000482913", "Balance": 32750.25},
+	EventReversalDebit:        {"Amount": 5000.0, "Reference": "20240314103000482913", "Balance": 7250.5},
+	EventDisputeOpened:        {"DisputeID": 311, "Reference": "20240314103000482913", "Amount": 5000.0, "ResolveBy": sampleDate},
+	EventDisputeRaised:        {"DisputeID": 311, "Reference": "20240314103000482913", "Held": 5000.0, "RespondBy": sampleDate},
+	EventDisputeResolved:      {"DisputeID": 311, "Reference": "20240314103000482913", "Amount": 5000.0, "Refunded": true},
+	EventDisputeNoResponse:    {"DisputeID": 311, "Reference": "20240314103000482913", "RespondBy": sampleDate},
+	EventDisputeDelayed:       {"DisputeID": 311, "Reference": "20240314103000482913", "ResolveBy": sampleDate},
+	EventTicketReply:          {"FirstName": "Ada", "TicketID": 1042, "Reply": "We have reversed the transfer and the funds are back in your wallet."},
+}
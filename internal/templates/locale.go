@@ -0,0 +1,141 @@
+package templates
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+const DefaultLocale = "en"
+
+// SupportedLocales are English, Yoruba, Hausa, Igbo and Nigerian Pidgin
+var SupportedLocales = []string{"en", "yo", "ha", "ig", "pcm"}
+
+// NormaliseLocale maps a language tag such as "yo-NG" to a supported locale
+func NormaliseLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		locale = locale[:i]
+	}
+	for _, supported := range SupportedLocales {
+		if locale == supported {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+type localeFormat struct {
+	thousands string
+	decimal   string
+	currency  string
+	months    [12]string
+	date      string // order of day, month and year, e.g. "%d %s %d"
+}
+
+// Amounts use NGN rather than ₦ so English SMS stay within the GSM-7 alphabet
+var formats = map[string]localeFormat{
+	"en": {
+		thousands: ",", decimal: ".", currency: "NGN ",
+		months: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		date:   "%d %s %d",
+	},
+	"yo": {
+		thousands: ",", decimal: ".", currency: "NGN ",
+		months: [12]string{"Ṣẹ́rẹ́", "Èrèlè", "Ẹrẹ̀nà", "Ìgbé", "Ẹ̀bibi", "Òkúdu", "Agẹmọ", "Ògún", "Owewe", "Ọ̀wàrà", "Bélú", "Ọ̀pẹ̀"},
+		date:   "%d %s %d",
+	},
+	"ha": {
+		thousands: ",", decimal: ".", currency: "NGN ",
+		months: [12]string{"Janairu", "Faburairu", "Maris", "Afirilu", "Mayu", "Yuni", "Yuli", "Agusta", "Satumba", "Oktoba", "Nuwamba", "Disamba"},
+		date:   "%d %s, %d",
+	},
+	"ig": {
+		thousands: ",", decimal: ".", currency: "NGN ",
+		months: [12]string{"Jenụwarị", "Febrụwarị", "Maachị", "Epreel", "Mee", "Jun", "Julaị", "Ọgọọst", "Septemba", "Ọktoba", "Novemba", "Disemba"},
+		date:   "%d %s %d",
+	},
+	"pcm": {
+		thousands: ",", decimal: ".", currency: "NGN ",
+		months: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		date:   "%d %s %d",
+	},
+}
+
+func funcsFor(locale string) texttemplate.FuncMap {
+	format := formats[locale]
+	return texttemplate.FuncMap{
+		"amount": func(value interface{}) string {
+			return format.amount(toFloat(value))
+		},
+		// Events without a date of their own are stamped with the time of rendering
+		"date": func(value interface{}) string {
+			return format.formatDate(toTime(value))
+		},
+	}
+}
+
+// FormatAmount renders a naira amount the way templates in the locale do
+func FormatAmount(locale string, amount float64) string {
+	return formats[NormaliseLocale(locale)].amount(amount)
+}
+
+// FormatDate renders a date the way templates in the locale do
+func FormatDate(locale string, t time.Time) string {
+	return formats[NormaliseLocale(locale)].formatDate(t)
+}
+
+func (f localeFormat) amount(value float64) string {
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	kobo := int64(math.Round(value * 100))
+	whole := fmt.Sprintf("%d", kobo/100)
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteString(f.thousands)
+		}
+		grouped.WriteRune(digit)
+	}
+	return fmt.Sprintf("%s%s%s%s%02d", sign, f.currency, grouped.String(), f.decimal, kobo%100)
+}
+
+func (f localeFormat) formatDate(t time.Time) string {
+	return fmt.Sprintf(f.date, t.Day(), f.months[t.Month()-1], t.Year())
+}
+
+// toTime also accepts the RFC 3339 strings dates become once data has been
+// stored as JSON
+func toTime(value interface{}) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
+
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	default:
+		return 0
+	}
+}
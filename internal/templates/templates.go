@@ -0,0 +1,133 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+// Data is the set of values a template can refer to, e.g. {{.Amount}}
+type Data map[string]interface{}
+
+// Message is the source of one event in one locale. Subject is only used for
+// email; Body is shared by SMS, the email text part and the email HTML part.
+type Message struct {
+	Subject string
+	Body    string
+}
+
+// Rendered is a message ready to send
+type Rendered struct {
+	Event   string `json:"event"`
+	Locale  string `json:"locale"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+var ErrUnknownEvent = errors.New("unknown notification event")
+
+var (
+	cache   = map[string]*texttemplate.Template{}
+	cacheMu sync.Mutex
+)
+
+// Render fills in the template for the event in the locale, falling back to
+// English when the locale has no translation
+func Render(event, locale string, data Data) (*Rendered, error) {
+	locale = NormaliseLocale(locale)
+	messages, ok := catalog[event]
+	if !ok {
+		return nil, ErrUnknownEvent
+	}
+	if _, ok := messages[locale]; !ok {
+		locale = DefaultLocale
+	}
+
+	subject, err := execute(event, locale, "subject", messages[locale].Subject, data)
+	if err != nil {
+		return nil, err
+	}
+	text, err := execute(event, locale, "body", messages[locale].Body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var html bytes.Buffer
+	if err := emailLayout.Execute(&html, struct {
+		Subject    string
+		Paragraphs []string
+		Footer     string
+	}{
+		Subject:    subject,
+		Paragraphs: strings.Split(text, "\n\n"),
+		Footer:     footers[locale],
+	}); err != nil {
+		return nil, err
+	}
+
+	return &Rendered{
+		Event:   event,
+		Locale:  locale,
+		Subject: subject,
+		Text:    text,
+		HTML:    html.String(),
+	}, nil
+}
+
+func execute(event, locale, part, source string, data Data) (string, error) {
+	key := event + "|" + locale + "|" + part
+	cacheMu.Lock()
+	tmpl, ok := cache[key]
+	if !ok {
+		var err error
+		tmpl, err = texttemplate.New(key).Funcs(funcsFor(locale)).Option("missingkey=zero").Parse(source)
+		if err != nil {
+			cacheMu.Unlock()
+			return "", err
+		}
+		cache[key] = tmpl
+	}
+	cacheMu.Unlock()
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// Events lists every event with the locales it has been translated into
+func Events() map[string][]string {
+	events := map[string][]string{}
+	for event, messages := range catalog {
+		for locale := range messages {
+			events[event] = append(events[event], locale)
+		}
+		sort.Strings(events[event])
+	}
+	return events
+}
+
+// SampleData returns example values for previewing an event
+func SampleData(event string) Data {
+	return samples[event]
+}
+
+var emailLayout = htmltemplate.Must(htmltemplate.New("email").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Subject}}</title></head>
+<body style="margin:0;padding:24px;background:#f5f5f7;font-family:Helvetica,Arial,sans-serif;color:#1d1d1f">
+  <table width="100%" cellpadding="0" cellspacing="0" style="max-width:560px;margin:0 auto;background:#ffffff;border-radius:8px">
+    <tr><td style="padding:24px 32px;font-size:20px;font-weight:bold;color:#4b2ee0">Olra</td></tr>
+    <tr><td style="padding:0 32px 24px;font-size:15px;line-height:1.5">
+      {{range .Paragraphs}}<p>{{.}}</p>{{end}}
+    </td></tr>
+    <tr><td style="padding:16px 32px;font-size:12px;color:#86868b;border-top:1px solid #e5e5ea">{{.Footer}}</td></tr>
+  </table>
+</body>
+</html>`))
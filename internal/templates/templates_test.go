@@ -0,0 +1,81 @@
+package templates
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCatalogComplete(t *testing.T) {
+	for event, messages := range catalog {
+		if _, ok := samples[event]; !ok {
+			t.Errorf("%s has no sample data", event)
+		}
+		for _, locale := range SupportedLocales {
+			message, ok := messages[locale]
+			if !ok {
+				t.Errorf("%s has no %s translation", event, locale)
+				continue
+			}
+			if message.Subject == "" || message.Body == "" {
+				t.Errorf("%s in %s has an empty subject or body", event, locale)
+			}
+			rendered, err := Render(event, locale, SampleData(event))
+			if err != nil {
+				t.Errorf("Render(%s, %s) error = %v", event, locale, err)
+				continue
+			}
+			if strings.Contains(rendered.Text, "<no value>") {
+				t.Errorf("%s in %s renders a missing value: %q", event, locale, rendered.Text)
+			}
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := Data{"Tag": "tunde", "Amount": 1234567.5, "Balance": 0.05, "Date": time.Date(2024, time.March, 14, 0, 0, 0, 0, time.UTC)}
+
+	rendered, err := Render(EventCredit, "yo-NG", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if rendered.Locale != "yo" || !strings.Contains(rendered.Text, "NGN 1,234,567.50") || !strings.Contains(rendered.Text, "14 Ẹrẹ̀nà 2024") {
+		t.Errorf("Render() = %+v, want a Yoruba credit alert", rendered)
+	}
+	if !strings.Contains(rendered.HTML, "NGN 0.05") || !strings.Contains(rendered.HTML, footers["yo"]) {
+		t.Errorf("HTML = %q, want the body and the Yoruba footer", rendered.HTML)
+	}
+
+	rendered, err = Render(EventCredit, "fr", data)
+	if err != nil || rendered.Locale != DefaultLocale {
+		t.Errorf("Render() in an unsupported locale = %+v, %v, want English", rendered, err)
+	}
+	if _, err := Render("no-such-event", "en", data); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("Render() of an unknown event error = %v, want %v", err, ErrUnknownEvent)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   string
+	}{
+		{0, "NGN 0.00"},
+		{999.999, "NGN 1,000.00"},
+		{1234567.89, "NGN 1,234,567.89"},
+		{-50.5, "-NGN 50.50"},
+	}
+	for _, tt := range tests {
+		if got := FormatAmount("en", tt.amount); got != tt.want {
+			t.Errorf("FormatAmount(%v) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestDatesSurviveJSON(t *testing.T) {
+	date := time.Date(2024, time.March, 14, 10, 30, 0, 0, time.UTC)
+	if got := FormatDate("ha", toTime(date.Format(time.RFC3339))); got != "14 Maris, 2024" {
+		t.Errorf("date from JSON = %q, want 14 Maris, 2024", got)
+	}
+}
@@ -73,6 +73,18 @@ func AuthMiddleware(c *gin.Context) {
 		c.Abort()
 		return
 	}
+	// A token issued before the account was closed is still signed and unexpired
+	var dbUser database.User
+	if err := database.DB.Select("status").Where("user_id = ?", claims.UserID).First(&dbUser).Error; err != nil || dbUser.Status == "closed" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         true,
+			"response code": 401,
+			"message":       "This account is no longer active",
+			"data":          "",
+		})
+		c.Abort()
+		return
+	}
 	user := User{
 		UserId:   &claims.UserID,
 		DeviceId: &claims.DeviceID,
@@ -93,6 +105,10 @@ func LogoutUserFromDevice(deviceID string) error {
 	if err := database.DB.Save(&user).Error; err != nil {
 		return errors.New("failed to update user record")
 	}
+	// Stop pushes to the device that is being signed out
+	if err := database.DB.Where("device_id = ?", deviceID).Delete(&database.PushToken{}).Error; err != nil {
+		return err
+	}
 	// Logout user previous session
 	result := database.DB.Where("device_id = ?", deviceID).Delete(&dbTokenMap)
 	if result.Error != nil {
@@ -103,3 +119,61 @@ func LogoutUserFromDevice(deviceID string) error {
 	}
 	return nil
 }
+
+// AdminMiddleware must run after AuthMiddleware and only lets admin users through
+func AdminMiddleware(c *gin.Context) {
+	RequireRole("admin")(c)
+}
+
+// RequireRole must run after AuthMiddleware and only lets users with one of the roles through
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requireRole(c, roles)
+	}
+}
+
+func requireRole(c *gin.Context, roles []string) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         true,
+			"response code": 401,
+			"message":       "User not found",
+			"data":          "",
+		})
+		c.Abort()
+		return
+	}
+	userStruct, ok := user.(User)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         true,
+			"response code": 400,
+			"message":       "User not a valid struct",
+			"data":          "",
+		})
+		c.Abort()
+		return
+	}
+	var dbUser database.User
+	if err := database.DB.Where("user_id = ?", *userStruct.UserId).First(&dbUser).Error; err != nil || !hasRole(dbUser.Role, roles) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":         true,
+			"response code": 403,
+			"message":       "You are not allowed to access this resource",
+			"data":          "",
+		})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func hasRole(role string, roles []string) bool {
+	for _, r := range roles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	JwtKey = []byte("test-secret")
+	db := dbtest.Open(t, &database.User{})
+	active := database.User{Email: "ada@example.com", PhoneNumber: "1", Tag: "ada", RefCode: "A"}
+	closed := database.User{Email: "tunde@example.com", PhoneNumber: "2", Tag: "tunde", RefCode: "T", Status: "closed"}
+	db.Create(&active)
+	db.Create(&closed)
+
+	token := func(userID uint, expires time.Duration) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+			UserID:         userID,
+			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(expires).Unix()},
+		}).SignedString(JwtKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"active user", token(active.UserID, time.Hour), http.StatusOK},
+		{"closed user", token(closed.UserID, time.Hour), http.StatusUnauthorized},
+		{"unknown user", token(999, time.Hour), http.StatusUnauthorized},
+		{"expired", token(active.UserID, -time.Hour), http.StatusUnauthorized},
+		{"no token", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", AuthMiddleware, func(c *gin.Context) { c.Status(http.StatusOK) })
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				req.Header.Set("token", tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/structs"
+)
+
+var (
+	ErrAccountClosed    = errors.New("account is already closed")
+	ErrWalletNotEmpty   = errors.New("wallet balance must be zero before closing the account")
+	ErrFundsHeld        = errors.New("funds are still held on your wallet")
+	ErrTransfersPending = errors.New("you have bank transfers that have not settled yet")
+	ErrGroupHasFunds    = errors.New("you admin a group that still holds funds")
+	ErrAccountNotFound  = errors.New("user does not exist")
+	ErrRecipientClosed  = errors.New("the recipient's account is closed")
+)
+
+// BuildDataExport gathers everything we hold about a user into a single archive
+func BuildDataExport(userID uint) (*structs.DataExport, error) {
+	var user database.User
+	if err := database.DB.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, ErrAccountNotFound
+	}
+
+	export := &structs.DataExport{
+		GeneratedAt: time.Now(),
+		Profile: structs.ExportProfile{
+			UserID:        user.UserID,
+			FirstName:     user.FirstName,
+			LastName:      user.LastName,
+			Email:         user.Email,
+			PhoneNumber:   user.PhoneNumber,
+			Tag:           user.Tag,
+			RefCode:       user.RefCode,
+			EmailVerified: user.EmailVerified,
+			PhoneVerified: user.PhoneVerified,
+			BvnVerified:   user.BvnVerified,
+			KycStatus:     user.KycStatus,
+			SignupLevel:   user.SignupLevel,
+			CreatedAt:     user.CreatedAt,
+		},
+		Transactions: []structs.ExportTransaction{},
+		Groups:       []structs.ExportGroup{},
+		Budgets:      []structs.ExportBudget{},
+		Sessions:     []structs.ExportSession{},
+	}
+
+	var virtualAccount database.VirtualAccount
+	if err := database.DB.Where("user_id = ?", userID).First(&virtualAccount).Error; err == nil {
+		export.VirtualAccount = &structs.ExportVirtualAccount{
+			Bank:          virtualAccount.VirtualAccountBank,
+			AccountNumber: virtualAccount.VirtualAccountAccount,
+			AccountName:   virtualAccount.VirtualAccountName,
+			Balance:       virtualAccount.Balance,
+		}
+	}
+
+	var transactions []database.Transaction
+	if err := database.DB.Scopes(involvingUser(&user)).
+		Order("transaction_date desc").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range transactions {
+		export.Transactions = append(export.Transactions, structs.ExportTransaction{
+			TransactionID:      t.TransactionID,
+			TransactionEnviron: t.TransactionEnviron,
+			TransactionType:    t.TransactionType,
+			Amount:             t.Amount,
+			Description:        t.Description,
+			Requestee:          t.Requestee,
+			Receiver:           t.Receiver,
+			Sender:             t.Sender,
+			Status:             t.Status,
+			TransactionDate:    t.TransactionDate,
+		})
+	}
+
+	var adminGroups []database.Group
+	if err := database.DB.Where("admin_id = ?", userID).Find(&adminGroups).Error; err != nil {
+		return nil, err
+	}
+	for _, g := range adminGroups {
+		export.Groups = append(export.Groups, structs.ExportGroup{
+			GroupID:   g.GroupID,
+			GroupName: g.GroupName,
+			GroupTag:  g.GroupTag,
+			Role:      "admin",
+			JoinedAt:  g.CreatedAt,
+		})
+	}
+	var memberships []database.GroupMember
+	if err := database.DB.Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range memberships {
+		var group database.Group
+		if err := database.DB.Where("group_id = ?", m.GroupID).First(&group).Error; err != nil {
+			continue
+		}
+		export.Groups = append(export.Groups, structs.ExportGroup{
+			GroupID:   group.GroupID,
+			GroupName: group.GroupName,
+			GroupTag:  group.GroupTag,
+			Role:      "member",
+			JoinedAt:  m.JoinedAt,
+		})
+	}
+
+	var budgets []database.Budget
+	if err := database.DB.Where("user_id = ?", userID).Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	for _, b := range budgets {
+		export.Budgets = append(export.Budgets, structs.ExportBudget{
+			BudgetName: b.BudgetName,
+			Category:   b.Category,
+			Type:       b.Type,
+			Amount:     b.Amount,
+			StartDate:  b.StartDate,
+			EndDate:    b.EndDate,
+		})
+	}
+
+	// Tokens are never exported, only the devices that hold them
+	if user.DeviceID != "" {
+		var session database.DeviceTokenMapping
+		active := database.DB.Where("device_id = ?", user.DeviceID).First(&session).Error == nil
+		export.Sessions = append(export.Sessions, structs.ExportSession{
+			DeviceID: user.DeviceID,
+			Active:   active,
+		})
+	}
+
+	now := time.Now()
+	database.DB.Create(&database.DataRequest{
+		UserID:      userID,
+		Type:        "export",
+		CompletedAt: &now,
+	})
+
+	return export, nil
+}
+
+// involvingUser matches the transactions a user is a party to. A reversal names
+// the original receiver as its sender, so they see it too.
+func involvingUser(user *database.User) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(
+			"user_id = ? OR receiver = ? OR requestee = ? OR (transaction_type = ? AND sender = ?)",
+			user.UserID, user.Tag, user.Tag, "reversal", user.Tag,
+		)
+	}
+}
+
+// TransactionHistory lists the user's transactions newest first, reversals
+// alongside the transactions they undo
+func TransactionHistory(userID uint, page, limit int) ([]database.Transaction, error) {
+	var user database.User
+	if err := database.DB.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, ErrAccountNotFound
+	}
+	var transactions []database.Transaction
+	err := database.DB.Scopes(involvingUser(&user)).
+		Order("transaction_date desc").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&transactions).Error
+	return transactions, err
+}
+
+// CheckAccountClosable returns an error when the user still has money with us
+// or on its way. It locks the wallet and the group wallets the user admins, so
+// call it in the closing transaction and nothing can land before the closure.
+func CheckAccountClosable(tx *gorm.DB, userID uint) error {
+	var wallet database.VirtualAccount
+	err := tx.Where("user_id = ?", userID).First(&wallet).Error
+	switch {
+	case err == nil:
+		if err := lockWallets(tx, &wallet); err != nil {
+			return err
+		}
+		balance, err := LedgerBalance(tx, WalletAccount(wallet.VirtualAccountID))
+		if err != nil {
+			return err
+		}
+		if balance != 0 {
+			return ErrWalletNotEmpty
+		}
+		held, err := heldAmount(tx, wallet.VirtualAccountID)
+		if err != nil {
+			return err
+		}
+		if held != 0 {
+			return ErrFundsHeld
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+
+	var pendingTransfers int64
+	if err := tx.Model(&database.BankTransfer{}).
+		Where("user_id = ? AND status = ?", userID, BankTransferPending).
+		Count(&pendingTransfers).Error; err != nil {
+		return err
+	}
+	if pendingTransfers > 0 {
+		return ErrTransfersPending
+	}
+
+	var groupWallets []database.GroupVirtualAccount
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Joins("JOIN groups ON groups.group_id = group_virtual_accounts.group_id").
+		Where("groups.admin_id = ?", userID).
+		Find(&groupWallets).Error; err != nil {
+		return err
+	}
+	for _, groupWallet := range groupWallets {
+		balance, err := LedgerBalance(tx, GroupWalletAccount(groupWallet.GroupVirtualAccountID))
+		if err != nil {
+			return err
+		}
+		if balance != 0 {
+			return ErrGroupHasFunds
+		}
+	}
+	return nil
+}
+
+// CloseAccount anonymises the user's personal data. Transactions, wallets and
+// ledger rows are kept for regulatory retention.
+func CloseAccount(userID uint, reason string) error {
+	var user database.User
+	var refused error
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).First(&user).Error; err != nil {
+			return ErrAccountNotFound
+		}
+		if user.Status == "closed" {
+			return ErrAccountClosed
+		}
+		if refused = CheckAccountClosable(tx, userID); refused != nil {
+			return refused
+		}
+
+		if user.DeviceID != "" {
+			if err := tx.Where("device_id = ?", user.DeviceID).Delete(&database.DeviceTokenMapping{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&database.Otp{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&database.PushToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&database.Bank{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&database.Budget{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&database.GroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&database.VirtualAccount{}).Where("user_id = ?", userID).
+			Update("virtual_account_name", "Closed Account").Error; err != nil {
+			return err
+		}
+
+		// Unique columns get a per-user placeholder so the constraints still hold
+		now := time.Now()
+		if err := tx.Model(&user).Select(
+			"FirstName", "LastName", "Email", "PhoneNumber", "Tag", "PasswordHash",
+			"ProfilePic", "DeviceID", "Status", "ClosedAt",
+		).Updates(database.User{
+			FirstName:    "Closed",
+			LastName:     "Account",
+			Email:        fmt.Sprintf("closed-%d@deleted.olra", userID),
+			PhoneNumber:  fmt.Sprintf("closed-%d", userID),
+			Tag:          fmt.Sprintf("closed%d", userID),
+			PasswordHash: "",
+			ProfilePic:   "",
+			DeviceID:     "",
+			Status:       "closed",
+			ClosedAt:     &now,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&database.DataRequest{
+			UserID:      userID,
+			Type:        "deletion",
+			Reason:      reason,
+			CompletedAt: &now,
+		}).Error
+	})
+	if refused != nil && err == refused {
+		// Recorded once the closing transaction has rolled back
+		database.DB.Create(&database.DataRequest{
+			UserID: userID,
+			Type:   "deletion",
+			Status: "rejected",
+			Reason: refused.Error(),
+		})
+	}
+	if err != nil {
+		return err
+	}
+	// The bank account can be reopened by ops if this fails, so it does not
+	// undo the closure
+	if err := closeProvisionedWallet(userID); err != nil {
+		log.Printf("Closing bank account for user %d failed: %v", userID, err)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+// walletModels are the tables a payment between wallets touches
+var walletModels = []interface{}{
+	&database.User{}, &database.VirtualAccount{}, &database.Transaction{},
+	&database.LedgerEntry{}, &database.Hold{}, &database.PricingRule{},
+	&database.Notification{}, &database.NotificationPreference{}, &database.OutboxMessage{},
+}
+
+// closureModels are the extra tables closing an account clears or checks
+var closureModels = []interface{}{
+	&database.Group{}, &database.GroupVirtualAccount{}, &database.GroupMember{},
+	&database.DeviceTokenMapping{}, &database.Otp{}, &database.PushToken{},
+	&database.Bank{}, &database.Budget{}, &database.DataRequest{},
+	&database.BankTransfer{}, &database.AccountProvisioning{},
+}
+
+// createWalletUser creates a user with a wallet the ledger credits with
+// balance kobo
+func createWalletUser(t *testing.T, db *gorm.DB, tag string, balance int64) (*database.User, *database.VirtualAccount) {
+	t.Helper()
+	user := database.User{
+		FirstName:   "Test",
+		LastName:    tag,
+		Email:       tag + "@example.com",
+		PhoneNumber: "0800" + tag,
+		Tag:         tag,
+		RefCode:     "REF" + strings.ToUpper(tag),
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	wallet := database.VirtualAccount{
+		VirtualAccountBank:    "Wema Bank",
+		VirtualAccountAccount: fmt.Sprintf("90%08d", user.UserID),
+		VirtualAccountName:    "Test " + tag,
+		UserID:                user.UserID,
+	}
+	if err := db.Create(&wallet).Error; err != nil {
+		t.Fatalf("creating wallet: %v", err)
+	}
+	if balance != 0 {
+		fundWallet(t, db, WalletAccount(wallet.VirtualAccountID), balance)
+	}
+	if err := db.First(&wallet, wallet.VirtualAccountID).Error; err != nil {
+		t.Fatalf("reading wallet: %v", err)
+	}
+	return &user, &wallet
+}
+
+// fundWallet credits a wallet or group ledger account from the bank settlement account
+func fundWallet(t *testing.T, db *gorm.DB, account string, amount int64) {
+	t.Helper()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return PostJournal(tx, "fund-"+account, "Funding", Leg{Account: SystemBankSettlementAccount, Amount: -amount},
+			Leg{Account: account, Amount: amount})
+	})
+	if err != nil {
+		t.Fatalf("funding %s: %v", account, err)
+	}
+}
+
+func assertLedgerBalance(t *testing.T, account string, want int64) {
+	t.Helper()
+	balance, err := LedgerBalance(database.DB, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != want {
+		t.Errorf("%s balance = %d, want %d", account, balance, want)
+	}
+}
+
+func TestCloseAccountRefused(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, db *gorm.DB, user *database.User, wallet *database.VirtualAccount)
+		err   error
+	}{
+		{
+			name: "money in the wallet",
+			setup: func(t *testing.T, db *gorm.DB, user *database.User, wallet *database.VirtualAccount) {
+				fundWallet(t, db, WalletAccount(wallet.VirtualAccountID), 100)
+			},
+			err: ErrWalletNotEmpty,
+		},
+		{
+			name: "funds held",
+			setup: func(t *testing.T, db *gorm.DB, user *database.User, wallet *database.VirtualAccount) {
+				db.Create(&database.Hold{VirtualAccountID: wallet.VirtualAccountID, Reference: "HOLD1",
+					Reason: HoldDispute, Amount: 500, Status: HoldActive})
+			},
+			err: ErrFundsHeld,
+		},
+		{
+			name: "bank transfer not settled",
+			setup: func(t *testing.T, db *gorm.DB, user *database.User, wallet *database.VirtualAccount) {
+				db.Create(&database.BankTransfer{UserID: user.UserID, Reference: "BT1", Provider: "test",
+					AccountNumber: "0123456789", BankCode: "058", AccountName: "Ada Obi", Amount: 50,
+					Status: BankTransferPending})
+			},
+			err: ErrTransfersPending,
+		},
+		{
+			name: "admin of a group with funds",
+			setup: func(t *testing.T, db *gorm.DB, user *database.User, wallet *database.VirtualAccount) {
+				group := database.Group{GroupName: "Savers", GroupTag: "savers", CreatedBy: user.UserID, AdminID: user.UserID}
+				db.Create(&group)
+				groupWallet := database.GroupVirtualAccount{GroupID: group.GroupID, GroupVirtualAccountBank: "Wema Bank",
+					GroupVirtualAccountNumber: "9100000001", GroupVirtualAccountName: "Savers"}
+				db.Create(&groupWallet)
+				fundWallet(t, db, GroupWalletAccount(groupWallet.GroupVirtualAccountID), 100)
+			},
+			err: ErrGroupHasFunds,
+		},
+		{
+			name: "already closed",
+			setup: func(t *testing.T, db *gorm.DB, user *database.User, wallet *database.VirtualAccount) {
+				db.Model(user).Update("status", "closed")
+			},
+			err: ErrAccountClosed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t, append(walletModels, closureModels...)...)
+			user, wallet := createWalletUser(t, db, "ada", 0)
+			tt.setup(t, db, user, wallet)
+
+			if err := CloseAccount(user.UserID, "leaving"); !errors.Is(err, tt.err) {
+				t.Fatalf("CloseAccount() error = %v, want %v", err, tt.err)
+			}
+			var after database.User
+			db.First(&after, user.UserID)
+			if after.Email != user.Email {
+				t.Errorf("CloseAccount() anonymised a refused account")
+			}
+		})
+	}
+}
+
+func TestCloseAccountRecordsRefusal(t *testing.T) {
+	db := dbtest.Open(t, append(walletModels, closureModels...)...)
+	user, _ := createWalletUser(t, db, "ada", 100)
+
+	if err := CloseAccount(user.UserID, "leaving"); !errors.Is(err, ErrWalletNotEmpty) {
+		t.Fatalf("CloseAccount() error = %v, want %v", err, ErrWalletNotEmpty)
+	}
+	var request database.DataRequest
+	if err := db.Where("user_id = ?", user.UserID).First(&request).Error; err != nil {
+		t.Fatalf("no data request recorded: %v", err)
+	}
+	if request.Status != "rejected" || request.Reason != ErrWalletNotEmpty.Error() {
+		t.Errorf("data request = %s %q, want rejected %q", request.Status, request.Reason, ErrWalletNotEmpty)
+	}
+}
+
+func TestCloseAccount(t *testing.T) {
+	db := dbtest.Open(t, append(walletModels, closureModels...)...)
+	user, wallet := createWalletUser(t, db, "ada", 0)
+	db.Create(&database.Bank{BankName: "GTBank", BankCode: "058", AccountNumber: "0123456789",
+		AccountName: "Ada Obi", UserID: user.UserID})
+	// A hold that has been captured reserves nothing
+	db.Create(&database.Hold{VirtualAccountID: wallet.VirtualAccountID, Reference: "HOLD1",
+		Reason: HoldDispute, Amount: 500, Captured: 500, Status: HoldCaptured})
+
+	if err := CloseAccount(user.UserID, "leaving"); err != nil {
+		t.Fatalf("CloseAccount() error = %v", err)
+	}
+	var after database.User
+	db.First(&after, user.UserID)
+	if after.Status != "closed" || after.ClosedAt == nil {
+		t.Errorf("status = %q, closed at %v, want closed", after.Status, after.ClosedAt)
+	}
+	if after.Email == user.Email || after.PhoneNumber == user.PhoneNumber || after.Tag == user.Tag {
+		t.Errorf("CloseAccount() kept personal data: %s %s %s", after.Email, after.PhoneNumber, after.Tag)
+	}
+	var banks int64
+	db.Model(&database.Bank{}).Where("user_id = ?", user.UserID).Count(&banks)
+	if banks != 0 {
+		t.Errorf("%d saved beneficiaries left, want 0", banks)
+	}
+	var request database.DataRequest
+	db.Where("user_id = ?", user.UserID).First(&request)
+	if request.Type != "deletion" || request.Status != "completed" {
+		t.Errorf("data request = %s %s, want a completed deletion", request.Type, request.Status)
+	}
+}
+
+func TestTransferToClosedAccount(t *testing.T) {
+	db := dbtest.Open(t, walletModels...)
+	sender, senderWallet := createWalletUser(t, db, "ada", 100000)
+	receiver, receiverWallet := createWalletUser(t, db, "tunde", 0)
+	// Closed after the sender looked the receiver up
+	db.Model(&database.User{}).Where("user_id = ?", receiver.UserID).Update("status", "closed")
+
+	_, err := TransferToUser(sender, receiver, senderWallet, receiverWallet, 500, "", ChannelApp)
+	if !errors.Is(err, ErrRecipientClosed) {
+		t.Fatalf("TransferToUser() error = %v, want %v", err, ErrRecipientClosed)
+	}
+	balance, _ := LedgerBalance(db, WalletAccount(receiverWallet.VirtualAccountID))
+	if balance != 0 {
+		t.Errorf("closed wallet balance = %d, want 0", balance)
+	}
+}
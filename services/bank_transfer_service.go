@@ -0,0 +1,366 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/nuban"
+	"olra-v1/internal/templates"
+	helpers "olra-v1/utils"
+)
+
+const (
+	BankTransferPending    = "pending"
+	BankTransferSuccessful = "successful"
+	BankTransferReversed   = "reversed"
+)
+
+var (
+	ErrTransferBelowMinimum     = errors.New("amount is below the minimum transfer")
+	ErrTransferAboveMaximum     = errors.New("amount is above the maximum for a single transfer")
+	ErrDailyLimitExceeded       = errors.New("transfer would exceed your daily limit")
+	ErrBankTransferNotFound     = errors.New("bank transfer not found")
+	ErrNameEnquiryFailed        = errors.New("could not verify the destination account")
+	ErrInvalidAccountNumber     = errors.New("account number is not valid for this bank")
+	ErrTransferReferenceUnknown = errors.New("the banking provider has no record of this transfer")
+)
+
+// NameEnquiry resolves an account number at another bank to its holder. The
+// check digit of a bank's NUBAN is verified first so a mistyped number never
+// reaches the provider, and the bank name comes from the directory so it reads the same
+// whichever provider answered.
+func NameEnquiry(accountNumber, bankCode string) (*BankAccount, error) {
+	bank, err := lookupBank(bankCode)
+	if err != nil {
+		return nil, err
+	}
+	if bank.Type == "fintech" {
+		// Fintech wallets are numbered after the holder's phone number, not
+		// the CBN scheme, so only the shape is checked and the provider decides
+		if len(accountNumber) != nuban.Length || strings.Trim(accountNumber, "0123456789") != "" {
+			return nil, ErrInvalidAccountNumber
+		}
+	} else if err := nuban.Validate(bank.Code, accountNumber); err != nil {
+		return nil, ErrInvalidAccountNumber
+	}
+	account, err := Banking().NameEnquiry(accountNumber, bankCode)
+	if errors.Is(err, ErrAccountNotProvided) {
+		return nil, ErrNameEnquiryFailed
+	}
+	if err != nil {
+		return nil, err
+	}
+	account.Bank = bank.Name
+	account.BankCode = bank.Code
+	return account, nil
+}
+
+// checkTransferLimits enforces BANK_TRANSFER_MIN_AMOUNT, BANK_TRANSFER_MAX_AMOUNT
+// and the BANK_TRANSFER_DAILY_LIMIT on transfers that have not been reversed
+func checkTransferLimits(tx *gorm.DB, userID uint, amount float64) error {
+	if amount < envFloat("BANK_TRANSFER_MIN_AMOUNT", 100) {
+		return ErrTransferBelowMinimum
+	}
+	if amount > envFloat("BANK_TRANSFER_MAX_AMOUNT", 1000000) {
+		return ErrTransferAboveMaximum
+	}
+	now := time.Now().In(lagos)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, lagos)
+	var sentToday float64
+	if err := tx.Model(&database.BankTransfer{}).
+		Where("user_id = ? AND created_at >= ? AND status <> ?", userID, startOfDay, BankTransferReversed).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&sentToday).Error; err != nil {
+		return err
+	}
+	if sentToday+amount > envFloat("BANK_TRANSFER_DAILY_LIMIT", 5000000) {
+		return ErrDailyLimitExceeded
+	}
+	return nil
+}
+
+// SendBankTransfer withdraws to an account at another bank. The PIN is checked
+// and the amount and fee are held on the wallet before the provider is called,
+// so the wallet cannot spend the money again while it is in flight.
+// The transfer stays pending until the webhook or the poller settles it.
+func SendBankTransfer(
+	user *database.User,
+	wallet *database.VirtualAccount,
+	accountNumber, bankCode string,
+	amount float64,
+	narration, pin, channel string,
+) (*database.BankTransfer, error) {
+	if err := VerifyTransactionPin(user, pin); err != nil {
+		return nil, err
+	}
+	account, err := NameEnquiry(accountNumber, bankCode)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := Banking()
+	transfer := database.BankTransfer{
+		UserID:        user.UserID,
+		Reference:     helpers.GenerateTransactionReference(),
+		Provider:      provider.Name(),
+		AccountNumber: accountNumber,
+		BankCode:      bankCode,
+		AccountName:   account.AccountName,
+		Amount:        amount,
+		Narration:     narration,
+		Status:        BankTransferPending,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockWallets(tx, wallet); err != nil {
+			return err
+		}
+		if err := checkTransferLimits(tx, user.UserID, amount); err != nil {
+			return err
+		}
+		quote, err := QuoteFee(tx, user, PricedBankTransfer, channel, amount)
+		if err != nil {
+			return err
+		}
+		transfer.Fee = quote.Fee
+		if _, err := PlaceHold(tx, wallet, HoldBankWithdrawal, transfer.Reference, quote.Total, nil); err != nil {
+			return err
+		}
+		if err := tx.Create(&transfer).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&database.Transaction{
+			UserID:             user.UserID,
+			Reference:          transfer.Reference,
+			TransactionEnviron: "outsideOlra",
+			TransactionType:    "bankTransfer-out",
+			Amount:             amount,
+			Fee:                quote.Fee,
+			Description:        narration,
+			Receiver:           account.AccountName,
+			ReceiverAccount:    accountNumber,
+			ReceiverBank:       account.Bank,
+			Sender:             user.Tag,
+			Status:             "pending",
+			TransactionDate:    time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		// The money is held rather than moved, so the user is shown what they
+		// can still spend
+		available, err := AvailableBalance(tx, wallet)
+		if err != nil {
+			return err
+		}
+		if err := Dispatch(tx, user, Notice{
+			Type:         NotificationDebit,
+			Event:        templates.EventDebit,
+			ResourceType: ResourceTransaction,
+			ResourceID:   transfer.Reference,
+			Data: templates.Data{
+				"Amount":  quote.Total,
+				"Balance": available,
+				"Date":    time.Now(),
+			},
+		}); err != nil {
+			return err
+		}
+		return dispatchLowBalance(tx, user, available+quote.Total, available)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := provider.Transfer(TransferInstruction{
+		Reference:     transfer.Reference,
+		AccountNumber: accountNumber,
+		BankCode:      bankCode,
+		AccountName:   account.AccountName,
+		Amount:        amount,
+		Narration:     narration,
+	})
+	switch {
+	case errors.Is(err, ErrTransferRejected):
+		log.Printf("Transfer %s rejected: %v", transfer.Reference, err)
+		reason := err.Error()
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			return SettleBankTransfer(tx, transfer.Reference, TransferFailed, reason)
+		}); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		// The provider may still have received it, so the poller finds out
+		// rather than releasing the hold now
+		log.Printf("Submitting transfer %s failed, leaving it for the poller: %v", transfer.Reference, err)
+	case result.Status != TransferPending:
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			return SettleBankTransfer(tx, transfer.Reference, result.Status, result.Message)
+		}); err != nil {
+			return nil, err
+		}
+	default:
+		database.DB.Model(&transfer).Update("provider_reference", result.ProviderReference)
+	}
+	if err := database.DB.First(&transfer, transfer.ID).Error; err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// SettleBankTransfer applies the provider's final answer. On success the hold
+// is captured, the amount going to the settlement account and the fee to
+// income; on failure the hold is released. A transfer already settled is
+// left as is.
+func SettleBankTransfer(tx *gorm.DB, reference, outcome, reason string) error {
+	var transfer database.BankTransfer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("reference = ?", reference).First(&transfer).Error; err != nil {
+		return ErrBankTransferNotFound
+	}
+	if transfer.Status != BankTransferPending || outcome == TransferPending {
+		return nil
+	}
+	// Every transfer holds its funds when it is sent, so a missing hold means
+	// the money was never reserved and must not be paid out
+	hold, err := findHold(tx, reference)
+	if err != nil {
+		return err
+	}
+	if hold == nil {
+		return ErrHoldNotFound
+	}
+	now := time.Now()
+	description := "Transfer to " + transfer.AccountName
+
+	if outcome == TransferSuccessful {
+		credits := []Leg{
+			{Account: SystemBankSettlementAccount, Amount: helpers.ToKobo(transfer.Amount)},
+			{Account: SystemFeeIncomeAccount, Amount: helpers.ToKobo(transfer.Fee)},
+		}
+		if _, err := CaptureHold(tx, reference, transfer.Reference, description, credits...); err != nil {
+			return err
+		}
+		if err := tx.Model(&database.Transaction{}).Where("reference = ?", reference).
+			Update("status", "completed").Error; err != nil {
+			return err
+		}
+		return tx.Model(&transfer).Updates(map[string]interface{}{
+			"status":       BankTransferSuccessful,
+			"completed_at": now,
+		}).Error
+	}
+
+	var wallet database.VirtualAccount
+	if err := tx.Where("user_id = ?", transfer.UserID).First(&wallet).Error; err != nil {
+		return err
+	}
+	if _, err := ReleaseHold(tx, reference); err != nil {
+		return err
+	}
+	if err := tx.Model(&database.Transaction{}).Where("reference = ?", reference).
+		Update("status", "reversed").Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&transfer).Updates(map[string]interface{}{
+		"status":       BankTransferReversed,
+		"error":        reason,
+		"completed_at": now,
+	}).Error; err != nil {
+		return err
+	}
+	if err := tx.First(&wallet, wallet.VirtualAccountID).Error; err != nil {
+		return err
+	}
+	available, err := AvailableBalance(tx, &wallet)
+	if err != nil {
+		return err
+	}
+	var user database.User
+	if err := tx.First(&user, transfer.UserID).Error; err != nil {
+		return err
+	}
+	return Dispatch(tx, &user, Notice{
+		Type:         NotificationDebit,
+		Event:        templates.EventTransferReversed,
+		ResourceType: ResourceTransaction,
+		ResourceID:   transfer.Reference,
+		Data: templates.Data{
+			"Amount":      transfer.Amount,
+			"Total":       transfer.Amount + transfer.Fee,
+			"AccountName": transfer.AccountName,
+			"Balance":     available,
+		},
+	})
+}
+
+// GetBankTransfer returns one of the user's transfers, for the app to poll
+func GetBankTransfer(userID uint, reference string) (*database.BankTransfer, error) {
+	var transfer database.BankTransfer
+	if err := database.DB.Where("user_id = ? AND reference = ?", userID, reference).
+		First(&transfer).Error; err != nil {
+		return nil, ErrBankTransferNotFound
+	}
+	return &transfer, nil
+}
+
+// StartBankTransferPoller asks the provider about pending transfers every
+// BANK_TRANSFER_POLL_SECONDS in case their webhook never arrives
+func StartBankTransferPoller() {
+	interval := time.Duration(envInt("BANK_TRANSFER_POLL_SECONDS", 60)) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := PollBankTransfers(); err != nil {
+				log.Println("Error polling bank transfers:", err)
+			}
+		}
+	}()
+}
+
+// PollBankTransfers settles transfers that have been pending for over a
+// minute. A transfer the provider has never heard of is reversed once it is
+// BANK_TRANSFER_UNKNOWN_MINUTES old, as no money can have left for it.
+func PollBankTransfers() error {
+	var transfers []database.BankTransfer
+	if err := database.DB.Where("status = ? AND created_at < ?", BankTransferPending, time.Now().Add(-time.Minute)).
+		Order("created_at").
+		Limit(100).
+		Find(&transfers).Error; err != nil {
+		return err
+	}
+	provider := Banking()
+	unknownAfter := time.Duration(envInt("BANK_TRANSFER_UNKNOWN_MINUTES", 30)) * time.Minute
+	for _, transfer := range transfers {
+		if transfer.Provider != provider.Name() {
+			continue
+		}
+		outcome, reason := TransferPending, ""
+		result, err := provider.TransferStatus(transfer.Reference)
+		switch {
+		case errors.Is(err, ErrTransferReferenceUnknown):
+			if time.Since(transfer.CreatedAt) > unknownAfter {
+				outcome, reason = TransferFailed, err.Error()
+			}
+		case err != nil:
+			log.Printf("Checking transfer %s failed: %v", transfer.Reference, err)
+			continue
+		default:
+			outcome, reason = result.Status, result.Message
+		}
+		if outcome == TransferPending {
+			continue
+		}
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			return SettleBankTransfer(tx, transfer.Reference, outcome, reason)
+		}); err != nil {
+			log.Printf("Settling transfer %s failed: %v", transfer.Reference, err)
+		}
+	}
+	return nil
+}
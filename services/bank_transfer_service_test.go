@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+// A GTBank account number, and the same one with the wrong check digit
+const (
+	testAccountNumber = "0000012349"
+	badCheckDigit     = "0000012348"
+)
+
+var bankTransferModels = append([]interface{}{
+	&database.BankTransfer{}, &database.BankDirectory{},
+}, walletModels...)
+
+// sendTestBankTransfer sends amount naira to another bank from a wallet
+// holding balance kobo
+func sendTestBankTransfer(t *testing.T, balance int64, amount float64) (*database.BankTransfer, *database.VirtualAccount) {
+	t.Helper()
+	db := dbtest.Open(t, bankTransferModels...)
+	useSandboxBanking(t)
+	db.Create(&database.BankDirectory{Code: "058", Name: "GTBank", Type: "commercial"})
+	user, wallet := createWalletUser(t, db, "ada", balance)
+	setTestPin(t, db, user, "1234")
+
+	transfer, err := SendBankTransfer(user, wallet, testAccountNumber, "058", amount, "rent", "1234", ChannelApp)
+	if err != nil {
+		t.Fatalf("SendBankTransfer() error = %v", err)
+	}
+	return transfer, wallet
+}
+
+// setTestPin sets the user's transaction PIN, hashed at the lowest cost so
+// tests stay fast
+func setTestPin(t *testing.T, db *gorm.DB, user *database.User, pin string) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.TransactionPinHash = string(hash)
+	if err := db.Model(user).Update("transaction_pin_hash", user.TransactionPinHash).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func settleTestBankTransfer(reference, outcome string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return SettleBankTransfer(tx, reference, outcome, "test")
+	})
+}
+
+func TestSettleBankTransfer(t *testing.T) {
+	tests := []struct {
+		name       string
+		outcome    string
+		status     string
+		txStatus   string
+		walletLeft int64
+	}{
+		{"successful", TransferSuccessful, BankTransferSuccessful, "completed", 500000},
+		{"failed", TransferFailed, BankTransferReversed, "reversed", 1000000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfer, wallet := sendTestBankTransfer(t, 1000000, 5000)
+			if transfer.Status != BankTransferPending {
+				t.Fatalf("status after sending = %q, want %q", transfer.Status, BankTransferPending)
+			}
+
+			if err := settleTestBankTransfer(transfer.Reference, tt.outcome); err != nil {
+				t.Fatalf("SettleBankTransfer() error = %v", err)
+			}
+			// A second answer from the provider changes nothing
+			if err := settleTestBankTransfer(transfer.Reference, TransferFailed); err != nil {
+				t.Fatalf("second SettleBankTransfer() error = %v", err)
+			}
+			var settled database.BankTransfer
+			database.DB.First(&settled, transfer.ID)
+			if settled.Status != tt.status || settled.CompletedAt == nil {
+				t.Errorf("transfer = %s completed at %v, want %s", settled.Status, settled.CompletedAt, tt.status)
+			}
+			var transaction database.Transaction
+			database.DB.Where("reference = ?", transfer.Reference).First(&transaction)
+			if transaction.Status != tt.txStatus {
+				t.Errorf("transaction status = %q, want %q", transaction.Status, tt.txStatus)
+			}
+			assertLedgerBalance(t, WalletAccount(wallet.VirtualAccountID), tt.walletLeft)
+		})
+	}
+}
+
+func TestSendBankTransferRefused(t *testing.T) {
+	db := dbtest.Open(t, bankTransferModels...)
+	useSandboxBanking(t)
+	db.Create(&database.BankDirectory{Code: "058", Name: "GTBank", Type: "commercial"})
+	user, wallet := createWalletUser(t, db, "ada", 1000000)
+	setTestPin(t, db, user, "1234")
+
+	tests := []struct {
+		name          string
+		accountNumber string
+		amount        float64
+		pin           string
+		err           error
+	}{
+		{"wrong PIN", testAccountNumber, 5000, "4321", ErrIncorrectPin},
+		{"more than the wallet holds", testAccountNumber, 10001, "1234", ErrInsufficientBalance},
+		{"below the minimum", testAccountNumber, 50, "1234", ErrTransferBelowMinimum},
+		{"bad check digit", badCheckDigit, 5000, "1234", ErrInvalidAccountNumber},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := SendBankTransfer(user, wallet, tt.accountNumber, "058", tt.amount, "", tt.pin, ChannelApp)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("SendBankTransfer() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+	var transfers int64
+	db.Model(&database.BankTransfer{}).Count(&transfers)
+	if transfers != 0 {
+		t.Errorf("%d transfers recorded, want none", transfers)
+	}
+}
+
+func TestPendingBankTransferHoldsFunds(t *testing.T) {
+	transfer, wallet := sendTestBankTransfer(t, 1000000, 5000)
+	// Nothing has left the wallet yet, but it cannot be spent again
+	assertLedgerBalance(t, WalletAccount(wallet.VirtualAccountID), 1000000)
+	assertAvailable(t, wallet, 5000)
+
+	// A transfer whose hold is gone is not paid out from nowhere
+	database.DB.Where("reference = ?", transfer.Reference).Delete(&database.Hold{})
+	if err := settleTestBankTransfer(transfer.Reference, TransferSuccessful); !errors.Is(err, ErrHoldNotFound) {
+		t.Fatalf("SettleBankTransfer() without a hold error = %v, want %v", err, ErrHoldNotFound)
+	}
+	var pending database.BankTransfer
+	database.DB.First(&pending, transfer.ID)
+	if pending.Status != BankTransferPending {
+		t.Errorf("status = %q, want %q", pending.Status, BankTransferPending)
+	}
+	assertLedgerBalance(t, WalletAccount(wallet.VirtualAccountID), 1000000)
+}
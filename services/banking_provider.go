@@ -0,0 +1,396 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/nuban"
+	helpers "olra-v1/utils"
+)
+
+// BankingProvider holds the bank accounts behind wallets and moves money to
+// other banks
+type BankingProvider interface {
+	Name() string
+	// CreateWallet asks for a new account. Most providers answer through a
+	// callback later, in which case the result has no account number yet.
+	CreateWallet(request WalletCreation) (*ProvisionResult, error)
+	GetAccount(accountNumber string) (*BankAccount, error)
+	CloseAccount(accountNumber string) error
+	// NameEnquiry resolves an account at any bank to its holder's name
+	NameEnquiry(accountNumber, bankCode string) (*BankAccount, error)
+	// Transfer sends money to another bank. The outcome usually arrives later,
+	// through the webhook or TransferStatus.
+	Transfer(instruction TransferInstruction) (*TransferResult, error)
+	TransferStatus(reference string) (*TransferResult, error)
+	// Statement lists what moved through our settlement account on the Lagos
+	// day starting at day
+	Statement(day time.Time) ([]StatementLine, error)
+}
+
+// WalletCreation is what the provider needs to open an account. Reference is
+// ours and comes back on the callback when the provider supports it.
+type WalletCreation struct {
+	Reference   string
+	AccountName string
+	FirstName   string
+	LastName    string
+	Email       string
+	PhoneNumber string
+	Gender      string
+	Dob         string
+}
+
+type ProvisionResult struct {
+	Reference     string
+	AccountNumber string // empty until the provider's callback arrives
+	AccountName   string
+	Bank          string
+}
+
+type BankAccount struct {
+	AccountNumber string  `json:"accountNumber"`
+	AccountName   string  `json:"accountName"`
+	Bank          string  `json:"bank"`
+	BankCode      string  `json:"bankCode,omitempty"`
+	Status        string  `json:"status"`
+	Balance       float64 `json:"balance"`
+}
+
+// TransferInstruction is an outbound transfer. Reference is ours and is sent
+// to the provider so a retried submission is not paid twice.
+type TransferInstruction struct {
+	Reference     string
+	AccountNumber string
+	BankCode      string
+	AccountName   string
+	Amount        float64
+	Narration     string
+}
+
+type TransferResult struct {
+	Reference         string
+	ProviderReference string
+	Status            string // pending, successful, failed
+	Message           string
+}
+
+// StatementLine is one movement on the settlement account as the bank reports
+// it. Credits are money in, debits money out.
+type StatementLine struct {
+	Reference string    `json:"reference"`
+	Amount    float64   `json:"amount"`
+	Direction string    `json:"direction"` // credit, debit
+	Date      time.Time `json:"date"`
+	Narration string    `json:"narration"`
+}
+
+const (
+	TransferPending    = "pending"
+	TransferSuccessful = "successful"
+	TransferFailed     = "failed"
+)
+
+const (
+	ProvisioningPending = "pending"
+	ProvisioningActive  = "active"
+	ProvisioningFailed  = "failed"
+	ProvisioningClosed  = "closed"
+
+	OwnerUser  = "user"
+	OwnerGroup = "group"
+)
+
+var (
+	ErrProvisioningNotFound = errors.New("no wallet provisioning found")
+	ErrAccountNotProvided   = errors.New("account not found at the banking provider")
+	// ErrTransferRejected means the provider declined the transfer outright, so
+	// no money can have left and the hold can be released
+	ErrTransferRejected = errors.New("transfer rejected by the banking provider")
+)
+
+// bankingProvider is read on every call to the bank and may be replaced at
+// any time, so both go through bankingProviderMu
+var (
+	bankingProvider   BankingProvider
+	bankingProviderMu sync.RWMutex
+)
+
+// Banking returns the configured provider. BANKING_PROVIDER picks wema or
+// sandbox; when it is unset Wema is used if WEMA_SUBSCRIPTION_KEY is set.
+func Banking() BankingProvider {
+	bankingProviderMu.RLock()
+	provider := bankingProvider
+	bankingProviderMu.RUnlock()
+	if provider != nil {
+		return provider
+	}
+	bankingProviderMu.Lock()
+	defer bankingProviderMu.Unlock()
+	if bankingProvider == nil {
+		bankingProvider = newBankingProviderFromEnv()
+	}
+	return bankingProvider
+}
+
+// SettlementAccountNumber is BANKING_SETTLEMENT_ACCOUNT, the omnibus account
+// at the provider that holds every wallet's money
+func SettlementAccountNumber() string {
+	return os.Getenv("BANKING_SETTLEMENT_ACCOUNT")
+}
+
+// SetBankingProvider replaces the provider, mainly so tests can install the
+// sandbox
+func SetBankingProvider(provider BankingProvider) {
+	bankingProviderMu.Lock()
+	defer bankingProviderMu.Unlock()
+	bankingProvider = provider
+}
+
+func newBankingProviderFromEnv() BankingProvider {
+	switch os.Getenv("BANKING_PROVIDER") {
+	case "wema":
+		return NewWemaProvider()
+	case "sandbox":
+		return NewSandboxBankingProvider()
+	}
+	if os.Getenv("WEMA_SUBSCRIPTION_KEY") != "" {
+		return NewWemaProvider()
+	}
+	log.Println("WEMA_SUBSCRIPTION_KEY not set, using the sandbox banking provider")
+	return NewSandboxBankingProvider()
+}
+
+// ProvisionUserWallet requests an account number for the user's wallet. It
+// returns the open request instead of making another while one is pending or
+// done.
+func ProvisionUserWallet(user *database.User) (*database.AccountProvisioning, error) {
+	return provisionWallet(OwnerUser, user.UserID, WalletCreation{
+		AccountName: user.FirstName + " " + user.LastName,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Email:       user.Email,
+		PhoneNumber: user.PhoneNumber,
+	})
+}
+
+// ProvisionGroupWallet requests an account number for a group wallet, opened
+// with the group admin's contact details
+func ProvisionGroupWallet(group *database.Group, admin *database.User) (*database.AccountProvisioning, error) {
+	return provisionWallet(OwnerGroup, group.GroupID, WalletCreation{
+		AccountName: group.GroupName,
+		FirstName:   admin.FirstName,
+		LastName:    admin.LastName,
+		Email:       admin.Email,
+		PhoneNumber: admin.PhoneNumber,
+	})
+}
+
+func provisionWallet(ownerType string, ownerID uint, request WalletCreation) (*database.AccountProvisioning, error) {
+	var existing database.AccountProvisioning
+	err := database.DB.Where("owner_type = ? AND owner_id = ? AND status IN ?",
+		ownerType, ownerID, []string{ProvisioningPending, ProvisioningActive}).
+		Order("created_at desc").
+		First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	provider := Banking()
+	request.Reference = helpers.GenerateTransactionReference()
+	provisioning := database.AccountProvisioning{
+		Provider:    provider.Name(),
+		Reference:   request.Reference,
+		OwnerType:   ownerType,
+		OwnerID:     ownerID,
+		AccountName: request.AccountName,
+		PhoneNumber: request.PhoneNumber,
+		Email:       request.Email,
+		Status:      ProvisioningPending,
+	}
+	if err := database.DB.Create(&provisioning).Error; err != nil {
+		return nil, err
+	}
+
+	result, err := provider.CreateWallet(request)
+	if err != nil {
+		log.Printf("Wallet provisioning %s failed: %v", provisioning.Reference, err)
+		provisioning.Status = ProvisioningFailed
+		provisioning.Error = err.Error()
+		database.DB.Save(&provisioning)
+		return &provisioning, err
+	}
+	if result.AccountNumber == "" {
+		return &provisioning, nil
+	}
+	// The provider answered straight away, so there is no callback to wait for
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return completeProvisioning(tx, &provisioning, result.AccountNumber, result.AccountName, result.Bank)
+	}); err != nil {
+		return nil, err
+	}
+	return &provisioning, nil
+}
+
+// IssueAccountNumber takes the next serial for the bank code and returns its
+// NUBAN. The sequence row is locked so two callers never share a serial.
+func IssueAccountNumber(bankCode string) (string, error) {
+	var accountNumber string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&database.NubanSequence{BankCode: bankCode}).Error; err != nil {
+			return err
+		}
+		var sequence database.NubanSequence
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("bank_code = ?", bankCode).First(&sequence).Error; err != nil {
+			return err
+		}
+		number, err := nuban.Generate(bankCode, sequence.LastSerial+1)
+		if err != nil {
+			return err
+		}
+		accountNumber = number
+		return tx.Model(&sequence).Update("last_serial", sequence.LastSerial+1).Error
+	})
+	return accountNumber, err
+}
+
+// ProvisioningCallback is the provider telling us an account is ready. The
+// reference is used when the provider echoes it; otherwise the oldest pending
+// request for the phone number or email is matched.
+type ProvisioningCallback struct {
+	Reference     string
+	PhoneNumber   string
+	Email         string
+	AccountNumber string
+	AccountName   string
+	Bank          string
+}
+
+// CompleteProvisioning creates the wallet for a provisioning request the
+// provider has fulfilled. A callback for an already active request is ignored.
+func CompleteProvisioning(tx *gorm.DB, callback ProvisioningCallback) (*database.AccountProvisioning, error) {
+	var provisioning database.AccountProvisioning
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	switch {
+	case callback.Reference != "":
+		query = query.Where("reference = ?", callback.Reference)
+	case callback.PhoneNumber != "":
+		query = query.Where("phone_number = ? AND status = ?", callback.PhoneNumber, ProvisioningPending)
+	case callback.Email != "":
+		query = query.Where("email = ? AND status = ?", callback.Email, ProvisioningPending)
+	default:
+		return nil, ErrProvisioningNotFound
+	}
+	if err := query.Order("created_at").First(&provisioning).Error; err != nil {
+		return nil, ErrProvisioningNotFound
+	}
+	if provisioning.Status == ProvisioningActive && provisioning.AccountNumber == callback.AccountNumber {
+		return &provisioning, nil
+	}
+	// Wema only issues its own NUBANs, so anything else is a corrupt callback
+	if provisioning.Provider == "wema" && nuban.Validate(wemaBankCode, callback.AccountNumber) != nil {
+		return nil, ErrInvalidAccountNumber
+	}
+	name := callback.AccountName
+	if name == "" {
+		name = provisioning.AccountName
+	}
+	return &provisioning, completeProvisioning(tx, &provisioning, callback.AccountNumber, name, callback.Bank)
+}
+
+// completeProvisioning writes the wallet row, updating a wallet the owner
+// already has rather than giving them a second one
+func completeProvisioning(tx *gorm.DB, provisioning *database.AccountProvisioning, accountNumber, accountName, bank string) error {
+	switch provisioning.OwnerType {
+	case OwnerUser:
+		var wallet database.VirtualAccount
+		err := tx.Where("user_id = ?", provisioning.OwnerID).First(&wallet).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = tx.Create(&database.VirtualAccount{
+				VirtualAccountBank:    bank,
+				VirtualAccountAccount: accountNumber,
+				VirtualAccountName:    accountName,
+				UserID:                provisioning.OwnerID,
+			}).Error
+		} else if err == nil {
+			err = tx.Model(&wallet).Updates(map[string]interface{}{
+				"virtual_account_bank":    bank,
+				"virtual_account_account": accountNumber,
+				"virtual_account_name":    accountName,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+	case OwnerGroup:
+		var wallet database.GroupVirtualAccount
+		err := tx.Where("group_id = ?", provisioning.OwnerID).First(&wallet).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = tx.Create(&database.GroupVirtualAccount{
+				GroupID:                   provisioning.OwnerID,
+				GroupVirtualAccountBank:   bank,
+				GroupVirtualAccountNumber: accountNumber,
+				GroupVirtualAccountName:   accountName,
+			}).Error
+		} else if err == nil {
+			err = tx.Model(&wallet).Updates(map[string]interface{}{
+				"group_virtual_account_bank":   bank,
+				"group_virtual_account_number": accountNumber,
+				"group_virtual_account_name":   accountName,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+	provisioning.Status = ProvisioningActive
+	provisioning.AccountNumber = accountNumber
+	provisioning.AccountName = accountName
+	provisioning.Bank = bank
+	provisioning.Error = ""
+	return tx.Save(provisioning).Error
+}
+
+// WalletStatus is the latest provisioning request for an owner, for the app to
+// poll while the account number is pending
+func WalletStatus(ownerType string, ownerID uint) (*database.AccountProvisioning, error) {
+	var provisioning database.AccountProvisioning
+	if err := database.DB.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Order("created_at desc").
+		First(&provisioning).Error; err != nil {
+		return nil, ErrProvisioningNotFound
+	}
+	return &provisioning, nil
+}
+
+// closeProvisionedWallet closes the user's account at the provider that opened
+// it. The wallet row itself is kept for retention.
+func closeProvisionedWallet(userID uint) error {
+	var provisioning database.AccountProvisioning
+	if err := database.DB.Where("owner_type = ? AND owner_id = ? AND status = ?",
+		OwnerUser, userID, ProvisioningActive).First(&provisioning).Error; err != nil {
+		return nil
+	}
+	provider := Banking()
+	if provider.Name() != provisioning.Provider {
+		log.Printf("Account %s was opened with %s, not closing it with %s",
+			provisioning.AccountNumber, provisioning.Provider, provider.Name())
+		return nil
+	}
+	if err := provider.CloseAccount(provisioning.AccountNumber); err != nil {
+		return err
+	}
+	return database.DB.Model(&provisioning).Update("status", ProvisioningClosed).Error
+}
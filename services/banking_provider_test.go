@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+var provisioningModels = []interface{}{
+	&database.AccountProvisioning{}, &database.VirtualAccount{}, &database.GroupVirtualAccount{},
+	&database.NubanSequence{},
+}
+
+// useSandboxBanking installs a sandbox that answers long after the test ends
+func useSandboxBanking(t *testing.T) {
+	t.Helper()
+	t.Setenv("BANKING_SANDBOX_DELAY_MS", "3600000")
+	previous := Banking()
+	SetBankingProvider(NewSandboxBankingProvider())
+	t.Cleanup(func() { SetBankingProvider(previous) })
+}
+
+func completeTestProvisioning(callback ProvisioningCallback) (*database.AccountProvisioning, error) {
+	var provisioning *database.AccountProvisioning
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		provisioning, err = CompleteProvisioning(tx, callback)
+		return err
+	})
+	return provisioning, err
+}
+
+func TestProvisionUserWallet(t *testing.T) {
+	db := dbtest.Open(t, provisioningModels...)
+	useSandboxBanking(t)
+	user := &database.User{UserID: 7, FirstName: "Ada", LastName: "Obi", Email: "ada@example.com", PhoneNumber: "08000000001"}
+
+	provisioning, err := ProvisionUserWallet(user)
+	if err != nil {
+		t.Fatalf("ProvisionUserWallet() error = %v", err)
+	}
+	if provisioning.Status != ProvisioningPending || provisioning.Provider != "sandbox" {
+		t.Errorf("provisioning = %s with %s, want pending with the sandbox", provisioning.Status, provisioning.Provider)
+	}
+	// Asking again while the provider is working returns the same request
+	again, err := ProvisionUserWallet(user)
+	if err != nil || again.ID != provisioning.ID {
+		t.Errorf("second ProvisionUserWallet() = %+v, %v, want request %d", again, err, provisioning.ID)
+	}
+
+	callback := ProvisioningCallback{Reference: provisioning.Reference, AccountNumber: "9000000017", Bank: "Wema Bank"}
+	for i := 0; i < 2; i++ {
+		completed, err := completeTestProvisioning(callback)
+		if err != nil {
+			t.Fatalf("CompleteProvisioning() error = %v", err)
+		}
+		if completed.Status != ProvisioningActive || completed.AccountName != "Ada Obi" {
+			t.Errorf("provisioning = %s for %q, want active for Ada Obi", completed.Status, completed.AccountName)
+		}
+	}
+	var wallets []database.VirtualAccount
+	db.Where("user_id = ?", user.UserID).Find(&wallets)
+	if len(wallets) != 1 || wallets[0].VirtualAccountAccount != "9000000017" || wallets[0].Balance != 0 {
+		t.Errorf("wallets = %+v, want one empty wallet numbered 9000000017", wallets)
+	}
+}
+
+func TestCompleteProvisioningForGroup(t *testing.T) {
+	db := dbtest.Open(t, provisioningModels...)
+	useSandboxBanking(t)
+	group := &database.Group{GroupID: 3, GroupName: "Savers"}
+	admin := &database.User{UserID: 7, FirstName: "Ada", LastName: "Obi", Email: "ada@example.com", PhoneNumber: "08000000001"}
+	if _, err := ProvisionGroupWallet(group, admin); err != nil {
+		t.Fatalf("ProvisionGroupWallet() error = %v", err)
+	}
+
+	// Matched by email when the provider does not echo our reference
+	if _, err := completeTestProvisioning(ProvisioningCallback{Email: admin.Email, AccountNumber: "9000000024",
+		Bank: "Wema Bank"}); err != nil {
+		t.Fatalf("CompleteProvisioning() error = %v", err)
+	}
+	var wallet database.GroupVirtualAccount
+	if err := db.Where("group_id = ?", group.GroupID).First(&wallet).Error; err != nil {
+		t.Fatalf("no group wallet: %v", err)
+	}
+	if wallet.GroupVirtualAccountNumber != "9000000024" || wallet.GroupVirtualAccountName != "Savers" {
+		t.Errorf("group wallet = %s %q, want 9000000024 Savers", wallet.GroupVirtualAccountNumber, wallet.GroupVirtualAccountName)
+	}
+	if _, err := completeTestProvisioning(ProvisioningCallback{Reference: "unknown", AccountNumber: "9000000031"}); !errors.Is(err, ErrProvisioningNotFound) {
+		t.Errorf("CompleteProvisioning() for an unknown request error = %v, want %v", err, ErrProvisioningNotFound)
+	}
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+)
+
+const sandboxBankName = "Olra Sandbox Bank"
+
+// SandboxBankingProvider opens accounts and sends transfers locally. Like a
+// real provider it answers later through a callback, after
+// BANKING_SANDBOX_DELAY_MS. Accounts are numbered as NUBANs of
+// BANKING_SANDBOX_BANK_CODE. External account numbers ending in 0000 do not
+// exist and transfers to ones ending in 9999 fail.
+type SandboxBankingProvider struct {
+	Delay     time.Duration
+	BankCode  string
+	mu        sync.Mutex
+	accounts  map[string]*BankAccount
+	transfers map[string]*TransferResult
+	paid      []StatementLine
+}
+
+func NewSandboxBankingProvider() *SandboxBankingProvider {
+	bankCode := os.Getenv("BANKING_SANDBOX_BANK_CODE")
+	if bankCode == "" {
+		bankCode = wemaBankCode
+	}
+	return &SandboxBankingProvider{
+		Delay:     time.Duration(envInt("BANKING_SANDBOX_DELAY_MS", 2000)) * time.Millisecond,
+		BankCode:  bankCode,
+		accounts:  map[string]*BankAccount{},
+		transfers: map[string]*TransferResult{},
+	}
+}
+
+func (s *SandboxBankingProvider) Name() string {
+	return "sandbox"
+}
+
+func (s *SandboxBankingProvider) CreateWallet(request WalletCreation) (*ProvisionResult, error) {
+	accountNumber, err := s.newAccountNumber()
+	if err != nil {
+		return nil, err
+	}
+	account := &BankAccount{
+		AccountNumber: accountNumber,
+		AccountName:   request.AccountName,
+		Bank:          sandboxBankName,
+		Status:        "active",
+	}
+	s.mu.Lock()
+	s.accounts[accountNumber] = account
+	s.mu.Unlock()
+
+	time.AfterFunc(s.Delay, func() {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			_, err := CompleteProvisioning(tx, ProvisioningCallback{
+				Reference:     request.Reference,
+				AccountNumber: account.AccountNumber,
+				AccountName:   account.AccountName,
+				Bank:          account.Bank,
+			})
+			return err
+		})
+		if err != nil {
+			log.Printf("Sandbox callback for %s failed: %v", request.Reference, err)
+		}
+	})
+	return &ProvisionResult{Reference: request.Reference}, nil
+}
+
+func (s *SandboxBankingProvider) GetAccount(accountNumber string) (*BankAccount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.accounts[accountNumber]
+	if !ok {
+		return nil, ErrAccountNotProvided
+	}
+	copied := *account
+	return &copied, nil
+}
+
+func (s *SandboxBankingProvider) CloseAccount(accountNumber string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.accounts[accountNumber]
+	if !ok {
+		return ErrAccountNotProvided
+	}
+	account.Status = "closed"
+	return nil
+}
+
+func (s *SandboxBankingProvider) NameEnquiry(accountNumber, bankCode string) (*BankAccount, error) {
+	if strings.HasSuffix(accountNumber, "0000") {
+		return nil, ErrAccountNotProvided
+	}
+	return &BankAccount{
+		AccountNumber: accountNumber,
+		AccountName:   "Sandbox Account " + accountNumber[len(accountNumber)-4:],
+		Bank:          "Sandbox Bank " + bankCode,
+		BankCode:      bankCode,
+		Status:        "active",
+	}, nil
+}
+
+func (s *SandboxBankingProvider) Transfer(instruction TransferInstruction) (*TransferResult, error) {
+	if strings.HasSuffix(instruction.AccountNumber, "0000") {
+		return nil, fmt.Errorf("%w: unknown account", ErrTransferRejected)
+	}
+	result := &TransferResult{
+		Reference:         instruction.Reference,
+		ProviderReference: "SBX" + instruction.Reference,
+		Status:            TransferPending,
+	}
+	s.mu.Lock()
+	s.transfers[instruction.Reference] = result
+	s.mu.Unlock()
+
+	time.AfterFunc(s.Delay, func() {
+		outcome := TransferSuccessful
+		if strings.HasSuffix(instruction.AccountNumber, "9999") {
+			outcome = TransferFailed
+		}
+		s.mu.Lock()
+		result.Status = outcome
+		if outcome == TransferSuccessful {
+			s.paid = append(s.paid, StatementLine{
+				Reference: instruction.Reference,
+				Amount:    instruction.Amount,
+				Direction: StatementDebit,
+				Date:      time.Now(),
+				Narration: instruction.Narration,
+			})
+		}
+		s.mu.Unlock()
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			return SettleBankTransfer(tx, instruction.Reference, outcome, "sandbox")
+		})
+		if err != nil {
+			log.Printf("Sandbox callback for transfer %s failed: %v", instruction.Reference, err)
+		}
+	})
+	copied := *result
+	return &copied, nil
+}
+
+func (s *SandboxBankingProvider) TransferStatus(reference string) (*TransferResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, ok := s.transfers[reference]
+	if !ok {
+		return nil, ErrTransferReferenceUnknown
+	}
+	copied := *result
+	return &copied, nil
+}
+
+// Statement reports the transfers the sandbox has paid out since it started
+// and the inbound credits recorded against it, which arrive on the webhook
+func (s *SandboxBankingProvider) Statement(day time.Time) ([]StatementLine, error) {
+	end := day.Add(24 * time.Hour)
+	var lines []StatementLine
+	s.mu.Lock()
+	for _, line := range s.paid {
+		if !line.Date.Before(day) && line.Date.Before(end) {
+			lines = append(lines, line)
+		}
+	}
+	s.mu.Unlock()
+
+	var credits []database.InboundCredit
+	if err := database.DB.Where("provider = ? AND created_at >= ? AND created_at < ?", s.Name(), day, end).
+		Find(&credits).Error; err != nil {
+		return nil, err
+	}
+	for _, credit := range credits {
+		lines = append(lines, StatementLine{
+			Reference: credit.SessionID,
+			Amount:    credit.Amount,
+			Direction: StatementCredit,
+			Date:      credit.CreatedAt,
+			Narration: credit.Narration,
+		})
+	}
+	return lines, nil
+}
+
+// newAccountNumber issues the next number in the sequence, stepping over any
+// that a wallet already uses from before numbers were issued in order
+func (s *SandboxBankingProvider) newAccountNumber() (string, error) {
+	for {
+		candidate, err := IssueAccountNumber(s.BankCode)
+		if err != nil {
+			return "", err
+		}
+		var taken int64
+		if err := database.DB.Model(&database.VirtualAccount{}).
+			Where("virtual_account_account = ?", candidate).Count(&taken).Error; err != nil {
+			return "", err
+		}
+		if taken == 0 {
+			if err := database.DB.Model(&database.GroupVirtualAccount{}).
+				Where("group_virtual_account_number = ?", candidate).Count(&taken).Error; err != nil {
+				return "", err
+			}
+		}
+		s.mu.Lock()
+		_, issued := s.accounts[candidate]
+		s.mu.Unlock()
+		if taken == 0 && !issued {
+			return candidate, nil
+		}
+	}
+}
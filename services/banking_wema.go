@@ -0,0 +1,297 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	wemaBankName = "Wema Bank"
+	wemaBankCode = "035"
+)
+
+// WemaProvider opens wallets through the Wema ALAT wallet API and sends
+// transfers over its funds transfer API. New account numbers and transfer
+// outcomes arrive on the webhook.
+type WemaProvider struct {
+	BaseURL         string
+	SubscriptionKey string
+	client          *http.Client
+}
+
+// NewWemaProvider reads WEMA_BASE_URL and WEMA_SUBSCRIPTION_KEY
+func NewWemaProvider() *WemaProvider {
+	baseURL := os.Getenv("WEMA_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://wema-alatdev-apimgt.azure-api.net"
+	}
+	return &WemaProvider{
+		BaseURL:         baseURL,
+		SubscriptionKey: os.Getenv("WEMA_SUBSCRIPTION_KEY"),
+		client:          &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (w *WemaProvider) Name() string {
+	return "wema"
+}
+
+type wemaWalletRequest struct {
+	Gender      string `json:"gender"`
+	Email       string `json:"email"`
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	Dob         string `json:"dob"`
+	PhoneNumber string `json:"phoneNumber"`
+}
+
+type wemaResponse struct {
+	Successful bool            `json:"Successful"`
+	Message    string          `json:"Message"`
+	Data       json.RawMessage `json:"Data"`
+}
+
+func (w *WemaProvider) CreateWallet(request WalletCreation) (*ProvisionResult, error) {
+	_, err := w.do(http.MethodPost, "/onboarding-wallets/api/CustomerAccount/GenerateWalletV2", wemaWalletRequest{
+		Gender:      request.Gender,
+		Email:       request.Email,
+		FirstName:   request.FirstName,
+		LastName:    request.LastName,
+		Dob:         request.Dob,
+		PhoneNumber: request.PhoneNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ProvisionResult{Reference: request.Reference, Bank: wemaBankName}, nil
+}
+
+func (w *WemaProvider) GetAccount(accountNumber string) (*BankAccount, error) {
+	response, err := w.do(http.MethodGet,
+		"/onboarding-wallets/api/CustomerAccount/GetWalletDetails?accountNumber="+url.QueryEscape(accountNumber), nil)
+	if err != nil {
+		return nil, err
+	}
+	var details struct {
+		NUBAN       string  `json:"NUBAN"`
+		NUBANName   string  `json:"NUBANName"`
+		NUBANStatus string  `json:"NUBANStatus"`
+		Balance     float64 `json:"AvailableBalance"`
+	}
+	if err := json.Unmarshal(response.Data, &details); err != nil || details.NUBAN == "" {
+		return nil, ErrAccountNotProvided
+	}
+	return &BankAccount{
+		AccountNumber: details.NUBAN,
+		AccountName:   details.NUBANName,
+		Bank:          wemaBankName,
+		Status:        details.NUBANStatus,
+		Balance:       details.Balance,
+	}, nil
+}
+
+func (w *WemaProvider) CloseAccount(accountNumber string) error {
+	_, err := w.do(http.MethodPost, "/onboarding-wallets/api/CustomerAccount/CloseWallet",
+		map[string]string{"accountNumber": accountNumber})
+	return err
+}
+
+func (w *WemaProvider) NameEnquiry(accountNumber, bankCode string) (*BankAccount, error) {
+	response, err := w.do(http.MethodGet, "/fundstransfer/api/FundsTransfer/NameEnquiry?accountNumber="+
+		url.QueryEscape(accountNumber)+"&bankCode="+url.QueryEscape(bankCode), nil)
+	var apiErr *wemaAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+		return nil, ErrAccountNotProvided
+	}
+	if err != nil {
+		return nil, err
+	}
+	var details struct {
+		AccountName string `json:"AccountName"`
+		BankName    string `json:"BankName"`
+	}
+	if err := json.Unmarshal(response.Data, &details); err != nil || details.AccountName == "" {
+		return nil, ErrAccountNotProvided
+	}
+	return &BankAccount{
+		AccountNumber: accountNumber,
+		AccountName:   details.AccountName,
+		Bank:          details.BankName,
+		BankCode:      bankCode,
+	}, nil
+}
+
+type wemaTransferRequest struct {
+	TransactionReference     string  `json:"transactionReference"`
+	DestinationAccountNumber string  `json:"destinationAccountNumber"`
+	DestinationBankCode      string  `json:"destinationBankCode"`
+	DestinationAccountName   string  `json:"destinationAccountName"`
+	Amount                   float64 `json:"amount"`
+	Narration                string  `json:"narration"`
+}
+
+type wemaTransferStatus struct {
+	SessionID string `json:"SessionID"`
+	Status    string `json:"Status"`
+	Message   string `json:"Message"`
+}
+
+func (w *WemaProvider) Transfer(instruction TransferInstruction) (*TransferResult, error) {
+	response, err := w.do(http.MethodPost, "/fundstransfer/api/FundsTransfer/Transfer", wemaTransferRequest{
+		TransactionReference:     instruction.Reference,
+		DestinationAccountNumber: instruction.AccountNumber,
+		DestinationBankCode:      instruction.BankCode,
+		DestinationAccountName:   instruction.AccountName,
+		Amount:                   instruction.Amount,
+		Narration:                instruction.Narration,
+	})
+	var apiErr *wemaAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+		return nil, fmt.Errorf("%w: %s", ErrTransferRejected, apiErr.Message)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w.transferResult(instruction.Reference, response), nil
+}
+
+func (w *WemaProvider) TransferStatus(reference string) (*TransferResult, error) {
+	response, err := w.do(http.MethodGet,
+		"/fundstransfer/api/FundsTransfer/TransactionStatus?transactionReference="+url.QueryEscape(reference), nil)
+	var apiErr *wemaAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, ErrTransferReferenceUnknown
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w.transferResult(reference, response), nil
+}
+
+type wemaStatementEntry struct {
+	TransactionReference string  `json:"TransactionReference"`
+	SessionID            string  `json:"SessionID"`
+	Amount               float64 `json:"Amount"`
+	TranType             string  `json:"TranType"` // C or D
+	TransactionDate      string  `json:"TransactionDate"`
+	Narration            string  `json:"Narration"`
+}
+
+// Statement reads the settlement account for the day.
+// Inbound credits are known to us by NIP session id and our own transfers by
+// the reference we sent, so each line carries whichever we match on.
+func (w *WemaProvider) Statement(day time.Time) ([]StatementLine, error) {
+	query := url.Values{
+		"accountNumber": {SettlementAccountNumber()},
+		"fromDate":      {day.Format("2006-01-02")},
+		"toDate":        {day.Format("2006-01-02")},
+	}
+	response, err := w.do(http.MethodGet, "/accountmaintenance/api/Account/GetStatement?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var entries []wemaStatementEntry
+	if err := json.Unmarshal(response.Data, &entries); err != nil {
+		return nil, err
+	}
+	lines := make([]StatementLine, 0, len(entries))
+	for _, entry := range entries {
+		line := StatementLine{
+			Reference: entry.TransactionReference,
+			Amount:    entry.Amount,
+			Direction: StatementDebit,
+			Narration: entry.Narration,
+		}
+		if strings.EqualFold(entry.TranType, "C") {
+			line.Direction = StatementCredit
+			if entry.SessionID != "" {
+				line.Reference = entry.SessionID
+			}
+		}
+		if line.Reference == "" {
+			line.Reference = entry.SessionID
+		}
+		line.Date, _ = time.ParseInLocation("2006-01-02T15:04:05", entry.TransactionDate, lagos)
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func (w *WemaProvider) transferResult(reference string, response *wemaResponse) *TransferResult {
+	var status wemaTransferStatus
+	json.Unmarshal(response.Data, &status)
+	return &TransferResult{
+		Reference:         reference,
+		ProviderReference: status.SessionID,
+		Status:            wemaTransferOutcome(status.Status),
+		Message:           status.Message,
+	}
+}
+
+// wemaTransferOutcome maps Wema's status, an NIP response code or a word,
+// onto ours. Anything not clearly final is still pending.
+func wemaTransferOutcome(status string) string {
+	switch strings.ToLower(status) {
+	case "00", "successful", "success", "completed":
+		return TransferSuccessful
+	case "failed", "reversed", "declined":
+		return TransferFailed
+	}
+	return TransferPending
+}
+
+// wemaAPIError is a response Wema returned but marked unsuccessful, as opposed
+// to a request that never got an answer
+type wemaAPIError struct {
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *wemaAPIError) Error() string {
+	return fmt.Sprintf("wema %s: status %d: %s", e.Path, e.StatusCode, e.Message)
+}
+
+// do sends a request and fails unless Wema reports it successful
+func (w *WemaProvider) do(method, path string, body interface{}) (*wemaResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, w.BaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Ocp-Apim-Subscription-Key", w.SubscriptionKey)
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	var response wemaResponse
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return nil, fmt.Errorf("wema %s: status %d: %s", path, res.StatusCode, raw)
+	}
+	if res.StatusCode >= 300 || !response.Successful {
+		return nil, &wemaAPIError{Path: path, StatusCode: res.StatusCode, Message: response.Message}
+	}
+	return &response, nil
+}
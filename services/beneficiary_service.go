@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+)
+
+var (
+	ErrUnknownBank         = errors.New("unknown bank code")
+	ErrBeneficiaryExists   = errors.New("this account is already saved")
+	ErrBeneficiaryNotFound = errors.New("beneficiary not found")
+)
+
+// bankDirectory is seeded into the bank_directories table at start up. Banks
+// use their CBN code; mobile money operators and fintech banks use the NIP
+// institution code they are known by.
+var bankDirectory = []database.BankDirectory{
+	{Code: "044", Name: "Access Bank", Type: "commercial"},
+	{Code: "023", Name: "Citibank Nigeria", Type: "commercial"},
+	{Code: "050", Name: "Ecobank Nigeria", Type: "commercial"},
+	{Code: "070", Name: "Fidelity Bank", Type: "commercial"},
+	{Code: "011", Name: "First Bank of Nigeria", Type: "commercial"},
+	{Code: "214", Name: "First City Monument Bank", Type: "commercial"},
+	{Code: "103", Name: "Globus Bank", Type: "commercial"},
+	{Code: "058", Name: "Guaranty Trust Bank", Type: "commercial"},
+	{Code: "030", Name: "Heritage Bank", Type: "commercial"},
+	{Code: "082", Name: "Keystone Bank", Type: "commercial"},
+	{Code: "107", Name: "Optimus Bank", Type: "commercial"},
+	{Code: "104", Name: "Parallex Bank", Type: "commercial"},
+	{Code: "076", Name: "Polaris Bank", Type: "commercial"},
+	{Code: "105", Name: "PremiumTrust Bank", Type: "commercial"},
+	{Code: "101", Name: "Providus Bank", Type: "commercial"},
+	{Code: "106", Name: "Signature Bank", Type: "commercial"},
+	{Code: "221", Name: "Stanbic IBTC Bank", Type: "commercial"},
+	{Code: "068", Name: "Standard Chartered Bank", Type: "commercial"},
+	{Code: "232", Name: "Sterling Bank", Type: "commercial"},
+	{Code: "100", Name: "SunTrust Bank", Type: "commercial"},
+	{Code: "102", Name: "Titan Trust Bank", Type: "commercial"},
+	{Code: "032", Name: "Union Bank of Nigeria", Type: "commercial"},
+	{Code: "033", Name: "United Bank for Africa", Type: "commercial"},
+	{Code: "215", Name: "Unity Bank", Type: "commercial"},
+	{Code: "035", Name: "Wema Bank", Type: "commercial"},
+	{Code: "057", Name: "Zenith Bank", Type: "commercial"},
+	{Code: "559", Name: "Coronation Merchant Bank", Type: "merchant"},
+	{Code: "060", Name: "FBNQuest Merchant Bank", Type: "merchant"},
+	{Code: "502", Name: "Rand Merchant Bank", Type: "merchant"},
+	{Code: "301", Name: "Jaiz Bank", Type: "non-interest"},
+	{Code: "303", Name: "Lotus Bank", Type: "non-interest"},
+	{Code: "302", Name: "TAJ Bank", Type: "non-interest"},
+	{Code: "50211", Name: "Kuda Microfinance Bank", Type: "microfinance"},
+	{Code: "50515", Name: "Moniepoint Microfinance Bank", Type: "microfinance"},
+	{Code: "566", Name: "VFD Microfinance Bank", Type: "microfinance"},
+	{Code: "999992", Name: "OPay", Type: "fintech"},
+	{Code: "999991", Name: "PalmPay", Type: "fintech"},
+}
+
+// SeedBankDirectory writes the built-in directory, updating names that have
+// changed and leaving banks added by hand alone
+func SeedBankDirectory() error {
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "type"}),
+	}).Create(&bankDirectory).Error
+}
+
+// SearchBanks matches the query against bank names and codes
+func SearchBanks(query string) ([]database.BankDirectory, error) {
+	var banks []database.BankDirectory
+	db := database.DB.Order("name")
+	if query = strings.TrimSpace(query); query != "" {
+		db = db.Where("name ILIKE ? OR code = ?", "%"+query+"%", query)
+	}
+	err := db.Find(&banks).Error
+	return banks, err
+}
+
+func lookupBank(code string) (*database.BankDirectory, error) {
+	var bank database.BankDirectory
+	if err := database.DB.Where("code = ?", code).First(&bank).Error; err != nil {
+		return nil, ErrUnknownBank
+	}
+	return &bank, nil
+}
+
+func ListBeneficiaries(userID uint) ([]database.Bank, error) {
+	var beneficiaries []database.Bank
+	err := database.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&beneficiaries).Error
+	return beneficiaries, err
+}
+
+// AddBeneficiary saves an account after name enquiry so the name shown later
+// is the one the bank holds, not one the user typed
+func AddBeneficiary(userID uint, accountNumber, bankCode, nickname string) (*database.Bank, error) {
+	account, err := NameEnquiry(accountNumber, bankCode)
+	if err != nil {
+		return nil, err
+	}
+	beneficiary := database.Bank{
+		UserID:        userID,
+		BankName:      account.Bank,
+		BankCode:      bankCode,
+		AccountNumber: accountNumber,
+		AccountName:   account.AccountName,
+		Nickname:      nickname,
+	}
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&beneficiary)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrBeneficiaryExists
+	}
+	return &beneficiary, nil
+}
+
+func UpdateBeneficiary(userID, id uint, nickname string) (*database.Bank, error) {
+	var beneficiary database.Bank
+	if err := database.DB.Where("user_id = ? AND bank_id = ?", userID, id).First(&beneficiary).Error; err != nil {
+		return nil, ErrBeneficiaryNotFound
+	}
+	if err := database.DB.Model(&beneficiary).Update("nickname", nickname).Error; err != nil {
+		return nil, err
+	}
+	return &beneficiary, nil
+}
+
+func DeleteBeneficiary(userID, id uint) error {
+	result := database.DB.Where("user_id = ? AND bank_id = ?", userID, id).Delete(&database.Bank{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBeneficiaryNotFound
+	}
+	return nil
+}
+
+// RecentRecipient is someone the user has sent money to, either an Olra tag
+// or an account at another bank
+type RecentRecipient struct {
+	Type          string    `json:"type"` // tag, bank
+	Tag           string    `json:"tag,omitempty"`
+	AccountNumber string    `json:"accountNumber,omitempty"`
+	BankCode      string    `json:"bankCode,omitempty"`
+	BankName      string    `json:"bankName,omitempty"`
+	Name          string    `json:"name"`
+	LastSentAt    time.Time `json:"lastSentAt"`
+}
+
+// RecentRecipients lists the distinct people the user last sent money to,
+// newest first, from Olra transfers and bank transfers that were not reversed
+func RecentRecipients(userID uint, limit int) ([]RecentRecipient, error) {
+	var tags []struct {
+		Receiver   string
+		LastSentAt time.Time
+	}
+	if err := database.DB.Model(&database.Transaction{}).
+		Select("receiver, MAX(transaction_date) AS last_sent_at").
+		Where("user_id = ? AND transaction_type = ? AND receiver <> ''", userID, "olraTransfer-out").
+		Group("receiver").
+		Order("last_sent_at desc").
+		Limit(limit).
+		Scan(&tags).Error; err != nil {
+		return nil, err
+	}
+	var accounts []struct {
+		AccountNumber string
+		BankCode      string
+		AccountName   string
+		LastSentAt    time.Time
+	}
+	if err := database.DB.Model(&database.BankTransfer{}).
+		Select("account_number, bank_code, MAX(account_name) AS account_name, MAX(created_at) AS last_sent_at").
+		Where("user_id = ? AND status <> ?", userID, BankTransferReversed).
+		Group("account_number, bank_code").
+		Order("last_sent_at desc").
+		Limit(limit).
+		Scan(&accounts).Error; err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	var banks []database.BankDirectory
+	database.DB.Find(&banks)
+	for _, bank := range banks {
+		names[bank.Code] = bank.Name
+	}
+
+	recipients := make([]RecentRecipient, 0, len(tags)+len(accounts))
+	for _, tag := range tags {
+		recipients = append(recipients, RecentRecipient{
+			Type:       "tag",
+			Tag:        tag.Receiver,
+			Name:       tag.Receiver,
+			LastSentAt: tag.LastSentAt,
+		})
+	}
+	for _, account := range accounts {
+		recipients = append(recipients, RecentRecipient{
+			Type:          "bank",
+			AccountNumber: account.AccountNumber,
+			BankCode:      account.BankCode,
+			BankName:      names[account.BankCode],
+			Name:          account.AccountName,
+			LastSentAt:    account.LastSentAt,
+		})
+	}
+	sort.Slice(recipients, func(i, j int) bool {
+		return recipients[i].LastSentAt.After(recipients[j].LastSentAt)
+	})
+	if len(recipients) > limit {
+		recipients = recipients[:limit]
+	}
+	return recipients, nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+func TestAddBeneficiary(t *testing.T) {
+	db := dbtest.Open(t, &database.Bank{}, &database.BankDirectory{})
+	useSandboxBanking(t)
+	db.Create(&database.BankDirectory{Code: "058", Name: "Guaranty Trust Bank", Type: "commercial"})
+
+	saved, err := AddBeneficiary(1, testAccountNumber, "058", "Landlord")
+	if err != nil {
+		t.Fatalf("AddBeneficiary() error = %v", err)
+	}
+	// The names come from the bank, not from the user
+	if saved.BankName != "Guaranty Trust Bank" || saved.AccountName == "" {
+		t.Errorf("saved %q at %q, want the name enquiry's", saved.AccountName, saved.BankName)
+	}
+
+	tests := []struct {
+		name          string
+		userID        uint
+		accountNumber string
+		bankCode      string
+		err           error
+	}{
+		{"another user saves the same account", 2, testAccountNumber, "058", nil},
+		{"saved twice", 1, testAccountNumber, "058", ErrBeneficiaryExists},
+		{"unknown bank", 1, testAccountNumber, "999", ErrUnknownBank},
+		{"bad check digit", 1, badCheckDigit, "058", ErrInvalidAccountNumber},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := AddBeneficiary(tt.userID, tt.accountNumber, tt.bankCode, ""); !errors.Is(err, tt.err) {
+				t.Fatalf("AddBeneficiary() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestBeneficiariesBelongToTheirUser(t *testing.T) {
+	db := dbtest.Open(t, &database.Bank{})
+	beneficiary := database.Bank{BankName: "Guaranty Trust Bank", BankCode: "058",
+		AccountNumber: testAccountNumber, AccountName: "Ada Obi", UserID: 1}
+	db.Create(&beneficiary)
+
+	if _, err := UpdateBeneficiary(2, beneficiary.BankID, "Mine now"); !errors.Is(err, ErrBeneficiaryNotFound) {
+		t.Errorf("UpdateBeneficiary() by another user error = %v, want %v", err, ErrBeneficiaryNotFound)
+	}
+	if err := DeleteBeneficiary(2, beneficiary.BankID); !errors.Is(err, ErrBeneficiaryNotFound) {
+		t.Errorf("DeleteBeneficiary() by another user error = %v, want %v", err, ErrBeneficiaryNotFound)
+	}
+	if updated, err := UpdateBeneficiary(1, beneficiary.BankID, "Landlord"); err != nil || updated.Nickname != "Landlord" {
+		t.Errorf("UpdateBeneficiary() = %+v, %v", updated, err)
+	}
+	if err := DeleteBeneficiary(1, beneficiary.BankID); err != nil {
+		t.Errorf("DeleteBeneficiary() error = %v", err)
+	}
+	if beneficiaries, _ := ListBeneficiaries(1); len(beneficiaries) != 0 {
+		t.Errorf("ListBeneficiaries() after deleting = %+v", beneficiaries)
+	}
+}
@@ -0,0 +1,655 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/templates"
+	helpers "olra-v1/utils"
+)
+
+const (
+	DisputeOpen                 = "open"
+	DisputeUnderReview          = "under-review"
+	DisputeAwaitingCounterparty = "awaiting-counterparty"
+	DisputeResolvedOpener       = "resolved-opener"
+	DisputeResolvedCounterparty = "resolved-counterparty"
+
+	DisputePartyOpener       = "opener"
+	DisputePartyCounterparty = "counterparty"
+	DisputePartyStaff        = "staff"
+)
+
+// DisputeReasons are the reasons a user can give for a dispute
+var DisputeReasons = []string{"wrong-recipient", "not-received", "unauthorised", "other"}
+
+// disputableTypes are the transactions a sender can dispute
+var disputableTypes = []string{PricedOlraTransfer, PricedGroupPayment, PricedBankTransfer}
+
+var unresolvedDisputeStatuses = []string{DisputeOpen, DisputeUnderReview, DisputeAwaitingCounterparty}
+
+// Evidence files are checked by their content, not the name or header sent
+var evidenceContentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+var (
+	ErrDisputeNotFound       = errors.New("dispute not found")
+	ErrDisputeExists         = errors.New("this transaction already has an open dispute")
+	ErrNotDisputable         = errors.New("this transaction cannot be disputed")
+	ErrDisputeWindowClosed   = errors.New("this transaction is too old to dispute")
+	ErrDisputeExceedsAmount  = errors.New("dispute is more than the amount left on the transaction")
+	ErrDisputeResolved       = errors.New("dispute has already been resolved")
+	ErrInvalidDisputeStatus  = errors.New("invalid dispute status")
+	ErrNoCounterparty        = errors.New("there is no counterparty to wait for on this dispute")
+	ErrEmptyEvidence         = errors.New("evidence needs a note or a file")
+	ErrEvidenceTooLarge      = errors.New("evidence file is too large")
+	ErrEvidenceType          = errors.New("evidence must be a JPEG, PNG or PDF file")
+	ErrEvidenceFileNotFound  = errors.New("evidence not found")
+	ErrInvalidDisputeOutcome = errors.New("a dispute is resolved for the opener or the counterparty")
+)
+
+// disputeWindow is DISPUTE_WINDOW_DAYS, how long after a transaction it can
+// still be disputed
+func disputeWindow() time.Duration {
+	return time.Duration(envInt("DISPUTE_WINDOW_DAYS", 60)) * 24 * time.Hour
+}
+
+// disputeResponseTime is DISPUTE_RESPONSE_DAYS, how long the counterparty
+// has to answer before the dispute goes to review without them
+func disputeResponseTime() time.Duration {
+	return time.Duration(envInt("DISPUTE_RESPONSE_DAYS", 3)) * 24 * time.Hour
+}
+
+// disputeResolutionTime is DISPUTE_RESOLUTION_DAYS, the time staff have to
+// decide a dispute
+func disputeResolutionTime() time.Duration {
+	return time.Duration(envInt("DISPUTE_RESOLUTION_DAYS", 14)) * 24 * time.Hour
+}
+
+func evidenceDir() string {
+	dir := os.Getenv("DISPUTE_EVIDENCE_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "olra-disputes")
+	}
+	return dir
+}
+
+// OpenDispute raises a dispute on a transaction the user sent, for amount or
+// all that has not been reversed when amount is zero. When the money went to
+// another Olra wallet its owner becomes the counterparty and as much of the
+// amount as they can still spend is held until the dispute is resolved.
+func OpenDispute(userID uint, reference, reason, description string, amount float64) (*database.Dispute, error) {
+	var dispute database.Dispute
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var transaction database.Transaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("reference = ? AND user_id = ? AND transaction_type IN ?", reference, userID, disputableTypes).
+			First(&transaction).Error; err != nil {
+			return ErrTransactionNotFound
+		}
+		if transaction.Status != "completed" {
+			return ErrNotDisputable
+		}
+		if time.Since(transaction.TransactionDate) > disputeWindow() {
+			return ErrDisputeWindowClosed
+		}
+		var open int64
+		if err := tx.Model(&database.Dispute{}).
+			Where("transaction_reference = ? AND status IN ?", reference, unresolvedDisputeStatuses).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrDisputeExists
+		}
+		plan, err := planReversal(tx, reference)
+		if errors.Is(err, ErrNotReversible) {
+			return ErrNotDisputable
+		}
+		if err != nil {
+			return err
+		}
+		remaining := plan.Principal - helpers.ToKobo(transaction.ReversedAmount)
+		kobo := helpers.ToKobo(amount)
+		if kobo == 0 {
+			kobo = remaining
+		}
+		if kobo > remaining {
+			return ErrDisputeExceedsAmount
+		}
+
+		now := time.Now()
+		dispute = database.Dispute{
+			TransactionReference: reference,
+			OpenedBy:             userID,
+			Amount:               helpers.FromKobo(kobo),
+			Reason:               reason,
+			Description:          description,
+			Status:               DisputeOpen,
+			ResolveBy:            now.Add(disputeResolutionTime()),
+		}
+		var (
+			counterparty *database.User
+			wallet       database.VirtualAccount
+			held         int64
+		)
+		if kind, walletID, ok := parseLedgerAccount(plan.Receiver); ok && kind == "wallet" {
+			wallet.VirtualAccountID = walletID
+			if err := lockWallets(tx, &wallet); err != nil {
+				return err
+			}
+			counterparty = &database.User{}
+			if err := tx.First(counterparty, wallet.UserID).Error; err != nil {
+				return err
+			}
+			respondBy := now.Add(disputeResponseTime())
+			dispute.CounterpartyID = &counterparty.UserID
+			dispute.Status = DisputeAwaitingCounterparty
+			dispute.RespondBy = &respondBy
+
+			available, err := AvailableBalance(tx, &wallet)
+			if err != nil {
+				return err
+			}
+			held = min(kobo, helpers.ToKobo(available))
+			if held > 0 {
+				dispute.HoldReference = helpers.GenerateTransactionReference()
+				if _, err := PlaceHold(tx, &wallet, HoldDispute, dispute.HoldReference, helpers.FromKobo(held), nil); err != nil {
+					return err
+				}
+			}
+		}
+		if err := tx.Create(&dispute).Error; err != nil {
+			return err
+		}
+
+		var opener database.User
+		if err := tx.First(&opener, userID).Error; err != nil {
+			return err
+		}
+		if err := Dispatch(tx, &opener, disputeNotice(&dispute, templates.EventDisputeOpened, templates.Data{
+			"Amount":    dispute.Amount,
+			"ResolveBy": dispute.ResolveBy,
+		})); err != nil {
+			return err
+		}
+		if counterparty == nil {
+			return nil
+		}
+		return Dispatch(tx, counterparty, disputeNotice(&dispute, templates.EventDisputeRaised, templates.Data{
+			"Held":      helpers.FromKobo(held),
+			"RespondBy": *dispute.RespondBy,
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+// disputeNotice is a dispute notification carrying the fields every dispute
+// template uses. Disputes decide where money goes, so they are sent as
+// security notices the user cannot turn off.
+func disputeNotice(dispute *database.Dispute, event string, data templates.Data) Notice {
+	data["DisputeID"] = dispute.ID
+	data["Reference"] = dispute.TransactionReference
+	return Notice{
+		Type:         NotificationSecurity,
+		Event:        event,
+		ResourceType: ResourceDispute,
+		ResourceID:   fmt.Sprint(dispute.ID),
+		Data:         data,
+	}
+}
+
+// disputeParty is the side userID is on in a dispute, or "" when they are
+// not part of it
+func disputeParty(dispute *database.Dispute, userID uint) string {
+	switch {
+	case dispute.OpenedBy == userID:
+		return DisputePartyOpener
+	case dispute.CounterpartyID != nil && *dispute.CounterpartyID == userID:
+		return DisputePartyCounterparty
+	}
+	return ""
+}
+
+func isResolved(dispute *database.Dispute) bool {
+	return dispute.Status == DisputeResolvedOpener || dispute.Status == DisputeResolvedCounterparty
+}
+
+// ListMyDisputes returns the disputes the user opened or is the
+// counterparty on, newest first
+func ListMyDisputes(userID uint, page, limit int) ([]database.Dispute, error) {
+	var disputes []database.Dispute
+	err := database.DB.Where("opened_by = ? OR counterparty_id = ?", userID, userID).
+		Order("created_at desc").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&disputes).Error
+	return disputes, err
+}
+
+// GetMyDispute returns a dispute with its evidence when the user is a party
+// to it
+func GetMyDispute(id, userID uint) (*database.Dispute, error) {
+	dispute, err := GetDispute(id)
+	if err != nil {
+		return nil, err
+	}
+	if disputeParty(dispute, userID) == "" {
+		return nil, ErrDisputeNotFound
+	}
+	return dispute, nil
+}
+
+// ListDisputes is the staff queue, soonest deadline first. Overdue limits it
+// to unresolved disputes past their resolution deadline.
+func ListDisputes(status string, overdue bool, page, limit int) ([]database.Dispute, error) {
+	var disputes []database.Dispute
+	db := database.DB.Order("resolve_by asc").Offset((page - 1) * limit).Limit(limit)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if overdue {
+		db = db.Where("status IN ? AND resolve_by <= ?", unresolvedDisputeStatuses, time.Now())
+	}
+	err := db.Find(&disputes).Error
+	return disputes, err
+}
+
+func GetDispute(id uint) (*database.Dispute, error) {
+	var dispute database.Dispute
+	if err := database.DB.Preload("Evidence", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at asc")
+	}).First(&dispute, id).Error; err != nil {
+		return nil, ErrDisputeNotFound
+	}
+	return &dispute, nil
+}
+
+// EvidenceUpload is a file added to a dispute
+type EvidenceUpload struct {
+	Name    string
+	Size    int64
+	Content io.Reader
+}
+
+// AddDisputeEvidence records a note, a file or both on an unresolved dispute.
+// Staff may add to any dispute; users only to their own. Evidence from the
+// counterparty while we wait on them is their response and sends the dispute
+// to review.
+func AddDisputeEvidence(disputeID, userID uint, staff bool, note string, upload *EvidenceUpload) (*database.DisputeEvidence, error) {
+	note = strings.TrimSpace(note)
+	if note == "" && upload == nil {
+		return nil, ErrEmptyEvidence
+	}
+	var evidence database.DisputeEvidence
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var dispute database.Dispute
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, disputeID).Error; err != nil {
+			return ErrDisputeNotFound
+		}
+		party := DisputePartyStaff
+		if !staff {
+			if party = disputeParty(&dispute, userID); party == "" {
+				return ErrDisputeNotFound
+			}
+		}
+		if isResolved(&dispute) {
+			return ErrDisputeResolved
+		}
+		evidence = database.DisputeEvidence{
+			DisputeID:  dispute.ID,
+			UploadedBy: userID,
+			Party:      party,
+			Note:       note,
+		}
+		if upload != nil {
+			if err := saveEvidenceFile(&evidence, upload); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&evidence).Error; err != nil {
+			return err
+		}
+		if party == DisputePartyCounterparty && dispute.Status == DisputeAwaitingCounterparty {
+			return tx.Model(&dispute).Update("status", DisputeUnderReview).Error
+		}
+		return nil
+	})
+	if err != nil {
+		if evidence.Path != "" {
+			os.Remove(filepath.Join(evidenceDir(), evidence.Path))
+		}
+		return nil, err
+	}
+	return &evidence, nil
+}
+
+// saveEvidenceFile writes an upload into the evidence directory under a name
+// of our own, keeping the name it was sent with for downloads
+func saveEvidenceFile(evidence *database.DisputeEvidence, upload *EvidenceUpload) error {
+	maxBytes := int64(envInt("DISPUTE_EVIDENCE_MAX_BYTES", 5<<20))
+	if upload.Size > maxBytes {
+		return ErrEvidenceTooLarge
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(upload.Content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrEvidenceType
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	extension, ok := evidenceContentTypes[contentType]
+	if !ok {
+		return ErrEvidenceType
+	}
+
+	dir := evidenceDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s%s", evidence.DisputeID, helpers.GenerateTransactionReference(), extension)
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	evidence.Path = name
+	written, err := io.Copy(file, io.LimitReader(io.MultiReader(bytes.NewReader(head), upload.Content), maxBytes+1))
+	if err != nil {
+		return err
+	}
+	if written > maxBytes {
+		return ErrEvidenceTooLarge
+	}
+	evidence.FileName = filepath.Base(upload.Name)
+	evidence.ContentType = contentType
+	evidence.Size = written
+	return nil
+}
+
+// EvidenceFile finds the stored file for a piece of evidence. Users can only
+// reach evidence on disputes they are part of.
+func EvidenceFile(disputeID, evidenceID, userID uint, staff bool) (*database.DisputeEvidence, string, error) {
+	var dispute database.Dispute
+	if err := database.DB.First(&dispute, disputeID).Error; err != nil {
+		return nil, "", ErrDisputeNotFound
+	}
+	if !staff && disputeParty(&dispute, userID) == "" {
+		return nil, "", ErrDisputeNotFound
+	}
+	var evidence database.DisputeEvidence
+	if err := database.DB.Where("id = ? AND dispute_id = ?", evidenceID, disputeID).
+		First(&evidence).Error; err != nil || evidence.Path == "" {
+		return nil, "", ErrEvidenceFileNotFound
+	}
+	return &evidence, filepath.Join(evidenceDir(), evidence.Path), nil
+}
+
+// UpdateDisputeStatus moves an unresolved dispute between review and waiting
+// on the counterparty. Asking the counterparty again gives them a fresh
+// deadline and tells them so.
+func UpdateDisputeStatus(id uint, status string) (*database.Dispute, error) {
+	if status != DisputeOpen && status != DisputeUnderReview && status != DisputeAwaitingCounterparty {
+		return nil, ErrInvalidDisputeStatus
+	}
+	var dispute database.Dispute
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, id).Error; err != nil {
+			return ErrDisputeNotFound
+		}
+		if isResolved(&dispute) {
+			return ErrDisputeResolved
+		}
+		dispute.Status = status
+		if status != DisputeAwaitingCounterparty {
+			return tx.Save(&dispute).Error
+		}
+		if dispute.CounterpartyID == nil {
+			return ErrNoCounterparty
+		}
+		respondBy := time.Now().Add(disputeResponseTime())
+		dispute.RespondBy = &respondBy
+		if err := tx.Save(&dispute).Error; err != nil {
+			return err
+		}
+		var counterparty database.User
+		if err := tx.First(&counterparty, *dispute.CounterpartyID).Error; err != nil {
+			return err
+		}
+		held, err := disputeHeld(tx, &dispute)
+		if err != nil {
+			return err
+		}
+		return Dispatch(tx, &counterparty, disputeNotice(&dispute, templates.EventDisputeRaised, templates.Data{
+			"Held":      held,
+			"RespondBy": respondBy,
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+// disputeHeld is what the dispute's hold still reserves, in naira
+func disputeHeld(tx *gorm.DB, dispute *database.Dispute) (float64, error) {
+	if dispute.HoldReference == "" {
+		return 0, nil
+	}
+	hold, err := activeHold(tx, dispute.HoldReference)
+	if errors.Is(err, ErrHoldNotFound) || errors.Is(err, ErrHoldNotActive) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return helpers.FromKobo(hold.Amount - hold.Captured), nil
+}
+
+// ResolveDispute decides a dispute. For the opener the disputed amount, or a
+// smaller one staff settle on, is reversed; the hold stays until the reversal
+// posts so a reversal waiting for approval still has the funds behind it. For
+// the counterparty the hold is released and the transaction stands.
+func ResolveDispute(id, staffID uint, outcome string, amount float64, resolution string) (*database.Dispute, error) {
+	if outcome != DisputePartyOpener && outcome != DisputePartyCounterparty {
+		return nil, ErrInvalidDisputeOutcome
+	}
+	var dispute database.Dispute
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, id).Error; err != nil {
+			return ErrDisputeNotFound
+		}
+		if isResolved(&dispute) {
+			return ErrDisputeResolved
+		}
+		if amount == 0 {
+			amount = dispute.Amount
+		}
+		now := time.Now()
+		dispute.Resolution = resolution
+		dispute.ResolvedBy = &staffID
+		dispute.ResolvedAt = &now
+		if outcome == DisputePartyOpener {
+			if helpers.ToKobo(amount) > helpers.ToKobo(dispute.Amount) {
+				return ErrDisputeExceedsAmount
+			}
+			reversal, err := requestReversal(tx, dispute.TransactionReference, amount,
+				fmt.Sprintf("Dispute #%d: %s", dispute.ID, resolution), staffID)
+			if err != nil {
+				return err
+			}
+			dispute.ReversalID = &reversal.ID
+			dispute.Status = DisputeResolvedOpener
+		} else {
+			amount = 0
+			if dispute.HoldReference != "" {
+				if _, err := ReleaseHold(tx, dispute.HoldReference); err != nil &&
+					!errors.Is(err, ErrHoldNotFound) && !errors.Is(err, ErrHoldNotActive) {
+					return err
+				}
+			}
+			dispute.Status = DisputeResolvedCounterparty
+		}
+		if err := tx.Save(&dispute).Error; err != nil {
+			return err
+		}
+
+		return notifyDisputeParties(tx, &dispute, templates.EventDisputeResolved, templates.Data{
+			"Amount":   amount,
+			"Refunded": outcome == DisputePartyOpener,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+// releaseDisputeHolds releases the holds of the disputes matching the query.
+// Holds that already lapsed or were released are skipped.
+func releaseDisputeHolds(tx *gorm.DB, query string, args ...interface{}) error {
+	var references []string
+	if err := tx.Model(&database.Dispute{}).
+		Where(query, args...).
+		Where("hold_reference <> ?", "").
+		Pluck("hold_reference", &references).Error; err != nil {
+		return err
+	}
+	for _, reference := range references {
+		if _, err := ReleaseHold(tx, reference); err != nil &&
+			!errors.Is(err, ErrHoldNotFound) && !errors.Is(err, ErrHoldNotActive) {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyDisputeParties sends the notice to the opener and, when there is
+// one, the counterparty
+func notifyDisputeParties(tx *gorm.DB, dispute *database.Dispute, event string, data templates.Data) error {
+	parties := []uint{dispute.OpenedBy}
+	if dispute.CounterpartyID != nil {
+		parties = append(parties, *dispute.CounterpartyID)
+	}
+	for _, userID := range parties {
+		var user database.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if err := Dispatch(tx, &user, disputeNotice(dispute, event, data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessDisputeDeadlines acts on the deadlines that have passed. Disputes
+// whose counterparty did not answer in time go to review without them.
+// Disputes still undecided at ResolveBy are escalated, once, so they are
+// logged for staff. Both parties are told either way. It returns how many
+// disputes it changed.
+func ProcessDisputeDeadlines() (int64, error) {
+	now := time.Now()
+	var lapsed, overdue []uint
+	if err := database.DB.Model(&database.Dispute{}).
+		Where("status = ? AND respond_by <= ?", DisputeAwaitingCounterparty, now).
+		Pluck("id", &lapsed).Error; err != nil {
+		return 0, err
+	}
+	if err := database.DB.Model(&database.Dispute{}).
+		Where("status IN ? AND resolve_by <= ? AND escalated_at IS NULL", unresolvedDisputeStatuses, now).
+		Pluck("id", &overdue).Error; err != nil {
+		return 0, err
+	}
+
+	var processed int64
+	for _, id := range lapsed {
+		changed, err := processDisputeDeadline(id, func(dispute *database.Dispute) (string, templates.Data) {
+			if dispute.Status != DisputeAwaitingCounterparty || dispute.RespondBy == nil || dispute.RespondBy.After(now) {
+				return "", nil
+			}
+			dispute.Status = DisputeUnderReview
+			return templates.EventDisputeNoResponse, templates.Data{"RespondBy": *dispute.RespondBy}
+		})
+		if err != nil {
+			return processed, err
+		}
+		if changed {
+			processed++
+		}
+	}
+	for _, id := range overdue {
+		changed, err := processDisputeDeadline(id, func(dispute *database.Dispute) (string, templates.Data) {
+			if isResolved(dispute) || dispute.EscalatedAt != nil || dispute.ResolveBy.After(now) {
+				return "", nil
+			}
+			dispute.EscalatedAt = &now
+			log.Printf("Dispute #%d on %s was not decided by %s and has been escalated",
+				dispute.ID, dispute.TransactionReference, dispute.ResolveBy.Format(time.RFC3339))
+			return templates.EventDisputeDelayed, templates.Data{"ResolveBy": dispute.ResolveBy}
+		})
+		if err != nil {
+			return processed, err
+		}
+		if changed {
+			processed++
+		}
+	}
+	return processed, nil
+}
+
+// processDisputeDeadline locks the dispute and lets apply change it. Apply
+// checks the deadline again under the lock, as staff may have acted since
+// the dispute was listed, and returns the event to tell both parties about,
+// or "" to leave the dispute alone.
+func processDisputeDeadline(id uint, apply func(*database.Dispute) (string, templates.Data)) (bool, error) {
+	changed := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var dispute database.Dispute
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, id).Error; err != nil {
+			return err
+		}
+		event, data := apply(&dispute)
+		if event == "" {
+			return nil
+		}
+		if err := tx.Save(&dispute).Error; err != nil {
+			return err
+		}
+		changed = true
+		return notifyDisputeParties(tx, &dispute, event, data)
+	})
+	return changed, err
+}
+
+// StartDisputeDeadlines runs ProcessDisputeDeadlines every
+// DISPUTE_DEADLINE_SECONDS
+func StartDisputeDeadlines() {
+	interval := time.Duration(envInt("DISPUTE_DEADLINE_SECONDS", 300)) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := ProcessDisputeDeadlines(); err != nil {
+				log.Println("Error processing dispute deadlines:", err)
+			}
+		}
+	}()
+}
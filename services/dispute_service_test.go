@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/templates"
+)
+
+// openTestDispute has the sender of a 5000 naira transfer dispute all of it
+func openTestDispute(t *testing.T) (*database.Dispute, *database.VirtualAccount, *database.VirtualAccount) {
+	t.Helper()
+	transaction, senderWallet, receiverWallet := sendForReversal(t, 5000, 0)
+	dispute, err := OpenDispute(senderWallet.UserID, transaction.Reference, "wrong-recipient", "", 0)
+	if err != nil {
+		t.Fatalf("OpenDispute() error = %v", err)
+	}
+	return dispute, senderWallet, receiverWallet
+}
+
+// notified lists who was sent the event, by user id
+func notified(t *testing.T, event string) []uint {
+	t.Helper()
+	var userIDs []uint
+	if err := database.DB.Model(&database.Notification{}).Where("event = ?", event).
+		Order("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+		t.Fatal(err)
+	}
+	return userIDs
+}
+
+func TestOpenDispute(t *testing.T) {
+	dispute, senderWallet, receiverWallet := openTestDispute(t)
+	if dispute.Status != DisputeAwaitingCounterparty || dispute.CounterpartyID == nil ||
+		*dispute.CounterpartyID != receiverWallet.UserID {
+		t.Errorf("dispute = %s against %v, want awaiting user %d", dispute.Status, dispute.CounterpartyID, receiverWallet.UserID)
+	}
+	assertAvailable(t, receiverWallet, 0)
+	if _, err := OpenDispute(senderWallet.UserID, dispute.TransactionReference, "other", "", 0); !errors.Is(err, ErrDisputeExists) {
+		t.Errorf("second OpenDispute() error = %v, want %v", err, ErrDisputeExists)
+	}
+}
+
+func TestResolveDispute(t *testing.T) {
+	tests := []struct {
+		name         string
+		outcome      string
+		senderLeft   int64
+		receiverLeft int64
+	}{
+		{"for the opener", DisputePartyOpener, 500000, 0},
+		{"for the counterparty", DisputePartyCounterparty, 0, 500000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispute, senderWallet, receiverWallet := openTestDispute(t)
+
+			resolved, err := ResolveDispute(dispute.ID, 1, tt.outcome, 0, "checked")
+			if err != nil {
+				t.Fatalf("ResolveDispute() error = %v", err)
+			}
+			if !isResolved(resolved) {
+				t.Errorf("status = %q, want resolved", resolved.Status)
+			}
+			assertLedgerBalance(t, WalletAccount(senderWallet.VirtualAccountID), tt.senderLeft)
+			assertLedgerBalance(t, WalletAccount(receiverWallet.VirtualAccountID), tt.receiverLeft)
+			assertAvailable(t, receiverWallet, float64(tt.receiverLeft)/100)
+			if got := notified(t, templates.EventDisputeResolved); len(got) != 2 {
+				t.Errorf("resolution sent to %v, want both parties", got)
+			}
+			if _, err := ResolveDispute(dispute.ID, 1, tt.outcome, 0, "again"); !errors.Is(err, ErrDisputeResolved) {
+				t.Errorf("second ResolveDispute() error = %v, want %v", err, ErrDisputeResolved)
+			}
+		})
+	}
+}
+
+func TestProcessDisputeDeadlines(t *testing.T) {
+	dispute, senderWallet, receiverWallet := openTestDispute(t)
+	parties := []uint{senderWallet.UserID, receiverWallet.UserID}
+
+	if processed, err := ProcessDisputeDeadlines(); err != nil || processed != 0 {
+		t.Fatalf("ProcessDisputeDeadlines() before any deadline = %d, %v, want 0", processed, err)
+	}
+
+	// The counterparty did not answer in time
+	database.DB.Model(dispute).Update("respond_by", time.Now().Add(-time.Minute))
+	if processed, err := ProcessDisputeDeadlines(); err != nil || processed != 1 {
+		t.Fatalf("ProcessDisputeDeadlines() = %d, %v, want 1", processed, err)
+	}
+	var lapsed database.Dispute
+	database.DB.First(&lapsed, dispute.ID)
+	if lapsed.Status != DisputeUnderReview {
+		t.Errorf("status = %q, want %q", lapsed.Status, DisputeUnderReview)
+	}
+	if got := notified(t, templates.EventDisputeNoResponse); len(got) != 2 || got[0] != parties[0] || got[1] != parties[1] {
+		t.Errorf("lapsed response sent to %v, want %v", got, parties)
+	}
+
+	// Staff did not decide it in time either
+	database.DB.Model(dispute).Update("resolve_by", time.Now().Add(-time.Minute))
+	if processed, err := ProcessDisputeDeadlines(); err != nil || processed != 1 {
+		t.Fatalf("ProcessDisputeDeadlines() after resolve by = %d, %v, want 1", processed, err)
+	}
+	var overdue database.Dispute
+	database.DB.First(&overdue, dispute.ID)
+	if overdue.EscalatedAt == nil || overdue.Status != DisputeUnderReview {
+		t.Errorf("dispute = %s escalated at %v, want escalated and still under review", overdue.Status, overdue.EscalatedAt)
+	}
+	if got := notified(t, templates.EventDisputeDelayed); len(got) != 2 {
+		t.Errorf("delay sent to %v, want both parties", got)
+	}
+
+	// Each deadline is acted on once
+	if processed, err := ProcessDisputeDeadlines(); err != nil || processed != 0 {
+		t.Errorf("ProcessDisputeDeadlines() again = %d, %v, want 0", processed, err)
+	}
+	if got := notified(t, templates.EventDisputeDelayed); len(got) != 2 {
+		t.Errorf("delay sent %d times, want 2", len(got))
+	}
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	helpers "olra-v1/utils"
+)
+
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+	HoldExpired  = "expired"
+
+	HoldBankWithdrawal = "bank-withdrawal"
+	HoldDispute        = "dispute"
+)
+
+var (
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrHoldNotActive      = errors.New("hold is no longer active")
+	ErrCaptureExceedsHold = errors.New("capture is more than the amount still held")
+	ErrWalletNotFound     = errors.New("wallet not found")
+)
+
+// heldAmount is what active, unexpired holds still reserve on a wallet, in kobo
+func heldAmount(tx *gorm.DB, virtualAccountID uint) (int64, error) {
+	var held int64
+	err := tx.Model(&database.Hold{}).
+		Where("virtual_account_id = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)",
+			virtualAccountID, HoldActive, time.Now()).
+		Select("COALESCE(SUM(amount - captured), 0)").
+		Scan(&held).Error
+	return held, err
+}
+
+// AvailableBalance is the wallet's ledger balance less its active holds. Every
+// debit must be checked against this rather than Balance.
+func AvailableBalance(tx *gorm.DB, wallet *database.VirtualAccount) (float64, error) {
+	// The ledger rather than the cached Balance, so a cache that has drifted
+	// cannot authorise a debit
+	balance, err := LedgerBalance(tx, WalletAccount(wallet.VirtualAccountID))
+	if err != nil {
+		return 0, err
+	}
+	held, err := heldAmount(tx, wallet.VirtualAccountID)
+	if err != nil {
+		return 0, err
+	}
+	return helpers.FromKobo(balance - held), nil
+}
+
+// checkAvailable fails with ErrInsufficientBalance when the wallet cannot
+// cover amount. The wallet should be locked by the caller.
+func checkAvailable(tx *gorm.DB, wallet *database.VirtualAccount, amount float64) error {
+	available, err := AvailableBalance(tx, wallet)
+	if err != nil {
+		return err
+	}
+	if helpers.ToKobo(available) < helpers.ToKobo(amount) {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
+// PlaceHold reserves amount on a locked wallet. A hold with no expiry lasts
+// until it is captured or released.
+func PlaceHold(
+	tx *gorm.DB,
+	wallet *database.VirtualAccount,
+	reason, reference string,
+	amount float64,
+	expiresAt *time.Time,
+) (*database.Hold, error) {
+	if err := checkAvailable(tx, wallet, amount); err != nil {
+		return nil, err
+	}
+	hold := database.Hold{
+		VirtualAccountID: wallet.VirtualAccountID,
+		Reference:        reference,
+		Reason:           reason,
+		Amount:           helpers.ToKobo(amount),
+		Status:           HoldActive,
+		ExpiresAt:        expiresAt,
+	}
+	if err := tx.Create(&hold).Error; err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// findHold locks the hold with reference, returning nil when there is none
+func findHold(tx *gorm.DB, reference string) (*database.Hold, error) {
+	var hold database.Hold
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("reference = ?", reference).First(&hold).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func activeHold(tx *gorm.DB, reference string) (*database.Hold, error) {
+	hold, err := findHold(tx, reference)
+	if err != nil {
+		return nil, err
+	}
+	if hold == nil {
+		return nil, ErrHoldNotFound
+	}
+	if !holdIsActive(hold, time.Now()) {
+		return hold, ErrHoldNotActive
+	}
+	return hold, nil
+}
+
+func holdIsActive(hold *database.Hold, now time.Time) bool {
+	return hold.Status == HoldActive && (hold.ExpiresAt == nil || hold.ExpiresAt.After(now))
+}
+
+// holdRemaining is what the hold still reserves, in kobo
+func holdRemaining(hold *database.Hold) int64 {
+	return hold.Amount - hold.Captured
+}
+
+// captureLegs totals the credit legs of a capture, dropping empty ones, and
+// checks the hold can cover them
+func captureLegs(hold *database.Hold, credits []Leg) ([]Leg, int64, error) {
+	var amount int64
+	legs := make([]Leg, 0, len(credits))
+	for _, credit := range credits {
+		if credit.Amount == 0 {
+			continue
+		}
+		amount += credit.Amount
+		legs = append(legs, credit)
+	}
+	if amount > holdRemaining(hold) {
+		return nil, 0, ErrCaptureExceedsHold
+	}
+	return legs, amount, nil
+}
+
+// applyCapture records amount as taken from the hold, which is captured once
+// nothing is left
+func applyCapture(hold *database.Hold, amount int64) {
+	hold.Captured += amount
+	if holdRemaining(hold) == 0 {
+		hold.Status = HoldCaptured
+	}
+}
+
+// CaptureHold takes money out of a hold and posts it to the credit legs, under
+// journalReference. Capturing less than the hold leaves the rest reserved for
+// a later capture or release; the hold is captured once nothing is left.
+func CaptureHold(tx *gorm.DB, reference, journalReference, description string, credits ...Leg) (*database.Hold, error) {
+	hold, err := activeHold(tx, reference)
+	if err != nil {
+		return hold, err
+	}
+	legs, amount, err := captureLegs(hold, credits)
+	if err != nil {
+		return hold, err
+	}
+	wallet := database.VirtualAccount{VirtualAccountID: hold.VirtualAccountID}
+	if err := lockWallets(tx, &wallet); err != nil {
+		return hold, err
+	}
+	legs = append([]Leg{{Account: WalletAccount(wallet.VirtualAccountID), Amount: -amount}}, legs...)
+	if err := PostJournal(tx, journalReference, description, legs...); err != nil {
+		return hold, err
+	}
+	applyCapture(hold, amount)
+	return hold, tx.Model(hold).Updates(map[string]interface{}{
+		"captured": hold.Captured,
+		"status":   hold.Status,
+	}).Error
+}
+
+// ReleaseHold gives whatever is still held back to the available balance
+func ReleaseHold(tx *gorm.DB, reference string) (*database.Hold, error) {
+	hold, err := activeHold(tx, reference)
+	if err != nil {
+		return hold, err
+	}
+	hold.Status = HoldReleased
+	return hold, tx.Model(hold).Update("status", HoldReleased).Error
+}
+
+// HeldFunds is a hold as the app shows it, in naira
+type HeldFunds struct {
+	Reference string     `json:"reference"`
+	Reason    string     `json:"reason"`
+	Amount    float64    `json:"amount"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type WalletBalance struct {
+	Balance   float64     `json:"balance"`
+	Held      float64     `json:"held"`
+	Available float64     `json:"available"`
+	Holds     []HeldFunds `json:"holds"`
+}
+
+// GetWalletBalance breaks the user's balance down into what is held and what
+// they can spend
+func GetWalletBalance(userID uint) (*WalletBalance, error) {
+	var wallet database.VirtualAccount
+	if err := database.DB.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, ErrWalletNotFound
+	}
+	var holds []database.Hold
+	if err := database.DB.Where("virtual_account_id = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)",
+		wallet.VirtualAccountID, HoldActive, time.Now()).
+		Order("created_at desc").
+		Find(&holds).Error; err != nil {
+		return nil, err
+	}
+	ledgerBalance, err := LedgerBalance(database.DB, WalletAccount(wallet.VirtualAccountID))
+	if err != nil {
+		return nil, err
+	}
+	balance := &WalletBalance{Balance: helpers.FromKobo(ledgerBalance), Holds: make([]HeldFunds, 0, len(holds))}
+	var held int64
+	for _, hold := range holds {
+		held += holdRemaining(&hold)
+		balance.Holds = append(balance.Holds, HeldFunds{
+			Reference: hold.Reference,
+			Reason:    hold.Reason,
+			Amount:    helpers.FromKobo(holdRemaining(&hold)),
+			ExpiresAt: hold.ExpiresAt,
+			CreatedAt: hold.CreatedAt,
+		})
+	}
+	balance.Held = helpers.FromKobo(held)
+	balance.Available = helpers.FromKobo(ledgerBalance - held)
+	return balance, nil
+}
+
+// ExpireHolds marks holds past their expiry. They stop counting against the
+// available balance as soon as they expire; this only records it.
+func ExpireHolds() (int64, error) {
+	result := database.DB.Model(&database.Hold{}).
+		Where("status = ? AND expires_at <= ?", HoldActive, time.Now()).
+		Update("status", HoldExpired)
+	return result.RowsAffected, result.Error
+}
+
+// StartHoldExpiry runs ExpireHolds every HOLD_EXPIRY_SECONDS
+func StartHoldExpiry() {
+	interval := time.Duration(envInt("HOLD_EXPIRY_SECONDS", 60)) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := ExpireHolds(); err != nil {
+				log.Println("Error expiring holds:", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+func TestCaptureLegs(t *testing.T) {
+	tests := []struct {
+		name     string
+		hold     database.Hold
+		credits  []Leg
+		wantLegs int
+		want     int64
+		err      error
+	}{
+		{
+			name:     "full capture",
+			hold:     database.Hold{Amount: 10000},
+			credits:  []Leg{{Account: SystemBankSettlementAccount, Amount: 10000}},
+			wantLegs: 1,
+			want:     10000,
+		},
+		{
+			name: "capture split between legs",
+			hold: database.Hold{Amount: 10000},
+			credits: []Leg{
+				{Account: SystemBankSettlementAccount, Amount: 9000},
+				{Account: SystemFeeIncomeAccount, Amount: 1000},
+			},
+			wantLegs: 2,
+			want:     10000,
+		},
+		{
+			name: "empty legs are dropped",
+			hold: database.Hold{Amount: 10000},
+			credits: []Leg{
+				{Account: SystemBankSettlementAccount, Amount: 4000},
+				{Account: SystemFeeIncomeAccount, Amount: 0},
+			},
+			wantLegs: 1,
+			want:     4000,
+		},
+		{
+			name:     "rest of a part captured hold",
+			hold:     database.Hold{Amount: 10000, Captured: 4000},
+			credits:  []Leg{{Account: SystemBankSettlementAccount, Amount: 6000}},
+			wantLegs: 1,
+			want:     6000,
+		},
+		{
+			name:    "more than the hold",
+			hold:    database.Hold{Amount: 10000},
+			credits: []Leg{{Account: SystemBankSettlementAccount, Amount: 10001}},
+			err:     ErrCaptureExceedsHold,
+		},
+		{
+			name: "more than is left after a part capture",
+			hold: database.Hold{Amount: 10000, Captured: 4000},
+			credits: []Leg{
+				{Account: SystemBankSettlementAccount, Amount: 5000},
+				{Account: SystemFeeIncomeAccount, Amount: 1001},
+			},
+			err: ErrCaptureExceedsHold,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legs, amount, err := captureLegs(&tt.hold, tt.credits)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("captureLegs() error = %v, want %v", err, tt.err)
+			}
+			if len(legs) != tt.wantLegs {
+				t.Errorf("captureLegs() returned %d legs, want %d", len(legs), tt.wantLegs)
+			}
+			if amount != tt.want {
+				t.Errorf("captureLegs() amount = %d, want %d", amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyCapture(t *testing.T) {
+	tests := []struct {
+		name         string
+		amount       int64
+		captures     []int64
+		wantCaptured int64
+		wantStatus   string
+		wantLeft     int64
+	}{
+		{"nothing captured", 10000, nil, 0, HoldActive, 10000},
+		{"part capture", 10000, []int64{4000}, 4000, HoldActive, 6000},
+		{"captured in parts", 10000, []int64{4000, 6000}, 10000, HoldCaptured, 0},
+		{"captured at once", 10000, []int64{10000}, 10000, HoldCaptured, 0},
+		{"several part captures", 10000, []int64{2500, 2500, 2500}, 7500, HoldActive, 2500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hold := database.Hold{Amount: tt.amount, Status: HoldActive}
+			for _, capture := range tt.captures {
+				applyCapture(&hold, capture)
+			}
+			if hold.Captured != tt.wantCaptured {
+				t.Errorf("Captured = %d, want %d", hold.Captured, tt.wantCaptured)
+			}
+			if hold.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", hold.Status, tt.wantStatus)
+			}
+			if left := holdRemaining(&hold); left != tt.wantLeft {
+				t.Errorf("holdRemaining() = %d, want %d", left, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestHoldIsActive(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, lagos)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+	tests := []struct {
+		name string
+		hold database.Hold
+		want bool
+	}{
+		{"active without expiry", database.Hold{Status: HoldActive}, true},
+		{"active, expires later", database.Hold{Status: HoldActive, ExpiresAt: &future}, true},
+		{"active, expired", database.Hold{Status: HoldActive, ExpiresAt: &past}, false},
+		{"active, expires now", database.Hold{Status: HoldActive, ExpiresAt: &now}, false},
+		{"captured", database.Hold{Status: HoldCaptured}, false},
+		{"released", database.Hold{Status: HoldReleased}, false},
+		{"marked expired", database.Hold{Status: HoldExpired, ExpiresAt: &past}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := holdIsActive(&tt.hold, now); got != tt.want {
+				t.Errorf("holdIsActive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// placeTestHold holds amount naira on a wallet funded with balance kobo
+func placeTestHold(t *testing.T, balance int64, amount float64, expiresAt *time.Time) (*database.Hold, *database.VirtualAccount) {
+	t.Helper()
+	db := dbtest.Open(t, walletModels...)
+	_, wallet := createWalletUser(t, db, "ada", balance)
+	var hold *database.Hold
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := lockWallets(tx, wallet); err != nil {
+			return err
+		}
+		var err error
+		hold, err = PlaceHold(tx, wallet, HoldDispute, "HOLD1", amount, expiresAt)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("PlaceHold() error = %v", err)
+	}
+	return hold, wallet
+}
+
+func assertAvailable(t *testing.T, wallet *database.VirtualAccount, want float64) {
+	t.Helper()
+	available, err := AvailableBalance(database.DB, wallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if available != want {
+		t.Errorf("AvailableBalance() = %v, want %v", available, want)
+	}
+}
+
+func TestPlaceHold(t *testing.T) {
+	_, wallet := placeTestHold(t, 1000000, 4000, nil)
+	assertAvailable(t, wallet, 6000)
+	assertLedgerBalance(t, WalletAccount(wallet.VirtualAccountID), 1000000)
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := PlaceHold(tx, wallet, HoldDispute, "HOLD2", 6000.01, nil)
+		return err
+	})
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("PlaceHold() over the available balance error = %v, want %v", err, ErrInsufficientBalance)
+	}
+	// Spending checks the available balance, not the ledger's
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return checkAvailable(tx, wallet, 6000.01)
+	})
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("checkAvailable() error = %v, want %v", err, ErrInsufficientBalance)
+	}
+}
+
+func TestCaptureHold(t *testing.T) {
+	hold, wallet := placeTestHold(t, 1000000, 4000, nil)
+	capture := func(journal string, amount int64) error {
+		return database.DB.Transaction(func(tx *gorm.DB) error {
+			_, err := CaptureHold(tx, hold.Reference, journal, "Capture",
+				Leg{Account: SystemBankSettlementAccount, Amount: amount})
+			return err
+		})
+	}
+
+	if err := capture("CAP1", 150000); err != nil {
+		t.Fatalf("CaptureHold() error = %v", err)
+	}
+	assertLedgerBalance(t, WalletAccount(wallet.VirtualAccountID), 850000)
+	assertAvailable(t, wallet, 6000)
+	if err := capture("CAP2", 250001); !errors.Is(err, ErrCaptureExceedsHold) {
+		t.Errorf("CaptureHold() of more than is left error = %v, want %v", err, ErrCaptureExceedsHold)
+	}
+	if err := capture("CAP2", 250000); err != nil {
+		t.Fatalf("CaptureHold() of the rest error = %v", err)
+	}
+	var captured database.Hold
+	database.DB.First(&captured, hold.ID)
+	if captured.Status != HoldCaptured || captured.Captured != 400000 {
+		t.Errorf("hold = %s with %d captured, want captured with 400000", captured.Status, captured.Captured)
+	}
+	assertLedgerBalance(t, WalletAccount(wallet.VirtualAccountID), 600000)
+	assertAvailable(t, wallet, 6000)
+	if err := capture("CAP3", 1); !errors.Is(err, ErrHoldNotActive) {
+		t.Errorf("CaptureHold() after it is captured error = %v, want %v", err, ErrHoldNotActive)
+	}
+}
+
+func TestReleaseHold(t *testing.T) {
+	hold, wallet := placeTestHold(t, 1000000, 4000, nil)
+	release := func() error {
+		return database.DB.Transaction(func(tx *gorm.DB) error {
+			_, err := ReleaseHold(tx, hold.Reference)
+			return err
+		})
+	}
+	if err := release(); err != nil {
+		t.Fatalf("ReleaseHold() error = %v", err)
+	}
+	assertAvailable(t, wallet, 10000)
+	assertLedgerBalance(t, WalletAccount(wallet.VirtualAccountID), 1000000)
+	if err := release(); !errors.Is(err, ErrHoldNotActive) {
+		t.Errorf("second ReleaseHold() error = %v, want %v", err, ErrHoldNotActive)
+	}
+}
+
+func TestExpiredHold(t *testing.T) {
+	soon := time.Now().Add(50 * time.Millisecond)
+	hold, wallet := placeTestHold(t, 1000000, 4000, &soon)
+	assertAvailable(t, wallet, 6000)
+	time.Sleep(100 * time.Millisecond)
+
+	// An expired hold stops counting before ExpireHolds records it
+	assertAvailable(t, wallet, 10000)
+	if expired, err := ExpireHolds(); err != nil || expired != 1 {
+		t.Errorf("ExpireHolds() = %d, %v, want 1", expired, err)
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := CaptureHold(tx, hold.Reference, "CAP1", "Capture", Leg{Account: SystemBankSettlementAccount, Amount: 1})
+		return err
+	})
+	if !errors.Is(err, ErrHoldNotActive) {
+		t.Errorf("CaptureHold() of an expired hold error = %v, want %v", err, ErrHoldNotActive)
+	}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/templates"
+	helpers "olra-v1/utils"
+)
+
+const (
+	InboundPending  = "pending"
+	InboundCredited = "credited"
+	InboundSuspense = "suspense"
+	InboundResolved = "resolved"
+)
+
+var (
+	ErrInvalidInboundCredit   = errors.New("inbound credit needs a session id and a positive amount")
+	ErrDuplicateInboundCredit = errors.New("inbound credit already received")
+	ErrInboundCreditNotFound  = errors.New("inbound credit not found")
+	ErrInboundCreditNotHeld   = errors.New("inbound credit is not in suspense")
+	ErrAccountNumberNotFound  = errors.New("no wallet has this account number")
+)
+
+// InboundTransfer is a credit notification from the bank for money sent to
+// one of our account numbers from another bank
+type InboundTransfer struct {
+	Provider      string
+	SessionID     string // the NIP session id, unique per transfer
+	AccountNumber string // the Olra account that was paid
+	Amount        float64
+	SenderName    string
+	SenderBank    string
+	SenderAccount string
+	Narration     string
+}
+
+// CreditInboundTransfer records the credit once per session id and posts it to
+// the wallet or group wallet with that account number. Credits for an account
+// number we do not know, or for a closed account, are posted to the suspense
+// account for ops.
+func CreditInboundTransfer(tx *gorm.DB, transfer InboundTransfer) (*database.InboundCredit, error) {
+	if transfer.SessionID == "" || transfer.Amount <= 0 {
+		return nil, ErrInvalidInboundCredit
+	}
+	credit := database.InboundCredit{
+		Provider:      transfer.Provider,
+		SessionID:     transfer.SessionID,
+		AccountNumber: transfer.AccountNumber,
+		Amount:        transfer.Amount,
+		SenderName:    transfer.SenderName,
+		SenderBank:    transfer.SenderBank,
+		SenderAccount: transfer.SenderAccount,
+		Narration:     transfer.Narration,
+		Status:        InboundPending,
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&credit)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDuplicateInboundCredit
+	}
+
+	err := applyInboundCredit(tx, &credit, SystemBankSettlementAccount, credit.AccountNumber)
+	if errors.Is(err, ErrAccountNumberNotFound) || errors.Is(err, ErrRecipientClosed) {
+		credit.Reference = helpers.GenerateTransactionReference()
+		credit.Status = InboundSuspense
+		if err := PostJournal(tx, credit.Reference, "Unmatched inbound transfer to "+credit.AccountNumber,
+			Leg{Account: SystemBankSettlementAccount, Amount: -helpers.ToKobo(credit.Amount)},
+			Leg{Account: SystemSuspenseAccount, Amount: helpers.ToKobo(credit.Amount)},
+		); err != nil {
+			return nil, err
+		}
+		return &credit, tx.Save(&credit).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &credit, nil
+}
+
+// ResolveSuspenseCredit moves a held credit out of suspense into the wallet
+// with the account number ops have identified
+func ResolveSuspenseCredit(id uint, accountNumber string) (*database.InboundCredit, error) {
+	var credit database.InboundCredit
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&credit, id).Error; err != nil {
+			return ErrInboundCreditNotFound
+		}
+		if credit.Status != InboundSuspense {
+			return ErrInboundCreditNotHeld
+		}
+		return applyInboundCredit(tx, &credit, SystemSuspenseAccount, accountNumber)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &credit, nil
+}
+
+// applyInboundCredit moves the credit from the given system account into the
+// wallet or group wallet with accountNumber and tells its owner. A credit to a
+// group wallet is recorded against the group, with no Olra user paying it.
+func applyInboundCredit(tx *gorm.DB, credit *database.InboundCredit, from, accountNumber string) error {
+	transaction := database.Transaction{
+		Reference:          helpers.GenerateTransactionReference(),
+		TransactionEnviron: "outsideOlra",
+		TransactionType:    "bankTransfer-in",
+		Amount:             credit.Amount,
+		Description:        credit.Narration,
+		Sender:             credit.SenderName,
+		SenderBank:         credit.SenderBank,
+		Status:             "completed",
+		TransactionDate:    time.Now(),
+	}
+	kobo := helpers.ToKobo(credit.Amount)
+	description := "Transfer from " + credit.SenderName + " (" + credit.SenderBank + ")"
+
+	var owner database.User
+	var notice Notice
+	var wallet database.VirtualAccount
+	var groupWallet database.GroupVirtualAccount
+	switch {
+	case tx.Where("virtual_account_account = ?", accountNumber).First(&wallet).Error == nil:
+		if err := lockWallets(tx, &wallet); err != nil {
+			return err
+		}
+		if err := tx.First(&owner, wallet.UserID).Error; err != nil {
+			return err
+		}
+		if owner.Status == "closed" {
+			return ErrRecipientClosed
+		}
+		if err := PostJournal(tx, transaction.Reference, description,
+			Leg{Account: from, Amount: -kobo},
+			Leg{Account: WalletAccount(wallet.VirtualAccountID), Amount: kobo},
+		); err != nil {
+			return err
+		}
+		if err := tx.First(&wallet, wallet.VirtualAccountID).Error; err != nil {
+			return err
+		}
+		transaction.UserID = owner.UserID
+		transaction.Receiver = owner.Tag
+		notice = Notice{
+			Type:  NotificationCredit,
+			Event: templates.EventCredit,
+			Data: templates.Data{
+				"Tag":     credit.SenderName,
+				"Amount":  credit.Amount,
+				"Balance": wallet.Balance,
+				"Date":    transaction.TransactionDate,
+			},
+		}
+	case tx.Where("group_virtual_account_number = ?", accountNumber).First(&groupWallet).Error == nil:
+		var group database.Group
+		if err := tx.First(&group, groupWallet.GroupID).Error; err != nil {
+			return err
+		}
+		if err := PostJournal(tx, transaction.Reference, description,
+			Leg{Account: from, Amount: -kobo},
+			Leg{Account: GroupWalletAccount(groupWallet.GroupVirtualAccountID), Amount: kobo},
+		); err != nil {
+			return err
+		}
+		ownerID := group.AdminID
+		if ownerID == 0 {
+			ownerID = group.CreatedBy
+		}
+		if err := tx.First(&owner, ownerID).Error; err != nil {
+			return err
+		}
+		transaction.Receiver = group.GroupTag
+		notice = Notice{
+			Type:  NotificationGroup,
+			Event: templates.EventGroupContribution,
+			Data: templates.Data{
+				"Tag":    credit.SenderName,
+				"Group":  group.GroupName,
+				"Amount": credit.Amount,
+			},
+		}
+	default:
+		return ErrAccountNumberNotFound
+	}
+
+	if err := tx.Create(&transaction).Error; err != nil {
+		return err
+	}
+	notice.ResourceType = ResourceTransaction
+	notice.ResourceID = transaction.Reference
+	if err := Dispatch(tx, &owner, notice); err != nil {
+		return err
+	}
+
+	if credit.Reference == "" {
+		credit.Reference = transaction.Reference
+	}
+	credit.TransactionReference = transaction.Reference
+	credit.Status = InboundCredited
+	if from == SystemSuspenseAccount {
+		credit.Status = InboundResolved
+	}
+	return tx.Save(credit).Error
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+var inboundModels = append([]interface{}{
+	&database.InboundCredit{}, &database.Group{}, &database.GroupVirtualAccount{},
+}, walletModels...)
+
+func creditInbound(db *gorm.DB, sessionID, accountNumber string, amount float64) (*database.InboundCredit, error) {
+	var credit *database.InboundCredit
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		credit, err = CreditInboundTransfer(tx, InboundTransfer{
+			Provider:      "test",
+			SessionID:     sessionID,
+			AccountNumber: accountNumber,
+			Amount:        amount,
+			SenderName:    "Ada Obi",
+			SenderBank:    "GTBank",
+		})
+		return err
+	})
+	return credit, err
+}
+
+func TestCreditInboundTransfer(t *testing.T) {
+	db := dbtest.Open(t, inboundModels...)
+	user, wallet := createWalletUser(t, db, "ada", 0)
+
+	credit, err := creditInbound(db, "S1", wallet.VirtualAccountAccount, 2500)
+	if err != nil {
+		t.Fatalf("CreditInboundTransfer() error = %v", err)
+	}
+	if credit.Status != InboundCredited {
+		t.Errorf("status = %q, want %q", credit.Status, InboundCredited)
+	}
+	if balance, _ := LedgerBalance(db, WalletAccount(wallet.VirtualAccountID)); balance != 250000 {
+		t.Errorf("wallet balance = %d, want 250000", balance)
+	}
+	var transaction database.Transaction
+	db.Where("reference = ?", credit.TransactionReference).First(&transaction)
+	if transaction.UserID != user.UserID || transaction.Receiver != user.Tag {
+		t.Errorf("transaction recorded for user %d %q, want %d %q",
+			transaction.UserID, transaction.Receiver, user.UserID, user.Tag)
+	}
+
+	if _, err := creditInbound(db, "S1", wallet.VirtualAccountAccount, 2500); !errors.Is(err, ErrDuplicateInboundCredit) {
+		t.Errorf("second credit error = %v, want %v", err, ErrDuplicateInboundCredit)
+	}
+	if balance, _ := LedgerBalance(db, WalletAccount(wallet.VirtualAccountID)); balance != 250000 {
+		t.Errorf("wallet balance after the duplicate = %d, want 250000", balance)
+	}
+}
+
+func TestCreditInboundTransferSuspense(t *testing.T) {
+	tests := []struct {
+		name  string
+		close bool
+	}{
+		{name: "unknown account number"},
+		{name: "closed account", close: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t, inboundModels...)
+			user, wallet := createWalletUser(t, db, "ada", 0)
+			accountNumber := "0000000000"
+			if tt.close {
+				accountNumber = wallet.VirtualAccountAccount
+				db.Model(user).Update("status", "closed")
+			}
+
+			credit, err := creditInbound(db, "S1", accountNumber, 1000)
+			if err != nil {
+				t.Fatalf("CreditInboundTransfer() error = %v", err)
+			}
+			if credit.Status != InboundSuspense {
+				t.Errorf("status = %q, want %q", credit.Status, InboundSuspense)
+			}
+			if balance, _ := LedgerBalance(db, SystemSuspenseAccount); balance != 100000 {
+				t.Errorf("suspense balance = %d, want 100000", balance)
+			}
+			if balance, _ := LedgerBalance(db, WalletAccount(wallet.VirtualAccountID)); balance != 0 {
+				t.Errorf("wallet balance = %d, want 0", balance)
+			}
+		})
+	}
+}
+
+func TestCreditInboundTransferToGroup(t *testing.T) {
+	db := dbtest.Open(t, inboundModels...)
+	admin, _ := createWalletUser(t, db, "ada", 0)
+	group := database.Group{GroupName: "Savers", GroupTag: "savers", CreatedBy: admin.UserID, AdminID: admin.UserID}
+	db.Create(&group)
+	groupWallet := database.GroupVirtualAccount{GroupID: group.GroupID, GroupVirtualAccountBank: "Wema Bank",
+		GroupVirtualAccountNumber: "9100000001", GroupVirtualAccountName: "Savers"}
+	db.Create(&groupWallet)
+
+	credit, err := creditInbound(db, "S1", groupWallet.GroupVirtualAccountNumber, 1000)
+	if err != nil {
+		t.Fatalf("CreditInboundTransfer() error = %v", err)
+	}
+	if balance, _ := LedgerBalance(db, GroupWalletAccount(groupWallet.GroupVirtualAccountID)); balance != 100000 {
+		t.Errorf("group balance = %d, want 100000", balance)
+	}
+	var transaction database.Transaction
+	db.Where("reference = ?", credit.TransactionReference).First(&transaction)
+	if transaction.UserID != 0 || transaction.Receiver != group.GroupTag {
+		t.Errorf("transaction recorded for user %d %q, want the group %q",
+			transaction.UserID, transaction.Receiver, group.GroupTag)
+	}
+	history, err := TransactionHistory(admin.UserID, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 0 {
+		t.Errorf("the credit shows in the admin's own history: %+v", history)
+	}
+	var notifications []database.Notification
+	db.Find(&notifications)
+	if len(notifications) != 1 || notifications[0].UserID != admin.UserID {
+		t.Errorf("notifications = %+v, want one for the admin", notifications)
+	}
+}
+
+func TestResolveSuspenseCredit(t *testing.T) {
+	db := dbtest.Open(t, inboundModels...)
+	_, wallet := createWalletUser(t, db, "ada", 0)
+	closed, closedWallet := createWalletUser(t, db, "tunde", 0)
+	db.Model(closed).Update("status", "closed")
+	credit, err := creditInbound(db, "S1", "0000000000", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ResolveSuspenseCredit(credit.ID, closedWallet.VirtualAccountAccount); !errors.Is(err, ErrRecipientClosed) {
+		t.Errorf("resolving to a closed account error = %v, want %v", err, ErrRecipientClosed)
+	}
+	if _, err := ResolveSuspenseCredit(credit.ID, "1111111111"); !errors.Is(err, ErrAccountNumberNotFound) {
+		t.Errorf("resolving to an unknown account error = %v, want %v", err, ErrAccountNumberNotFound)
+	}
+	resolved, err := ResolveSuspenseCredit(credit.ID, wallet.VirtualAccountAccount)
+	if err != nil {
+		t.Fatalf("ResolveSuspenseCredit() error = %v", err)
+	}
+	if resolved.Status != InboundResolved {
+		t.Errorf("status = %q, want %q", resolved.Status, InboundResolved)
+	}
+	if balance, _ := LedgerBalance(db, WalletAccount(wallet.VirtualAccountID)); balance != 100000 {
+		t.Errorf("wallet balance = %d, want 100000", balance)
+	}
+	if balance, _ := LedgerBalance(db, SystemSuspenseAccount); balance != 0 {
+		t.Errorf("suspense balance = %d, want 0", balance)
+	}
+	if _, err := ResolveSuspenseCredit(credit.ID, wallet.VirtualAccountAccount); !errors.Is(err, ErrInboundCreditNotHeld) {
+		t.Errorf("resolving twice error = %v, want %v", err, ErrInboundCreditNotHeld)
+	}
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	helpers "olra-v1/utils"
+)
+
+const (
+	IntegrityUnbalancedJournal = "unbalanced-journal"
+	IntegrityUnmatchedTransfer = "unmatched-transfer"
+	IntegrityNegativeBalance   = "negative-balance"
+	IntegrityBalanceDrift      = "balance-drift"
+	IntegrityOmnibusMismatch   = "omnibus-mismatch"
+
+	IntegrityPassed = "passed"
+	IntegrityFailed = "failed"
+)
+
+// IntegrityChecks are the kinds of finding a check can report
+var IntegrityChecks = []string{
+	IntegrityUnbalancedJournal,
+	IntegrityUnmatchedTransfer,
+	IntegrityNegativeBalance,
+	IntegrityBalanceDrift,
+	IntegrityOmnibusMismatch,
+}
+
+// transferTypes are the transactions that must have moved money in the
+// ledger once completed
+var transferTypes = []string{PricedOlraTransfer, PricedGroupPayment, PricedBankTransfer, "bankTransfer-in", "reversal"}
+
+// integrityRun collects findings, keeping at most INTEGRITY_MAX_FINDINGS rows
+// while counting them all
+type integrityRun struct {
+	report      *database.IntegrityReport
+	maxFindings int
+}
+
+func (r *integrityRun) add(check, subject string, expected, actual int64, detail string) {
+	switch check {
+	case IntegrityUnbalancedJournal:
+		r.report.UnbalancedJournals++
+	case IntegrityUnmatchedTransfer:
+		r.report.UnmatchedTransfers++
+	case IntegrityNegativeBalance:
+		r.report.NegativeBalances++
+	case IntegrityBalanceDrift:
+		r.report.BalanceDrifts++
+	case IntegrityOmnibusMismatch:
+		r.report.OmnibusMismatch = true
+	}
+	r.report.Status = IntegrityFailed
+	if len(r.report.Findings) >= r.maxFindings {
+		return
+	}
+	r.report.Findings = append(r.report.Findings, database.IntegrityFinding{
+		Check:    check,
+		Subject:  subject,
+		Expected: helpers.FromKobo(expected),
+		Actual:   helpers.FromKobo(actual),
+		Detail:   detail,
+	})
+}
+
+// CheckLedgerIntegrity verifies the invariants the ledger depends on and saves
+// the report:
+//
+//   - every journal nets to zero
+//   - every wallet's cached balance equals the sum of its ledger entries
+//   - no wallet or group wallet is negative
+//   - customer funds equal the omnibus bank balance less what the system
+//     accounts, fees among them, own or owe
+//   - every completed transfer has a debit and credits for its amount
+//
+// Journals and transfers are only checked from since when it is given;
+// balances always cover all history.
+func CheckLedgerIntegrity(since *time.Time) (*database.IntegrityReport, error) {
+	run := &integrityRun{
+		report: &database.IntegrityReport{
+			Since:     since,
+			Status:    IntegrityPassed,
+			StartedAt: time.Now(),
+		},
+		maxFindings: envInt("INTEGRITY_MAX_FINDINGS", 1000),
+	}
+	checks := []func(*integrityRun) error{
+		checkJournals,
+		checkBalances,
+		checkTransfers,
+	}
+	for _, check := range checks {
+		if err := check(run); err != nil {
+			return nil, err
+		}
+	}
+	run.report.CompletedAt = time.Now()
+	if err := database.DB.Create(run.report).Error; err != nil {
+		return nil, err
+	}
+	return run.report, nil
+}
+
+func checkJournals(run *integrityRun) error {
+	scope := func() *gorm.DB {
+		db := database.DB.Model(&database.LedgerEntry{})
+		if run.report.Since != nil {
+			db = db.Where("created_at >= ?", *run.report.Since)
+		}
+		return db
+	}
+	var journals int64
+	if err := scope().Distinct("reference").Count(&journals).Error; err != nil {
+		return err
+	}
+	run.report.JournalsChecked = int(journals)
+	var unbalanced []struct {
+		Reference string
+		Net       int64
+	}
+	if err := scope().
+		Select("reference, SUM(amount) AS net").
+		Group("reference").
+		Having("SUM(amount) <> 0").
+		Scan(&unbalanced).Error; err != nil {
+		return err
+	}
+	for _, journal := range unbalanced {
+		run.add(IntegrityUnbalancedJournal, journal.Reference, 0, journal.Net, "journal does not net to zero")
+	}
+	return nil
+}
+
+// checkBalances compares each wallet with its ledger, then the customer funds
+// they add up to with the omnibus account. The omnibus balance comes from the
+// provider when BANKING_SETTLEMENT_ACCOUNT is set, otherwise from the bank
+// settlement account in the ledger.
+func checkBalances(run *integrityRun) error {
+	var sums []struct {
+		Account string
+		Balance int64
+	}
+	if err := database.DB.Model(&database.LedgerEntry{}).
+		Select("account, SUM(amount) AS balance").
+		Group("account").
+		Scan(&sums).Error; err != nil {
+		return err
+	}
+	ledger := make(map[string]int64, len(sums))
+	var otherSystems int64
+	for _, sum := range sums {
+		ledger[sum.Account] = sum.Balance
+		switch sum.Account {
+		case SystemBankSettlementAccount, SystemOpeningBalancesAccount:
+		default:
+			if strings.HasPrefix(sum.Account, "system:") {
+				otherSystems += sum.Balance
+			}
+		}
+	}
+
+	var customerFunds int64
+	compare := func(account string, balance float64) {
+		cached := helpers.ToKobo(balance)
+		customerFunds += cached
+		run.report.AccountsChecked++
+		if cached != ledger[account] {
+			run.add(IntegrityBalanceDrift, account, ledger[account], cached, "cached balance differs from the ledger")
+		}
+		if cached < 0 || ledger[account] < 0 {
+			run.add(IntegrityNegativeBalance, account, 0, min(cached, ledger[account]), "balance is below zero")
+		}
+	}
+	var wallets []database.VirtualAccount
+	if err := database.DB.Select("virtual_account_id, balance").Find(&wallets).Error; err != nil {
+		return err
+	}
+	for _, wallet := range wallets {
+		compare(WalletAccount(wallet.VirtualAccountID), wallet.Balance)
+	}
+	var groupWallets []database.GroupVirtualAccount
+	if err := database.DB.Select("group_virtual_account_id, balance").Find(&groupWallets).Error; err != nil {
+		return err
+	}
+	for _, groupWallet := range groupWallets {
+		compare(GroupWalletAccount(groupWallet.GroupVirtualAccountID), groupWallet.Balance)
+	}
+
+	// Money arriving at the bank is a debit to the settlement account, so the
+	// bank holds the negative of its balance
+	omnibus := -ledger[SystemBankSettlementAccount]
+	omnibusAccount := SystemBankSettlementAccount
+	run.report.OmnibusSource = "ledger"
+	if number := SettlementAccountNumber(); number != "" {
+		account, err := Banking().GetAccount(number)
+		if err != nil {
+			return fmt.Errorf("reading omnibus balance: %w", err)
+		}
+		omnibus = helpers.ToKobo(account.Balance)
+		omnibusAccount = number
+		run.report.OmnibusSource = "provider"
+	}
+	expected := omnibus - otherSystems
+	// Money wallets held before the ledger never passed through the settlement
+	// account, though it is in the bank's balance
+	if run.report.OmnibusSource == "ledger" {
+		expected -= ledger[SystemOpeningBalancesAccount]
+	}
+	run.report.CustomerFunds = helpers.FromKobo(customerFunds)
+	run.report.OmnibusBalance = helpers.FromKobo(omnibus)
+	run.report.OmnibusDifference = helpers.FromKobo(customerFunds - expected)
+	tolerance := helpers.ToKobo(envFloat("INTEGRITY_OMNIBUS_TOLERANCE", 0))
+	if difference := customerFunds - expected; difference > tolerance || -difference > tolerance {
+		run.add(IntegrityOmnibusMismatch, omnibusAccount, expected, customerFunds,
+			"customer funds differ from the omnibus balance less system accounts ("+run.report.OmnibusSource+")")
+	}
+	return nil
+}
+
+// checkTransfers nets each completed transfer's journal and expects a debit
+// and credits to accounts other than fee income that add up to the
+// transaction amount
+func checkTransfers(run *integrityRun) error {
+	db := database.DB.Where("status = ? AND transaction_type IN ?", "completed", transferTypes)
+	if run.report.Since != nil {
+		db = db.Where("transaction_date >= ?", *run.report.Since)
+	}
+	var transactions []database.Transaction
+	return db.Select("transaction_id, reference, amount").FindInBatches(&transactions, 500, func(tx *gorm.DB, batch int) error {
+		references := make([]string, 0, len(transactions))
+		for _, transaction := range transactions {
+			references = append(references, transaction.Reference)
+		}
+		var nets []struct {
+			Reference string
+			Account   string
+			Net       int64
+		}
+		if err := database.DB.Model(&database.LedgerEntry{}).
+			Select("reference, account, SUM(amount) AS net").
+			Where("reference IN ?", references).
+			Group("reference, account").
+			Scan(&nets).Error; err != nil {
+			return err
+		}
+		type movement struct {
+			debited  bool
+			credited int64
+			entries  bool
+		}
+		byAccount := map[string]map[string]int64{}
+		for _, net := range nets {
+			if byAccount[net.Reference] == nil {
+				byAccount[net.Reference] = map[string]int64{}
+			}
+			byAccount[net.Reference][net.Account] = net.Net
+		}
+		for _, transaction := range transactions {
+			run.report.TransfersChecked++
+			var moved movement
+			for account, net := range byAccount[transaction.Reference] {
+				moved.entries = true
+				switch {
+				case net < 0:
+					moved.debited = true
+				case net > 0 && account != SystemFeeIncomeAccount:
+					moved.credited += net
+				}
+			}
+			amount := helpers.ToKobo(transaction.Amount)
+			switch {
+			case !moved.entries:
+				run.add(IntegrityUnmatchedTransfer, transaction.Reference, amount, 0, "no ledger entries")
+			case !moved.debited || moved.credited != amount:
+				run.add(IntegrityUnmatchedTransfer, transaction.Reference, amount, moved.credited,
+					"debit and credits do not match the transaction amount")
+			}
+		}
+		return nil
+	}).Error
+}
+
+func ListIntegrityReports(page, limit int) ([]database.IntegrityReport, error) {
+	var reports []database.IntegrityReport
+	err := database.DB.Order("completed_at desc").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&reports).Error
+	return reports, err
+}
+
+func GetIntegrityReport(id uint) (*database.IntegrityReport, error) {
+	var report database.IntegrityReport
+	if err := database.DB.Preload("Findings").First(&report, id).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// IntegrityMetrics renders the latest report in the Prometheus text format
+func IntegrityMetrics() (string, error) {
+	var report database.IntegrityReport
+	result := database.DB.Order("completed_at desc").Limit(1).Find(&report)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "# no integrity check has run yet\n", nil
+	}
+	passed, omnibusMismatch := 0, 0
+	if report.Status == IntegrityPassed {
+		passed = 1
+	}
+	if report.OmnibusMismatch {
+		omnibusMismatch = 1
+	}
+	var b strings.Builder
+	findings := map[string]int{
+		IntegrityUnbalancedJournal: report.UnbalancedJournals,
+		IntegrityUnmatchedTransfer: report.UnmatchedTransfers,
+		IntegrityNegativeBalance:   report.NegativeBalances,
+		IntegrityBalanceDrift:      report.BalanceDrifts,
+		IntegrityOmnibusMismatch:   omnibusMismatch,
+	}
+	fmt.Fprintf(&b, "# HELP olra_integrity_passed Whether the last ledger integrity check found nothing.\n")
+	fmt.Fprintf(&b, "# TYPE olra_integrity_passed gauge\nolra_integrity_passed %d\n", passed)
+	fmt.Fprintf(&b, "# HELP olra_integrity_last_run_timestamp_seconds When the last ledger integrity check finished.\n")
+	fmt.Fprintf(&b, "# TYPE olra_integrity_last_run_timestamp_seconds gauge\nolra_integrity_last_run_timestamp_seconds %d\n", report.CompletedAt.Unix())
+	fmt.Fprintf(&b, "# HELP olra_integrity_findings Findings from the last ledger integrity check by check.\n")
+	fmt.Fprintf(&b, "# TYPE olra_integrity_findings gauge\n")
+	for _, check := range IntegrityChecks {
+		fmt.Fprintf(&b, "olra_integrity_findings{check=%q} %d\n", check, findings[check])
+	}
+	fmt.Fprintf(&b, "# HELP olra_integrity_customer_funds_naira Sum of wallet and group wallet balances.\n")
+	fmt.Fprintf(&b, "# TYPE olra_integrity_customer_funds_naira gauge\nolra_integrity_customer_funds_naira %.2f\n", report.CustomerFunds)
+	fmt.Fprintf(&b, "# HELP olra_integrity_omnibus_difference_naira Customer funds less what the omnibus account should hold for them.\n")
+	fmt.Fprintf(&b, "# TYPE olra_integrity_omnibus_difference_naira gauge\nolra_integrity_omnibus_difference_naira %.2f\n", report.OmnibusDifference)
+	return b.String(), nil
+}
+
+// StartIntegrityChecks runs the checks every INTEGRITY_CHECK_SECONDS over the
+// last INTEGRITY_LOOKBACK_DAYS of journals and transfers
+func StartIntegrityChecks() {
+	interval := time.Duration(envInt("INTEGRITY_CHECK_SECONDS", 3600)) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			since := time.Now().AddDate(0, 0, -envInt("INTEGRITY_LOOKBACK_DAYS", 7))
+			report, err := CheckLedgerIntegrity(&since)
+			if err != nil {
+				log.Println("Error checking ledger integrity:", err)
+				continue
+			}
+			if report.Status == IntegrityFailed {
+				log.Printf("Ledger integrity check %d failed: %d unbalanced journals, %d unmatched transfers, %d negative balances, %d balance drifts, omnibus off by %.2f",
+					report.ID, report.UnbalancedJournals, report.UnmatchedTransfers, report.NegativeBalances,
+					report.BalanceDrifts, report.OmnibusDifference)
+			}
+		}
+	}()
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+var integrityModels = append([]interface{}{
+	&database.GroupVirtualAccount{}, &database.IntegrityReport{}, &database.IntegrityFinding{},
+}, walletModels...)
+
+func TestCheckLedgerIntegrity(t *testing.T) {
+	t.Setenv("BANKING_SETTLEMENT_ACCOUNT", "")
+	db := dbtest.Open(t, integrityModels...)
+	sender, senderWallet := createWalletUser(t, db, "ada", 100000)
+	receiver, receiverWallet := createWalletUser(t, db, "tunde", 0)
+	if _, err := TransferToUser(sender, receiver, senderWallet, receiverWallet, 500, "", ChannelApp); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := CheckLedgerIntegrity(nil)
+	if err != nil {
+		t.Fatalf("CheckLedgerIntegrity() error = %v", err)
+	}
+	if report.Status != IntegrityPassed || len(report.Findings) != 0 {
+		t.Fatalf("clean ledger = %s with findings %+v, want passed", report.Status, report.Findings)
+	}
+	if report.TransfersChecked == 0 || report.AccountsChecked != 2 {
+		t.Errorf("checked %d transfers and %d accounts", report.TransfersChecked, report.AccountsChecked)
+	}
+
+	// A journal that does not net to zero, a transfer with no journal and a
+	// cached balance that has drifted from the ledger
+	db.Create(&database.LedgerEntry{Reference: "BROKEN", Account: SystemSuspenseAccount, Amount: 100})
+	db.Create(&database.Transaction{Reference: "MISSING", TransactionEnviron: "withinOlra",
+		TransactionType: PricedOlraTransfer, Amount: 10, Status: "completed", TransactionDate: time.Now()})
+	db.Model(receiverWallet).Update("balance", 600)
+
+	report, err = CheckLedgerIntegrity(nil)
+	if err != nil {
+		t.Fatalf("CheckLedgerIntegrity() error = %v", err)
+	}
+	if report.Status != IntegrityFailed {
+		t.Errorf("status = %q, want %q", report.Status, IntegrityFailed)
+	}
+	if report.UnbalancedJournals != 1 || report.UnmatchedTransfers != 1 || report.BalanceDrifts != 1 {
+		t.Errorf("found %d unbalanced journals, %d unmatched transfers and %d drifts, want one of each",
+			report.UnbalancedJournals, report.UnmatchedTransfers, report.BalanceDrifts)
+	}
+}
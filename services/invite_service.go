@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/templates"
+	helpers "olra-v1/utils"
+)
+
+const inviteValidity = 14 * 24 * time.Hour
+
+var (
+	ErrInviteRequired = errors.New("an invite code is required to sign up")
+	ErrInvalidInvite  = errors.New("invite code is invalid or has already been used")
+	ErrInviteExpired  = errors.New("invite code has expired")
+)
+
+// InviteOnlyMode reports whether signups must present an invite code
+func InviteOnlyMode() bool {
+	return os.Getenv("INVITE_ONLY") == "true"
+}
+
+// ValidateInviteCode checks that an invite exists and can still be redeemed
+func ValidateInviteCode(code string) (*database.Invite, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, ErrInviteRequired
+	}
+	var invite database.Invite
+	if err := database.DB.Where("code = ? AND used_at IS NULL", code).First(&invite).Error; err != nil {
+		return nil, ErrInvalidInvite
+	}
+	if time.Now().After(invite.ExpiresAt) {
+		return nil, ErrInviteExpired
+	}
+	return &invite, nil
+}
+
+// RedeemInvite marks an invite as used by the new user and records the
+// waitlist conversion. It runs in the transaction that creates the user, and
+// the update only matches an invite that is still unused and unexpired, so
+// concurrent signups cannot share a single-use code.
+func RedeemInvite(tx *gorm.DB, code string, userID uint) error {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return ErrInviteRequired
+	}
+	var invite database.Invite
+	if err := tx.Where("code = ?", code).First(&invite).Error; err != nil {
+		return ErrInvalidInvite
+	}
+	now := time.Now()
+	result := tx.Model(&database.Invite{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", invite.ID, now).
+		Updates(map[string]interface{}{"used_at": now, "used_by_user_id": userID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if invite.UsedAt == nil && now.After(invite.ExpiresAt) {
+			return ErrInviteExpired
+		}
+		return ErrInvalidInvite
+	}
+	if invite.WaitlistID != 0 {
+		return tx.Model(&database.Waitlist{}).
+			Where("id = ? AND converted_at IS NULL", invite.WaitlistID).
+			Updates(map[string]interface{}{"converted_user_id": userID, "converted_at": now}).Error
+	}
+	return nil
+}
+
+// MarkWaitlistConverted attributes a signup to a waitlist entry that was never
+// invited, matched on email or phone
+func MarkWaitlistConverted(email, phone string, userID uint) {
+	if email == "" && phone == "" {
+		return
+	}
+	// Empty values must not match entries that left the field blank
+	query := database.DB.Model(&database.Waitlist{}).Where("converted_at IS NULL")
+	switch {
+	case email != "" && phone != "":
+		query = query.Where("email = ? OR phone = ?", email, phone)
+	case email != "":
+		query = query.Where("email = ?", email)
+	default:
+		query = query.Where("phone = ?", phone)
+	}
+	now := time.Now()
+	err := query.Updates(map[string]interface{}{"converted_user_id": userID, "converted_at": now}).Error
+	if err != nil {
+		log.Println("Error marking waitlist conversion:", err)
+	}
+}
+
+func newInviteCode() (string, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := helpers.GenerateReferralCode()
+		if err != nil {
+			return "", err
+		}
+		var count int64
+		if err := database.DB.Model(&database.Invite{}).Where("code = ?", code).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return code, nil
+		}
+	}
+	return "", errors.New("could not generate a unique invite code")
+}
+
+// InviteWaitlist issues invites to the given waitlist entries, or to the
+// oldest uninvited entries when no IDs are given, and sends them by email
+// and SMS. Delivery failures are logged and do not undo the invite.
+func InviteWaitlist(waitlistIDs []uint, limit int) ([]database.Invite, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var entries []database.Waitlist
+	query := database.DB.Where("invited_at IS NULL AND converted_at IS NULL")
+	if len(waitlistIDs) > 0 {
+		query = query.Where("id IN ?", waitlistIDs)
+	}
+	if err := query.Order("id asc").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	invites := []database.Invite{}
+	for _, entry := range entries {
+		code, err := newInviteCode()
+		if err != nil {
+			return invites, err
+		}
+		now := time.Now()
+		invite := database.Invite{
+			Code:       code,
+			WaitlistID: entry.ID,
+			Email:      entry.Email,
+			Phone:      entry.Phone,
+			ExpiresAt:  now.Add(inviteValidity),
+		}
+		if err := database.DB.Create(&invite).Error; err != nil {
+			return invites, err
+		}
+		database.DB.Model(&entry).Update("invited_at", now)
+
+		if entry.Email != "" {
+			if err := SendInviteEmail(templates.DefaultLocale, entry.FullName, entry.Email, code); err != nil {
+				log.Println("Error sending invite email:", err)
+			}
+		}
+		if entry.Phone != "" {
+			if _, err := SendInviteSMS(templates.DefaultLocale, entry.Phone, entry.FullName, code); err != nil {
+				log.Println("Error sending invite sms:", err)
+			}
+		}
+		invites = append(invites, invite)
+	}
+	return invites, nil
+}
+
+type WaitlistReport struct {
+	Total            int64   `json:"total"`
+	Invited          int64   `json:"invited"`
+	Converted        int64   `json:"converted"`
+	InvitedConverted int64   `json:"invitedConverted"` // converted entries that had been invited
+	PendingInvites   int64   `json:"pendingInvites"`
+	ExpiredInvites   int64   `json:"expiredInvites"`
+	ConversionRate   float64 `json:"conversionRate"` // invitedConverted / invited
+}
+
+func GetWaitlistReport() (*WaitlistReport, error) {
+	report := &WaitlistReport{}
+	if err := database.DB.Model(&database.Waitlist{}).Count(&report.Total).Error; err != nil {
+		return nil, err
+	}
+	database.DB.Model(&database.Waitlist{}).Where("invited_at IS NOT NULL").Count(&report.Invited)
+	database.DB.Model(&database.Waitlist{}).Where("converted_at IS NOT NULL").Count(&report.Converted)
+	// Entries that signed up without an invite are converted but not invited
+	database.DB.Model(&database.Waitlist{}).
+		Where("invited_at IS NOT NULL AND converted_at IS NOT NULL").
+		Count(&report.InvitedConverted)
+	database.DB.Model(&database.Invite{}).Where("used_at IS NULL AND expires_at > ?", time.Now()).Count(&report.PendingInvites)
+	database.DB.Model(&database.Invite{}).Where("used_at IS NULL AND expires_at <= ?", time.Now()).Count(&report.ExpiredInvites)
+	if report.Invited > 0 {
+		report.ConversionRate = float64(report.InvitedConverted) / float64(report.Invited)
+	}
+	return report, nil
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+// createTestInvite invites a new waitlist entry with an invite expiring at expiresAt
+func createTestInvite(t *testing.T, db *gorm.DB, code, email string, expiresAt time.Time) (*database.Waitlist, *database.Invite) {
+	t.Helper()
+	now := time.Now()
+	entry := database.Waitlist{FullName: "Test " + code, Email: email, InvitedAt: &now}
+	if err := db.Create(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	invite := database.Invite{Code: code, WaitlistID: entry.ID, Email: email, ExpiresAt: expiresAt}
+	if err := db.Create(&invite).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &entry, &invite
+}
+
+func TestRedeemInvite(t *testing.T) {
+	db := dbtest.Open(t, &database.Waitlist{}, &database.Invite{})
+	entry, invite := createTestInvite(t, db, "INVITE1", "ada@example.com", time.Now().Add(time.Hour))
+
+	if _, err := ValidateInviteCode(" invite1 "); err != nil {
+		t.Fatalf("ValidateInviteCode() error = %v", err)
+	}
+	if err := RedeemInvite(db, "invite1", 7); err != nil {
+		t.Fatalf("RedeemInvite() error = %v", err)
+	}
+	db.First(invite, invite.ID)
+	if invite.UsedAt == nil || invite.UsedByUserID == nil || *invite.UsedByUserID != 7 {
+		t.Errorf("invite = %+v, want used by user 7", invite)
+	}
+	db.First(entry, entry.ID)
+	if entry.ConvertedAt == nil || entry.ConvertedUserID == nil || *entry.ConvertedUserID != 7 {
+		t.Errorf("waitlist entry = %+v, want converted to user 7", entry)
+	}
+
+	if err := RedeemInvite(db, "INVITE1", 8); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("second redemption error = %v, want %v", err, ErrInvalidInvite)
+	}
+	if _, err := ValidateInviteCode("INVITE1"); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("ValidateInviteCode() after redemption error = %v, want %v", err, ErrInvalidInvite)
+	}
+}
+
+func TestRedeemInviteRefused(t *testing.T) {
+	db := dbtest.Open(t, &database.Waitlist{}, &database.Invite{})
+	createTestInvite(t, db, "EXPIRED", "ada@example.com", time.Now().Add(-time.Hour))
+
+	tests := []struct {
+		name string
+		code string
+		err  error
+	}{
+		{"blank", " ", ErrInviteRequired},
+		{"unknown", "NOSUCHCODE", ErrInvalidInvite},
+		{"expired", "EXPIRED", ErrInviteExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RedeemInvite(db, tt.code, 7); !errors.Is(err, tt.err) {
+				t.Errorf("RedeemInvite() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+	var used int64
+	db.Model(&database.Invite{}).Where("used_at IS NOT NULL").Count(&used)
+	if used != 0 {
+		t.Errorf("%d invites used, want none", used)
+	}
+}
+
+func TestMarkWaitlistConverted(t *testing.T) {
+	db := dbtest.Open(t, &database.Waitlist{}, &database.Invite{})
+	withEmail := database.Waitlist{FullName: "Ada", Email: "ada@example.com"}
+	withPhone := database.Waitlist{FullName: "Tunde", Phone: "08000000001"}
+	db.Create(&withEmail)
+	db.Create(&withPhone)
+
+	// A signup with no phone must not match the entry that left its email blank
+	MarkWaitlistConverted("ada@example.com", "", 7)
+
+	db.First(&withEmail, withEmail.ID)
+	db.First(&withPhone, withPhone.ID)
+	if withEmail.ConvertedAt == nil {
+		t.Error("entry with a matching email not converted")
+	}
+	if withPhone.ConvertedAt != nil {
+		t.Error("entry with a blank email converted")
+	}
+}
+
+func TestGetWaitlistReport(t *testing.T) {
+	db := dbtest.Open(t, &database.Waitlist{}, &database.Invite{})
+	createTestInvite(t, db, "INVITE1", "ada@example.com", time.Now().Add(time.Hour))
+	createTestInvite(t, db, "INVITE2", "tunde@example.com", time.Now().Add(time.Hour))
+	createTestInvite(t, db, "EXPIRED", "chioma@example.com", time.Now().Add(-time.Hour))
+	db.Create(&database.Waitlist{FullName: "Uninvited", Email: "bola@example.com"})
+	if err := RedeemInvite(db, "INVITE1", 7); err != nil {
+		t.Fatal(err)
+	}
+	MarkWaitlistConverted("bola@example.com", "", 8)
+
+	report, err := GetWaitlistReport()
+	if err != nil {
+		t.Fatalf("GetWaitlistReport() error = %v", err)
+	}
+	want := WaitlistReport{
+		Total:            4,
+		Invited:          3,
+		Converted:        2,
+		InvitedConverted: 1,
+		PendingInvites:   1,
+		ExpiredInvites:   1,
+		ConversionRate:   1.0 / 3,
+	}
+	if *report != want {
+		t.Errorf("report = %+v, want %+v", *report, want)
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	helpers "olra-v1/utils"
+)
+
+const (
+	SystemReferralAccount = "system:referral-rewards"
+	// Funds that have arrived at our settlement bank account
+	SystemBankSettlementAccount = "system:bank-settlement"
+	// Inbound funds that could not be matched to a wallet
+	SystemSuspenseAccount  = "system:suspense"
+	SystemFeeIncomeAccount = "system:fee-income"
+	// Balances wallets already held when the ledger was introduced
+	SystemOpeningBalancesAccount = "system:opening-balances"
+)
+
+const openingBalancePrefix = "opening-balance:"
+
+var (
+	ErrUnbalancedJournal = errors.New("journal legs do not sum to zero")
+	ErrEmptyJournal      = errors.New("journal must have at least two legs")
+)
+
+// Leg is a single movement in a journal, in kobo. Credits are positive.
+type Leg struct {
+	Account string
+	Amount  int64
+}
+
+func WalletAccount(virtualAccountID uint) string {
+	return fmt.Sprintf("wallet:%d", virtualAccountID)
+}
+
+func GroupWalletAccount(groupVirtualAccountID uint) string {
+	return fmt.Sprintf("group:%d", groupVirtualAccountID)
+}
+
+// parseLedgerAccount splits a wallet or group ledger account into its kind
+// and id. It reports false for system accounts.
+func parseLedgerAccount(account string) (string, uint, bool) {
+	kind, rawID, found := strings.Cut(account, ":")
+	if !found || kind == "system" {
+		return "", 0, false
+	}
+	id, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return kind, uint(id), true
+}
+
+// PostJournal writes a balanced set of ledger entries and moves the cached
+// balance on any wallet it touches. It must be called with a transaction so the
+// entries and the balance updates land together.
+func PostJournal(tx *gorm.DB, reference, description string, legs ...Leg) error {
+	if len(legs) < 2 {
+		return ErrEmptyJournal
+	}
+	var sum int64
+	for _, leg := range legs {
+		sum += leg.Amount
+	}
+	if sum != 0 {
+		return ErrUnbalancedJournal
+	}
+
+	for _, leg := range legs {
+		entry := database.LedgerEntry{
+			Reference:   reference,
+			Account:     leg.Account,
+			Amount:      leg.Amount,
+			Description: description,
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		if err := applyToBalance(tx, leg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyToBalance(tx *gorm.DB, leg Leg) error {
+	kind, rawID, found := strings.Cut(leg.Account, ":")
+	if !found || kind == "system" {
+		return nil
+	}
+	id, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid ledger account %q", leg.Account)
+	}
+
+	var result *gorm.DB
+	switch kind {
+	case "wallet":
+		result = tx.Model(&database.VirtualAccount{}).
+			Where("virtual_account_id = ?", id).
+			Update("balance", gorm.Expr("balance + ?", helpers.FromKobo(leg.Amount)))
+	case "group":
+		result = tx.Model(&database.GroupVirtualAccount{}).
+			Where("group_virtual_account_id = ?", id).
+			Update("balance", gorm.Expr("balance + ?", helpers.FromKobo(leg.Amount)))
+	default:
+		return fmt.Errorf("unknown ledger account type %q", kind)
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("ledger account %q not found", leg.Account)
+	}
+	return nil
+}
+
+// LedgerBalance sums the entries posted to an account, in kobo
+func LedgerBalance(db *gorm.DB, account string) (int64, error) {
+	var balance int64
+	err := db.Model(&database.LedgerEntry{}).
+		Where("account = ?", account).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&balance).Error
+	return balance, err
+}
+
+// PostOpeningBalances gives every wallet and group wallet whose cached balance
+// the ledger does not explain a journal from the opening balances account for
+// the difference. It brings in the money wallets held before the ledger and
+// runs once, as a migration at startup: the journals are dated just before the
+// first ledger entry so statements and the integrity checks see the money from
+// the start, and the cached balances, which already hold it, are left alone.
+func PostOpeningBalances(tx *gorm.DB) error {
+	epoch := time.Now()
+	var first database.LedgerEntry
+	err := tx.Order("created_at").First(&first).Error
+	switch {
+	case err == nil:
+		epoch = first.CreatedAt.Add(-time.Second)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+
+	var sums []struct {
+		Account string
+		Balance int64
+	}
+	if err := tx.Model(&database.LedgerEntry{}).
+		Select("account, SUM(amount) AS balance").
+		Group("account").
+		Scan(&sums).Error; err != nil {
+		return err
+	}
+	ledger := make(map[string]int64, len(sums))
+	for _, sum := range sums {
+		ledger[sum.Account] = sum.Balance
+	}
+
+	cached := make(map[string]float64)
+	var wallets []database.VirtualAccount
+	if err := tx.Select("virtual_account_id, balance").Find(&wallets).Error; err != nil {
+		return err
+	}
+	for _, wallet := range wallets {
+		cached[WalletAccount(wallet.VirtualAccountID)] = wallet.Balance
+	}
+	var groupWallets []database.GroupVirtualAccount
+	if err := tx.Select("group_virtual_account_id, balance").Find(&groupWallets).Error; err != nil {
+		return err
+	}
+	for _, groupWallet := range groupWallets {
+		cached[GroupWalletAccount(groupWallet.GroupVirtualAccountID)] = groupWallet.Balance
+	}
+
+	for account, balance := range cached {
+		difference := helpers.ToKobo(balance) - ledger[account]
+		if difference == 0 {
+			continue
+		}
+		// Written directly rather than through PostJournal, which would add
+		// the amount to the cached balance a second time
+		reference := openingBalancePrefix + account
+		entries := []database.LedgerEntry{
+			{Reference: reference, Account: SystemOpeningBalancesAccount, Amount: -difference, Description: "Opening balance", CreatedAt: epoch},
+			{Reference: reference, Account: account, Amount: difference, Description: "Opening balance", CreatedAt: epoch},
+		}
+		if err := tx.Create(&entries).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
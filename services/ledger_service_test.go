@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+func TestPostOpeningBalances(t *testing.T) {
+	db := dbtest.Open(t, append([]interface{}{&database.GroupVirtualAccount{}}, walletModels...)...)
+	// Funded before the ledger, then paid through it
+	_, wallet := createWalletUser(t, db, "ada", 0)
+	db.Model(wallet).Update("balance", 1500)
+	fundWallet(t, db, WalletAccount(wallet.VirtualAccountID), 50000)
+	_, emptyWallet := createWalletUser(t, db, "tunde", 0)
+	groupWallet := database.GroupVirtualAccount{GroupID: 1, GroupVirtualAccountBank: "Wema Bank",
+		GroupVirtualAccountNumber: "9100000001", GroupVirtualAccountName: "Savers", Balance: 200}
+	db.Create(&groupWallet)
+
+	migration := database.Migration{Name: "opening-balances", Run: PostOpeningBalances}
+	for i := 0; i < 2; i++ {
+		if err := database.Migrate(db, migration); err != nil {
+			t.Fatalf("Migrate() run %d error = %v", i+1, err)
+		}
+	}
+	t.Cleanup(func() { db.Migrator().DropTable(&database.SchemaMigration{}) })
+
+	assertLedgerBalance(t, WalletAccount(wallet.VirtualAccountID), 200000)
+	assertLedgerBalance(t, WalletAccount(emptyWallet.VirtualAccountID), 0)
+	assertLedgerBalance(t, GroupWalletAccount(groupWallet.GroupVirtualAccountID), 20000)
+	assertLedgerBalance(t, SystemOpeningBalancesAccount, -170000)
+	// The cached balance already held the money
+	db.First(wallet, wallet.VirtualAccountID)
+	if wallet.Balance != 2000 {
+		t.Errorf("cached balance = %v, want 2000", wallet.Balance)
+	}
+	assertAvailable(t, wallet, 2000)
+
+	var first database.LedgerEntry
+	db.Order("created_at").First(&first)
+	if first.Reference != openingBalancePrefix+WalletAccount(wallet.VirtualAccountID) &&
+		first.Reference != openingBalancePrefix+GroupWalletAccount(groupWallet.GroupVirtualAccountID) {
+		t.Errorf("first ledger entry is %q, want an opening balance", first.Reference)
+	}
+}
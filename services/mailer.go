@@ -0,0 +1,307 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/gomail.v2"
+
+	"olra-v1/internal/database"
+)
+
+// Mailer delivers a single email
+type Mailer interface {
+	Name() string
+	Send(email Email) error
+}
+
+// Email is sent as multipart/alternative when HTML is set
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+var (
+	ErrMailRateLimited     = errors.New("too many emails to this recipient, try again later")
+	ErrMailerNotConfigured = errors.New("no mailer configured: set SMTP_HOST, or MAILER=file or MAILER=memory to keep emails local")
+)
+
+func buildMessage(from string, email Email) *gomail.Message {
+	m := gomail.NewMessage()
+	m.SetHeader("From", from)
+	m.SetHeader("To", email.To)
+	m.SetHeader("Subject", email.Subject)
+	m.SetBody("text/plain", email.Text)
+	if email.HTML != "" {
+		m.AddAlternative("text/html", email.HTML)
+	}
+	return m
+}
+
+// SMTPMailer keeps up to SMTP_POOL_SIZE authenticated connections open and
+// reuses them between sends
+type SMTPMailer struct {
+	From   string
+	dialer *gomail.Dialer
+	pool   chan gomail.SendCloser
+}
+
+// NewSMTPMailer reads SMTP_HOST, SMTP_PORT, SMTP_USERNAME and MAIL_FROM. The
+// password comes from SMTP_PASSWORD or, for mounted secrets, the file named
+// by SMTP_PASSWORD_FILE.
+func NewSMTPMailer() *SMTPMailer {
+	password := os.Getenv("SMTP_PASSWORD")
+	if path := os.Getenv("SMTP_PASSWORD_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			log.Println("Error reading SMTP password file:", err)
+		}
+		password = strings.TrimSpace(string(raw))
+	}
+	username := os.Getenv("SMTP_USERNAME")
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = username
+	}
+	return &SMTPMailer{
+		From:   from,
+		dialer: gomail.NewDialer(os.Getenv("SMTP_HOST"), envInt("SMTP_PORT", 465), username, password),
+		pool:   make(chan gomail.SendCloser, envInt("SMTP_POOL_SIZE", 2)),
+	}
+}
+
+func (s *SMTPMailer) Name() string {
+	return "smtp"
+}
+
+func (s *SMTPMailer) Send(email Email) error {
+	message := buildMessage(s.From, email)
+	conn, err := s.acquire()
+	if err != nil {
+		return err
+	}
+	if err := conn.Send(s.From, []string{email.To}, message); err != nil {
+		conn.Close()
+		if isPermanentMailError(err) {
+			return err
+		}
+		// Pooled connections are dropped by the server when idle, so try once
+		// more on a fresh one
+		if conn, err = s.dialer.Dial(); err != nil {
+			return err
+		}
+		if err := conn.Send(s.From, []string{email.To}, message); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	s.release(conn)
+	return nil
+}
+
+func (s *SMTPMailer) acquire() (gomail.SendCloser, error) {
+	select {
+	case conn := <-s.pool:
+		return conn, nil
+	default:
+		return s.dialer.Dial()
+	}
+}
+
+func (s *SMTPMailer) release(conn gomail.SendCloser) {
+	select {
+	case s.pool <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+var smtpReplyCode = regexp.MustCompile(`^5\d\d `)
+
+// isPermanentMailError reports a 5xx reply, which means the server refused the
+// message or the address and retrying will not help
+func isPermanentMailError(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 500
+	}
+	return smtpReplyCode.MatchString(err.Error())
+}
+
+// FileMailer writes every email as an .eml file in MAIL_DROP_DIR so it can be
+// opened in a mail client during development
+type FileMailer struct {
+	Dir string
+}
+
+func NewFileMailer() *FileMailer {
+	dir := os.Getenv("MAIL_DROP_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "olra-mail")
+	}
+	return &FileMailer{Dir: dir}
+}
+
+func (f *FileMailer) Name() string {
+	return "file"
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]`)
+
+func (f *FileMailer) Send(email Email) error {
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000"), unsafeFileChars.ReplaceAllString(email.To, "_"))
+	file, err := os.Create(filepath.Join(f.Dir, name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = buildMessage("no-reply@olra.local", email).WriteTo(file)
+	return err
+}
+
+// MemoryMailer keeps every email in memory for tests
+type MemoryMailer struct {
+	mu     sync.Mutex
+	emails []Email
+	Fail   error // returned from every call when set
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Name() string {
+	return "memory"
+}
+
+func (m *MemoryMailer) Send(email Email) error {
+	if m.Fail != nil {
+		return m.Fail
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emails = append(m.emails, email)
+	return nil
+}
+
+// Emails returns a copy of everything sent so far
+func (m *MemoryMailer) Emails() []Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Email(nil), m.emails...)
+}
+
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emails = nil
+}
+
+// mailer is read on every send and may be replaced at any time, so both go
+// through mailerMu
+var (
+	mailer   Mailer
+	mailerMu sync.RWMutex
+)
+
+// Mail returns the configured mailer. MAILER picks smtp, file or memory; when
+// it is unset SMTP is used if SMTP_HOST is configured and the file drop if not.
+func Mail() Mailer {
+	mailerMu.RLock()
+	m := mailer
+	mailerMu.RUnlock()
+	if m != nil {
+		return m
+	}
+	mailerMu.Lock()
+	defer mailerMu.Unlock()
+	if mailer == nil {
+		mailer = newMailerFromEnv()
+	}
+	return mailer
+}
+
+// SetMailer replaces the mailer, mainly so tests can install a MemoryMailer
+func SetMailer(m Mailer) {
+	mailerMu.Lock()
+	defer mailerMu.Unlock()
+	mailer = m
+}
+
+// CheckMailer runs at startup. In release mode the file drop is only used when
+// MAILER asks for it, so OTP and invite emails are never quietly written to
+// disk in production.
+func CheckMailer() error {
+	if os.Getenv("GIN_MODE") == "release" && os.Getenv("MAILER") == "" && os.Getenv("SMTP_HOST") == "" {
+		return ErrMailerNotConfigured
+	}
+	log.Printf("Sending email with the %s mailer", Mail().Name())
+	return nil
+}
+
+func newMailerFromEnv() Mailer {
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		return NewSMTPMailer()
+	case "file":
+		return NewFileMailer()
+	case "memory":
+		return NewMemoryMailer()
+	}
+	if os.Getenv("SMTP_HOST") != "" {
+		return NewSMTPMailer()
+	}
+	log.Println("SMTP_HOST not set, writing emails to the mail drop directory")
+	return NewFileMailer()
+}
+
+// sendEmail is the single path every email takes. It enforces the
+// per-recipient limit of MAIL_RATE_LIMIT_PER_HOUR and records the outcome of
+// each attempt, separating bounces from transient failures.
+func sendEmail(to, subject, text, html string) error {
+	to = strings.ToLower(strings.TrimSpace(to))
+	m := Mail()
+	record := database.EmailLog{Recipient: to, Subject: subject, Provider: m.Name()}
+
+	var sent int64
+	if err := database.DB.Model(&database.EmailLog{}).
+		Where("recipient = ? AND status = ? AND created_at > ?", to, "sent", time.Now().Add(-time.Hour)).
+		Count(&sent).Error; err != nil {
+		return err
+	}
+	if sent >= int64(envInt("MAIL_RATE_LIMIT_PER_HOUR", 20)) {
+		record.Status = "rate-limited"
+		database.DB.Create(&record)
+		log.Printf("Email to %s rate limited", to)
+		return ErrMailRateLimited
+	}
+
+	err := m.Send(Email{To: to, Subject: subject, Text: text, HTML: html})
+	switch {
+	case err == nil:
+		record.Status = "sent"
+	case isPermanentMailError(err):
+		record.Status = "bounced"
+		record.Error = err.Error()
+		log.Printf("Email to %s bounced: %v", to, err)
+	default:
+		record.Status = "failed"
+		record.Error = err.Error()
+		log.Printf("Email to %s failed: %v", to, err)
+	}
+	database.DB.Create(&record)
+	return err
+}
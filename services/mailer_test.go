@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+// useMemoryMailer installs a MemoryMailer for the test
+func useMemoryMailer(t *testing.T) *MemoryMailer {
+	t.Helper()
+	previous := Mail()
+	memory := NewMemoryMailer()
+	SetMailer(memory)
+	t.Cleanup(func() { SetMailer(previous) })
+	return memory
+}
+
+func TestSendEmail(t *testing.T) {
+	t.Setenv("MAIL_RATE_LIMIT_PER_HOUR", "2")
+	db := dbtest.Open(t, &database.EmailLog{})
+	memory := useMemoryMailer(t)
+
+	for i := 0; i < 2; i++ {
+		if err := sendEmail(" Ada@Example.com", "Hello", "text", "<p>html</p>"); err != nil {
+			t.Fatalf("sendEmail() error = %v", err)
+		}
+	}
+	if err := sendEmail("ada@example.com", "Hello", "text", ""); !errors.Is(err, ErrMailRateLimited) {
+		t.Errorf("third sendEmail() error = %v, want %v", err, ErrMailRateLimited)
+	}
+	if emails := memory.Emails(); len(emails) != 2 || emails[0].To != "ada@example.com" {
+		t.Errorf("emails sent = %+v, want two to ada@example.com", emails)
+	}
+
+	memory.Fail = errors.New("550 5.1.1 mailbox unavailable")
+	if err := sendEmail("tunde@example.com", "Hello", "text", ""); err == nil {
+		t.Error("sendEmail() to a missing mailbox succeeded")
+	}
+	memory.Fail = errors.New("connection reset")
+	if err := sendEmail("tunde@example.com", "Hello", "text", ""); err == nil {
+		t.Error("sendEmail() with the connection down succeeded")
+	}
+
+	var statuses []string
+	db.Model(&database.EmailLog{}).Order("id").Pluck("status", &statuses)
+	want := []string{"sent", "sent", "rate-limited", "bounced", "failed"}
+	if len(statuses) != len(want) {
+		t.Fatalf("logged %v, want %v", statuses, want)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Errorf("log %d = %q, want %q", i, statuses[i], want[i])
+		}
+	}
+}
+
+func TestCheckMailer(t *testing.T) {
+	useMemoryMailer(t)
+	t.Setenv("GIN_MODE", "release")
+	t.Setenv("MAILER", "")
+	t.Setenv("SMTP_HOST", "")
+	if err := CheckMailer(); !errors.Is(err, ErrMailerNotConfigured) {
+		t.Errorf("CheckMailer() in release without a mailer error = %v, want %v", err, ErrMailerNotConfigured)
+	}
+	t.Setenv("MAILER", "memory")
+	if err := CheckMailer(); err != nil {
+		t.Errorf("CheckMailer() with MAILER set error = %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/templates"
+)
+
+// Notice is something to tell a user. Dispatch decides which channels it goes
+// out on.
+type Notice struct {
+	Type         string // credit, debit, request, group, security, marketing, regulatory
+	Event        string // template event
+	ResourceType string // what the app opens when it is tapped
+	ResourceID   string
+	Data         templates.Data
+}
+
+// Keys the push payload uses alongside the template data
+const (
+	pushNotificationIDKey = "_notificationId"
+	pushResourceTypeKey   = "_resourceType"
+	pushResourceIDKey     = "_resourceId"
+)
+
+// Dispatch is the single way notifications reach users. It writes the inbox
+// entry and queues SMS, email and push in the outbox for the channels the
+// user's preferences allow, holding external channels until quiet hours end.
+// Pass the transaction making the change so nothing is sent unless it commits.
+func Dispatch(tx *gorm.DB, user *database.User, notice Notice) error {
+	preferences, err := loadPreferences(tx, user.UserID)
+	if err != nil {
+		return err
+	}
+	channels := preferences[notice.Type]
+	mandatory := mandatoryNotificationTypes[notice.Type]
+	if mandatory {
+		channels = defaultPreferences[notice.Type]
+	}
+
+	deliverAt := time.Now()
+	if !mandatory {
+		if end, quiet := quietHoursEnd(user, deliverAt); quiet {
+			deliverAt = end
+		}
+	}
+
+	var notificationID string
+	if channels[ChannelInApp] {
+		rendered, err := templates.Render(notice.Event, user.Language, notice.Data)
+		if err != nil {
+			return err
+		}
+		notification := database.Notification{
+			UserID:       user.UserID,
+			Type:         notice.Type,
+			Event:        notice.Event,
+			Title:        rendered.Subject,
+			Body:         rendered.Text,
+			ResourceType: notice.ResourceType,
+			ResourceID:   notice.ResourceID,
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+		notificationID = strconv.FormatUint(uint64(notification.ID), 10)
+	}
+	if channels[ChannelPush] {
+		payload := templates.Data{}
+		for key, value := range notice.Data {
+			payload[key] = value
+		}
+		payload[pushNotificationIDKey] = notificationID
+		payload[pushResourceTypeKey] = notice.ResourceType
+		payload[pushResourceIDKey] = notice.ResourceID
+		if err := enqueueNotificationAt(tx, deliverAt, ChannelPush, notice.Event, user.Language,
+			strconv.FormatUint(uint64(user.UserID), 10), notice.ResourceID, payload); err != nil {
+			return err
+		}
+	}
+	if channels[ChannelSMS] {
+		if err := enqueueNotificationAt(tx, deliverAt, ChannelSMS, notice.Event, user.Language,
+			user.PhoneNumber, notice.ResourceID, notice.Data); err != nil {
+			return err
+		}
+	}
+	if channels[ChannelEmail] {
+		if err := enqueueNotificationAt(tx, deliverAt, ChannelEmail, notice.Event, user.Language,
+			user.Email, notice.ResourceID, notice.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DispatchGroupContribution tells every member of the group except the sender
+// about a contribution
+func DispatchGroupContribution(tx *gorm.DB, group *database.Group, sender *database.User, amount float64) error {
+	var members []database.User
+	if err := tx.Model(group).Association("Members").Find(&members); err != nil {
+		return err
+	}
+	for i := range members {
+		if members[i].UserID == sender.UserID {
+			continue
+		}
+		if err := Dispatch(tx, &members[i], Notice{
+			Type:         NotificationGroup,
+			Event:        templates.EventGroupContribution,
+			ResourceType: ResourceGroup,
+			ResourceID:   strconv.FormatUint(uint64(group.GroupID), 10),
+			Data: templates.Data{
+				"Tag":    sender.Tag,
+				"Group":  group.GroupName,
+				"Amount": amount,
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dispatchLowBalance alerts the user the first time a debit takes their
+// balance below the threshold they set
+func dispatchLowBalance(tx *gorm.DB, user *database.User, before, after float64) error {
+	threshold := user.LowBalanceThreshold
+	if threshold <= 0 || before < threshold || after >= threshold {
+		return nil
+	}
+	return Dispatch(tx, user, Notice{
+		Type:  NotificationDebit,
+		Event: templates.EventLowBalance,
+		Data: templates.Data{
+			"Balance":   after,
+			"Threshold": threshold,
+		},
+	})
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+)
+
+const (
+	ChannelSMS   = "sms"
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+	ChannelInApp = "in-app"
+)
+
+// Security and regulatory notices always go out on their default channels and
+// ignore quiet hours
+var mandatoryNotificationTypes = map[string]bool{
+	NotificationSecurity:   true,
+	NotificationRegulatory: true,
+}
+
+// PreferenceMatrix maps a notification type to whether each channel is on
+type PreferenceMatrix map[string]map[string]bool
+
+// SMS stays on for money movement as it was before preferences existed;
+// marketing is opt-in everywhere but the inbox
+var defaultPreferences = PreferenceMatrix{
+	NotificationCredit:     {ChannelSMS: true, ChannelEmail: false, ChannelPush: true, ChannelInApp: true},
+	NotificationDebit:      {ChannelSMS: true, ChannelEmail: false, ChannelPush: true, ChannelInApp: true},
+	NotificationRequest:    {ChannelSMS: true, ChannelEmail: false, ChannelPush: true, ChannelInApp: true},
+	NotificationGroup:      {ChannelSMS: false, ChannelEmail: false, ChannelPush: true, ChannelInApp: true},
+	NotificationSecurity:   {ChannelSMS: false, ChannelEmail: true, ChannelPush: true, ChannelInApp: true},
+	NotificationMarketing:  {ChannelSMS: false, ChannelEmail: false, ChannelPush: false, ChannelInApp: true},
+	NotificationRegulatory: {ChannelSMS: false, ChannelEmail: true, ChannelPush: false, ChannelInApp: true},
+}
+
+// Quiet hours are kept in Lagos time whatever the server's zone
+var lagos = time.FixedZone("WAT", 60*60)
+
+var (
+	ErrMandatoryNotification = errors.New("security and regulatory notifications cannot be turned off")
+	ErrUnknownNotification   = errors.New("unknown notification type or channel")
+	ErrInvalidQuietHours     = errors.New("quiet hours must both be set as HH:MM or both be empty")
+)
+
+type NotificationSettings struct {
+	Preferences         PreferenceMatrix `json:"preferences"`
+	Mandatory           []string         `json:"mandatory"`
+	QuietHoursStart     string           `json:"quietHoursStart"`
+	QuietHoursEnd       string           `json:"quietHoursEnd"`
+	LowBalanceThreshold float64          `json:"lowBalanceThreshold"`
+}
+
+// loadPreferences overlays the user's saved choices on the defaults
+func loadPreferences(db *gorm.DB, userID uint) (PreferenceMatrix, error) {
+	matrix := PreferenceMatrix{}
+	for kind, channels := range defaultPreferences {
+		matrix[kind] = map[string]bool{}
+		for channel, enabled := range channels {
+			matrix[kind][channel] = enabled
+		}
+	}
+	var saved []database.NotificationPreference
+	if err := db.Where("user_id = ?", userID).Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	for _, preference := range saved {
+		if mandatoryNotificationTypes[preference.Type] {
+			continue
+		}
+		if _, ok := matrix[preference.Type]; ok {
+			matrix[preference.Type][preference.Channel] = preference.Enabled
+		}
+	}
+	return matrix, nil
+}
+
+func GetNotificationSettings(userID uint) (*NotificationSettings, error) {
+	var user database.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return nil, ErrAccountNotFound
+	}
+	matrix, err := loadPreferences(database.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Regulatory notices are not a choice so they are left out of the matrix
+	delete(matrix, NotificationRegulatory)
+	return &NotificationSettings{
+		Preferences:         matrix,
+		Mandatory:           []string{NotificationSecurity},
+		QuietHoursStart:     user.QuietHoursStart,
+		QuietHoursEnd:       user.QuietHoursEnd,
+		LowBalanceThreshold: user.LowBalanceThreshold,
+	}, nil
+}
+
+// UpdateNotificationSettings saves the channels given in the matrix and
+// leaves the rest as they were. Nil settings are left unchanged.
+func UpdateNotificationSettings(
+	userID uint,
+	preferences PreferenceMatrix,
+	quietHoursStart, quietHoursEnd *string,
+	lowBalanceThreshold *float64,
+) (*NotificationSettings, error) {
+	for kind, channels := range preferences {
+		if _, ok := defaultPreferences[kind]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownNotification, kind)
+		}
+		for channel, enabled := range channels {
+			if _, ok := defaultPreferences[kind][channel]; !ok {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownNotification, channel)
+			}
+			if mandatoryNotificationTypes[kind] && enabled != defaultPreferences[kind][channel] {
+				return nil, ErrMandatoryNotification
+			}
+		}
+	}
+	if quietHoursStart != nil || quietHoursEnd != nil {
+		if quietHoursStart == nil || quietHoursEnd == nil || !validQuietHours(*quietHoursStart, *quietHoursEnd) {
+			return nil, ErrInvalidQuietHours
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for kind, channels := range preferences {
+			if mandatoryNotificationTypes[kind] {
+				continue
+			}
+			for channel, enabled := range channels {
+				if err := tx.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "channel"}},
+					DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+				}).Create(&database.NotificationPreference{
+					UserID:  userID,
+					Type:    kind,
+					Channel: channel,
+					Enabled: enabled,
+				}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		updates := map[string]interface{}{}
+		if quietHoursStart != nil {
+			updates["quiet_hours_start"] = *quietHoursStart
+			updates["quiet_hours_end"] = *quietHoursEnd
+		}
+		if lowBalanceThreshold != nil {
+			updates["low_balance_threshold"] = *lowBalanceThreshold
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(&database.User{}).Where("user_id = ?", userID).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetNotificationSettings(userID)
+}
+
+func validQuietHours(start, end string) bool {
+	if start == "" && end == "" {
+		return true
+	}
+	if _, err := time.Parse("15:04", start); err != nil {
+		return false
+	}
+	if _, err := time.Parse("15:04", end); err != nil {
+		return false
+	}
+	return start != end
+}
+
+// quietHoursEnd reports whether now falls in the user's quiet hours and, if
+// so, when they finish. Windows may run past midnight, e.g. 22:00 to 07:00.
+func quietHoursEnd(user *database.User, now time.Time) (time.Time, bool) {
+	if user.QuietHoursStart == "" || user.QuietHoursEnd == "" {
+		return now, false
+	}
+	start, err := time.Parse("15:04", user.QuietHoursStart)
+	if err != nil {
+		return now, false
+	}
+	end, err := time.Parse("15:04", user.QuietHoursEnd)
+	if err != nil {
+		return now, false
+	}
+	local := now.In(lagos)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	var quiet bool
+	if startMinute < endMinute {
+		quiet = minute >= startMinute && minute < endMinute
+	} else {
+		quiet = minute >= startMinute || minute < endMinute
+	}
+	if !quiet {
+		return now, false
+	}
+	finish := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, lagos)
+	if !finish.After(local) {
+		finish = finish.AddDate(0, 0, 1)
+	}
+	return finish, true
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+	"olra-v1/internal/templates"
+)
+
+func outboxChannels(t *testing.T, db *gorm.DB) map[string]database.OutboxMessage {
+	t.Helper()
+	var messages []database.OutboxMessage
+	if err := db.Find(&messages).Error; err != nil {
+		t.Fatal(err)
+	}
+	channels := map[string]database.OutboxMessage{}
+	for _, message := range messages {
+		channels[message.Channel] = message
+	}
+	return channels
+}
+
+func TestUpdateNotificationSettings(t *testing.T) {
+	db := dbtest.Open(t, walletModels...)
+	user, _ := createWalletUser(t, db, "ada", 0)
+	start, end := "22:00", "07:00"
+	threshold := 1000.0
+
+	settings, err := UpdateNotificationSettings(user.UserID,
+		PreferenceMatrix{NotificationCredit: {ChannelSMS: false}, NotificationMarketing: {ChannelEmail: true}},
+		&start, &end, &threshold)
+	if err != nil {
+		t.Fatalf("UpdateNotificationSettings() error = %v", err)
+	}
+	if settings.Preferences[NotificationCredit][ChannelSMS] || !settings.Preferences[NotificationCredit][ChannelPush] {
+		t.Errorf("credit preferences = %v, want sms off and push left on", settings.Preferences[NotificationCredit])
+	}
+	if !settings.Preferences[NotificationMarketing][ChannelEmail] {
+		t.Error("marketing email not turned on")
+	}
+	if settings.QuietHoursStart != start || settings.QuietHoursEnd != end || settings.LowBalanceThreshold != threshold {
+		t.Errorf("settings = %+v, want quiet hours %s-%s and threshold %v", settings, start, end, threshold)
+	}
+
+	// Saving again updates the stored row rather than adding another
+	if _, err := UpdateNotificationSettings(user.UserID, PreferenceMatrix{NotificationCredit: {ChannelSMS: true}}, nil, nil, nil); err != nil {
+		t.Fatalf("UpdateNotificationSettings() error = %v", err)
+	}
+	var rows int64
+	db.Model(&database.NotificationPreference{}).Where("type = ? AND channel = ?", NotificationCredit, ChannelSMS).Count(&rows)
+	if rows != 1 {
+		t.Errorf("stored %d credit sms preferences, want 1", rows)
+	}
+}
+
+func TestUpdateNotificationSettingsRefused(t *testing.T) {
+	db := dbtest.Open(t, walletModels...)
+	user, _ := createWalletUser(t, db, "ada", 0)
+	start, blank, same := "22:00", "", "22:00"
+
+	tests := []struct {
+		name        string
+		preferences PreferenceMatrix
+		start, end  *string
+		err         error
+	}{
+		{"security off", PreferenceMatrix{NotificationSecurity: {ChannelEmail: false}}, nil, nil, ErrMandatoryNotification},
+		{"unknown type", PreferenceMatrix{"weather": {ChannelSMS: true}}, nil, nil, ErrUnknownNotification},
+		{"unknown channel", PreferenceMatrix{NotificationCredit: {"fax": true}}, nil, nil, ErrUnknownNotification},
+		{"only a start", nil, &start, nil, ErrInvalidQuietHours},
+		{"half blank", nil, &start, &blank, ErrInvalidQuietHours},
+		{"empty window", nil, &start, &same, ErrInvalidQuietHours},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UpdateNotificationSettings(user.UserID, tt.preferences, tt.start, tt.end, nil); !errors.Is(err, tt.err) {
+				t.Errorf("UpdateNotificationSettings() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestQuietHoursEnd(t *testing.T) {
+	user := &database.User{QuietHoursStart: "22:00", QuietHoursEnd: "07:00"}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 1, hour, minute, 0, 0, lagos)
+	}
+	tests := []struct {
+		name  string
+		now   time.Time
+		quiet bool
+		end   time.Time
+	}{
+		{"before the window", at(21, 59), false, time.Time{}},
+		{"evening", at(23, 30), true, time.Date(2024, 3, 2, 7, 0, 0, 0, lagos)},
+		{"after midnight", at(3, 0), true, at(7, 0)},
+		{"window over", at(7, 0), false, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, quiet := quietHoursEnd(user, tt.now)
+			if quiet != tt.quiet || (quiet && !end.Equal(tt.end)) {
+				t.Errorf("quietHoursEnd() = %v, %v, want %v, %v", end, quiet, tt.end, tt.quiet)
+			}
+		})
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	db := dbtest.Open(t, walletModels...)
+	user, _ := createWalletUser(t, db, "ada", 0)
+	if _, err := UpdateNotificationSettings(user.UserID, PreferenceMatrix{NotificationCredit: {ChannelSMS: false}}, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return Dispatch(tx, user, Notice{
+			Type:         NotificationCredit,
+			Event:        templates.EventCredit,
+			ResourceType: ResourceTransaction,
+			ResourceID:   "REF1",
+			Data:         templates.Data{"Tag": "tunde", "Amount": 500.0, "Balance": 1500.0, "Date": "today"},
+		})
+	})
+	if err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	var notification database.Notification
+	if err := db.Where("user_id = ?", user.UserID).First(&notification).Error; err != nil {
+		t.Fatalf("no inbox entry: %v", err)
+	}
+	if notification.ResourceID != "REF1" || notification.Body == "" {
+		t.Errorf("inbox entry = %+v, want a rendered credit for REF1", notification)
+	}
+	channels := outboxChannels(t, db)
+	if _, ok := channels[ChannelPush]; !ok || len(channels) != 1 {
+		t.Errorf("queued channels = %v, want push only", channels)
+	}
+}
+
+func TestDispatchQuietHours(t *testing.T) {
+	db := dbtest.Open(t, walletModels...)
+	user, _ := createWalletUser(t, db, "ada", 0)
+	// A window that started a minute ago and runs until nearly a day from now
+	now := time.Now().In(lagos)
+	user.QuietHoursStart = now.Add(-time.Minute).Format("15:04")
+	user.QuietHoursEnd = now.Add(-2 * time.Minute).Format("15:04")
+
+	dispatch := func(kind string) {
+		t.Helper()
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return Dispatch(tx, user, Notice{
+				Type:  kind,
+				Event: templates.EventCredit,
+				Data:  templates.Data{"Tag": "tunde", "Amount": 500.0, "Balance": 1500.0, "Date": "today"},
+			})
+		})
+		if err != nil {
+			t.Fatalf("Dispatch(%s) error = %v", kind, err)
+		}
+	}
+
+	dispatch(NotificationCredit)
+	held := outboxChannels(t, db)[ChannelSMS]
+	if !held.NextAttemptAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("sms due at %v during quiet hours, want it held until they end", held.NextAttemptAt)
+	}
+
+	db.Where("1 = 1").Delete(&database.OutboxMessage{})
+	dispatch(NotificationSecurity)
+	email := outboxChannels(t, db)[ChannelEmail]
+	if email.ID == 0 || email.NextAttemptAt.After(time.Now()) {
+		t.Errorf("security email = %+v, want it queued for now", email)
+	}
+}
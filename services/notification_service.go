@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"olra-v1/internal/database"
+)
+
+const (
+	NotificationCredit     = "credit"
+	NotificationDebit      = "debit"
+	NotificationRequest    = "request"
+	NotificationGroup      = "group"
+	NotificationSecurity   = "security"
+	NotificationMarketing  = "marketing"
+	NotificationRegulatory = "regulatory"
+)
+
+const (
+	ResourceTransaction = "transaction"
+	ResourceRequest     = "request"
+	ResourceGroup       = "group"
+	ResourceDispute     = "dispute"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// ListNotifications returns a page of the user's inbox, newest first, with the
+// total number of entries matching the filter
+func ListNotifications(userID uint, unreadOnly bool, page, limit int) ([]database.Notification, int64, error) {
+	query := database.DB.Model(&database.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var notifications []database.Notification
+	err := query.Order("created_at desc").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&notifications).Error
+	return notifications, total, err
+}
+
+func UnreadNotificationCount(userID uint) (int64, error) {
+	var count int64
+	err := database.DB.Model(&database.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func MarkNotificationRead(userID, notificationID uint) error {
+	var notification database.Notification
+	if err := database.DB.Where(
+		"id = ? AND user_id = ?", notificationID, userID,
+	).First(&notification).Error; err != nil {
+		return ErrNotificationNotFound
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	return database.DB.Model(&notification).Update("read_at", time.Now()).Error
+}
+
+// MarkAllNotificationsRead returns how many entries were marked
+func MarkAllNotificationsRead(userID uint) (int64, error) {
+	result := database.DB.Model(&database.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// PurgeNotifications deletes inbox entries older than
+// NOTIFICATION_RETENTION_DAYS, read or not
+func PurgeNotifications() (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -envInt("NOTIFICATION_RETENTION_DAYS", 90))
+	result := database.DB.Where("created_at < ?", cutoff).Delete(&database.Notification{})
+	return result.RowsAffected, result.Error
+}
+
+// StartNotificationCleanup purges expired inbox entries once a day until the
+// process exits
+func StartNotificationCleanup() {
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			purged, err := PurgeNotifications()
+			if err != nil {
+				log.Println("Error purging notifications:", err)
+				continue
+			}
+			log.Printf("Purged %d expired notifications", purged)
+		}
+	}()
+}
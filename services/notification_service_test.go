@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+func createTestNotifications(t *testing.T, db *gorm.DB, userID uint, count int) []database.Notification {
+	t.Helper()
+	notifications := make([]database.Notification, count)
+	for i := range notifications {
+		notifications[i] = database.Notification{
+			UserID:    userID,
+			Type:      NotificationCredit,
+			Title:     "Credit",
+			CreatedAt: time.Now().Add(time.Duration(i) * time.Minute),
+		}
+		if err := db.Create(&notifications[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return notifications
+}
+
+func TestListNotifications(t *testing.T) {
+	db := dbtest.Open(t, &database.Notification{})
+	created := createTestNotifications(t, db, 1, 3)
+	createTestNotifications(t, db, 2, 1)
+
+	page, total, err := ListNotifications(1, false, 1, 2)
+	if err != nil {
+		t.Fatalf("ListNotifications() error = %v", err)
+	}
+	if total != 3 || len(page) != 2 || page[0].ID != created[2].ID || page[1].ID != created[1].ID {
+		t.Fatalf("first page = %+v of %d, want the two newest of 3", page, total)
+	}
+	page, _, _ = ListNotifications(1, false, 2, 2)
+	if len(page) != 1 || page[0].ID != created[0].ID {
+		t.Errorf("second page = %+v, want the oldest", page)
+	}
+
+	if err := MarkNotificationRead(1, created[2].ID); err != nil {
+		t.Fatalf("MarkNotificationRead() error = %v", err)
+	}
+	unread, total, _ := ListNotifications(1, true, 1, 10)
+	if total != 2 || len(unread) != 2 {
+		t.Errorf("unread = %+v of %d, want 2", unread, total)
+	}
+}
+
+func TestMarkNotificationRead(t *testing.T) {
+	db := dbtest.Open(t, &database.Notification{})
+	mine := createTestNotifications(t, db, 1, 2)
+	theirs := createTestNotifications(t, db, 2, 1)
+
+	if err := MarkNotificationRead(1, theirs[0].ID); !errors.Is(err, ErrNotificationNotFound) {
+		t.Errorf("marking another user's notification error = %v, want %v", err, ErrNotificationNotFound)
+	}
+	if err := MarkNotificationRead(1, mine[0].ID); err != nil {
+		t.Fatalf("MarkNotificationRead() error = %v", err)
+	}
+	if count, _ := UnreadNotificationCount(1); count != 1 {
+		t.Errorf("unread count = %d, want 1", count)
+	}
+	if marked, err := MarkAllNotificationsRead(1); err != nil || marked != 1 {
+		t.Errorf("MarkAllNotificationsRead() = %d, %v, want 1", marked, err)
+	}
+	if count, _ := UnreadNotificationCount(2); count != 1 {
+		t.Errorf("other user's unread count = %d, want 1", count)
+	}
+}
+
+func TestPurgeNotifications(t *testing.T) {
+	db := dbtest.Open(t, &database.Notification{})
+	t.Setenv("NOTIFICATION_RETENTION_DAYS", "30")
+	old := database.Notification{UserID: 1, Type: NotificationCredit, CreatedAt: time.Now().AddDate(0, 0, -31)}
+	recent := database.Notification{UserID: 1, Type: NotificationCredit, CreatedAt: time.Now().AddDate(0, 0, -29)}
+	db.Create(&old)
+	db.Create(&recent)
+
+	purged, err := PurgeNotifications()
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeNotifications() = %d, %v, want 1", purged, err)
+	}
+	if err := db.First(&database.Notification{}, recent.ID).Error; err != nil {
+		t.Errorf("recent notification purged: %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/templates"
+	helpers "olra-v1/utils"
+)
+
+const (
+	OTPPurposePhoneSignup       = "phone-signup"
+	OTPPurposeLogin             = "login"
+	OTPPurposeEmailVerification = "email-verification"
+)
+
+type otpPolicy struct {
+	ttl         time.Duration
+	maxAttempts int
+	event       string
+}
+
+var otpPolicies = map[string]otpPolicy{
+	OTPPurposePhoneSignup: {
+		ttl:         10 * time.Minute,
+		maxAttempts: 3,
+		event:       templates.EventOTPPhoneSignup,
+	},
+	OTPPurposeLogin: {
+		ttl:         10 * time.Minute,
+		maxAttempts: 3,
+		event:       templates.EventOTPLogin,
+	},
+	OTPPurposeEmailVerification: {
+		ttl:         30 * time.Minute,
+		maxAttempts: 5,
+		event:       templates.EventOTPEmailVerification,
+	},
+}
+
+var (
+	ErrUnknownOTPPurpose   = errors.New("unknown otp purpose")
+	ErrUnknownOTPChannel   = errors.New("unknown otp channel")
+	ErrOTPNotFound         = errors.New("OTP not valid")
+	ErrOTPExpired          = errors.New("OTP has expired")
+	ErrOTPAttemptsExceeded = errors.New("too many incorrect attempts, request a new OTP")
+	ErrInvalidOTP          = errors.New("Invalid token")
+	ErrOTPSecretMissing    = errors.New("OTP_SECRET or SECRETS must be set to hash codes")
+)
+
+// IssuedOTP is what callers learn about a code; the code itself never leaves
+// the service
+type IssuedOTP struct {
+	Recipient string    `json:"recipient"`
+	Channel   string    `json:"channel"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// useProviderPins reports whether SMS codes go through the SMS provider's pin
+// API (OTP_ENGINE=provider) rather than being generated here
+func useProviderPins(channel string) bool {
+	return os.Getenv("OTP_ENGINE") == "provider" && channel == "sms"
+}
+
+// IssueOTP creates a code for the purpose and recipient, replacing any code
+// still outstanding for them, and delivers it over the channel in the locale.
+// The user's own language takes precedence when the recipient is known.
+func IssueOTP(purpose, channel, recipient, locale string, userID uint) (*IssuedOTP, error) {
+	policy, ok := otpPolicies[purpose]
+	if !ok {
+		return nil, ErrUnknownOTPPurpose
+	}
+	if channel == "" {
+		channel = "sms"
+	}
+	recipient = normaliseRecipient(recipient)
+
+	data := templates.Data{}
+	if userID != 0 {
+		var user database.User
+		if err := database.DB.Select("first_name", "language").First(&user, userID).Error; err == nil {
+			data["FirstName"] = user.FirstName
+			if user.Language != "" {
+				locale = user.Language
+			}
+		}
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(&database.Otp{}).
+		Where("purpose = ? AND recipient = ? AND used_at IS NULL", purpose, recipient).
+		Update("expires_at", now).Error; err != nil {
+		return nil, err
+	}
+
+	otp := database.Otp{
+		UserID:      userID,
+		Purpose:     purpose,
+		Recipient:   recipient,
+		Channel:     channel,
+		MaxAttempts: policy.maxAttempts,
+		ExpiresAt:   now.Add(policy.ttl),
+	}
+	if useProviderPins(channel) {
+		data["Code"] = OTPPlaceholder
+		message, err := templates.Render(policy.event, locale, data)
+		if err != nil {
+			return nil, err
+		}
+		result, err := SMS().SendOTP(recipient, message.Text)
+		if err != nil {
+			return nil, err
+		}
+		otp.ProviderPinID = result.PinId
+		if err := database.DB.Create(&otp).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		code, err := helpers.GenerateSecureOTP(6)
+		if err != nil {
+			return nil, err
+		}
+		otp.CodeHash, err = hashOTP(purpose, recipient, code)
+		if err != nil {
+			return nil, err
+		}
+		if err := database.DB.Create(&otp).Error; err != nil {
+			return nil, err
+		}
+		data["Code"] = code
+		message, err := templates.Render(policy.event, locale, data)
+		if err != nil {
+			return nil, err
+		}
+		if err := deliver(channel, recipient, message); err != nil {
+			return nil, err
+		}
+	}
+	return &IssuedOTP{Recipient: recipient, Channel: channel, ExpiresAt: otp.ExpiresAt}, nil
+}
+
+// VerifyOTPCode checks a code against the latest outstanding OTP for the
+// purpose and recipient and consumes it on success
+func VerifyOTPCode(purpose, recipient, code string) error {
+	var otp database.Otp
+	if err := database.DB.Where(
+		"purpose = ? AND recipient = ? AND used_at IS NULL", purpose, normaliseRecipient(recipient),
+	).Order("created_at desc").First(&otp).Error; err != nil {
+		return ErrOTPNotFound
+	}
+	if time.Now().After(otp.ExpiresAt) {
+		return ErrOTPExpired
+	}
+	// The attempt is counted before the code is checked, in one guarded
+	// statement, so parallel guesses cannot all read the same count
+	attempt := database.DB.Model(&database.Otp{}).
+		Where("id = ? AND attempts < max_attempts", otp.ID).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if attempt.Error != nil {
+		return attempt.Error
+	}
+	if attempt.RowsAffected == 0 {
+		return ErrOTPAttemptsExceeded
+	}
+
+	var valid bool
+	if otp.ProviderPinID != "" {
+		result, err := SMS().VerifyOTP(otp.ProviderPinID, code)
+		if err != nil {
+			return err
+		}
+		if result.Expired {
+			return ErrOTPExpired
+		}
+		valid = result.Verified
+	} else {
+		hash, err := hashOTP(otp.Purpose, otp.Recipient, code)
+		if err != nil {
+			return err
+		}
+		valid = hmac.Equal([]byte(otp.CodeHash), []byte(hash))
+	}
+	if !valid {
+		return ErrInvalidOTP
+	}
+
+	// The used_at guard makes sure a code can only be consumed once
+	result := database.DB.Model(&database.Otp{}).
+		Where("id = ? AND used_at IS NULL", otp.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOTPNotFound
+	}
+	return nil
+}
+
+// hashOTP keys the hash with OTP_SECRET, or SECRETS, and refuses to hash with
+// an empty key that would let anyone holding the database brute-force codes
+func hashOTP(purpose, recipient, code string) (string, error) {
+	secret := os.Getenv("OTP_SECRET")
+	if secret == "" {
+		secret = os.Getenv("SECRETS")
+	}
+	if secret == "" {
+		return "", ErrOTPSecretMissing
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose + "|" + recipient + "|" + code))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func normaliseRecipient(recipient string) string {
+	recipient = strings.TrimSpace(recipient)
+	if strings.Contains(recipient, "@") {
+		return strings.ToLower(recipient)
+	}
+	return recipient
+}
+
+// deliver sends a rendered message over sms, whatsapp or email
+func deliver(channel, recipient string, message *templates.Rendered) error {
+	switch channel {
+	case "sms":
+		_, err := SMS().Send(recipient, message.Text)
+		return err
+	case "whatsapp":
+		_, err := NewTermiiWhatsAppProvider().Send(recipient, message.Text)
+		return err
+	case "email":
+		return sendEmail(recipient, message.Subject, message.Text, message.HTML)
+	default:
+		return ErrUnknownOTPChannel
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+var otpCodePattern = regexp.MustCompile(`\b\d{6}\b`)
+
+// useFakeSMS installs a fake SMS provider for the test
+func useFakeSMS(t *testing.T) *FakeSMSProvider {
+	t.Helper()
+	previous := SMS()
+	fake := NewFakeSMSProvider()
+	SetSMSProvider(fake)
+	t.Cleanup(func() { SetSMSProvider(previous) })
+	return fake
+}
+
+// issueTestOTP issues a login code by SMS and returns the code that was sent
+func issueTestOTP(t *testing.T, sms *FakeSMSProvider, recipient string) string {
+	t.Helper()
+	if _, err := IssueOTP(OTPPurposeLogin, "sms", recipient, "en", 0); err != nil {
+		t.Fatalf("IssueOTP() error = %v", err)
+	}
+	messages := sms.Messages()
+	if len(messages) == 0 {
+		t.Fatal("no OTP sent")
+	}
+	code := otpCodePattern.FindString(messages[len(messages)-1].Message)
+	if code == "" {
+		t.Fatalf("no code in %q", messages[len(messages)-1].Message)
+	}
+	return code
+}
+
+func TestVerifyOTPCode(t *testing.T) {
+	db := dbtest.Open(t, &database.User{}, &database.Otp{})
+	t.Setenv("OTP_SECRET", "test-secret")
+	sms := useFakeSMS(t)
+
+	code := issueTestOTP(t, sms, "08000000001")
+	var otp database.Otp
+	db.Last(&otp)
+	if otp.CodeHash == "" || otp.CodeHash == code {
+		t.Fatalf("stored code hash = %q, want a hash of the code", otp.CodeHash)
+	}
+	if err := VerifyOTPCode(OTPPurposeLogin, " 08000000001 ", code); err != nil {
+		t.Fatalf("VerifyOTPCode() error = %v", err)
+	}
+	if err := VerifyOTPCode(OTPPurposeLogin, "08000000001", code); !errors.Is(err, ErrOTPNotFound) {
+		t.Errorf("reusing a code error = %v, want %v", err, ErrOTPNotFound)
+	}
+	if err := VerifyOTPCode(OTPPurposePhoneSignup, "08000000001", code); !errors.Is(err, ErrOTPNotFound) {
+		t.Errorf("code for another purpose error = %v, want %v", err, ErrOTPNotFound)
+	}
+}
+
+func TestVerifyOTPCodeAttempts(t *testing.T) {
+	db := dbtest.Open(t, &database.User{}, &database.Otp{})
+	t.Setenv("OTP_SECRET", "test-secret")
+	sms := useFakeSMS(t)
+
+	code := issueTestOTP(t, sms, "08000000001")
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	for attempt := 0; attempt < otpPolicies[OTPPurposeLogin].maxAttempts; attempt++ {
+		if err := VerifyOTPCode(OTPPurposeLogin, "08000000001", wrong); !errors.Is(err, ErrInvalidOTP) {
+			t.Fatalf("attempt %d error = %v, want %v", attempt+1, err, ErrInvalidOTP)
+		}
+	}
+	if err := VerifyOTPCode(OTPPurposeLogin, "08000000001", code); !errors.Is(err, ErrOTPAttemptsExceeded) {
+		t.Errorf("right code after too many attempts error = %v, want %v", err, ErrOTPAttemptsExceeded)
+	}
+	var otp database.Otp
+	db.Last(&otp)
+	if otp.Attempts != otp.MaxAttempts || otp.UsedAt != nil {
+		t.Errorf("otp = %+v, want %d attempts and unused", otp, otp.MaxAttempts)
+	}
+}
+
+func TestIssueOTPReplacesOutstandingCode(t *testing.T) {
+	dbtest.Open(t, &database.User{}, &database.Otp{})
+	t.Setenv("OTP_SECRET", "test-secret")
+	sms := useFakeSMS(t)
+
+	first := issueTestOTP(t, sms, "08000000001")
+	second := issueTestOTP(t, sms, "08000000001")
+	if first != second {
+		if err := VerifyOTPCode(OTPPurposeLogin, "08000000001", first); !errors.Is(err, ErrInvalidOTP) {
+			t.Errorf("replaced code error = %v, want %v", err, ErrInvalidOTP)
+		}
+	}
+	if err := VerifyOTPCode(OTPPurposeLogin, "08000000001", second); err != nil {
+		t.Errorf("VerifyOTPCode() error = %v", err)
+	}
+}
+
+func TestIssueOTPWithoutSecret(t *testing.T) {
+	db := dbtest.Open(t, &database.User{}, &database.Otp{})
+	t.Setenv("OTP_SECRET", "")
+	t.Setenv("SECRETS", "")
+	sms := useFakeSMS(t)
+
+	if _, err := IssueOTP(OTPPurposeLogin, "sms", "08000000001", "en", 0); !errors.Is(err, ErrOTPSecretMissing) {
+		t.Fatalf("IssueOTP() error = %v, want %v", err, ErrOTPSecretMissing)
+	}
+	var count int64
+	db.Model(&database.Otp{}).Count(&count)
+	if count != 0 || len(sms.Messages()) != 0 {
+		t.Errorf("stored %d codes and sent %d messages, want none", count, len(sms.Messages()))
+	}
+}
+
+func TestProviderPins(t *testing.T) {
+	db := dbtest.Open(t, &database.User{}, &database.Otp{})
+	t.Setenv("OTP_ENGINE", "provider")
+	sms := useFakeSMS(t)
+
+	if _, err := IssueOTP(OTPPurposeLogin, "sms", "08000000001", "en", 0); err != nil {
+		t.Fatalf("IssueOTP() error = %v", err)
+	}
+	var otp database.Otp
+	db.Last(&otp)
+	if otp.ProviderPinID == "" || otp.CodeHash != "" {
+		t.Fatalf("otp = %+v, want a provider pin and no local hash", otp)
+	}
+	if err := VerifyOTPCode(OTPPurposeLogin, "08000000001", "999999"); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("wrong pin error = %v, want %v", err, ErrInvalidOTP)
+	}
+	if err := VerifyOTPCode(OTPPurposeLogin, "08000000001", FakeOTPCode); err != nil {
+		t.Errorf("VerifyOTPCode() error = %v", err)
+	}
+	if len(sms.Messages()) != 1 {
+		t.Errorf("sent %d messages, want 1", len(sms.Messages()))
+	}
+}
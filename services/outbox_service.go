@@ -0,0 +1,209 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/templates"
+)
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead"
+)
+
+// A claimed message is pushed this far into the future so another dispatcher
+// does not pick it up while it is being delivered
+const outboxLease = 5 * time.Minute
+
+var (
+	ErrOutboxMessageNotFound = errors.New("outbox message not found")
+	ErrOutboxMessageSent     = errors.New("outbox message has already been sent")
+)
+
+func outboxMaxAttempts() int {
+	return envInt("OUTBOX_MAX_ATTEMPTS", 8)
+}
+
+// outboxBackoff doubles the wait after every failed attempt, starting at
+// OUTBOX_BACKOFF_SECONDS and never waiting more than an hour
+func outboxBackoff(attempts int) time.Duration {
+	base := time.Duration(envInt("OUTBOX_BACKOFF_SECONDS", 30)) * time.Second
+	wait := time.Duration(float64(base) * math.Pow(2, float64(attempts-1)))
+	if wait <= 0 || wait > time.Hour {
+		return time.Hour
+	}
+	return wait
+}
+
+// EnqueueNotification writes a notification to the outbox. It must be called
+// with the transaction making the change the notification reports on, so the
+// notification exists if and only if the change was committed.
+func EnqueueNotification(tx *gorm.DB, channel, event, locale, recipient, reference string, data templates.Data) error {
+	return enqueueNotificationAt(tx, time.Now(), channel, event, locale, recipient, reference, data)
+}
+
+// enqueueNotificationAt holds the notification back until deliverAt, which is
+// how quiet hours are honoured
+func enqueueNotificationAt(tx *gorm.DB, deliverAt time.Time, channel, event, locale, recipient, reference string, data templates.Data) error {
+	if recipient == "" {
+		return nil
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&database.OutboxMessage{
+		Channel:       channel,
+		Event:         event,
+		Locale:        locale,
+		Recipient:     recipient,
+		Payload:       string(payload),
+		Reference:     reference,
+		Status:        OutboxPending,
+		MaxAttempts:   outboxMaxAttempts(),
+		NextAttemptAt: deliverAt,
+	}).Error
+}
+
+// StartOutboxDispatcher delivers due outbox messages every
+// OUTBOX_INTERVAL_SECONDS until the process exits
+func StartOutboxDispatcher() {
+	interval := time.Duration(envInt("OUTBOX_INTERVAL_SECONDS", 10)) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := DispatchOutbox(50); err != nil {
+				log.Println("Error dispatching outbox:", err)
+			}
+		}
+	}()
+}
+
+// DispatchOutbox claims up to limit due messages and tries to deliver each
+// one, returning how many were sent
+func DispatchOutbox(limit int) (int, error) {
+	var messages []database.OutboxMessage
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", OutboxPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&messages).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+		}
+		return tx.Model(&database.OutboxMessage{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(outboxLease)).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range messages {
+		if deliverOutboxMessage(&messages[i]) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func deliverOutboxMessage(message *database.OutboxMessage) bool {
+	err := sendOutboxMessage(message)
+	now := time.Now()
+	if err == nil {
+		database.DB.Model(message).Updates(map[string]interface{}{
+			"status":     OutboxSent,
+			"attempts":   message.Attempts + 1,
+			"sent_at":    now,
+			"last_error": "",
+		})
+		return true
+	}
+
+	attempts := message.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"last_error":      err.Error(),
+		"next_attempt_at": now.Add(outboxBackoff(attempts)),
+	}
+	if attempts >= message.MaxAttempts {
+		updates["status"] = OutboxDead
+		log.Printf("Outbox message %d dead-lettered after %d attempts: %v", message.ID, attempts, err)
+	}
+	database.DB.Model(message).Updates(updates)
+	return false
+}
+
+func sendOutboxMessage(message *database.OutboxMessage) error {
+	var data templates.Data
+	if message.Payload != "" {
+		if err := json.Unmarshal([]byte(message.Payload), &data); err != nil {
+			return err
+		}
+	}
+	rendered, err := templates.Render(message.Event, message.Locale, data)
+	if err != nil {
+		return err
+	}
+	if message.Channel == "push" {
+		userID, err := strconv.ParseUint(message.Recipient, 10, 64)
+		if err != nil {
+			return err
+		}
+		return SendPushToUser(uint(userID), PushMessage{
+			Title: rendered.Subject,
+			Body:  rendered.Text,
+			Data: map[string]string{
+				"event":          message.Event,
+				"notificationId": fmt.Sprint(data[pushNotificationIDKey]),
+				"resourceType":   fmt.Sprint(data[pushResourceTypeKey]),
+				"resourceId":     fmt.Sprint(data[pushResourceIDKey]),
+			},
+		})
+	}
+	return deliver(message.Channel, message.Recipient, rendered)
+}
+
+// RetryOutboxMessage puts a dead or pending message back in the queue with
+// a fresh set of attempts. A message that has been sent is left alone, even
+// when it is delivered between the request and the update.
+func RetryOutboxMessage(id uint) (*database.OutboxMessage, error) {
+	result := database.DB.Model(&database.OutboxMessage{}).
+		Where("id = ? AND status <> ?", id, OutboxSent).
+		Updates(map[string]interface{}{
+			"status":          OutboxPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	var message database.OutboxMessage
+	if err := database.DB.First(&message, id).Error; err != nil {
+		return nil, ErrOutboxMessageNotFound
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrOutboxMessageSent
+	}
+	return &message, nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+	"olra-v1/internal/templates"
+)
+
+func enqueueTestSMS(t *testing.T, db *gorm.DB) *database.OutboxMessage {
+	t.Helper()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return EnqueueNotification(tx, "sms", templates.EventRequestFunds, "en", "08000000001", "REF1",
+			templates.Data{"FirstName": "Ada", "LastName": "Obi", "Tag": "tunde", "Amount": 5000.0})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message database.OutboxMessage
+	if err := db.Last(&message).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &message
+}
+
+func TestDispatchOutbox(t *testing.T) {
+	db := dbtest.Open(t, &database.OutboxMessage{})
+	fake := useFakeSMS(t)
+	message := enqueueTestSMS(t, db)
+
+	sent, err := DispatchOutbox(10)
+	if err != nil || sent != 1 {
+		t.Fatalf("DispatchOutbox() = %d, %v, want 1", sent, err)
+	}
+	db.First(message, message.ID)
+	if message.Status != OutboxSent || message.SentAt == nil || message.Attempts != 1 {
+		t.Errorf("message = %s after %d attempts, sent at %v, want sent", message.Status, message.Attempts, message.SentAt)
+	}
+	if messages := fake.Messages(); len(messages) != 1 || messages[0].To != "08000000001" {
+		t.Errorf("sms sent = %+v, want one to 08000000001", messages)
+	}
+	if sent, _ := DispatchOutbox(10); sent != 0 {
+		t.Errorf("second DispatchOutbox() sent %d, want 0", sent)
+	}
+}
+
+func TestDispatchOutboxDeadLetters(t *testing.T) {
+	t.Setenv("OUTBOX_MAX_ATTEMPTS", "2")
+	db := dbtest.Open(t, &database.OutboxMessage{})
+	fake := useFakeSMS(t)
+	fake.Fail = errors.New("provider down")
+	message := enqueueTestSMS(t, db)
+
+	for attempt, want := range []string{OutboxPending, OutboxDead} {
+		// Due again straight away rather than after the backoff
+		db.Model(message).Update("next_attempt_at", message.CreatedAt)
+		if sent, err := DispatchOutbox(10); err != nil || sent != 0 {
+			t.Fatalf("DispatchOutbox() = %d, %v, want 0", sent, err)
+		}
+		db.First(message, message.ID)
+		if message.Status != want || message.Attempts != attempt+1 || message.LastError != "provider down" {
+			t.Errorf("after attempt %d message = %s, %d attempts, %q", attempt+1, message.Status, message.Attempts, message.LastError)
+		}
+	}
+}
+
+func TestRetryOutboxMessage(t *testing.T) {
+	db := dbtest.Open(t, &database.OutboxMessage{})
+	message := enqueueTestSMS(t, db)
+	db.Model(message).Updates(map[string]interface{}{"status": OutboxDead, "attempts": 8})
+
+	retried, err := RetryOutboxMessage(message.ID)
+	if err != nil {
+		t.Fatalf("RetryOutboxMessage() error = %v", err)
+	}
+	if retried.Status != OutboxPending || retried.Attempts != 0 {
+		t.Errorf("message = %s after %d attempts, want pending with none", retried.Status, retried.Attempts)
+	}
+
+	db.Model(message).Update("status", OutboxSent)
+	if _, err := RetryOutboxMessage(message.ID); !errors.Is(err, ErrOutboxMessageSent) {
+		t.Errorf("retrying a sent message error = %v, want %v", err, ErrOutboxMessageSent)
+	}
+	db.First(message, message.ID)
+	if message.Status != OutboxSent {
+		t.Errorf("sent message status = %q, want %q", message.Status, OutboxSent)
+	}
+	if _, err := RetryOutboxMessage(message.ID + 1); !errors.Is(err, ErrOutboxMessageNotFound) {
+		t.Errorf("retrying an unknown message error = %v, want %v", err, ErrOutboxMessageNotFound)
+	}
+}
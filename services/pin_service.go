@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	helpers "olra-v1/utils"
+)
+
+var (
+	ErrPinNotSet         = errors.New("set a transaction PIN before sending money")
+	ErrIncorrectPin      = errors.New("incorrect transaction PIN")
+	ErrPinLocked         = errors.New("too many incorrect PIN attempts, try again later")
+	ErrIncorrectPasscode = errors.New("incorrect passcode")
+)
+
+// SetTransactionPin sets or changes the PIN. The passcode is asked for so a
+// borrowed unlocked phone cannot be used to take over payments.
+func SetTransactionPin(userID uint, passcode, pin string) error {
+	var user database.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return ErrAccountNotFound
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(passcode)); err != nil {
+		return ErrIncorrectPasscode
+	}
+	return database.DB.Model(&user).Updates(map[string]interface{}{
+		"transaction_pin_hash": helpers.HashPassword(pin),
+		"pin_attempts":         0,
+		"pin_locked_until":     nil,
+	}).Error
+}
+
+// VerifyTransactionPin checks the PIN, locking it for TRANSACTION_PIN_LOCK_MINUTES
+// after TRANSACTION_PIN_MAX_ATTEMPTS wrong tries in a row. Each try is counted
+// in the database before the PIN is compared, so a burst of parallel guesses
+// gets no more tries than one at a time would.
+func VerifyTransactionPin(user *database.User, pin string) error {
+	if user.TransactionPinHash == "" {
+		return ErrPinNotSet
+	}
+	now := time.Now()
+	maxAttempts := envInt("TRANSACTION_PIN_MAX_ATTEMPTS", 5)
+	var counted database.User
+	result := database.DB.Model(&counted).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "pin_attempts"}}}).
+		Where("user_id = ? AND pin_attempts < ? AND (pin_locked_until IS NULL OR pin_locked_until <= ?)",
+			user.UserID, maxAttempts, now).
+		Update("pin_attempts", gorm.Expr("pin_attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPinLocked
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.TransactionPinHash), []byte(pin)); err != nil {
+		if counted.PinAttempts >= maxAttempts {
+			lockedUntil := now.Add(time.Duration(envInt("TRANSACTION_PIN_LOCK_MINUTES", 30)) * time.Minute)
+			if err := database.DB.Model(&database.User{}).Where("user_id = ?", user.UserID).
+				Updates(map[string]interface{}{"pin_attempts": 0, "pin_locked_until": lockedUntil}).Error; err != nil {
+				return err
+			}
+		}
+		return ErrIncorrectPin
+	}
+	return database.DB.Model(&database.User{}).Where("user_id = ?", user.UserID).
+		Updates(map[string]interface{}{"pin_attempts": 0, "pin_locked_until": nil}).Error
+}
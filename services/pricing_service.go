@@ -0,0 +1,275 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	helpers "olra-v1/utils"
+)
+
+const (
+	PricedOlraTransfer = "olraTransfer-out"
+	PricedGroupPayment = "group-payment"
+	PricedBankTransfer = "bankTransfer-out"
+	FeeTypeFlat        = "flat"
+	FeeTypePercentage  = "percentage"
+	ChannelApp         = "app"
+	ChannelWeb         = "web"
+	ChannelUSSD        = "ussd"
+)
+
+var (
+	ErrPricingRuleNotFound = errors.New("pricing rule not found")
+	ErrInvalidPricingRule  = errors.New("invalid pricing rule")
+)
+
+// PricedTransactionTypes are the transaction types a rule can be written for
+var PricedTransactionTypes = []string{PricedOlraTransfer, PricedGroupPayment, PricedBankTransfer}
+
+// FeeQuote is what a transaction will cost before it is sent
+type FeeQuote struct {
+	TransactionType string  `json:"transactionType"`
+	Channel         string  `json:"channel"`
+	Amount          float64 `json:"amount"`
+	Fee             float64 `json:"fee"`
+	Total           float64 `json:"total"`
+	RuleID          *uint   `json:"ruleId,omitempty"`
+	Rule            string  `json:"rule,omitempty"`
+	// FreeRemaining is how many more transactions this month the rule waives,
+	// counting this one
+	FreeRemaining int `json:"freeRemaining"`
+}
+
+// KycTier is the tier pricing and limits are set by: 1 on sign up, 2 once the
+// BVN is verified and 3 after full KYC
+func KycTier(user *database.User) int {
+	switch {
+	case user.KycStatus:
+		return 3
+	case user.BvnVerified:
+		return 2
+	}
+	return 1
+}
+
+// matchPricingRule finds the rule for a transaction among those written for
+// its type. See pickPricingRule for which one wins.
+func matchPricingRule(tx *gorm.DB, transactionType string, tier int, channel string, amount float64, at time.Time) (*database.PricingRule, error) {
+	var rules []database.PricingRule
+	if err := tx.Where("transaction_type = ?", transactionType).
+		Where("effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)", at, at).
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return pickPricingRule(rules, tier, channel, amount, at), nil
+}
+
+// pickPricingRule returns the rule that applies, or nil if none does. The
+// highest priority wins; among equal priorities a rule for the user's tier or
+// channel beats a general one, and a newer rule beats an older one.
+func pickPricingRule(rules []database.PricingRule, tier int, channel string, amount float64, at time.Time) *database.PricingRule {
+	var best *database.PricingRule
+	for i := range rules {
+		rule := &rules[i]
+		if !pricingRuleApplies(rule, tier, channel, amount, at) {
+			continue
+		}
+		if best == nil || pricingRuleBeats(rule, best) {
+			best = rule
+		}
+	}
+	return best
+}
+
+func pricingRuleApplies(rule *database.PricingRule, tier int, channel string, amount float64, at time.Time) bool {
+	switch {
+	case rule.Tier != 0 && rule.Tier != tier,
+		rule.Channel != "" && rule.Channel != channel,
+		amount < rule.MinAmount,
+		rule.MaxAmount != 0 && amount > rule.MaxAmount,
+		rule.EffectiveFrom.After(at),
+		rule.EffectiveTo != nil && !rule.EffectiveTo.After(at):
+		return false
+	}
+	return true
+}
+
+func pricingRuleBeats(rule, other *database.PricingRule) bool {
+	switch {
+	case rule.Priority != other.Priority:
+		return rule.Priority > other.Priority
+	case rule.Tier != other.Tier:
+		return rule.Tier > other.Tier
+	case rule.Channel != other.Channel:
+		return rule.Channel > other.Channel
+	case !rule.EffectiveFrom.Equal(other.EffectiveFrom):
+		return rule.EffectiveFrom.After(other.EffectiveFrom)
+	}
+	return rule.ID > other.ID
+}
+
+// ruleFee is the charge a rule sets on an amount, rounded to the kobo
+func ruleFee(rule *database.PricingRule, amount float64) float64 {
+	fee := rule.FlatFee
+	if rule.FeeType == FeeTypePercentage {
+		fee += amount * rule.Percentage / 100
+	}
+	if rule.Cap > 0 {
+		fee = math.Min(fee, rule.Cap)
+	}
+	return helpers.FromKobo(helpers.ToKobo(fee))
+}
+
+// QuoteFee prices a transaction for the user. Pass the transaction the
+// payment runs in so the free allowance cannot be used twice by concurrent
+// payments; quotes shown before confirming can use database.DB.
+func QuoteFee(tx *gorm.DB, user *database.User, transactionType, channel string, amount float64) (*FeeQuote, error) {
+	if channel == "" {
+		channel = ChannelApp
+	}
+	quote := &FeeQuote{
+		TransactionType: transactionType,
+		Channel:         channel,
+		Amount:          amount,
+		Total:           amount,
+	}
+	now := time.Now()
+	rule, err := matchPricingRule(tx, transactionType, KycTier(user), channel, amount, now)
+	if err != nil || rule == nil {
+		return quote, err
+	}
+	quote.RuleID = &rule.ID
+	quote.Rule = rule.Name
+	if rule.FreePerMonth > 0 {
+		var used int64
+		if err := tx.Model(&database.Transaction{}).
+			Where("user_id = ? AND transaction_type = ? AND status <> ? AND transaction_date >= ?",
+				user.UserID, transactionType, "reversed", pricingMonthStart(now)).
+			Count(&used).Error; err != nil {
+			return nil, err
+		}
+		if quote.FreeRemaining = freeRemaining(rule, used); quote.FreeRemaining > 0 {
+			return quote, nil
+		}
+	}
+	quote.Fee = ruleFee(rule, amount)
+	quote.Total = helpers.FromKobo(helpers.ToKobo(amount) + helpers.ToKobo(quote.Fee))
+	return quote, nil
+}
+
+// pricingMonthStart is the start of the Lagos calendar month the free
+// allowance is counted over
+func pricingMonthStart(now time.Time) time.Time {
+	local := now.In(lagos)
+	return time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, lagos)
+}
+
+// freeRemaining is how many more transactions the rule waives this month,
+// counting the one being priced, once used have been made
+func freeRemaining(rule *database.PricingRule, used int64) int {
+	if used >= int64(rule.FreePerMonth) {
+		return 0
+	}
+	return rule.FreePerMonth - int(used)
+}
+
+// SeedPricingRules installs the bank transfer charges we have always passed on
+// when no bank transfer rule has been written yet
+func SeedPricingRules() error {
+	var count int64
+	if err := database.DB.Model(&database.PricingRule{}).
+		Where("transaction_type = ?", PricedBankTransfer).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, lagos)
+	return database.DB.Create(&[]database.PricingRule{
+		{Name: "Bank transfer up to 5,000", TransactionType: PricedBankTransfer,
+			MaxAmount: 5000, FeeType: FeeTypeFlat, FlatFee: 10, EffectiveFrom: start},
+		{Name: "Bank transfer up to 50,000", TransactionType: PricedBankTransfer,
+			MinAmount: 5000.01, MaxAmount: 50000, FeeType: FeeTypeFlat, FlatFee: 25, EffectiveFrom: start},
+		{Name: "Bank transfer above 50,000", TransactionType: PricedBankTransfer,
+			MinAmount: 50000.01, FeeType: FeeTypeFlat, FlatFee: 50, EffectiveFrom: start},
+	}).Error
+}
+
+func validatePricingRule(rule *database.PricingRule) error {
+	known := false
+	for _, transactionType := range PricedTransactionTypes {
+		known = known || rule.TransactionType == transactionType
+	}
+	switch {
+	case !known,
+		rule.FeeType != FeeTypeFlat && rule.FeeType != FeeTypePercentage,
+		rule.MaxAmount != 0 && rule.MaxAmount < rule.MinAmount,
+		rule.EffectiveTo != nil && !rule.EffectiveTo.After(rule.EffectiveFrom):
+		return ErrInvalidPricingRule
+	}
+	return nil
+}
+
+// ListPricingRules returns the rules for a transaction type, or all of them,
+// including ones that have ended or not yet started
+func ListPricingRules(transactionType string) ([]database.PricingRule, error) {
+	var rules []database.PricingRule
+	db := database.DB.Order("transaction_type, priority desc, min_amount, effective_from desc")
+	if transactionType != "" {
+		db = db.Where("transaction_type = ?", transactionType)
+	}
+	err := db.Find(&rules).Error
+	return rules, err
+}
+
+func CreatePricingRule(rule *database.PricingRule) error {
+	if rule.EffectiveFrom.IsZero() {
+		rule.EffectiveFrom = time.Now()
+	}
+	if err := validatePricingRule(rule); err != nil {
+		return err
+	}
+	return database.DB.Create(rule).Error
+}
+
+// UpdatePricingRule replaces a rule's terms. Fees already charged are kept on
+// their transactions, so past receipts do not change.
+func UpdatePricingRule(id uint, changes *database.PricingRule) (*database.PricingRule, error) {
+	var rule database.PricingRule
+	if err := database.DB.First(&rule, id).Error; err != nil {
+		return nil, ErrPricingRuleNotFound
+	}
+	changes.ID = rule.ID
+	changes.CreatedAt = rule.CreatedAt
+	if changes.EffectiveFrom.IsZero() {
+		changes.EffectiveFrom = rule.EffectiveFrom
+	}
+	if err := validatePricingRule(changes); err != nil {
+		return nil, err
+	}
+	if err := database.DB.Save(changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// EndPricingRule stops a rule applying from now. Rules are never deleted so
+// the pricing in force at any time can be looked up later.
+func EndPricingRule(id uint) (*database.PricingRule, error) {
+	var rule database.PricingRule
+	if err := database.DB.First(&rule, id).Error; err != nil {
+		return nil, ErrPricingRuleNotFound
+	}
+	now := time.Now()
+	if rule.EffectiveTo != nil && !rule.EffectiveTo.After(now) {
+		return &rule, nil
+	}
+	if err := database.DB.Model(&rule).Update("effective_to", now).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"olra-v1/internal/database"
+)
+
+func TestPickPricingRule(t *testing.T) {
+	at := time.Date(2024, 6, 15, 12, 0, 0, 0, lagos)
+	earlier := at.Add(-48 * time.Hour)
+	later := at.Add(48 * time.Hour)
+	ended := at.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		rules   []database.PricingRule
+		tier    int
+		channel string
+		amount  float64
+		want    uint // 0 for no rule
+	}{
+		{
+			name:   "no rules",
+			tier:   1,
+			amount: 1000,
+		},
+		{
+			name: "general rule",
+			rules: []database.PricingRule{
+				{ID: 1, EffectiveFrom: earlier},
+			},
+			tier:    1,
+			channel: ChannelApp,
+			amount:  1000,
+			want:    1,
+		},
+		{
+			name: "higher priority beats a more specific rule",
+			rules: []database.PricingRule{
+				{ID: 1, Priority: 1, EffectiveFrom: earlier},
+				{ID: 2, Tier: 1, Channel: ChannelApp, EffectiveFrom: earlier},
+			},
+			tier:    1,
+			channel: ChannelApp,
+			amount:  1000,
+			want:    1,
+		},
+		{
+			name: "tier rule beats a general one",
+			rules: []database.PricingRule{
+				{ID: 1, EffectiveFrom: earlier},
+				{ID: 2, Tier: 2, EffectiveFrom: earlier},
+			},
+			tier:    2,
+			channel: ChannelApp,
+			amount:  1000,
+			want:    2,
+		},
+		{
+			name: "tier rule beats a channel rule",
+			rules: []database.PricingRule{
+				{ID: 1, Channel: ChannelUSSD, EffectiveFrom: earlier},
+				{ID: 2, Tier: 2, EffectiveFrom: earlier},
+			},
+			tier:    2,
+			channel: ChannelUSSD,
+			amount:  1000,
+			want:    2,
+		},
+		{
+			name: "channel rule beats a general one",
+			rules: []database.PricingRule{
+				{ID: 1, EffectiveFrom: earlier},
+				{ID: 2, Channel: ChannelUSSD, EffectiveFrom: earlier},
+			},
+			tier:    1,
+			channel: ChannelUSSD,
+			amount:  1000,
+			want:    2,
+		},
+		{
+			name: "rule for another tier or channel is skipped",
+			rules: []database.PricingRule{
+				{ID: 1, EffectiveFrom: earlier},
+				{ID: 2, Tier: 3, EffectiveFrom: earlier},
+				{ID: 3, Channel: ChannelWeb, EffectiveFrom: earlier},
+			},
+			tier:    1,
+			channel: ChannelApp,
+			amount:  1000,
+			want:    1,
+		},
+		{
+			name: "newer rule beats an older one",
+			rules: []database.PricingRule{
+				{ID: 1, EffectiveFrom: earlier.Add(-time.Hour)},
+				{ID: 2, EffectiveFrom: earlier},
+			},
+			tier:    1,
+			channel: ChannelApp,
+			amount:  1000,
+			want:    2,
+		},
+		{
+			name: "higher id breaks a tie",
+			rules: []database.PricingRule{
+				{ID: 2, EffectiveFrom: earlier},
+				{ID: 1, EffectiveFrom: earlier},
+			},
+			tier:    1,
+			channel: ChannelApp,
+			amount:  1000,
+			want:    2,
+		},
+		{
+			name: "amount band",
+			rules: []database.PricingRule{
+				{ID: 1, MaxAmount: 5000, EffectiveFrom: earlier},
+				{ID: 2, MinAmount: 5000.01, MaxAmount: 50000, EffectiveFrom: earlier},
+				{ID: 3, MinAmount: 50000.01, EffectiveFrom: earlier},
+			},
+			tier:    1,
+			channel: ChannelApp,
+			amount:  20000,
+			want:    2,
+		},
+		{
+			name: "amount on the upper bound",
+			rules: []database.PricingRule{
+				{ID: 1, MaxAmount: 5000, EffectiveFrom: earlier},
+				{ID: 2, MinAmount: 5000.01, EffectiveFrom: earlier},
+			},
+			tier:    1,
+			channel: ChannelApp,
+			amount:  5000,
+			want:    1,
+		},
+		{
+			name: "amount outside every band",
+			rules: []database.PricingRule{
+				{ID: 1, MinAmount: 100, MaxAmount: 5000, EffectiveFrom: earlier},
+			},
+			tier:    1,
+			channel: ChannelApp,
+			amount:  50,
+		},
+		{
+			name: "rule not yet effective",
+			rules: []database.PricingRule{
+				{ID: 1, EffectiveFrom: earlier},
+				{ID: 2, Priority: 5, EffectiveFrom: later},
+			},
+			tier:    1,
+			channel: ChannelApp,
+			amount:  1000,
+			want:    1,
+		},
+		{
+			name: "ended rule",
+			rules: []database.PricingRule{
+				{ID: 1, EffectiveFrom: earlier},
+				{ID: 2, Priority: 5, EffectiveFrom: earlier, EffectiveTo: &ended},
+			},
+			tier:    1,
+			channel: ChannelApp,
+			amount:  1000,
+			want:    1,
+		},
+		{
+			name: "rule ending now no longer applies",
+			rules: []database.PricingRule{
+				{ID: 1, EffectiveFrom: earlier, EffectiveTo: &at},
+			},
+			tier:    1,
+			channel: ChannelApp,
+			amount:  1000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := pickPricingRule(tt.rules, tt.tier, tt.channel, tt.amount, at)
+			var got uint
+			if rule != nil {
+				got = rule.ID
+			}
+			if got != tt.want {
+				t.Errorf("pickPricingRule() = rule %d, want rule %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFreeRemaining(t *testing.T) {
+	tests := []struct {
+		name         string
+		freePerMonth int
+		used         int64
+		want         int
+	}{
+		{"no allowance", 0, 0, 0},
+		{"none used", 3, 0, 3},
+		{"some used", 3, 1, 2},
+		{"last free one", 3, 2, 1},
+		{"allowance used up", 3, 3, 0},
+		{"more made than allowed", 3, 7, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &database.PricingRule{FreePerMonth: tt.freePerMonth}
+			if got := freeRemaining(rule, tt.used); got != tt.want {
+				t.Errorf("freeRemaining(%d, %d) = %d, want %d", tt.freePerMonth, tt.used, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPricingMonthStart(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "mid month",
+			now:  time.Date(2024, 6, 15, 12, 0, 0, 0, lagos),
+			want: time.Date(2024, 6, 1, 0, 0, 0, 0, lagos),
+		},
+		{
+			name: "first instant of the month",
+			now:  time.Date(2024, 6, 1, 0, 0, 0, 0, lagos),
+			want: time.Date(2024, 6, 1, 0, 0, 0, 0, lagos),
+		},
+		{
+			// 23:30 UTC on 31 May is already June in Lagos
+			name: "month turns over in Lagos before UTC",
+			now:  time.Date(2024, 5, 31, 23, 30, 0, 0, time.UTC),
+			want: time.Date(2024, 6, 1, 0, 0, 0, 0, lagos),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pricingMonthStart(tt.now); !got.Equal(tt.want) {
+				t.Errorf("pricingMonthStart(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleFee(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   database.PricingRule
+		amount float64
+		want   float64
+	}{
+		{"flat", database.PricingRule{FeeType: FeeTypeFlat, FlatFee: 10}, 5000, 10},
+		{"percentage", database.PricingRule{FeeType: FeeTypePercentage, Percentage: 1.5}, 1000, 15},
+		{"percentage plus flat", database.PricingRule{FeeType: FeeTypePercentage, FlatFee: 5, Percentage: 1}, 1000, 15},
+		{"capped", database.PricingRule{FeeType: FeeTypePercentage, Percentage: 1.5, Cap: 2000}, 1000000, 2000},
+		{"rounded to the kobo", database.PricingRule{FeeType: FeeTypePercentage, Percentage: 1.5}, 333.33, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ruleFee(&tt.rule, tt.amount); got != tt.want {
+				t.Errorf("ruleFee() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// APNsProvider sends to Apple's HTTP/2 provider API using token based
+// authentication with the .p8 key in APNS_KEY_FILE
+type APNsProvider struct {
+	KeyID  string
+	TeamID string
+	Topic  string // the app's bundle id
+	Host   string
+	Client *http.Client
+
+	key      *ecdsa.PrivateKey
+	mu       sync.Mutex
+	jwt      string
+	issuedAt time.Time
+}
+
+var ErrAPNsNotConfigured = errors.New("apns: signing key not configured")
+
+// Apple rejects provider tokens older than an hour and throttles ones
+// refreshed more often than every twenty minutes
+const apnsTokenLifetime = 50 * time.Minute
+
+func NewAPNsProvider() *APNsProvider {
+	host := "https://api.push.apple.com"
+	if os.Getenv("APNS_ENV") == "sandbox" {
+		host = "https://api.sandbox.push.apple.com"
+	}
+	provider := &APNsProvider{
+		KeyID:  os.Getenv("APNS_KEY_ID"),
+		TeamID: os.Getenv("APNS_TEAM_ID"),
+		Topic:  os.Getenv("APNS_TOPIC"),
+		Host:   host,
+		Client: &http.Client{
+			Timeout:   15 * time.Second,
+			Transport: &http.Transport{ForceAttemptHTTP2: true},
+		},
+	}
+	path := os.Getenv("APNS_KEY_FILE")
+	if path == "" {
+		return provider
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		log.Println("Error reading APNs key:", err)
+		return provider
+	}
+	key, err := parseAPNsKey(raw)
+	if err != nil {
+		log.Println("Error parsing APNs key:", err)
+		return provider
+	}
+	provider.key = key
+	return provider
+}
+
+// parseAPNsKey reads the PKCS#8 encoded key Apple issues as a .p8 file
+func parseAPNsKey(raw []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, jwt.ErrKeyMustBePEMEncoded
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, jwt.ErrNotECPrivateKey
+	}
+	return key, nil
+}
+
+func (a *APNsProvider) Name() string {
+	return "apns"
+}
+
+func (a *APNsProvider) Send(token string, message PushMessage) (*PushResult, error) {
+	providerToken, err := a.providerToken()
+	if err != nil {
+		return nil, err
+	}
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{
+				"title": message.Title,
+				"body":  message.Body,
+			},
+			"sound": "default",
+		},
+	}
+	for key, value := range message.Data {
+		payload[key] = value
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/3/device/%s", a.Host, token), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", a.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+
+	res, err := a.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		return &PushResult{Provider: a.Name(), MessageID: res.Header.Get("apns-id")}, nil
+	}
+
+	raw, _ := io.ReadAll(res.Body)
+	var response struct {
+		Reason string `json:"reason"`
+	}
+	json.Unmarshal(raw, &response)
+	switch response.Reason {
+	case "Unregistered", "BadDeviceToken", "DeviceTokenNotForTopic":
+		return nil, ErrInvalidPushToken
+	}
+	return nil, &ProviderError{Provider: a.Name(), StatusCode: res.StatusCode, Body: string(raw)}
+}
+
+func (a *APNsProvider) providerToken() (string, error) {
+	if a.key == nil || a.KeyID == "" || a.TeamID == "" {
+		return "", ErrAPNsNotConfigured
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.jwt != "" && time.Since(a.issuedAt) < apnsTokenLifetime {
+		return a.jwt, nil
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.TeamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = a.KeyID
+	signed, err := token.SignedString(a.key)
+	if err != nil {
+		return "", err
+	}
+	a.jwt = signed
+	a.issuedAt = now
+	return a.jwt, nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FakePushProvider keeps every push in memory instead of sending it. Tokens
+// added with Invalidate are rejected the way FCM and APNs reject stale tokens.
+type FakePushProvider struct {
+	mu      sync.Mutex
+	pushes  []FakePush
+	invalid map[string]bool
+	Fail    error // returned from every call when set
+}
+
+type FakePush struct {
+	Token   string
+	Message PushMessage
+	SentAt  time.Time
+}
+
+func NewFakePushProvider() *FakePushProvider {
+	return &FakePushProvider{invalid: map[string]bool{}}
+}
+
+func (f *FakePushProvider) Name() string {
+	return "fake"
+}
+
+func (f *FakePushProvider) Send(token string, message PushMessage) (*PushResult, error) {
+	if f.Fail != nil {
+		return nil, f.Fail
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.invalid[token] {
+		return nil, ErrInvalidPushToken
+	}
+	f.pushes = append(f.pushes, FakePush{Token: token, Message: message, SentAt: time.Now()})
+	return &PushResult{Provider: f.Name(), MessageID: fmt.Sprintf("fake-push-%d", len(f.pushes))}, nil
+}
+
+// Invalidate makes later sends to the token fail with ErrInvalidPushToken
+func (f *FakePushProvider) Invalidate(token string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.invalid[token] = true
+}
+
+// Pushes returns a copy of everything sent so far
+func (f *FakePushProvider) Pushes() []FakePush {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakePush(nil), f.pushes...)
+}
+
+func (f *FakePushProvider) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pushes = nil
+	f.invalid = map[string]bool{}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMProvider sends through the Firebase Cloud Messaging HTTP v1 API,
+// authenticating with a service account from FCM_CREDENTIALS_FILE
+type FCMProvider struct {
+	ProjectID   string
+	ClientEmail string
+	TokenURI    string
+	Client      *http.Client
+
+	key         *rsa.PrivateKey
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+var ErrFCMNotConfigured = errors.New("fcm: service account not configured")
+
+func NewFCMProvider() *FCMProvider {
+	provider := &FCMProvider{
+		ProjectID: os.Getenv("FCM_PROJECT_ID"),
+		TokenURI:  "https://oauth2.googleapis.com/token",
+		Client:    &http.Client{Timeout: 15 * time.Second},
+	}
+	path := os.Getenv("FCM_CREDENTIALS_FILE")
+	if path == "" {
+		return provider
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		log.Println("Error reading FCM credentials:", err)
+		return provider
+	}
+	var credentials struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(raw, &credentials); err != nil {
+		log.Println("Error parsing FCM credentials:", err)
+		return provider
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(credentials.PrivateKey))
+	if err != nil {
+		log.Println("Error parsing FCM private key:", err)
+		return provider
+	}
+	if provider.ProjectID == "" {
+		provider.ProjectID = credentials.ProjectID
+	}
+	if credentials.TokenURI != "" {
+		provider.TokenURI = credentials.TokenURI
+	}
+	provider.ClientEmail = credentials.ClientEmail
+	provider.key = key
+	return provider
+}
+
+func (f *FCMProvider) Name() string {
+	return "fcm"
+}
+
+func (f *FCMProvider) Send(token string, message PushMessage) (*PushResult, error) {
+	accessToken, err := f.token()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token": token,
+			"notification": map[string]string{
+				"title": message.Title,
+				"body":  message.Body,
+			},
+			"data": message.Data,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", f.ProjectID)
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	res, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		// UNREGISTERED means the app was uninstalled or the token rotated
+		if res.StatusCode == http.StatusNotFound || strings.Contains(string(body), "UNREGISTERED") {
+			return nil, ErrInvalidPushToken
+		}
+		return nil, &ProviderError{Provider: f.Name(), StatusCode: res.StatusCode, Body: string(body)}
+	}
+	var response struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	return &PushResult{Provider: f.Name(), MessageID: response.Name}, nil
+}
+
+// token exchanges a signed service account assertion for an OAuth access
+// token, reusing it until shortly before it expires
+func (f *FCMProvider) token() (string, error) {
+	if f.key == nil || f.ProjectID == "" {
+		return "", ErrFCMNotConfigured
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.accessToken != "" && time.Now().Before(f.expiresAt) {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(f.key)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	res, err := f.Client.PostForm(f.TokenURI, form)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", &ProviderError{Provider: f.Name(), StatusCode: res.StatusCode, Body: string(body)}
+	}
+	var response struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", err
+	}
+	f.accessToken = response.AccessToken
+	f.expiresAt = now.Add(time.Duration(response.ExpiresIn)*time.Second - time.Minute)
+	return f.accessToken, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+)
+
+// PushProvider delivers a notification to a single device push token
+type PushProvider interface {
+	Name() string
+	Send(token string, message PushMessage) (*PushResult, error)
+}
+
+type PushMessage struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+type PushResult struct {
+	Provider  string `json:"provider"`
+	MessageID string `json:"message_id"`
+}
+
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+)
+
+var (
+	// ErrInvalidPushToken is returned when the provider says a token will never
+	// be deliverable again; the token is removed from the registry
+	ErrInvalidPushToken = errors.New("push token is no longer valid")
+	ErrNoPushProvider   = errors.New("no push provider configured")
+)
+
+// pushProviders is read on every send and may be replaced at any time, so
+// both go through pushProvidersMu
+var (
+	pushProviders   map[string]PushProvider
+	pushProvidersMu sync.RWMutex
+)
+
+// Push returns the provider for a platform. Android tokens go to FCM and iOS
+// tokens to APNs unless PUSH_PROVIDER=fake routes every platform to the fake.
+func Push(platform string) (PushProvider, error) {
+	pushProvidersMu.RLock()
+	provider, ok := pushProviders[platform]
+	loaded := pushProviders != nil
+	pushProvidersMu.RUnlock()
+	if !loaded {
+		pushProvidersMu.Lock()
+		if pushProviders == nil {
+			pushProviders = newPushProvidersFromEnv()
+		}
+		provider, ok = pushProviders[platform]
+		pushProvidersMu.Unlock()
+	}
+	if !ok {
+		return nil, ErrNoPushProvider
+	}
+	return provider, nil
+}
+
+// SetPushProvider replaces the provider for a platform, mainly so tests can
+// install a fake. The other platforms keep their configured providers.
+func SetPushProvider(platform string, provider PushProvider) {
+	pushProvidersMu.Lock()
+	defer pushProvidersMu.Unlock()
+	if pushProviders == nil {
+		pushProviders = newPushProvidersFromEnv()
+	}
+	pushProviders[platform] = provider
+}
+
+func newPushProvidersFromEnv() map[string]PushProvider {
+	if os.Getenv("PUSH_PROVIDER") == "fake" {
+		fake := NewFakePushProvider()
+		return map[string]PushProvider{PlatformAndroid: fake, PlatformIOS: fake}
+	}
+	return map[string]PushProvider{
+		PlatformAndroid: NewFCMProvider(),
+		PlatformIOS:     NewAPNsProvider(),
+	}
+}
+
+// RegisterPushToken records the push token for a signed-in device. A device
+// holds one token at a time and a token belongs to one device.
+func RegisterPushToken(userID uint, deviceID, platform, token string) (*database.PushToken, error) {
+	pushToken := database.PushToken{
+		UserID:   userID,
+		DeviceID: deviceID,
+		Platform: platform,
+		Token:    token,
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token = ? OR device_id = ?", token, deviceID).
+			Delete(&database.PushToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&pushToken).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pushToken, nil
+}
+
+func UnregisterPushTokens(deviceID string) error {
+	return database.DB.Where("device_id = ?", deviceID).Delete(&database.PushToken{}).Error
+}
+
+// SendPushToUser delivers the message to every device the user has registered,
+// pruning tokens the provider rejects as invalid. It only fails when no device
+// could be reached, so a retry does not repeat a push that already arrived.
+func SendPushToUser(userID uint, message PushMessage) error {
+	var tokens []database.PushToken
+	if err := database.DB.Where("user_id = ?", userID).Find(&tokens).Error; err != nil {
+		return err
+	}
+	var (
+		errs      []error
+		delivered bool
+	)
+	for _, token := range tokens {
+		provider, err := Push(token.Platform)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		_, err = provider.Send(token.Token, message)
+		switch {
+		case err == nil:
+			delivered = true
+		case errors.Is(err, ErrInvalidPushToken):
+			log.Printf("Pruning invalid %s push token for user %d", provider.Name(), userID)
+			database.DB.Delete(&token)
+		default:
+			errs = append(errs, err)
+		}
+	}
+	if delivered || len(errs) == 0 {
+		return nil
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+// usePushProviders starts the test with the providers PUSH_PROVIDER
+// configures and puts the previous ones back afterwards
+func usePushProviders(t *testing.T, pushProvider string) {
+	t.Helper()
+	t.Setenv("PUSH_PROVIDER", pushProvider)
+	pushProvidersMu.Lock()
+	previous := pushProviders
+	pushProviders = nil
+	pushProvidersMu.Unlock()
+	t.Cleanup(func() {
+		pushProvidersMu.Lock()
+		pushProviders = previous
+		pushProvidersMu.Unlock()
+	})
+}
+
+func TestSetPushProviderKeepsOtherPlatforms(t *testing.T) {
+	usePushProviders(t, "")
+	fake := NewFakePushProvider()
+	SetPushProvider(PlatformAndroid, fake)
+
+	android, err := Push(PlatformAndroid)
+	if err != nil || android != fake {
+		t.Errorf("Push(android) = %v, %v, want the fake", android, err)
+	}
+	ios, err := Push(PlatformIOS)
+	if err != nil || ios.Name() != "apns" {
+		t.Errorf("Push(ios) = %v, %v, want apns", ios, err)
+	}
+	if _, err := Push("windows"); !errors.Is(err, ErrNoPushProvider) {
+		t.Errorf("Push(windows) error = %v, want %v", err, ErrNoPushProvider)
+	}
+}
+
+func TestSendPushToUser(t *testing.T) {
+	db := dbtest.Open(t, &database.PushToken{})
+	usePushProviders(t, "fake")
+	provider, _ := Push(PlatformAndroid)
+	fake := provider.(*FakePushProvider)
+
+	if _, err := RegisterPushToken(1, "phone", PlatformAndroid, "token-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RegisterPushToken(1, "tablet", PlatformIOS, "token-2"); err != nil {
+		t.Fatal(err)
+	}
+	// The same token registered from another device moves there
+	if _, err := RegisterPushToken(1, "old-phone", PlatformAndroid, "stale"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RegisterPushToken(1, "new-phone", PlatformAndroid, "stale"); err != nil {
+		t.Fatal(err)
+	}
+	fake.Invalidate("stale")
+
+	if err := SendPushToUser(1, PushMessage{Title: "Credit alert", Body: "You have been paid"}); err != nil {
+		t.Fatalf("SendPushToUser() error = %v", err)
+	}
+	if pushes := fake.Pushes(); len(pushes) != 2 {
+		t.Errorf("%d pushes sent, want 2: %+v", len(pushes), pushes)
+	}
+	var tokens []string
+	db.Model(&database.PushToken{}).Order("token").Pluck("token", &tokens)
+	if len(tokens) != 2 || tokens[0] != "token-1" || tokens[1] != "token-2" {
+		t.Errorf("tokens left = %v, want the stale one pruned", tokens)
+	}
+}
@@ -0,0 +1,522 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	helpers "olra-v1/utils"
+)
+
+const (
+	StatementCredit = "credit"
+	StatementDebit  = "debit"
+
+	StatementSourceCSV  = "csv"
+	StatementSourceJSON = "json"
+	StatementSourceAPI  = "api"
+
+	ReconciliationMatched        = "matched"
+	ReconciliationMissingOurs    = "missing-ours"
+	ReconciliationMissingTheirs  = "missing-theirs"
+	ReconciliationAmountMismatch = "amount-mismatch"
+
+	ReconciliationBalanced = "balanced"
+	ReconciliationOpen     = "open"
+	ReconciliationReviewed = "reviewed"
+)
+
+var (
+	ErrInvalidStatement           = errors.New("invalid statement file")
+	ErrStatementFormat            = errors.New("statement format must be csv or json")
+	ErrReconciliationNotFound     = errors.New("reconciliation report not found")
+	ErrReconciliationItemNotFound = errors.New("reconciliation item not found")
+	ErrItemAlreadyMatched         = errors.New("matched items need no review")
+	ErrUnresolvedItems            = errors.New("every exception must be resolved before the report is reviewed")
+	ErrReconciliationReviewed     = errors.New("reconciliation report has already been reviewed")
+)
+
+// statementDateLayouts are the date formats seen in provider statement files
+var statementDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02/01/2006 15:04:05",
+	"02/01/2006",
+}
+
+// Column names providers use in CSV statements, by the field they fill
+var statementColumns = map[string][]string{
+	"reference": {"reference", "session_id", "sessionid", "transaction_reference", "transactionreference"},
+	"amount":    {"amount"},
+	"direction": {"direction", "type", "trantype", "dr_cr"},
+	"credit":    {"credit", "cr"},
+	"debit":     {"debit", "dr"},
+	"date":      {"date", "transaction_date", "transactiondate", "value_date"},
+	"narration": {"narration", "description", "remarks"},
+}
+
+// ParseStatement reads a provider statement file. CSV files need a header
+// row with a reference and either an amount, signed or with a direction
+// column, or separate credit and debit columns. JSON files are an array of
+// lines, or an object holding them under entries.
+func ParseStatement(format string, r io.Reader) ([]StatementLine, error) {
+	switch format {
+	case StatementSourceCSV:
+		return parseCSVStatement(r)
+	case StatementSourceJSON:
+		return parseJSONStatement(r)
+	}
+	return nil, ErrStatementFormat
+}
+
+func parseCSVStatement(r io.Reader) ([]StatementLine, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		for field, aliases := range statementColumns {
+			for _, alias := range aliases {
+				if _, seen := columns[field]; !seen && name == alias {
+					columns[field] = i
+				}
+			}
+		}
+	}
+	_, hasAmount := columns["amount"]
+	_, hasCredit := columns["credit"]
+	_, hasDebit := columns["debit"]
+	if _, ok := columns["reference"]; !ok || !(hasAmount || hasCredit && hasDebit) {
+		return nil, fmt.Errorf("%w: header needs a reference and an amount or credit and debit columns", ErrInvalidStatement)
+	}
+
+	var lines []StatementLine
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+		}
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		amount, direction := field("amount"), field("direction")
+		if !hasAmount {
+			amount, direction = field("credit"), StatementCredit
+			if strings.Trim(amount, "0.,") == "" {
+				amount, direction = field("debit"), StatementDebit
+			}
+		}
+		line, err := statementLine(field("reference"), amount, direction, field("date"), field("narration"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidStatement, row, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func parseJSONStatement(r io.Reader) ([]StatementLine, error) {
+	type jsonLine struct {
+		Reference string      `json:"reference"`
+		Amount    json.Number `json:"amount"`
+		Direction string      `json:"direction"`
+		Date      string      `json:"date"`
+		Narration string      `json:"narration"`
+	}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var entries []jsonLine
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		var wrapped struct {
+			Entries []jsonLine `json:"entries"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+		}
+		entries = wrapped.Entries
+	}
+	lines := make([]StatementLine, 0, len(entries))
+	for i, entry := range entries {
+		line, err := statementLine(entry.Reference, entry.Amount.String(), entry.Direction, entry.Date, entry.Narration)
+		if err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %v", ErrInvalidStatement, i+1, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// statementLine builds a line from text fields. Without a direction a
+// negative amount is a debit.
+func statementLine(reference, amount, direction, date, narration string) (StatementLine, error) {
+	line := StatementLine{Reference: strings.TrimSpace(reference), Narration: narration}
+	if line.Reference == "" {
+		return line, errors.New("missing reference")
+	}
+	cleaned := strings.NewReplacer(",", "", "₦", "", "NGN", "", " ", "").Replace(amount)
+	value, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return line, fmt.Errorf("invalid amount %q", amount)
+	}
+	switch strings.ToLower(strings.TrimSpace(direction)) {
+	case "credit", "c", "cr":
+		line.Direction = StatementCredit
+	case "debit", "d", "dr":
+		line.Direction = StatementDebit
+	case "":
+		line.Direction = StatementCredit
+		if value < 0 {
+			line.Direction = StatementDebit
+		}
+	default:
+		return line, fmt.Errorf("invalid direction %q", direction)
+	}
+	line.Amount = math.Abs(value)
+	if date != "" {
+		for _, layout := range statementDateLayouts {
+			if parsed, err := time.ParseInLocation(layout, date, lagos); err == nil {
+				line.Date = parsed
+				break
+			}
+		}
+		if line.Date.IsZero() {
+			return line, fmt.Errorf("invalid date %q", date)
+		}
+	}
+	return line, nil
+}
+
+// StartOfLagosDay is midnight, Lagos time, on the day t falls on there
+func StartOfLagosDay(t time.Time) time.Time {
+	local := t.In(lagos)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, lagos)
+}
+
+// ledgerMovement is an entry on the bank settlement account with the
+// references the bank could know it by. Amounts are in kobo.
+type ledgerMovement struct {
+	Keys        []string
+	Reference   string
+	Direction   string
+	Amount      int64
+	Description string
+}
+
+// settlementMovements lists what the ledger says passed through the bank on
+// the day. Inbound credits take the credit off the settlement account, and
+// the bank knows them by session id; transfers out put money on it, known
+// by our reference or the provider's.
+func settlementMovements(day time.Time) ([]ledgerMovement, error) {
+	var entries []database.LedgerEntry
+	if err := database.DB.Where("account = ? AND created_at >= ? AND created_at < ?",
+		SystemBankSettlementAccount, day, day.Add(24*time.Hour)).
+		Order("entry_id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	references := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		references = append(references, entry.Reference)
+	}
+	var credits []database.InboundCredit
+	if err := database.DB.Where("reference IN ?", references).Find(&credits).Error; err != nil {
+		return nil, err
+	}
+	sessions := map[string]string{}
+	for _, credit := range credits {
+		sessions[credit.Reference] = credit.SessionID
+	}
+	var transfers []database.BankTransfer
+	if err := database.DB.Where("reference IN ?", references).Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	providerReferences := map[string]string{}
+	for _, transfer := range transfers {
+		providerReferences[transfer.Reference] = transfer.ProviderReference
+	}
+
+	movements := make([]ledgerMovement, 0, len(entries))
+	for i, entry := range entries {
+		movement := ledgerMovement{
+			Keys:        []string{references[i]},
+			Reference:   entry.Reference,
+			Direction:   StatementDebit,
+			Amount:      entry.Amount,
+			Description: entry.Description,
+		}
+		if entry.Amount < 0 {
+			movement.Direction = StatementCredit
+			movement.Amount = -entry.Amount
+		}
+		if session, ok := sessions[references[i]]; ok {
+			movement.Keys = []string{session}
+		}
+		if providerReference := providerReferences[references[i]]; providerReference != "" {
+			movement.Keys = append(movement.Keys, providerReference)
+		}
+		movements = append(movements, movement)
+	}
+	return movements, nil
+}
+
+// Reconcile matches statement lines for the day to the ledger by reference,
+// then amount and direction, and saves the report. Each ledger entry matches
+// at most one line, so a line the bank repeats shows as missing on our side.
+func Reconcile(provider, source string, day time.Time, lines []StatementLine, staffID *uint) (*database.ReconciliationReport, error) {
+	day = StartOfLagosDay(day)
+	movements, err := settlementMovements(day)
+	if err != nil {
+		return nil, err
+	}
+	byKey := map[string][]int{}
+	for i, movement := range movements {
+		for _, key := range movement.Keys {
+			byKey[key] = append(byKey[key], i)
+		}
+	}
+	used := make([]bool, len(movements))
+
+	report := database.ReconciliationReport{
+		Provider:      provider,
+		StatementDate: day,
+		Source:        source,
+		CreatedBy:     staffID,
+	}
+	var statementNet, ledgerNet int64
+	for _, line := range lines {
+		amount := helpers.ToKobo(line.Amount)
+		statementNet += signed(line.Direction, amount)
+		item := database.ReconciliationItem{
+			Reference:       line.Reference,
+			Direction:       line.Direction,
+			StatementAmount: line.Amount,
+			Narration:       line.Narration,
+			Status:          ReconciliationMissingOurs,
+		}
+		for _, i := range byKey[line.Reference] {
+			if used[i] {
+				continue
+			}
+			used[i] = true
+			movement := movements[i]
+			item.LedgerReference = movement.Reference
+			item.LedgerAmount = helpers.FromKobo(movement.Amount)
+			item.Status = ReconciliationMatched
+			if movement.Amount != amount || movement.Direction != line.Direction {
+				item.Status = ReconciliationAmountMismatch
+			}
+			break
+		}
+		report.Items = append(report.Items, item)
+	}
+	for i, movement := range movements {
+		ledgerNet += signed(movement.Direction, movement.Amount)
+		if used[i] {
+			continue
+		}
+		report.Items = append(report.Items, database.ReconciliationItem{
+			Reference:       movement.Keys[0],
+			LedgerReference: movement.Reference,
+			Direction:       movement.Direction,
+			LedgerAmount:    helpers.FromKobo(movement.Amount),
+			Narration:       movement.Description,
+			Status:          ReconciliationMissingTheirs,
+		})
+	}
+
+	for _, item := range report.Items {
+		switch item.Status {
+		case ReconciliationMatched:
+			report.Matched++
+		case ReconciliationMissingOurs:
+			report.MissingOurs++
+		case ReconciliationMissingTheirs:
+			report.MissingTheirs++
+		case ReconciliationAmountMismatch:
+			report.AmountMismatch++
+		}
+	}
+	report.StatementNet = helpers.FromKobo(statementNet)
+	report.LedgerNet = helpers.FromKobo(ledgerNet)
+	report.Status = ReconciliationBalanced
+	if report.MissingOurs+report.MissingTheirs+report.AmountMismatch > 0 {
+		report.Status = ReconciliationOpen
+	}
+	if err := database.DB.Create(&report).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func signed(direction string, amount int64) int64 {
+	if direction == StatementDebit {
+		return -amount
+	}
+	return amount
+}
+
+// ReconcileFromProvider fetches the day's statement from the banking provider
+// and reconciles it
+func ReconcileFromProvider(day time.Time, staffID *uint) (*database.ReconciliationReport, error) {
+	day = StartOfLagosDay(day)
+	lines, err := Banking().Statement(day)
+	if err != nil {
+		return nil, err
+	}
+	return Reconcile(Banking().Name(), StatementSourceAPI, day, lines, staffID)
+}
+
+func ListReconciliationReports(page, limit int) ([]database.ReconciliationReport, error) {
+	var reports []database.ReconciliationReport
+	err := database.DB.Order("statement_date desc, created_at desc").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&reports).Error
+	return reports, err
+}
+
+// GetReconciliationReport returns a report with its items, only those with
+// status when it is given
+func GetReconciliationReport(id uint, status string) (*database.ReconciliationReport, error) {
+	var report database.ReconciliationReport
+	if err := database.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		if status != "" {
+			db = db.Where("status = ?", status)
+		}
+		return db.Order("id")
+	}).First(&report, id).Error; err != nil {
+		return nil, ErrReconciliationNotFound
+	}
+	return &report, nil
+}
+
+// ResolveReconciliationItem records what finance found behind an exception,
+// such as a journal posted to correct it or a transfer the bank settled late
+func ResolveReconciliationItem(reportID, itemID, staffID uint, note string) (*database.ReconciliationItem, error) {
+	var item database.ReconciliationItem
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var report database.ReconciliationReport
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, reportID).Error; err != nil {
+			return ErrReconciliationNotFound
+		}
+		if report.Status == ReconciliationReviewed {
+			return ErrReconciliationReviewed
+		}
+		if err := tx.Where("id = ? AND report_id = ?", itemID, reportID).First(&item).Error; err != nil {
+			return ErrReconciliationItemNotFound
+		}
+		if item.Status == ReconciliationMatched {
+			return ErrItemAlreadyMatched
+		}
+		now := time.Now()
+		item.Note = note
+		item.ResolvedBy = &staffID
+		item.ResolvedAt = &now
+		return tx.Save(&item).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ReviewReconciliationReport signs a report off once every exception on it
+// has been resolved
+func ReviewReconciliationReport(id, staffID uint) (*database.ReconciliationReport, error) {
+	var report database.ReconciliationReport
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, id).Error; err != nil {
+			return ErrReconciliationNotFound
+		}
+		if report.Status == ReconciliationReviewed {
+			return ErrReconciliationReviewed
+		}
+		var unresolved int64
+		if err := tx.Model(&database.ReconciliationItem{}).
+			Where("report_id = ? AND status <> ? AND resolved_at IS NULL", id, ReconciliationMatched).
+			Count(&unresolved).Error; err != nil {
+			return err
+		}
+		if unresolved > 0 {
+			return ErrUnresolvedItems
+		}
+		now := time.Now()
+		report.Status = ReconciliationReviewed
+		report.ReviewedBy = &staffID
+		report.ReviewedAt = &now
+		return tx.Save(&report).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ReconcileYesterday reconciles the previous Lagos day from the provider's
+// statement unless that has already been done. It waits until
+// RECONCILIATION_HOUR so the bank has closed the day.
+func ReconcileYesterday() error {
+	now := time.Now().In(lagos)
+	if now.Hour() < envInt("RECONCILIATION_HOUR", 6) {
+		return nil
+	}
+	yesterday := StartOfLagosDay(now).AddDate(0, 0, -1)
+	var done int64
+	if err := database.DB.Model(&database.ReconciliationReport{}).
+		Where("provider = ? AND source = ? AND statement_date = ?", Banking().Name(), StatementSourceAPI, yesterday).
+		Count(&done).Error; err != nil {
+		return err
+	}
+	if done > 0 {
+		return nil
+	}
+	report, err := ReconcileFromProvider(yesterday, nil)
+	if err != nil {
+		return err
+	}
+	if report.Status == ReconciliationOpen {
+		log.Printf("Reconciliation for %s found %d missing on our side, %d missing on theirs and %d mismatched",
+			yesterday.Format("2006-01-02"), report.MissingOurs, report.MissingTheirs, report.AmountMismatch)
+	}
+	return nil
+}
+
+// StartDailyReconciliation checks every RECONCILIATION_CHECK_SECONDS whether
+// yesterday still needs reconciling
+func StartDailyReconciliation() {
+	interval := time.Duration(envInt("RECONCILIATION_CHECK_SECONDS", 3600)) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := ReconcileYesterday(); err != nil {
+				log.Println("Error reconciling bank statement:", err)
+			}
+		}
+	}()
+}
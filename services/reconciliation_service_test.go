@@ -0,0 +1,271 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+func TestParseCSVStatement(t *testing.T) {
+	day := time.Date(2024, 6, 14, 0, 0, 0, 0, lagos)
+	tests := []struct {
+		name string
+		file string
+		want []StatementLine
+		err  error
+	}{
+		{
+			name: "signed amounts",
+			file: "reference,amount,date,narration\n" +
+				"REF1,5000.00,2024-06-14,Inbound\n" +
+				"REF2,-1200.50,2024-06-14,Outbound\n",
+			want: []StatementLine{
+				{Reference: "REF1", Amount: 5000, Direction: StatementCredit, Date: day, Narration: "Inbound"},
+				{Reference: "REF2", Amount: 1200.5, Direction: StatementDebit, Date: day, Narration: "Outbound"},
+			},
+		},
+		{
+			name: "direction column and provider aliases",
+			file: "Session_ID, Amount, TranType, Value_Date, Remarks\n" +
+				"S1, \"5,000.00\", CR, 14/06/2024, Salary\n" +
+				"S2, 250, D, 14/06/2024 09:30:00, Airtime\n",
+			want: []StatementLine{
+				{Reference: "S1", Amount: 5000, Direction: StatementCredit, Date: day, Narration: "Salary"},
+				{Reference: "S2", Amount: 250, Direction: StatementDebit, Date: day.Add(9*time.Hour + 30*time.Minute), Narration: "Airtime"},
+			},
+		},
+		{
+			name: "credit and debit columns",
+			file: "reference,credit,debit\n" +
+				"REF1,5000,0.00\n" +
+				"REF2,,750\n" +
+				"REF3,0,\"1,000.00\"\n",
+			want: []StatementLine{
+				{Reference: "REF1", Amount: 5000, Direction: StatementCredit},
+				{Reference: "REF2", Amount: 750, Direction: StatementDebit},
+				{Reference: "REF3", Amount: 1000, Direction: StatementDebit},
+			},
+		},
+		{
+			name: "byte order mark before the header",
+			file: "\xef\xbb\xbfreference,amount\nREF1,100\n",
+			want: []StatementLine{
+				{Reference: "REF1", Amount: 100, Direction: StatementCredit},
+			},
+		},
+		{
+			name: "currency prefixes and RFC 3339 date",
+			file: "reference,amount,date\nREF1,\"₦1,250.75\",2024-06-14T08:00:00Z\nREF2,NGN 300,2024-06-14T09:00:00+01:00\n",
+			want: []StatementLine{
+				{Reference: "REF1", Amount: 1250.75, Direction: StatementCredit, Date: time.Date(2024, 6, 14, 8, 0, 0, 0, time.UTC)},
+				{Reference: "REF2", Amount: 300, Direction: StatementCredit, Date: day.Add(9 * time.Hour)},
+			},
+		},
+		{
+			name: "short row",
+			file: "reference,amount,narration\nREF1,100\n",
+			want: []StatementLine{
+				{Reference: "REF1", Amount: 100, Direction: StatementCredit},
+			},
+		},
+		{
+			name: "header only",
+			file: "reference,amount\n",
+		},
+		{
+			name: "no reference column",
+			file: "id,amount\n1,100\n",
+			err:  ErrInvalidStatement,
+		},
+		{
+			name: "credit column without debit",
+			file: "reference,credit\nREF1,100\n",
+			err:  ErrInvalidStatement,
+		},
+		{
+			name: "empty file",
+			file: "",
+			err:  ErrInvalidStatement,
+		},
+		{
+			name: "missing reference",
+			file: "reference,amount\n,100\n",
+			err:  ErrInvalidStatement,
+		},
+		{
+			name: "bad amount",
+			file: "reference,amount\nREF1,abc\n",
+			err:  ErrInvalidStatement,
+		},
+		{
+			name: "bad direction",
+			file: "reference,amount,direction\nREF1,100,sideways\n",
+			err:  ErrInvalidStatement,
+		},
+		{
+			name: "bad date",
+			file: "reference,amount,date\nREF1,100,June 14\n",
+			err:  ErrInvalidStatement,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStatement(StatementSourceCSV, strings.NewReader(tt.file))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseStatement() error = %v, want %v", err, tt.err)
+			}
+			assertStatementLines(t, got, tt.want)
+		})
+	}
+}
+
+func TestParseJSONStatement(t *testing.T) {
+	day := time.Date(2024, 6, 14, 0, 0, 0, 0, lagos)
+	tests := []struct {
+		name string
+		file string
+		want []StatementLine
+		err  error
+	}{
+		{
+			name: "array of lines",
+			file: `[
+				{"reference": "REF1", "amount": 5000, "direction": "credit", "date": "2024-06-14", "narration": "Inbound"},
+				{"reference": "REF2", "amount": -1200.5, "date": "2024-06-14 00:00:00"}
+			]`,
+			want: []StatementLine{
+				{Reference: "REF1", Amount: 5000, Direction: StatementCredit, Date: day, Narration: "Inbound"},
+				{Reference: "REF2", Amount: 1200.5, Direction: StatementDebit, Date: day},
+			},
+		},
+		{
+			name: "lines under entries",
+			file: `{"account": "0123456789", "entries": [{"reference": "REF1", "amount": "250", "direction": "DR"}]}`,
+			want: []StatementLine{
+				{Reference: "REF1", Amount: 250, Direction: StatementDebit},
+			},
+		},
+		{
+			name: "no lines",
+			file: `[]`,
+		},
+		{
+			name: "not JSON",
+			file: `reference,amount`,
+			err:  ErrInvalidStatement,
+		},
+		{
+			name: "missing reference",
+			file: `[{"amount": 100}]`,
+			err:  ErrInvalidStatement,
+		},
+		{
+			name: "missing amount",
+			file: `[{"reference": "REF1"}]`,
+			err:  ErrInvalidStatement,
+		},
+		{
+			name: "bad date",
+			file: `[{"reference": "REF1", "amount": 100, "date": "yesterday"}]`,
+			err:  ErrInvalidStatement,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStatement(StatementSourceJSON, strings.NewReader(tt.file))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseStatement() error = %v, want %v", err, tt.err)
+			}
+			assertStatementLines(t, got, tt.want)
+		})
+	}
+}
+
+func TestParseStatementFormat(t *testing.T) {
+	if _, err := ParseStatement("xlsx", strings.NewReader("")); !errors.Is(err, ErrStatementFormat) {
+		t.Errorf("ParseStatement(xlsx) error = %v, want %v", err, ErrStatementFormat)
+	}
+}
+
+func assertStatementLines(t *testing.T, got, want []StatementLine) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		// Dates are compared as instants, whatever zone they were read in
+		if !got[i].Date.Equal(want[i].Date) {
+			t.Errorf("line %d date = %v, want %v", i+1, got[i].Date, want[i].Date)
+		}
+		got[i].Date, want[i].Date = time.Time{}, time.Time{}
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("line %d = %+v, want %+v", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	db := dbtest.Open(t, &database.LedgerEntry{}, &database.InboundCredit{}, &database.BankTransfer{},
+		&database.ReconciliationReport{}, &database.ReconciliationItem{})
+	post := func(reference string, settlement int64) {
+		t.Helper()
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return PostJournal(tx, reference, "Test", Leg{Account: SystemBankSettlementAccount, Amount: settlement},
+				Leg{Account: SystemSuspenseAccount, Amount: -settlement})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An inbound credit the bank knows by its session id, a transfer out it
+	// knows by the provider's reference and one it has not reported
+	db.Create(&database.InboundCredit{Provider: "test", SessionID: "S1", AccountNumber: "9000000001",
+		Amount: 500, Status: InboundCredited, Reference: "IN1"})
+	post("IN1", -50000)
+	db.Create(&database.BankTransfer{UserID: 1, Reference: "BT1", Provider: "test", ProviderReference: "P1",
+		AccountNumber: testAccountNumber, BankCode: "058", AccountName: "Ada Obi", Amount: 200, Status: BankTransferSuccessful})
+	post("BT1", 20000)
+	post("BT2", 30000)
+
+	lines := []StatementLine{
+		{Reference: "S1", Amount: 500, Direction: StatementCredit},
+		{Reference: "S1", Amount: 500, Direction: StatementCredit},
+		{Reference: "P1", Amount: 250, Direction: StatementDebit},
+		{Reference: "UNKNOWN", Amount: 10, Direction: StatementCredit},
+	}
+	report, err := Reconcile("test", StatementSourceCSV, time.Now(), lines, nil)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	statuses := map[string][]string{}
+	for _, item := range report.Items {
+		statuses[item.Reference] = append(statuses[item.Reference], item.Status)
+	}
+	want := map[string][]string{
+		"S1":      {ReconciliationMatched, ReconciliationMissingOurs},
+		"P1":      {ReconciliationAmountMismatch},
+		"UNKNOWN": {ReconciliationMissingOurs},
+		"BT2":     {ReconciliationMissingTheirs},
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("items = %v, want %v", statuses, want)
+	}
+	if report.Matched != 1 || report.MissingOurs != 2 || report.MissingTheirs != 1 || report.AmountMismatch != 1 {
+		t.Errorf("counts = %d matched, %d missing ours, %d missing theirs, %d mismatched",
+			report.Matched, report.MissingOurs, report.MissingTheirs, report.AmountMismatch)
+	}
+	if report.Status != ReconciliationOpen {
+		t.Errorf("status = %q, want %q", report.Status, ReconciliationOpen)
+	}
+	if report.StatementNet != 760 || report.LedgerNet != 0 {
+		t.Errorf("nets = %v statement, %v ledger, want 760 and 0", report.StatementNet, report.LedgerNet)
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	helpers "olra-v1/utils"
+)
+
+var (
+	referrerReward      = envFloat("REFERRAL_REFERRER_REWARD", 500)
+	refereeReward       = envFloat("REFERRAL_REFEREE_REWARD", 250)
+	referralMonthlyCap  = envInt("REFERRAL_MONTHLY_CAP", 10)
+	referralLifetimeCap = envInt("REFERRAL_LIFETIME_CAP", 50)
+)
+
+var (
+	ErrInvalidReferralCode = errors.New("invalid referral code")
+	ErrSelfReferral        = errors.New("you cannot refer yourself")
+	ErrAlreadyReferred     = errors.New("user has already been referred")
+)
+
+func envFloat(key string, fallback float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// NewReferralCode returns a referral code that is not yet held by any user
+func NewReferralCode() (string, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := helpers.GenerateReferralCode()
+		if err != nil {
+			return "", err
+		}
+		var count int64
+		if err := database.DB.Model(&database.User{}).Where("ref_code = ?", code).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return code, nil
+		}
+	}
+	return "", errors.New("could not generate a unique referral code")
+}
+
+// LinkReferral records that referee signed up with the given referral code
+func LinkReferral(referee *database.User, code string) error {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	var referrer database.User
+	if err := database.DB.Where("ref_code = ? AND status = ?", code, "active").First(&referrer).Error; err != nil {
+		return ErrInvalidReferralCode
+	}
+	if referrer.UserID == referee.UserID {
+		return ErrSelfReferral
+	}
+	var existing database.Referral
+	if err := database.DB.Where("referee_id = ?", referee.UserID).First(&existing).Error; err == nil {
+		return ErrAlreadyReferred
+	}
+	referral := database.Referral{
+		ReferrerID: referrer.UserID,
+		RefereeID:  referee.UserID,
+		Code:       code,
+	}
+	// Both accounts signing in from one device is the commonest self-referral
+	if referrer.DeviceID != "" && referrer.DeviceID == referee.DeviceID {
+		referral.Status = "rejected"
+	}
+	return database.DB.Create(&referral).Error
+}
+
+// EvaluateReferral pays out the referral rewards once the referee has reached
+// KYC tier 2 (BVN verified) and completed a first transfer. It is safe to call
+// after any event that could move a user towards the milestone. A referrer
+// who has reached a cap is not paid, but the referee still gets their reward.
+func EvaluateReferral(refereeID uint) {
+	var referral database.Referral
+	if err := database.DB.Where("referee_id = ? AND status = ?", refereeID, "pending").First(&referral).Error; err != nil {
+		return
+	}
+	var referee database.User
+	if err := database.DB.Where("user_id = ?", refereeID).First(&referee).Error; err != nil {
+		return
+	}
+	if !referee.BvnVerified {
+		return
+	}
+	var transfers int64
+	database.DB.Model(&database.Transaction{}).Where(
+		"user_id = ? AND transaction_type IN ? AND status = ?",
+		refereeID, []string{"olraTransfer-out", "group-payment", "bankTransfer-out"}, "completed",
+	).Count(&transfers)
+	if transfers == 0 {
+		return
+	}
+
+	var referrerAccount, refereeAccount database.VirtualAccount
+	if err := database.DB.Where("user_id = ?", referral.ReferrerID).First(&referrerAccount).Error; err != nil {
+		return
+	}
+	if err := database.DB.Where("user_id = ?", refereeID).First(&refereeAccount).Error; err != nil {
+		return
+	}
+
+	now := time.Now()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Evaluations for the same referrer queue on their row, so the cap is
+		// counted with every earlier reward committed
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("user_id").First(&database.User{}, referral.ReferrerID).Error; err != nil {
+			return err
+		}
+		capped, err := referrerCapped(tx, referral.ReferrerID)
+		if err != nil {
+			return err
+		}
+		updates := map[string]interface{}{
+			"status":         "rewarded",
+			"referee_reward": refereeReward,
+			"reference":      helpers.GenerateTransactionReference(),
+			"rewarded_at":    now,
+		}
+		if capped {
+			updates["status"] = "capped"
+		} else {
+			updates["referrer_reward"] = referrerReward
+			updates["referrer_reference"] = helpers.GenerateTransactionReference()
+		}
+		// Guard against two evaluations racing to pay the same referral
+		result := tx.Model(&database.Referral{}).
+			Where("referral_id = ? AND status = ?", referral.ReferralID, "pending").
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("referral already processed")
+		}
+
+		if err := payReferralReward(tx, updates["reference"].(string), refereeID, &refereeAccount, refereeReward,
+			"Welcome reward for joining with a referral code", now); err != nil {
+			return err
+		}
+		if capped {
+			return nil
+		}
+		return payReferralReward(tx, updates["referrer_reference"].(string), referral.ReferrerID, &referrerAccount,
+			referrerReward, "Referral reward for inviting "+referee.Tag, now)
+	})
+	if err != nil {
+		log.Println("Referral reward not paid:", err)
+	}
+}
+
+// payReferralReward posts one reward from the referral programme account and
+// records it as its own transaction
+func payReferralReward(tx *gorm.DB, reference string, userID uint, wallet *database.VirtualAccount, amount float64, description string, now time.Time) error {
+	if err := PostJournal(tx, reference, "Referral reward",
+		Leg{Account: SystemReferralAccount, Amount: -helpers.ToKobo(amount)},
+		Leg{Account: WalletAccount(wallet.VirtualAccountID), Amount: helpers.ToKobo(amount)},
+	); err != nil {
+		return err
+	}
+	return tx.Create(&database.Transaction{
+		UserID:             userID,
+		Reference:          reference,
+		TransactionEnviron: "withinOlra",
+		TransactionType:    "referral-reward",
+		Amount:             amount,
+		Description:        description,
+		Receiver:           wallet.VirtualAccountAccount,
+		Sender:             "olra",
+		Status:             "completed",
+		TransactionDate:    now,
+	}).Error
+}
+
+func referrerCapped(tx *gorm.DB, referrerID uint) (bool, error) {
+	var lifetime, monthly int64
+	if err := tx.Model(&database.Referral{}).
+		Where("referrer_id = ? AND status = ?", referrerID, "rewarded").
+		Count(&lifetime).Error; err != nil {
+		return false, err
+	}
+	if lifetime >= int64(referralLifetimeCap) {
+		return true, nil
+	}
+	monthStart := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.Local)
+	if err := tx.Model(&database.Referral{}).
+		Where("referrer_id = ? AND status = ? AND rewarded_at >= ?", referrerID, "rewarded", monthStart).
+		Count(&monthly).Error; err != nil {
+		return false, err
+	}
+	return monthly >= int64(referralMonthlyCap), nil
+}
+
+type ReferralDashboard struct {
+	RefCode      string             `json:"refCode"`
+	Total        int                `json:"total"`
+	Pending      int                `json:"pending"`
+	Rewarded     int                `json:"rewarded"`
+	TotalEarned  float64            `json:"totalEarned"`
+	MonthlyCap   int                `json:"monthlyCap"`
+	LifetimeCap  int                `json:"lifetimeCap"`
+	RewardAmount float64            `json:"rewardAmount"`
+	Referees     []ReferralSnapshot `json:"referees"`
+}
+
+type ReferralSnapshot struct {
+	Tag       string    `json:"tag"`
+	Status    string    `json:"status"`
+	Reward    float64   `json:"reward"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func GetReferralDashboard(userID uint) (*ReferralDashboard, error) {
+	var user database.User
+	if err := database.DB.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, ErrAccountNotFound
+	}
+	var referrals []database.Referral
+	if err := database.DB.Where("referrer_id = ?", userID).Order("created_at desc").Find(&referrals).Error; err != nil {
+		return nil, err
+	}
+	dashboard := &ReferralDashboard{
+		RefCode:      user.RefCode,
+		Total:        len(referrals),
+		MonthlyCap:   referralMonthlyCap,
+		LifetimeCap:  referralLifetimeCap,
+		RewardAmount: referrerReward,
+		Referees:     []ReferralSnapshot{},
+	}
+	for _, referral := range referrals {
+		switch referral.Status {
+		case "pending":
+			dashboard.Pending++
+		case "rewarded":
+			dashboard.Rewarded++
+			dashboard.TotalEarned += referral.ReferrerReward
+		}
+		var referee database.User
+		database.DB.Select("tag").Where("user_id = ?", referral.RefereeID).First(&referee)
+		dashboard.Referees = append(dashboard.Referees, ReferralSnapshot{
+			Tag:       referee.Tag,
+			Status:    referral.Status,
+			Reward:    referral.ReferrerReward,
+			CreatedAt: referral.CreatedAt,
+		})
+	}
+	return dashboard, nil
+}
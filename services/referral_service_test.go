@@ -0,0 +1,141 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+var referralModels = append([]interface{}{&database.Referral{}}, walletModels...)
+
+// qualifyTestReferee links referee to referrer's code and takes them past
+// the milestone: BVN verified with one completed transfer
+func qualifyTestReferee(t *testing.T, db *gorm.DB, referrer, referee *database.User) {
+	t.Helper()
+	if err := LinkReferral(referee, referrer.RefCode); err != nil {
+		t.Fatalf("LinkReferral() error = %v", err)
+	}
+	if err := db.Model(referee).Update("bvn_verified", true).Error; err != nil {
+		t.Fatalf("verifying referee: %v", err)
+	}
+	transfer := database.Transaction{
+		UserID:          referee.UserID,
+		Reference:       "first-transfer-" + referee.Tag,
+		TransactionType: "olraTransfer-out",
+		Amount:          100,
+		Status:          "completed",
+		TransactionDate: time.Now(),
+	}
+	if err := db.Create(&transfer).Error; err != nil {
+		t.Fatalf("recording transfer: %v", err)
+	}
+}
+
+func TestEvaluateReferral(t *testing.T) {
+	db := dbtest.Open(t, referralModels...)
+	referrer, referrerWallet := createWalletUser(t, db, "ada", 0)
+	referee, refereeWallet := createWalletUser(t, db, "tunde", 0)
+	qualifyTestReferee(t, db, referrer, referee)
+
+	EvaluateReferral(referee.UserID)
+	// A second evaluation must not pay again
+	EvaluateReferral(referee.UserID)
+
+	assertLedgerBalance(t, WalletAccount(referrerWallet.VirtualAccountID), int64(referrerReward*100))
+	assertLedgerBalance(t, WalletAccount(refereeWallet.VirtualAccountID), int64(refereeReward*100))
+	var referral database.Referral
+	if err := db.Where("referee_id = ?", referee.UserID).First(&referral).Error; err != nil {
+		t.Fatalf("reading referral: %v", err)
+	}
+	if referral.Status != "rewarded" || referral.RewardedAt == nil {
+		t.Fatalf("referral = %+v, want rewarded", referral)
+	}
+	if referral.Reference == "" || referral.ReferrerReference == "" || referral.Reference == referral.ReferrerReference {
+		t.Errorf("references = %q and %q, want two distinct references", referral.Reference, referral.ReferrerReference)
+	}
+	for userID, reference := range map[uint]string{referee.UserID: referral.Reference, referrer.UserID: referral.ReferrerReference} {
+		var rewards []database.Transaction
+		db.Where("reference = ?", reference).Find(&rewards)
+		if len(rewards) != 1 || rewards[0].UserID != userID || rewards[0].TransactionType != "referral-reward" {
+			t.Errorf("transactions under %s = %+v, want one reward to user %d", reference, rewards, userID)
+		}
+	}
+}
+
+func TestEvaluateReferralCapped(t *testing.T) {
+	db := dbtest.Open(t, referralModels...)
+	monthlyCap := referralMonthlyCap
+	referralMonthlyCap = 1
+	t.Cleanup(func() { referralMonthlyCap = monthlyCap })
+
+	referrer, referrerWallet := createWalletUser(t, db, "ada", 0)
+	first, _ := createWalletUser(t, db, "tunde", 0)
+	second, secondWallet := createWalletUser(t, db, "chioma", 0)
+	qualifyTestReferee(t, db, referrer, first)
+	qualifyTestReferee(t, db, referrer, second)
+
+	EvaluateReferral(first.UserID)
+	EvaluateReferral(second.UserID)
+
+	assertLedgerBalance(t, WalletAccount(referrerWallet.VirtualAccountID), int64(referrerReward*100))
+	assertLedgerBalance(t, WalletAccount(secondWallet.VirtualAccountID), int64(refereeReward*100))
+	var referral database.Referral
+	if err := db.Where("referee_id = ?", second.UserID).First(&referral).Error; err != nil {
+		t.Fatalf("reading referral: %v", err)
+	}
+	if referral.Status != "capped" || referral.ReferrerReward != 0 || referral.ReferrerReference != "" {
+		t.Errorf("referral = %+v, want capped with no referrer reward", referral)
+	}
+	if referral.RefereeReward != refereeReward || referral.Reference == "" {
+		t.Errorf("referral = %+v, want the referee rewarded", referral)
+	}
+}
+
+func TestEvaluateReferralBeforeMilestone(t *testing.T) {
+	db := dbtest.Open(t, referralModels...)
+	referrer, referrerWallet := createWalletUser(t, db, "ada", 0)
+	referee, refereeWallet := createWalletUser(t, db, "tunde", 0)
+	if err := LinkReferral(referee, referrer.RefCode); err != nil {
+		t.Fatalf("LinkReferral() error = %v", err)
+	}
+
+	EvaluateReferral(referee.UserID)
+
+	assertLedgerBalance(t, WalletAccount(referrerWallet.VirtualAccountID), 0)
+	assertLedgerBalance(t, WalletAccount(refereeWallet.VirtualAccountID), 0)
+}
+
+func TestLinkReferral(t *testing.T) {
+	db := dbtest.Open(t, referralModels...)
+	referrer, _ := createWalletUser(t, db, "ada", 0)
+	referee, _ := createWalletUser(t, db, "tunde", 0)
+	sameDevice, _ := createWalletUser(t, db, "chioma", 0)
+	db.Model(referrer).Update("device_id", "device-1")
+	db.Model(sameDevice).Update("device_id", "device-1")
+	referrer.DeviceID, sameDevice.DeviceID = "device-1", "device-1"
+
+	if err := LinkReferral(referrer, referrer.RefCode); err != ErrSelfReferral {
+		t.Errorf("self referral error = %v, want %v", err, ErrSelfReferral)
+	}
+	if err := LinkReferral(referee, "NOSUCHCODE"); err != ErrInvalidReferralCode {
+		t.Errorf("unknown code error = %v, want %v", err, ErrInvalidReferralCode)
+	}
+	if err := LinkReferral(referee, " "+referrer.RefCode+" "); err != nil {
+		t.Fatalf("LinkReferral() error = %v", err)
+	}
+	if err := LinkReferral(referee, referrer.RefCode); err != ErrAlreadyReferred {
+		t.Errorf("second referral error = %v, want %v", err, ErrAlreadyReferred)
+	}
+	if err := LinkReferral(sameDevice, referrer.RefCode); err != nil {
+		t.Fatalf("LinkReferral() error = %v", err)
+	}
+	var referral database.Referral
+	db.Where("referee_id = ?", sameDevice.UserID).First(&referral)
+	if referral.Status != "rejected" {
+		t.Errorf("same-device referral status = %q, want rejected", referral.Status)
+	}
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/templates"
+	helpers "olra-v1/utils"
+)
+
+const (
+	ReversalPendingApproval = "pending-approval"
+	ReversalCompleted       = "completed"
+	ReversalRejected        = "rejected"
+)
+
+var (
+	ErrTransactionNotFound   = errors.New("transaction not found")
+	ErrNotReversible         = errors.New("this transaction cannot be reversed")
+	ErrAlreadyReversed       = errors.New("transaction has already been fully reversed")
+	ErrReversalExceedsAmount = errors.New("reversal is more than the amount left to reverse")
+	ErrReversalNotFound      = errors.New("reversal not found")
+	ErrReversalNotPending    = errors.New("reversal is not awaiting approval")
+	ErrSameApprover          = errors.New("a reversal must be approved by someone other than who requested it")
+)
+
+// reversalPlan is how a transaction moved money: one account paid, one
+// received the principal and fee income may have taken a fee. Amounts are in
+// kobo.
+type reversalPlan struct {
+	Payer     string
+	Receiver  string
+	Principal int64
+	Fee       int64
+}
+
+// ledgerNet is what a transaction's journals moved in or out of one account
+type ledgerNet struct {
+	Account string
+	Net     int64
+}
+
+// planReversal works out the plan from the transaction's journal
+func planReversal(tx *gorm.DB, reference string) (*reversalPlan, error) {
+	var nets []ledgerNet
+	if err := tx.Model(&database.LedgerEntry{}).
+		Select("account, SUM(amount) AS net").
+		Where("reference = ?", reference).
+		Group("account").
+		Scan(&nets).Error; err != nil {
+		return nil, err
+	}
+	return planFromNets(nets)
+}
+
+func planFromNets(nets []ledgerNet) (*reversalPlan, error) {
+	plan := &reversalPlan{}
+	for _, entry := range nets {
+		switch {
+		case entry.Net == 0:
+		case entry.Account == SystemFeeIncomeAccount && entry.Net > 0:
+			plan.Fee = entry.Net
+		case entry.Net < 0 && plan.Payer == "":
+			plan.Payer = entry.Account
+		case entry.Net > 0 && plan.Receiver == "":
+			plan.Receiver = entry.Account
+			plan.Principal = entry.Net
+		default:
+			// Journals with several payers or receivers, like referral
+			// rewards, have no single counterparty to refund
+			return nil, ErrNotReversible
+		}
+	}
+	if plan.Payer == "" || plan.Receiver == "" {
+		return nil, ErrNotReversible
+	}
+	return plan, nil
+}
+
+// reversalApprovalThreshold is REVERSAL_APPROVAL_THRESHOLD, the largest
+// reversal one member of staff can post alone
+func reversalApprovalThreshold() float64 {
+	return envFloat("REVERSAL_APPROVAL_THRESHOLD", 50000)
+}
+
+// lockOriginal locks the transaction being reversed. Only completed
+// transactions are reversed; anything still pending settles on its own.
+func lockOriginal(tx *gorm.DB, reference string) (*database.Transaction, error) {
+	var originals []database.Transaction
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("reference = ? AND transaction_type <> ?", reference, "reversal").
+		Find(&originals).Error; err != nil {
+		return nil, err
+	}
+	return reversibleOriginal(originals)
+}
+
+func reversibleOriginal(originals []database.Transaction) (*database.Transaction, error) {
+	switch {
+	case len(originals) == 0:
+		return nil, ErrTransactionNotFound
+	case len(originals) > 1:
+		return nil, ErrNotReversible
+	case originals[0].Status == "reversed":
+		return nil, ErrAlreadyReversed
+	case originals[0].Status != "completed":
+		return nil, ErrNotReversible
+	}
+	return &originals[0], nil
+}
+
+// remainingToReverse is the principal not yet reversed or waiting for
+// approval, in kobo
+func remainingToReverse(tx *gorm.DB, original *database.Transaction, plan *reversalPlan, excludeID uint) (int64, error) {
+	var pending float64
+	if err := tx.Model(&database.Reversal{}).
+		Where("original_reference = ? AND status = ? AND id <> ?", original.Reference, ReversalPendingApproval, excludeID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&pending).Error; err != nil {
+		return 0, err
+	}
+	return plan.Principal - helpers.ToKobo(original.ReversedAmount) - helpers.ToKobo(pending), nil
+}
+
+// reversalAmount is the kobo to reverse when amount is asked for with
+// remaining left, where zero asks for all of it
+func reversalAmount(amount float64, remaining int64) (int64, error) {
+	if remaining <= 0 {
+		return 0, ErrAlreadyReversed
+	}
+	kobo := helpers.ToKobo(amount)
+	if kobo == 0 {
+		kobo = remaining
+	}
+	if kobo < 0 || kobo > remaining {
+		return 0, ErrReversalExceedsAmount
+	}
+	return kobo, nil
+}
+
+// RequestReversal reverses amount of a transaction's principal, or all that is
+// left when amount is zero. The fee is refunded with whichever reversal brings
+// the principal to nothing. Reversals above the approval threshold are only
+// recorded until another member of staff approves them.
+func RequestReversal(reference string, amount float64, reason string, staffID uint) (*database.Reversal, error) {
+	var reversal *database.Reversal
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		reversal, err = requestReversal(tx, reference, amount, reason, staffID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+func requestReversal(tx *gorm.DB, reference string, amount float64, reason string, staffID uint) (*database.Reversal, error) {
+	original, err := lockOriginal(tx, reference)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := planReversal(tx, reference)
+	if err != nil {
+		return nil, err
+	}
+	remaining, err := remainingToReverse(tx, original, plan, 0)
+	if err != nil {
+		return nil, err
+	}
+	kobo, err := reversalAmount(amount, remaining)
+	if err != nil {
+		return nil, err
+	}
+	reversal := database.Reversal{
+		Reference:         helpers.GenerateTransactionReference(),
+		OriginalReference: reference,
+		Amount:            helpers.FromKobo(kobo),
+		Reason:            reason,
+		Status:            ReversalPendingApproval,
+		RequestedBy:       staffID,
+	}
+	if err := tx.Create(&reversal).Error; err != nil {
+		return nil, err
+	}
+	if reversal.Amount > reversalApprovalThreshold() {
+		return &reversal, nil
+	}
+	return &reversal, postReversal(tx, &reversal, original, plan)
+}
+
+// ApproveReversal posts a reversal that was waiting for a second approver
+func ApproveReversal(id, staffID uint) (*database.Reversal, error) {
+	var reversal database.Reversal
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reversal, id).Error; err != nil {
+			return ErrReversalNotFound
+		}
+		if reversal.Status != ReversalPendingApproval {
+			return ErrReversalNotPending
+		}
+		if reversal.RequestedBy == staffID {
+			return ErrSameApprover
+		}
+		original, err := lockOriginal(tx, reversal.OriginalReference)
+		if err != nil {
+			return err
+		}
+		plan, err := planReversal(tx, reversal.OriginalReference)
+		if err != nil {
+			return err
+		}
+		remaining, err := remainingToReverse(tx, original, plan, reversal.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := reversalAmount(reversal.Amount, remaining); err != nil {
+			return err
+		}
+		reversal.ReviewedBy = &staffID
+		return postReversal(tx, &reversal, original, plan)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reversal, nil
+}
+
+func RejectReversal(id, staffID uint) (*database.Reversal, error) {
+	var reversal database.Reversal
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reversal, id).Error; err != nil {
+			return ErrReversalNotFound
+		}
+		if reversal.Status != ReversalPendingApproval {
+			return ErrReversalNotPending
+		}
+		now := time.Now()
+		reversal.Status = ReversalRejected
+		reversal.ReviewedBy = &staffID
+		reversal.CompletedAt = &now
+		if err := tx.Save(&reversal).Error; err != nil {
+			return err
+		}
+		// A dispute decided for the sender kept the recipient's funds held
+		// for this reversal; with it rejected there is nothing left to hold
+		// them for
+		return releaseDisputeHolds(tx, "reversal_id = ?", reversal.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reversal, nil
+}
+
+func ListReversals(status string, page, limit int) ([]database.Reversal, error) {
+	var reversals []database.Reversal
+	db := database.DB.Order("created_at desc").Offset((page - 1) * limit).Limit(limit)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	err := db.Find(&reversals).Error
+	return reversals, err
+}
+
+// postReversal takes the amount back from the receiver and returns it to the
+// payer, with the fee when this reversal finishes the principal, then records
+// it on both transactions and tells the wallet owners involved
+func postReversal(tx *gorm.DB, reversal *database.Reversal, original *database.Transaction, plan *reversalPlan) error {
+	amount := helpers.ToKobo(reversal.Amount)
+	reversedBefore := helpers.ToKobo(original.ReversedAmount)
+	posting := planPosting(plan, reversedBefore, amount)
+	completes, refund, legs := posting.Completes, posting.Refund, posting.Legs
+	if posting.FeeRefunded > 0 {
+		reversal.FeeRefunded = helpers.FromKobo(posting.FeeRefunded)
+	}
+
+	if err := releaseDisputeHolds(tx, "transaction_reference = ?", original.Reference); err != nil {
+		return err
+	}
+	if err := ensureCanDebit(tx, plan.Receiver, amount); err != nil {
+		return err
+	}
+	if err := PostJournal(tx, reversal.Reference,
+		fmt.Sprintf("Reversal of %s: %s", original.Reference, reversal.Reason), legs...); err != nil {
+		return err
+	}
+
+	status := original.Status
+	if completes {
+		status = "reversed"
+	}
+	if err := tx.Model(original).Updates(map[string]interface{}{
+		"reversed_amount": helpers.FromKobo(reversedBefore + amount),
+		"status":          status,
+	}).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := tx.Create(&database.Transaction{
+		UserID:             original.UserID,
+		Reference:          reversal.Reference,
+		TransactionEnviron: original.TransactionEnviron,
+		TransactionType:    "reversal",
+		Amount:             helpers.FromKobo(refund),
+		Description:        "Reversal of " + original.Reference,
+		Sender:             original.Receiver,
+		Receiver:           original.Sender,
+		OriginalReference:  original.Reference,
+		Status:             "completed",
+		TransactionDate:    now,
+	}).Error; err != nil {
+		return err
+	}
+	reversal.Status = ReversalCompleted
+	reversal.CompletedAt = &now
+	if err := tx.Save(reversal).Error; err != nil {
+		return err
+	}
+
+	if err := notifyWalletOwner(tx, plan.Payer, templates.EventRefund, NotificationCredit, original.Reference, refund); err != nil {
+		return err
+	}
+	return notifyWalletOwner(tx, plan.Receiver, templates.EventReversalDebit, NotificationDebit, original.Reference, amount)
+}
+
+// reversalPosting is the journal a reversal posts. Amounts are in kobo.
+type reversalPosting struct {
+	Legs        []Leg
+	Refund      int64
+	FeeRefunded int64
+	// Completes is set when the reversal brings the principal to nothing
+	Completes bool
+}
+
+// planPosting reverses amount of the plan's principal when reversedBefore has
+// already been reversed, refunding the fee with the reversal that completes it
+func planPosting(plan *reversalPlan, reversedBefore, amount int64) reversalPosting {
+	posting := reversalPosting{
+		Refund:    amount,
+		Completes: reversedBefore+amount == plan.Principal,
+	}
+	posting.Legs = []Leg{{Account: plan.Receiver, Amount: -amount}}
+	if posting.Completes && plan.Fee > 0 {
+		posting.Legs = append(posting.Legs, Leg{Account: SystemFeeIncomeAccount, Amount: -plan.Fee})
+		posting.Refund += plan.Fee
+		posting.FeeRefunded = plan.Fee
+	}
+	posting.Legs = append(posting.Legs, Leg{Account: plan.Payer, Amount: posting.Refund})
+	return posting
+}
+
+// ensureCanDebit stops a reversal taking back more than a wallet can spend or
+// more than a group wallet holds. System accounts are not limited.
+func ensureCanDebit(tx *gorm.DB, account string, amount int64) error {
+	kind, id, ok := parseLedgerAccount(account)
+	if !ok {
+		return nil
+	}
+	switch kind {
+	case "wallet":
+		wallet := database.VirtualAccount{VirtualAccountID: id}
+		if err := lockWallets(tx, &wallet); err != nil {
+			return err
+		}
+		return checkAvailable(tx, &wallet, helpers.FromKobo(amount))
+	case "group":
+		var groupWallet database.GroupVirtualAccount
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&groupWallet, id).Error; err != nil {
+			return err
+		}
+		// The ledger rather than the cached Balance, as for wallets
+		balance, err := LedgerBalance(tx, GroupWalletAccount(groupWallet.GroupVirtualAccountID))
+		if err != nil {
+			return err
+		}
+		if balance < amount {
+			return ErrInsufficientBalance
+		}
+	}
+	return nil
+}
+
+// notifyWalletOwner tells the owner of a user wallet about a reversal. Group
+// and system accounts have no one to tell.
+func notifyWalletOwner(tx *gorm.DB, account, event, noticeType, reference string, amount int64) error {
+	kind, id, ok := parseLedgerAccount(account)
+	if !ok || kind != "wallet" {
+		return nil
+	}
+	var wallet database.VirtualAccount
+	if err := tx.First(&wallet, id).Error; err != nil {
+		return err
+	}
+	var owner database.User
+	if err := tx.First(&owner, wallet.UserID).Error; err != nil {
+		return err
+	}
+	available, err := AvailableBalance(tx, &wallet)
+	if err != nil {
+		return err
+	}
+	return Dispatch(tx, &owner, Notice{
+		Type:         noticeType,
+		Event:        event,
+		ResourceType: ResourceTransaction,
+		ResourceID:   reference,
+		Data: templates.Data{
+			"Amount":    helpers.FromKobo(amount),
+			"Reference": reference,
+			"Balance":   available,
+		},
+	})
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+	helpers "olra-v1/utils"
+)
+
+func TestPlanFromNets(t *testing.T) {
+	tests := []struct {
+		name string
+		nets []ledgerNet
+		want *reversalPlan
+		err  error
+	}{
+		{
+			name: "wallet to wallet",
+			nets: []ledgerNet{
+				{Account: "wallet:1", Net: -500000},
+				{Account: "wallet:2", Net: 500000},
+			},
+			want: &reversalPlan{Payer: "wallet:1", Receiver: "wallet:2", Principal: 500000},
+		},
+		{
+			name: "with a fee",
+			nets: []ledgerNet{
+				{Account: SystemFeeIncomeAccount, Net: 1000},
+				{Account: "wallet:1", Net: -501000},
+				{Account: "group:7", Net: 500000},
+			},
+			want: &reversalPlan{Payer: "wallet:1", Receiver: "group:7", Principal: 500000, Fee: 1000},
+		},
+		{
+			name: "several receivers",
+			nets: []ledgerNet{
+				{Account: SystemReferralAccount, Net: -200000},
+				{Account: "wallet:1", Net: 100000},
+				{Account: "wallet:2", Net: 100000},
+			},
+			err: ErrNotReversible,
+		},
+		{
+			name: "several payers",
+			nets: []ledgerNet{
+				{Account: "wallet:1", Net: -100000},
+				{Account: "wallet:2", Net: -100000},
+				{Account: "wallet:3", Net: 200000},
+			},
+			err: ErrNotReversible,
+		},
+		{
+			name: "no journal",
+			err:  ErrNotReversible,
+		},
+		{
+			name: "fee only",
+			nets: []ledgerNet{
+				{Account: "wallet:1", Net: -1000},
+				{Account: SystemFeeIncomeAccount, Net: 1000},
+			},
+			err: ErrNotReversible,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := planFromNets(tt.nets)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("planFromNets() error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planFromNets() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanPosting(t *testing.T) {
+	plan := &reversalPlan{Payer: "wallet:1", Receiver: "wallet:2", Principal: 500000, Fee: 1000}
+	tests := []struct {
+		name           string
+		plan           *reversalPlan
+		reversedBefore int64
+		amount         int64
+		want           reversalPosting
+	}{
+		{
+			name:   "full reversal refunds the fee",
+			plan:   plan,
+			amount: 500000,
+			want: reversalPosting{
+				Legs: []Leg{
+					{Account: "wallet:2", Amount: -500000},
+					{Account: SystemFeeIncomeAccount, Amount: -1000},
+					{Account: "wallet:1", Amount: 501000},
+				},
+				Refund:      501000,
+				FeeRefunded: 1000,
+				Completes:   true,
+			},
+		},
+		{
+			name:   "partial reversal keeps the fee",
+			plan:   plan,
+			amount: 200000,
+			want: reversalPosting{
+				Legs: []Leg{
+					{Account: "wallet:2", Amount: -200000},
+					{Account: "wallet:1", Amount: 200000},
+				},
+				Refund: 200000,
+			},
+		},
+		{
+			name:           "partial reversal that finishes the principal refunds the fee",
+			plan:           plan,
+			reversedBefore: 200000,
+			amount:         300000,
+			want: reversalPosting{
+				Legs: []Leg{
+					{Account: "wallet:2", Amount: -300000},
+					{Account: SystemFeeIncomeAccount, Amount: -1000},
+					{Account: "wallet:1", Amount: 301000},
+				},
+				Refund:      301000,
+				FeeRefunded: 1000,
+				Completes:   true,
+			},
+		},
+		{
+			name:   "full reversal without a fee",
+			plan:   &reversalPlan{Payer: "wallet:1", Receiver: "group:7", Principal: 500000},
+			amount: 500000,
+			want: reversalPosting{
+				Legs: []Leg{
+					{Account: "group:7", Amount: -500000},
+					{Account: "wallet:1", Amount: 500000},
+				},
+				Refund:    500000,
+				Completes: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planPosting(tt.plan, tt.reversedBefore, tt.amount)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planPosting() = %+v, want %+v", got, tt.want)
+			}
+			var sum int64
+			for _, leg := range got.Legs {
+				sum += leg.Amount
+			}
+			if sum != 0 {
+				t.Errorf("planPosting() legs sum to %d, want 0", sum)
+			}
+		})
+	}
+}
+
+func TestReversalAmount(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    float64
+		remaining int64
+		want      int64
+		err       error
+	}{
+		{"whole amount by default", 0, 500000, 500000, nil},
+		{"part of it", 2000, 500000, 200000, nil},
+		{"exactly what is left", 5000, 500000, 500000, nil},
+		{"more than is left", 5000.01, 500000, 0, ErrReversalExceedsAmount},
+		{"negative", -10, 500000, 0, ErrReversalExceedsAmount},
+		{"already reversed", 0, 0, 0, ErrAlreadyReversed},
+		{"already reversed, amount given", 100, 0, 0, ErrAlreadyReversed},
+		{"rest held by pending reversals", 100, -100, 0, ErrAlreadyReversed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reversalAmount(tt.amount, tt.remaining)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("reversalAmount(%v, %d) error = %v, want %v", tt.amount, tt.remaining, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("reversalAmount(%v, %d) = %d, want %d", tt.amount, tt.remaining, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReversibleOriginal(t *testing.T) {
+	tests := []struct {
+		name      string
+		originals []database.Transaction
+		err       error
+	}{
+		{"completed", []database.Transaction{{Status: "completed"}}, nil},
+		{"partly reversed", []database.Transaction{{Status: "completed", ReversedAmount: 2000}}, nil},
+		{"reversed twice", []database.Transaction{{Status: "reversed", ReversedAmount: 5000}}, ErrAlreadyReversed},
+		{"pending", []database.Transaction{{Status: "pending"}}, ErrNotReversible},
+		{"failed", []database.Transaction{{Status: "failed"}}, ErrNotReversible},
+		{"not found", nil, ErrTransactionNotFound},
+		{"reference shared by two transactions", []database.Transaction{{Status: "completed"}, {Status: "completed"}}, ErrNotReversible},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reversibleOriginal(tt.originals)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("reversibleOriginal() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && got != &tt.originals[0] {
+				t.Errorf("reversibleOriginal() did not return the transaction")
+			}
+		})
+	}
+}
+
+// A transaction reversed in two parts: the second reversal refunds the fee and
+// a third finds nothing left
+func TestReversalInParts(t *testing.T) {
+	plan := &reversalPlan{Payer: "wallet:1", Receiver: "wallet:2", Principal: 500000, Fee: 1000}
+	var reversed, refunded int64
+	for i, amount := range []float64{2000, 0} {
+		kobo, err := reversalAmount(amount, plan.Principal-reversed)
+		if err != nil {
+			t.Fatalf("reversal %d: %v", i+1, err)
+		}
+		posting := planPosting(plan, reversed, kobo)
+		reversed += kobo
+		refunded += posting.Refund
+	}
+	if reversed != plan.Principal {
+		t.Errorf("reversed %d, want %d", reversed, plan.Principal)
+	}
+	if refunded != plan.Principal+plan.Fee {
+		t.Errorf("refunded %d, want %d", refunded, plan.Principal+plan.Fee)
+	}
+	if _, err := reversalAmount(0, plan.Principal-reversed); !errors.Is(err, ErrAlreadyReversed) {
+		t.Errorf("third reversal error = %v, want %v", err, ErrAlreadyReversed)
+	}
+}
+
+var reversalModels = append([]interface{}{
+	&database.Reversal{}, &database.Dispute{}, &database.Group{}, &database.GroupVirtualAccount{},
+}, walletModels...)
+
+// sendForReversal makes a completed transfer of amount naira with a flat fee
+// of fee naira and returns it with the wallets involved
+func sendForReversal(t *testing.T, amount, fee float64) (*database.Transaction, *database.VirtualAccount, *database.VirtualAccount) {
+	t.Helper()
+	db := dbtest.Open(t, reversalModels...)
+	if fee > 0 {
+		db.Create(&database.PricingRule{Name: "Transfer fee", TransactionType: PricedOlraTransfer,
+			FeeType: FeeTypeFlat, FlatFee: fee, EffectiveFrom: time.Now().AddDate(0, 0, -1)})
+	}
+	sender, senderWallet := createWalletUser(t, db, "ada", helpers.ToKobo(amount+fee))
+	receiver, receiverWallet := createWalletUser(t, db, "tunde", 0)
+	transaction, err := TransferToUser(sender, receiver, senderWallet, receiverWallet, amount, "", ChannelApp)
+	if err != nil {
+		t.Fatalf("TransferToUser() error = %v", err)
+	}
+	return transaction, senderWallet, receiverWallet
+}
+
+func TestRequestReversal(t *testing.T) {
+	transaction, senderWallet, receiverWallet := sendForReversal(t, 5000, 10)
+
+	reversal, err := RequestReversal(transaction.Reference, 0, "sent in error", 1)
+	if err != nil {
+		t.Fatalf("RequestReversal() error = %v", err)
+	}
+	if reversal.Status != ReversalCompleted || reversal.FeeRefunded != 10 {
+		t.Errorf("reversal = %s with %v fee refunded, want completed with 10", reversal.Status, reversal.FeeRefunded)
+	}
+	assertLedgerBalance(t, WalletAccount(senderWallet.VirtualAccountID), 501000)
+	assertLedgerBalance(t, WalletAccount(receiverWallet.VirtualAccountID), 0)
+	assertLedgerBalance(t, SystemFeeIncomeAccount, 0)
+
+	var original database.Transaction
+	database.DB.First(&original, transaction.TransactionID)
+	if original.Status != "reversed" || original.ReversedAmount != 5000 {
+		t.Errorf("original = %s with %v reversed, want reversed with 5000", original.Status, original.ReversedAmount)
+	}
+	if _, err := RequestReversal(transaction.Reference, 0, "again", 1); !errors.Is(err, ErrAlreadyReversed) {
+		t.Errorf("second RequestReversal() error = %v, want %v", err, ErrAlreadyReversed)
+	}
+}
+
+func TestRequestReversalReceiverSpent(t *testing.T) {
+	transaction, _, receiverWallet := sendForReversal(t, 5000, 0)
+	fundWallet(t, database.DB, WalletAccount(receiverWallet.VirtualAccountID), -300000)
+
+	if _, err := RequestReversal(transaction.Reference, 0, "sent in error", 1); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("RequestReversal() error = %v, want %v", err, ErrInsufficientBalance)
+	}
+	var reversals int64
+	database.DB.Model(&database.Reversal{}).Count(&reversals)
+	if reversals != 0 {
+		t.Errorf("%d reversals recorded, want none", reversals)
+	}
+	assertLedgerBalance(t, WalletAccount(receiverWallet.VirtualAccountID), 200000)
+}
+
+func TestApproveReversal(t *testing.T) {
+	t.Setenv("REVERSAL_APPROVAL_THRESHOLD", "1000")
+	transaction, senderWallet, receiverWallet := sendForReversal(t, 5000, 0)
+
+	reversal, err := RequestReversal(transaction.Reference, 2000, "part sent in error", 1)
+	if err != nil {
+		t.Fatalf("RequestReversal() error = %v", err)
+	}
+	if reversal.Status != ReversalPendingApproval {
+		t.Fatalf("status = %q, want %q", reversal.Status, ReversalPendingApproval)
+	}
+	assertLedgerBalance(t, WalletAccount(receiverWallet.VirtualAccountID), 500000)
+	// The pending reversal counts against what is left to reverse
+	if _, err := RequestReversal(transaction.Reference, 3000.01, "the rest", 1); !errors.Is(err, ErrReversalExceedsAmount) {
+		t.Errorf("RequestReversal() of more than is left error = %v, want %v", err, ErrReversalExceedsAmount)
+	}
+
+	if _, err := ApproveReversal(reversal.ID, 1); !errors.Is(err, ErrSameApprover) {
+		t.Errorf("ApproveReversal() by the requester error = %v, want %v", err, ErrSameApprover)
+	}
+	approved, err := ApproveReversal(reversal.ID, 2)
+	if err != nil {
+		t.Fatalf("ApproveReversal() error = %v", err)
+	}
+	if approved.Status != ReversalCompleted || approved.ReviewedBy == nil || *approved.ReviewedBy != 2 {
+		t.Errorf("reversal = %+v, want completed and reviewed by 2", approved)
+	}
+	assertLedgerBalance(t, WalletAccount(senderWallet.VirtualAccountID), 200000)
+	assertLedgerBalance(t, WalletAccount(receiverWallet.VirtualAccountID), 300000)
+	if _, err := ApproveReversal(reversal.ID, 2); !errors.Is(err, ErrReversalNotPending) {
+		t.Errorf("second ApproveReversal() error = %v, want %v", err, ErrReversalNotPending)
+	}
+}
+
+func TestReversalFromGroupUsesLedger(t *testing.T) {
+	db := dbtest.Open(t, reversalModels...)
+	sender, senderWallet := createWalletUser(t, db, "ada", 500000)
+	group := database.Group{GroupName: "Savers", GroupTag: "savers", CreatedBy: sender.UserID, AdminID: sender.UserID}
+	db.Create(&group)
+	groupWallet := database.GroupVirtualAccount{GroupID: group.GroupID, GroupVirtualAccountBank: "Wema Bank",
+		GroupVirtualAccountNumber: "9100000001", GroupVirtualAccountName: "Savers"}
+	db.Create(&groupWallet)
+	transaction, err := TransferToGroup(sender, senderWallet, &group, &groupWallet, 5000, "", ChannelApp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fundWallet(t, db, GroupWalletAccount(groupWallet.GroupVirtualAccountID), -300000)
+	// A cached balance that has drifted above the ledger
+	db.Model(&groupWallet).Update("balance", 5000)
+
+	if _, err := RequestReversal(transaction.Reference, 0, "sent in error", 1); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("RequestReversal() error = %v, want %v", err, ErrInsufficientBalance)
+	}
+	if _, err := RequestReversal(transaction.Reference, 2000, "part sent in error", 1); err != nil {
+		t.Errorf("RequestReversal() of what the group holds error = %v", err)
+	}
+}
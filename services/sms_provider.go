@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SMSProvider sends plain messages and runs the OTP pin flow with an SMS gateway
+type SMSProvider interface {
+	Name() string
+	Send(to, message string) (*SMSResult, error)
+	// SendOTP delivers a one-time pin. The message carries the OTPPlaceholder
+	// where the provider puts the pin.
+	SendOTP(to, message string) (*OTPResult, error)
+	VerifyOTP(pinID, pin string) (*VerifyOTPResult, error)
+}
+
+const OTPPlaceholder = "< 1234 >"
+
+type SMSResult struct {
+	Provider  string  `json:"provider"`
+	MessageID string  `json:"message_id"`
+	Message   string  `json:"message"`
+	Balance   float64 `json:"balance"`
+	User      string  `json:"user"`
+}
+
+type OTPResult struct {
+	Provider  string `json:"provider"`
+	PinId     string `json:"pinId"`
+	To        string `json:"to"`
+	SmsStatus string `json:"smsStatus"`
+}
+
+type VerifyOTPResult struct {
+	PinId    string `json:"pinId"`
+	Verified bool   `json:"verified"`
+	Expired  bool   `json:"expired"`
+	Msisdn   string `json:"msisdn"`
+}
+
+// ProviderError is returned when a gateway answers with a non-success status
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s: unexpected status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+var ErrNoSMSProvider = errors.New("no sms provider configured")
+
+// FailoverSMSProvider tries each provider in order until one succeeds. Pin IDs
+// are prefixed with the issuing provider so verification goes back to it.
+type FailoverSMSProvider struct {
+	Providers []SMSProvider
+}
+
+func (f *FailoverSMSProvider) Name() string {
+	return "failover"
+}
+
+func (f *FailoverSMSProvider) Send(to, message string) (*SMSResult, error) {
+	var errs []error
+	for _, provider := range f.Providers {
+		result, err := provider.Send(to, message)
+		if err == nil {
+			return result, nil
+		}
+		log.Printf("SMS provider %s failed, trying next: %v", provider.Name(), err)
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, ErrNoSMSProvider
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (f *FailoverSMSProvider) SendOTP(to, message string) (*OTPResult, error) {
+	var errs []error
+	for _, provider := range f.Providers {
+		result, err := provider.SendOTP(to, message)
+		if err == nil {
+			result.PinId = provider.Name() + ":" + result.PinId
+			return result, nil
+		}
+		log.Printf("SMS provider %s failed, trying next: %v", provider.Name(), err)
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, ErrNoSMSProvider
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (f *FailoverSMSProvider) VerifyOTP(pinID, pin string) (*VerifyOTPResult, error) {
+	if len(f.Providers) == 0 {
+		return nil, ErrNoSMSProvider
+	}
+	name, rawPinID, found := strings.Cut(pinID, ":")
+	if found {
+		for _, provider := range f.Providers {
+			if provider.Name() == name {
+				return provider.VerifyOTP(rawPinID, pin)
+			}
+		}
+	}
+	// Pins issued before failover was enabled carry no prefix
+	return f.Providers[0].VerifyOTP(pinID, pin)
+}
+
+// smsProvider is read on every send and may be replaced at any time, so both
+// go through smsProviderMu
+var (
+	smsProvider   SMSProvider
+	smsProviderMu sync.RWMutex
+)
+
+// SMS returns the configured provider. SMS_PROVIDERS lists the providers to
+// try in order, e.g. "termii,twilio". It defaults to termii.
+func SMS() SMSProvider {
+	smsProviderMu.RLock()
+	provider := smsProvider
+	smsProviderMu.RUnlock()
+	if provider != nil {
+		return provider
+	}
+	smsProviderMu.Lock()
+	defer smsProviderMu.Unlock()
+	if smsProvider == nil {
+		smsProvider = newSMSProviderFromEnv()
+	}
+	return smsProvider
+}
+
+// SetSMSProvider replaces the provider, mainly so tests can install a fake
+func SetSMSProvider(provider SMSProvider) {
+	smsProviderMu.Lock()
+	defer smsProviderMu.Unlock()
+	smsProvider = provider
+}
+
+func newSMSProviderFromEnv() SMSProvider {
+	names := os.Getenv("SMS_PROVIDERS")
+	if names == "" {
+		names = "termii"
+	}
+	var providers []SMSProvider
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "termii":
+			providers = append(providers, NewTermiiProvider())
+		case "twilio":
+			providers = append(providers, NewTwilioProvider())
+		case "fake":
+			providers = append(providers, NewFakeSMSProvider())
+		default:
+			log.Printf("Unknown sms provider %q ignored", name)
+		}
+	}
+	if len(providers) == 1 {
+		return providers[0]
+	}
+	return &FailoverSMSProvider{Providers: providers}
+}
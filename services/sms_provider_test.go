@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// namedSMSProvider lets a failover chain hold more than one fake
+type namedSMSProvider struct {
+	*FakeSMSProvider
+	name string
+}
+
+func (n namedSMSProvider) Name() string {
+	return n.name
+}
+
+func TestFailoverSMSProviderSend(t *testing.T) {
+	errDown := errors.New("gateway down")
+	primary := namedSMSProvider{NewFakeSMSProvider(), "primary"}
+	backup := namedSMSProvider{NewFakeSMSProvider(), "backup"}
+	primary.Fail = errDown
+	failover := &FailoverSMSProvider{Providers: []SMSProvider{primary, backup}}
+
+	if _, err := failover.Send("08000000001", "hello"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(backup.Messages()) != 1 {
+		t.Errorf("backup sent %d messages, want 1", len(backup.Messages()))
+	}
+
+	backup.Fail = errDown
+	if _, err := failover.Send("08000000001", "hello"); !errors.Is(err, errDown) {
+		t.Errorf("Send() with every provider down error = %v, want %v", err, errDown)
+	}
+	if _, err := (&FailoverSMSProvider{}).Send("08000000001", "hello"); !errors.Is(err, ErrNoSMSProvider) {
+		t.Errorf("Send() with no providers error = %v, want %v", err, ErrNoSMSProvider)
+	}
+}
+
+func TestFailoverSMSProviderOTP(t *testing.T) {
+	primary := namedSMSProvider{NewFakeSMSProvider(), "primary"}
+	backup := namedSMSProvider{NewFakeSMSProvider(), "backup"}
+	primary.Fail = errors.New("gateway down")
+	failover := &FailoverSMSProvider{Providers: []SMSProvider{primary, backup}}
+
+	result, err := failover.SendOTP("08000000001", "Your code is "+OTPPlaceholder)
+	if err != nil {
+		t.Fatalf("SendOTP() error = %v", err)
+	}
+	if !strings.HasPrefix(result.PinId, "backup:") {
+		t.Fatalf("pin id = %q, want it prefixed with the issuing provider", result.PinId)
+	}
+	// The primary recovers but the pin must still be checked with the backup
+	primary.Fail = nil
+	verified, err := failover.VerifyOTP(result.PinId, FakeOTPCode)
+	if err != nil || !verified.Verified {
+		t.Errorf("VerifyOTP() = %+v, %v, want verified by the backup", verified, err)
+	}
+}
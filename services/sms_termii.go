@@ -0,0 +1,140 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"olra-v1/internal/structs"
+)
+
+type TermiiProvider struct {
+	BaseURL  string
+	APIKey   string
+	SenderID string
+	Channel  string
+	Client   *http.Client
+}
+
+func NewTermiiProvider() *TermiiProvider {
+	return &TermiiProvider{
+		BaseURL:  os.Getenv("TERMII_BASE_URL"),
+		APIKey:   os.Getenv("TERMII_API_KEY"),
+		SenderID: "N-Alert",
+		Channel:  "dnd",
+		Client:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// NewTermiiWhatsAppProvider sends through Termii's WhatsApp channel
+func NewTermiiWhatsAppProvider() *TermiiProvider {
+	provider := NewTermiiProvider()
+	provider.Channel = "whatsapp"
+	return provider
+}
+
+func (t *TermiiProvider) Name() string {
+	return "termii"
+}
+
+func (t *TermiiProvider) Send(to, message string) (*SMSResult, error) {
+	data := structs.FundsRequest{
+		APIKey:  t.APIKey,
+		To:      to,
+		From:    t.SenderID,
+		SMS:     message,
+		Type:    "plain",
+		Channel: t.Channel,
+	}
+	var response structs.FundsResponse
+	if _, err := t.post("/sms/send", data, &response); err != nil {
+		return nil, err
+	}
+	return &SMSResult{
+		Provider:  t.Name(),
+		MessageID: response.Message_ID,
+		Message:   response.Message,
+		Balance:   response.Balance,
+		User:      response.User,
+	}, nil
+}
+
+func (t *TermiiProvider) SendOTP(to, message string) (*OTPResult, error) {
+	data := structs.PhoneOTPRequest{
+		APIKey:         t.APIKey,
+		MessageType:    "NUMERIC",
+		To:             to,
+		From:           t.SenderID,
+		Channel:        t.Channel,
+		PINAttempts:    3,
+		PINTimeToLive:  10,
+		PINLength:      6,
+		PINPlaceholder: OTPPlaceholder,
+		MessageText:    message,
+		PINType:        "NUMERIC",
+	}
+	var response structs.PhoneOTPResponse
+	if _, err := t.post("/sms/otp/send", data, &response); err != nil {
+		return nil, err
+	}
+	return &OTPResult{
+		Provider:  t.Name(),
+		PinId:     response.PinId,
+		To:        response.To,
+		SmsStatus: response.SmsStatus,
+	}, nil
+}
+
+func (t *TermiiProvider) VerifyOTP(pinID, pin string) (*VerifyOTPResult, error) {
+	data := map[string]string{
+		"api_key": t.APIKey,
+		"pin_id":  pinID,
+		"pin":     pin,
+	}
+	var response structs.VerifyPhoneOTPBody
+	status, err := t.post("/sms/otp/verify", data, &response)
+	// Termii answers an expired or unknown pin with a 400
+	if status == http.StatusBadRequest || response.Status == http.StatusBadRequest {
+		return &VerifyOTPResult{PinId: pinID, Expired: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &VerifyOTPResult{
+		PinId:    response.PinId,
+		Verified: response.Verified,
+		Msisdn:   response.Msisdn,
+	}, nil
+}
+
+func (t *TermiiProvider) post(path string, payload, out interface{}) (int, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest("POST", t.BaseURL+path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("cache-control", "no-cache")
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		json.Unmarshal(body, out)
+		return resp.StatusCode, &ProviderError{Provider: t.Name(), StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return resp.StatusCode, json.Unmarshal(body, out)
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// TwilioProvider sends messages through the Programmable Messaging API and
+// pins through Twilio Verify, which writes its own OTP message text
+type TwilioProvider struct {
+	AccountSID       string
+	AuthToken        string
+	From             string
+	VerifyServiceSID string
+	Client           *http.Client
+}
+
+func NewTwilioProvider() *TwilioProvider {
+	return &TwilioProvider{
+		AccountSID:       os.Getenv("TWILIO_ACCOUNT_SID"),
+		AuthToken:        os.Getenv("TWILIO_AUTH_TOKEN"),
+		From:             os.Getenv("TWILIO_FROM"),
+		VerifyServiceSID: os.Getenv("TWILIO_VERIFY_SERVICE_SID"),
+		Client:           &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (t *TwilioProvider) Name() string {
+	return "twilio"
+}
+
+func (t *TwilioProvider) Send(to, message string) (*SMSResult, error) {
+	var response struct {
+		SID    string `json:"sid"`
+		Status string `json:"status"`
+	}
+	endpoint := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", t.AccountSID)
+	form := url.Values{"To": {to}, "From": {t.From}, "Body": {message}}
+	if err := t.post(endpoint, form, &response); err != nil {
+		return nil, err
+	}
+	return &SMSResult{
+		Provider:  t.Name(),
+		MessageID: response.SID,
+		Message:   response.Status,
+	}, nil
+}
+
+func (t *TwilioProvider) SendOTP(to, message string) (*OTPResult, error) {
+	var response struct {
+		SID    string `json:"sid"`
+		To     string `json:"to"`
+		Status string `json:"status"`
+	}
+	endpoint := fmt.Sprintf("https://verify.twilio.com/v2/Services/%s/Verifications", t.VerifyServiceSID)
+	form := url.Values{"To": {to}, "Channel": {"sms"}}
+	if err := t.post(endpoint, form, &response); err != nil {
+		return nil, err
+	}
+	return &OTPResult{
+		Provider:  t.Name(),
+		PinId:     response.SID,
+		To:        response.To,
+		SmsStatus: response.Status,
+	}, nil
+}
+
+func (t *TwilioProvider) VerifyOTP(pinID, pin string) (*VerifyOTPResult, error) {
+	var response struct {
+		SID    string `json:"sid"`
+		To     string `json:"to"`
+		Status string `json:"status"`
+	}
+	endpoint := fmt.Sprintf("https://verify.twilio.com/v2/Services/%s/VerificationCheck", t.VerifyServiceSID)
+	form := url.Values{"VerificationSid": {pinID}, "Code": {pin}}
+	err := t.post(endpoint, form, &response)
+	// Twilio answers a verification that has expired or been used up with a 404
+	if providerErr, ok := err.(*ProviderError); ok && providerErr.StatusCode == http.StatusNotFound {
+		return &VerifyOTPResult{PinId: pinID, Expired: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &VerifyOTPResult{
+		PinId:    response.SID,
+		Verified: response.Status == "approved",
+		Msisdn:   response.To,
+	}, nil
+}
+
+func (t *TwilioProvider) post(endpoint string, form url.Values, out interface{}) error {
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &ProviderError{Provider: t.Name(), StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,594 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/pdf"
+	"olra-v1/internal/templates"
+	helpers "olra-v1/utils"
+)
+
+const (
+	AccountStatementCSV = "csv"
+	AccountStatementPDF = "pdf"
+)
+
+var (
+	ErrAccountStatementFormat   = errors.New("statement format must be csv or pdf")
+	ErrStatementPeriod          = errors.New("from and to must be dates as YYYY-MM-DD, with from no later than to")
+	ErrStatementFutureDate      = errors.New("statement period cannot end in the future")
+	ErrStatementPeriodTooLong   = errors.New("statement period is too long")
+	ErrAccountStatementNotFound = errors.New("statement not found")
+	ErrGroupNotFound            = errors.New("group not found")
+	ErrNotGroupAdmin            = errors.New("only the group admin can request a group statement")
+	ErrStatementKeyMissing      = errors.New("STATEMENT_SIGNING_KEY or SECRETS must be set to sign statements")
+)
+
+// StatementEntry is one ledger entry on a statement, with the balance after it
+type StatementEntry struct {
+	Date         time.Time `json:"date"`
+	Reference    string    `json:"reference"`
+	Description  string    `json:"description"`
+	Counterparty string    `json:"counterparty"`
+	Debit        float64   `json:"debit"`
+	Credit       float64   `json:"credit"`
+	Balance      float64   `json:"balance"`
+}
+
+// AccountStatement is a statement as issued: the stored record a third party
+// can verify and the entries printed on it
+type AccountStatement struct {
+	Record  database.AccountStatement
+	Entries []StatementEntry
+}
+
+// StatementVerification is what the public verify endpoint shows. It is
+// enough to compare with the document without the entries themselves.
+type StatementVerification struct {
+	Code           string    `json:"code"`
+	AccountName    string    `json:"account_name"`
+	AccountNumber  string    `json:"account_number"`
+	Bank           string    `json:"bank"`
+	PeriodStart    time.Time `json:"period_start"`
+	PeriodEnd      time.Time `json:"period_end"`
+	OpeningBalance float64   `json:"opening_balance"`
+	ClosingBalance float64   `json:"closing_balance"`
+	TotalCredits   float64   `json:"total_credits"`
+	TotalDebits    float64   `json:"total_debits"`
+	Entries        int       `json:"entries"`
+	IssuedAt       time.Time `json:"issued_at"`
+	// HashMatches is set when a hash was given to check
+	HashMatches *bool `json:"hash_matches,omitempty"`
+}
+
+// ParseStatementPeriod reads the from and to dates of a statement as Lagos
+// days. To defaults to today and from to the first of to's month. Periods are
+// capped at STATEMENT_MAX_DAYS.
+func ParseStatementPeriod(from, to string) (time.Time, time.Time, error) {
+	today := StartOfLagosDay(time.Now())
+	end := today
+	if to != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", to, lagos)
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrStatementPeriod
+		}
+		end = parsed
+	}
+	start := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, lagos)
+	if from != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", from, lagos)
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrStatementPeriod
+		}
+		start = parsed
+	}
+	switch {
+	case start.After(end):
+		return time.Time{}, time.Time{}, ErrStatementPeriod
+	case end.After(today):
+		return time.Time{}, time.Time{}, ErrStatementFutureDate
+	case end.Sub(start) >= time.Duration(envInt("STATEMENT_MAX_DAYS", 366))*24*time.Hour:
+		return time.Time{}, time.Time{}, ErrStatementPeriodTooLong
+	}
+	return start, end, nil
+}
+
+// WalletStatement issues a statement of the user's wallet for the days from
+// start to end inclusive
+func WalletStatement(userID uint, start, end time.Time, format string) (*AccountStatement, error) {
+	var user database.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	var wallet database.VirtualAccount
+	if err := database.DB.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, ErrWalletNotFound
+	}
+	return issueStatement(database.AccountStatement{
+		OwnerType:     OwnerUser,
+		OwnerID:       userID,
+		Account:       WalletAccount(wallet.VirtualAccountID),
+		AccountNumber: wallet.VirtualAccountAccount,
+		AccountName:   wallet.VirtualAccountName,
+		Bank:          wallet.VirtualAccountBank,
+		RequestedBy:   userID,
+	}, user.Tag, start, end, format)
+}
+
+// GroupWalletStatement issues a statement of a group wallet. Only the group's
+// admin, or its creator where no admin is set, may ask for one.
+func GroupWalletStatement(userID, groupID uint, start, end time.Time, format string) (*AccountStatement, error) {
+	var group database.Group
+	if err := database.DB.First(&group, groupID).Error; err != nil {
+		return nil, ErrGroupNotFound
+	}
+	adminID := group.AdminID
+	if adminID == 0 {
+		adminID = group.CreatedBy
+	}
+	if adminID != userID {
+		return nil, ErrNotGroupAdmin
+	}
+	var wallet database.GroupVirtualAccount
+	if err := database.DB.Where("group_id = ?", groupID).First(&wallet).Error; err != nil {
+		return nil, ErrWalletNotFound
+	}
+	return issueStatement(database.AccountStatement{
+		OwnerType:     OwnerGroup,
+		OwnerID:       groupID,
+		Account:       GroupWalletAccount(wallet.GroupVirtualAccountID),
+		AccountNumber: wallet.GroupVirtualAccountNumber,
+		AccountName:   wallet.GroupVirtualAccountName,
+		Bank:          wallet.GroupVirtualAccountBank,
+		RequestedBy:   userID,
+	}, group.GroupTag, start, end, format)
+}
+
+// issueStatement reads the ledger account over the period, signs what will be
+// printed and records the statement so it can be verified later
+func issueStatement(record database.AccountStatement, ownerTag string, start, end time.Time, format string) (*AccountStatement, error) {
+	if format != AccountStatementCSV && format != AccountStatementPDF {
+		return nil, ErrAccountStatementFormat
+	}
+	key, err := statementSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	until := end.AddDate(0, 0, 1)
+
+	var opening int64
+	if err := database.DB.Model(&database.LedgerEntry{}).
+		Where("account = ? AND created_at < ?", record.Account, start).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&opening).Error; err != nil {
+		return nil, err
+	}
+	var ledgerEntries []database.LedgerEntry
+	if err := database.DB.
+		Where("account = ? AND created_at >= ? AND created_at < ?", record.Account, start, until).
+		Order("created_at, entry_id").
+		Find(&ledgerEntries).Error; err != nil {
+		return nil, err
+	}
+	transactions, err := statementTransactions(ledgerEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]StatementEntry, 0, len(ledgerEntries))
+	balance := opening
+	var credits, debits int64
+	for _, ledgerEntry := range ledgerEntries {
+		balance += ledgerEntry.Amount
+		entry := StatementEntry{
+			Date:        ledgerEntry.CreatedAt,
+			Reference:   ledgerEntry.Reference,
+			Description: ledgerEntry.Description,
+			Balance:     helpers.FromKobo(balance),
+		}
+		if ledgerEntry.Amount < 0 {
+			debits -= ledgerEntry.Amount
+			entry.Debit = helpers.FromKobo(-ledgerEntry.Amount)
+		} else {
+			credits += ledgerEntry.Amount
+			entry.Credit = helpers.FromKobo(ledgerEntry.Amount)
+		}
+		if transaction, ok := transactions[ledgerEntry.Reference]; ok {
+			entry.Counterparty = counterparty(transaction, ownerTag, ledgerEntry.Amount >= 0)
+			if transaction.Description != "" {
+				entry.Description = transaction.Description
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	code, err := helpers.GenerateRandomString(16)
+	if err != nil {
+		return nil, err
+	}
+	record.Code = strings.ToUpper(code)
+	record.Format = format
+	record.PeriodStart = start
+	record.PeriodEnd = end
+	record.OpeningBalance = helpers.FromKobo(opening)
+	record.ClosingBalance = helpers.FromKobo(balance)
+	record.TotalCredits = helpers.FromKobo(credits)
+	record.TotalDebits = helpers.FromKobo(debits)
+	record.Entries = len(entries)
+	record.Hash = signStatement(key, &record, entries)
+	if err := database.DB.Create(&record).Error; err != nil {
+		return nil, err
+	}
+	return &AccountStatement{Record: record, Entries: entries}, nil
+}
+
+// statementTransactions finds the transactions behind the entries, by the
+// reference their journals were posted under
+func statementTransactions(entries []database.LedgerEntry) (map[string]database.Transaction, error) {
+	seen := make(map[string]bool)
+	var references []string
+	for _, entry := range entries {
+		if !seen[entry.Reference] {
+			seen[entry.Reference] = true
+			references = append(references, entry.Reference)
+		}
+	}
+	transactions := make(map[string]database.Transaction, len(references))
+	for i := 0; i < len(references); i += 500 {
+		var batch []database.Transaction
+		if err := database.DB.Where("reference IN ?", references[i:min(i+500, len(references))]).
+			Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		for _, transaction := range batch {
+			transactions[transaction.Reference] = transaction
+		}
+	}
+	return transactions, nil
+}
+
+// counterparty is the other side of a transaction from the account owner's
+// point of view
+func counterparty(transaction database.Transaction, ownerTag string, credit bool) string {
+	first, second := transaction.Receiver, transaction.Sender
+	if credit {
+		first, second = transaction.Sender, transaction.Receiver
+	}
+	if first == "" || first == ownerTag {
+		first = second
+	}
+	if first == ownerTag {
+		return ""
+	}
+	if first == transaction.Receiver && transaction.ReceiverBank != "" {
+		return first + " (" + transaction.ReceiverBank + ")"
+	}
+	if first == transaction.Sender && transaction.SenderBank != "" {
+		return first + " (" + transaction.SenderBank + ")"
+	}
+	return first
+}
+
+// signStatement is an HMAC-SHA256, under the signing key, of everything a
+// statement shows. Amounts are signed in kobo so rounding cannot change it.
+func signStatement(key []byte, record *database.AccountStatement, entries []StatementEntry) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s|%s|%d|%s|%s|%s|%s|%s|%d|%d|%d|%d\n",
+		record.Code, record.OwnerType, record.OwnerID, record.AccountNumber, record.AccountName, record.Bank,
+		record.PeriodStart.Format("2006-01-02"), record.PeriodEnd.Format("2006-01-02"),
+		helpers.ToKobo(record.OpeningBalance), helpers.ToKobo(record.ClosingBalance),
+		helpers.ToKobo(record.TotalCredits), helpers.ToKobo(record.TotalDebits),
+	)
+	for _, entry := range entries {
+		fmt.Fprintf(mac, "%s|%s|%s|%d|%d|%d\n",
+			entry.Date.UTC().Format(time.RFC3339Nano), entry.Reference, entry.Counterparty,
+			helpers.ToKobo(entry.Debit), helpers.ToKobo(entry.Credit), helpers.ToKobo(entry.Balance),
+		)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// statementSigningKey is STATEMENT_SIGNING_KEY, falling back to the token
+// secret. With neither set statements are refused rather than signed with an
+// empty key anyone could reproduce.
+func statementSigningKey() ([]byte, error) {
+	if key := os.Getenv("STATEMENT_SIGNING_KEY"); key != "" {
+		return []byte(key), nil
+	}
+	if key := os.Getenv("SECRETS"); key != "" {
+		return []byte(key), nil
+	}
+	return nil, ErrStatementKeyMissing
+}
+
+// VerifyStatement looks a statement up by the code printed on it. When a hash
+// is given it also reports whether it is the one the statement was signed with.
+func VerifyStatement(code, hash string) (*StatementVerification, error) {
+	var record database.AccountStatement
+	if err := database.DB.Where("code = ?", strings.ToUpper(strings.TrimSpace(code))).
+		First(&record).Error; err != nil {
+		return nil, ErrAccountStatementNotFound
+	}
+	verification := &StatementVerification{
+		Code:           record.Code,
+		AccountName:    record.AccountName,
+		AccountNumber:  maskAccountNumber(record.AccountNumber),
+		Bank:           record.Bank,
+		PeriodStart:    record.PeriodStart,
+		PeriodEnd:      record.PeriodEnd,
+		OpeningBalance: record.OpeningBalance,
+		ClosingBalance: record.ClosingBalance,
+		TotalCredits:   record.TotalCredits,
+		TotalDebits:    record.TotalDebits,
+		Entries:        record.Entries,
+		IssuedAt:       record.CreatedAt,
+	}
+	if hash != "" {
+		matches := statementHashMatches(record.Hash, hash)
+		verification.HashMatches = &matches
+	}
+	return verification, nil
+}
+
+// statementHashMatches compares a hash as it was typed or pasted with the one
+// a statement was signed with, in constant time
+func statementHashMatches(signed, given string) bool {
+	given = strings.ToLower(strings.TrimSpace(given))
+	return signed != "" && subtle.ConstantTimeCompare([]byte(given), []byte(signed)) == 1
+}
+
+// maskAccountNumber keeps the last four digits
+func maskAccountNumber(number string) string {
+	if len(number) <= 4 {
+		return number
+	}
+	return strings.Repeat("*", len(number)-4) + number[len(number)-4:]
+}
+
+// StatementFileName is the download name of a statement
+func StatementFileName(statement *AccountStatement) string {
+	return fmt.Sprintf("olra-statement-%s-%s-%s.%s",
+		statement.Record.AccountNumber,
+		statement.Record.PeriodStart.Format("20060102"),
+		statement.Record.PeriodEnd.Format("20060102"),
+		statement.Record.Format,
+	)
+}
+
+// statementVerifyURL is where a statement says it can be checked,
+// STATEMENT_VERIFY_URL followed by the code
+func statementVerifyURL(code string) string {
+	base := os.Getenv("STATEMENT_VERIFY_URL")
+	if base == "" {
+		base = "/api/v1/statements/verify"
+	}
+	return strings.TrimRight(base, "/") + "/" + code
+}
+
+// statementAmount is an amount without the currency, for statement columns
+func statementAmount(amount float64) string {
+	return strings.Replace(templates.FormatAmount(templates.DefaultLocale, amount), "NGN ", "", 1)
+}
+
+// WriteStatementCSV writes the statement details, the opening balance, the
+// entries and the closing balance, then the verification code and hash
+func WriteStatementCSV(statement *AccountStatement, w io.Writer) error {
+	record := statement.Record
+	out := csv.NewWriter(w)
+	rows := [][]string{
+		{"Account name", record.AccountName},
+		{"Account number", record.AccountNumber},
+		{"Bank", record.Bank},
+		{"Period", record.PeriodStart.Format("2006-01-02"), record.PeriodEnd.Format("2006-01-02")},
+		{},
+		{"Date", "Reference", "Counterparty", "Description", "Debit", "Credit", "Balance"},
+		{record.PeriodStart.Format("2006-01-02"), "", "", "Opening balance", "", "", fmt.Sprintf("%.2f", record.OpeningBalance)},
+	}
+	for _, entry := range statement.Entries {
+		row := []string{
+			entry.Date.In(lagos).Format(time.RFC3339), entry.Reference, entry.Counterparty, entry.Description,
+			"", "", fmt.Sprintf("%.2f", entry.Balance),
+		}
+		if entry.Debit > 0 {
+			row[4] = fmt.Sprintf("%.2f", entry.Debit)
+		}
+		if entry.Credit > 0 {
+			row[5] = fmt.Sprintf("%.2f", entry.Credit)
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows,
+		[]string{record.PeriodEnd.Format("2006-01-02"), "", "", "Closing balance",
+			fmt.Sprintf("%.2f", record.TotalDebits), fmt.Sprintf("%.2f", record.TotalCredits),
+			fmt.Sprintf("%.2f", record.ClosingBalance)},
+		[]string{},
+		[]string{"Verification code", record.Code},
+		[]string{"Verification hash", record.Hash},
+		[]string{"Verify at", statementVerifyURL(record.Code)},
+	)
+	if err := out.WriteAll(rows); err != nil {
+		return err
+	}
+	return out.Error()
+}
+
+var (
+	statementBrand = pdf.RGB(0x4b, 0x2e, 0xe0)
+	statementText  = pdf.RGB(0x1d, 0x1d, 0x1f)
+	statementMuted = pdf.RGB(0x86, 0x86, 0x8b)
+	statementRule  = pdf.RGB(0xe5, 0xe5, 0xea)
+	statementShade = pdf.RGB(0xf5, 0xf5, 0xf7)
+	statementWhite = pdf.RGB(0xff, 0xff, 0xff)
+)
+
+// The page margin, then the left edges of the statement table's text
+// columns and the right edges of its amount columns
+const (
+	statementMargin = 40
+
+	columnDate         = 40
+	columnReference    = 100
+	columnCounterparty = 192
+	columnDescription  = 290
+	columnDebit        = 435
+	columnCredit       = 495
+	columnBalance      = 555
+)
+
+// WriteStatementPDF lays the statement out on A4 pages under the Olra
+// header, with the verification code and hash in every page's footer
+func WriteStatementPDF(statement *AccountStatement, w io.Writer) error {
+	record := statement.Record
+	period := templates.FormatDate(templates.DefaultLocale, record.PeriodStart) + " - " +
+		templates.FormatDate(templates.DefaultLocale, record.PeriodEnd)
+
+	doc := pdf.New()
+	doc.Title = "Olra account statement " + record.AccountNumber
+	doc.Author = "Olra"
+	doc.Subject = period
+	doc.Keywords = "verification-code:" + record.Code + " verification-hash:" + record.Hash
+	doc.Created = record.CreatedAt
+
+	var pages []*pdf.Page
+	var page *pdf.Page
+	var y float64
+	newPage := func() {
+		page = doc.AddPage()
+		pages = append(pages, page)
+		if len(pages) == 1 {
+			page.Rect(0, 0, pdf.PageWidth, 76, statementBrand)
+			page.Text(statementMargin, 46, pdf.HelveticaBold, 26, statementWhite, "Olra")
+			page.TextRight(columnBalance, 38, pdf.HelveticaBold, 13, statementWhite, "Account Statement")
+			page.TextRight(columnBalance, 54, pdf.Helvetica, 9, statementWhite, period)
+			y = statementDetails(page, &record)
+		} else {
+			page.Rect(0, 0, pdf.PageWidth, 36, statementBrand)
+			page.Text(statementMargin, 24, pdf.HelveticaBold, 14, statementWhite, "Olra")
+			page.TextRight(columnBalance, 23, pdf.Helvetica, 8, statementWhite,
+				record.AccountNumber+"  |  "+period)
+			y = 60
+		}
+		page.Rect(statementMargin, y, pdf.PageWidth-2*statementMargin, 18, statementShade)
+		y += 12
+		for _, heading := range []struct {
+			x     float64
+			right bool
+			text  string
+		}{
+			{columnDate + 2, false, "Date"},
+			{columnReference, false, "Reference"},
+			{columnCounterparty, false, "Counterparty"},
+			{columnDescription, false, "Description"},
+			{columnDebit, true, "Debit"},
+			{columnCredit, true, "Credit"},
+			{columnBalance - 2, true, "Balance"},
+		} {
+			if heading.right {
+				page.TextRight(heading.x, y, pdf.HelveticaBold, 7.5, statementText, heading.text)
+			} else {
+				page.Text(heading.x, y, pdf.HelveticaBold, 7.5, statementText, heading.text)
+			}
+		}
+		y += 6
+	}
+	row := func(date, reference, counterparty, description, debit, credit, balance string, font pdf.Font) {
+		if y+14 > pdf.PageHeight-70 {
+			newPage()
+		}
+		y += 14
+		page.Text(columnDate+2, y-4, font, 7, statementText, date)
+		page.Text(columnReference, y-4, font, 7, statementText,
+			pdf.Fit(font, 7, columnCounterparty-columnReference-6, reference))
+		page.Text(columnCounterparty, y-4, font, 7, statementText,
+			pdf.Fit(font, 7, columnDescription-columnCounterparty-6, counterparty))
+		page.Text(columnDescription, y-4, font, 7, statementText,
+			pdf.Fit(font, 7, columnDebit-columnDescription-50, description))
+		page.TextRight(columnDebit, y-4, font, 7, statementText, debit)
+		page.TextRight(columnCredit, y-4, font, 7, statementText, credit)
+		page.TextRight(columnBalance-2, y-4, font, 7, statementText, balance)
+		page.Line(statementMargin, y, pdf.PageWidth-statementMargin, y, 0.5, statementRule)
+	}
+
+	newPage()
+	row(record.PeriodStart.Format("02/01/2006"), "", "", "Opening balance", "", "",
+		statementAmount(record.OpeningBalance), pdf.HelveticaBold)
+	for _, entry := range statement.Entries {
+		var debit, credit string
+		if entry.Debit > 0 {
+			debit = statementAmount(entry.Debit)
+		}
+		if entry.Credit > 0 {
+			credit = statementAmount(entry.Credit)
+		}
+		row(entry.Date.In(lagos).Format("02/01/2006 15:04"), entry.Reference, entry.Counterparty,
+			entry.Description, debit, credit, statementAmount(entry.Balance), pdf.Helvetica)
+	}
+	row(record.PeriodEnd.Format("02/01/2006"), "", "", "Closing balance",
+		statementAmount(record.TotalDebits), statementAmount(record.TotalCredits),
+		statementAmount(record.ClosingBalance), pdf.HelveticaBold)
+
+	// The page count is only known once every row is placed
+	for i, page := range pages {
+		footer := pdf.PageHeight - 52
+		page.Line(statementMargin, footer, pdf.PageWidth-statementMargin, footer, 0.5, statementRule)
+		page.Text(statementMargin, footer+14, pdf.HelveticaBold, 7, statementText,
+			"Verification code: "+record.Code)
+		page.Text(statementMargin, footer+25, pdf.Helvetica, 6.5, statementMuted,
+			"Verification hash (HMAC-SHA256): "+record.Hash)
+		page.Text(statementMargin, footer+36, pdf.Helvetica, 6.5, statementMuted,
+			"Confirm this statement is genuine at "+statementVerifyURL(record.Code))
+		page.TextRight(columnBalance, footer+14, pdf.Helvetica, 7, statementMuted,
+			fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+	}
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// statementDetails prints the account details and the summary under the
+// first page's header and returns where the table starts
+func statementDetails(page *pdf.Page, record *database.AccountStatement) float64 {
+	details := [][2]string{
+		{"Account name", record.AccountName},
+		{"Account number", record.AccountNumber},
+		{"Bank", record.Bank},
+		{"Issued", record.CreatedAt.In(lagos).Format("02/01/2006 15:04") + " WAT"},
+	}
+	y := 104.0
+	for _, detail := range details {
+		page.Text(statementMargin, y, pdf.Helvetica, 8, statementMuted, detail[0])
+		page.Text(statementMargin+70, y, pdf.HelveticaBold, 8.5, statementText,
+			pdf.Fit(pdf.HelveticaBold, 8.5, 200, detail[1]))
+		y += 15
+	}
+
+	summary := [][2]string{
+		{"Opening balance", templates.FormatAmount(templates.DefaultLocale, record.OpeningBalance)},
+		{"Money in", templates.FormatAmount(templates.DefaultLocale, record.TotalCredits)},
+		{"Money out", templates.FormatAmount(templates.DefaultLocale, record.TotalDebits)},
+		{"Closing balance", templates.FormatAmount(templates.DefaultLocale, record.ClosingBalance)},
+	}
+	left := 340.0
+	page.Rect(left, 90, columnBalance-left, 66, statementShade)
+	y = 104
+	for i, line := range summary {
+		font := pdf.Helvetica
+		if i == len(summary)-1 {
+			font = pdf.HelveticaBold
+		}
+		page.Text(left+10, y, font, 8, statementText, line[0])
+		page.TextRight(columnBalance-10, y, font, 8, statementText, line[1])
+		y += 15
+	}
+	return 176
+}
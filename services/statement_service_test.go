@@ -0,0 +1,322 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+func testStatement() (database.AccountStatement, []StatementEntry) {
+	record := database.AccountStatement{
+		Code:           "ST7K2M9Q4X",
+		OwnerType:      OwnerUser,
+		OwnerID:        42,
+		AccountNumber:  "0123456789",
+		AccountName:    "Ada Obi",
+		Bank:           "Wema Bank",
+		PeriodStart:    time.Date(2024, 6, 1, 0, 0, 0, 0, lagos),
+		PeriodEnd:      time.Date(2024, 6, 30, 0, 0, 0, 0, lagos),
+		OpeningBalance: 1000,
+		ClosingBalance: 5500.5,
+		TotalCredits:   5000,
+		TotalDebits:    499.5,
+	}
+	entries := []StatementEntry{
+		{
+			Date:         time.Date(2024, 6, 3, 10, 0, 0, 0, lagos),
+			Reference:    "REF1",
+			Counterparty: "@tunde",
+			Credit:       5000,
+			Balance:      6000,
+		},
+		{
+			Date:         time.Date(2024, 6, 9, 15, 30, 0, 0, lagos),
+			Reference:    "REF2",
+			Counterparty: "MTN",
+			Debit:        499.5,
+			Balance:      5500.5,
+		},
+	}
+	return record, entries
+}
+
+func TestSignStatement(t *testing.T) {
+	key := []byte("statement-key")
+	record, entries := testStatement()
+	signed := signStatement(key, &record, entries)
+	if len(signed) != 64 || strings.ToLower(signed) != signed {
+		t.Fatalf("signStatement() = %q, want 64 lowercase hex digits", signed)
+	}
+	if again := signStatement(key, &record, entries); again != signed {
+		t.Errorf("signStatement() is not deterministic: %q then %q", signed, again)
+	}
+
+	// Fields that are not printed do not change the hash
+	unprinted := record
+	unprinted.ID, unprinted.Format, unprinted.RequestedBy, unprinted.Hash = 9, AccountStatementCSV, 7, signed
+	if got := signStatement(key, &unprinted, entries); got != signed {
+		t.Errorf("signStatement() changed with fields that are not printed")
+	}
+
+	tests := []struct {
+		name   string
+		key    []byte
+		change func(record *database.AccountStatement, entries []StatementEntry) []StatementEntry
+	}{
+		{
+			name: "another key",
+			key:  []byte("another-key"),
+		},
+		{
+			name: "account number",
+			change: func(record *database.AccountStatement, entries []StatementEntry) []StatementEntry {
+				record.AccountNumber = "0123456788"
+				return entries
+			},
+		},
+		{
+			name: "period",
+			change: func(record *database.AccountStatement, entries []StatementEntry) []StatementEntry {
+				record.PeriodEnd = record.PeriodEnd.AddDate(0, 0, 1)
+				return entries
+			},
+		},
+		{
+			name: "closing balance by a kobo",
+			change: func(record *database.AccountStatement, entries []StatementEntry) []StatementEntry {
+				record.ClosingBalance += 0.01
+				return entries
+			},
+		},
+		{
+			name: "entry amount",
+			change: func(record *database.AccountStatement, entries []StatementEntry) []StatementEntry {
+				entries[1].Debit = 4995
+				return entries
+			},
+		},
+		{
+			name: "entry counterparty",
+			change: func(record *database.AccountStatement, entries []StatementEntry) []StatementEntry {
+				entries[0].Counterparty = "@someone"
+				return entries
+			},
+		},
+		{
+			name: "entry removed",
+			change: func(record *database.AccountStatement, entries []StatementEntry) []StatementEntry {
+				return entries[:1]
+			},
+		},
+		{
+			name: "entries reordered",
+			change: func(record *database.AccountStatement, entries []StatementEntry) []StatementEntry {
+				return []StatementEntry{entries[1], entries[0]}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, entries := testStatement()
+			key := key
+			if tt.key != nil {
+				key = tt.key
+			}
+			if tt.change != nil {
+				entries = tt.change(&record, entries)
+			}
+			if got := signStatement(key, &record, entries); got == signed {
+				t.Errorf("signStatement() did not change")
+			}
+		})
+	}
+}
+
+func TestStatementHashMatches(t *testing.T) {
+	record, entries := testStatement()
+	signed := signStatement([]byte("statement-key"), &record, entries)
+	offByOne := signed[:63] + "0"
+	if signed[63] == '0' {
+		offByOne = signed[:63] + "1"
+	}
+	tests := []struct {
+		name   string
+		signed string
+		given  string
+		want   bool
+	}{
+		{"same hash", signed, signed, true},
+		{"upper case", signed, strings.ToUpper(signed), true},
+		{"surrounding whitespace", signed, " " + signed + "\n", true},
+		{"one digit off", signed, offByOne, false},
+		{"truncated", signed, signed[:32], false},
+		{"signed with another key", signed, signStatement([]byte("another-key"), &record, entries), false},
+		{"empty", signed, "", false},
+		{"nothing signed", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statementHashMatches(tt.signed, tt.given); got != tt.want {
+				t.Errorf("statementHashMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatementSigningKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		signingKey string
+		secrets    string
+		want       string
+		err        error
+	}{
+		{"signing key", "statement-key", "token-secret", "statement-key", nil},
+		{"token secret", "", "token-secret", "token-secret", nil},
+		{"neither set", "", "", "", ErrStatementKeyMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STATEMENT_SIGNING_KEY", tt.signingKey)
+			t.Setenv("SECRETS", tt.secrets)
+			got, err := statementSigningKey()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("statementSigningKey() error = %v, want %v", err, tt.err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("statementSigningKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStatementPeriod(t *testing.T) {
+	tomorrow := StartOfLagosDay(time.Now()).AddDate(0, 0, 1).Format("2006-01-02")
+	tests := []struct {
+		name      string
+		from, to  string
+		wantStart time.Time
+		wantEnd   time.Time
+		err       error
+	}{
+		{
+			name:      "both dates",
+			from:      "2024-06-01",
+			to:        "2024-06-30",
+			wantStart: time.Date(2024, 6, 1, 0, 0, 0, 0, lagos),
+			wantEnd:   time.Date(2024, 6, 30, 0, 0, 0, 0, lagos),
+		},
+		{
+			name:      "from defaults to the first of the month",
+			to:        "2024-06-14",
+			wantStart: time.Date(2024, 6, 1, 0, 0, 0, 0, lagos),
+			wantEnd:   time.Date(2024, 6, 14, 0, 0, 0, 0, lagos),
+		},
+		{
+			name:      "one day",
+			from:      "2024-06-14",
+			to:        "2024-06-14",
+			wantStart: time.Date(2024, 6, 14, 0, 0, 0, 0, lagos),
+			wantEnd:   time.Date(2024, 6, 14, 0, 0, 0, 0, lagos),
+		},
+		{name: "from after to", from: "2024-06-15", to: "2024-06-14", err: ErrStatementPeriod},
+		{name: "bad from", from: "01/06/2024", to: "2024-06-14", err: ErrStatementPeriod},
+		{name: "bad to", to: "June", err: ErrStatementPeriod},
+		{name: "ends in the future", to: tomorrow, err: ErrStatementFutureDate},
+		{name: "too long", from: "2022-01-01", to: "2024-06-14", err: ErrStatementPeriodTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := ParseStatementPeriod(tt.from, tt.to)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseStatementPeriod(%q, %q) error = %v, want %v", tt.from, tt.to, err, tt.err)
+			}
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("ParseStatementPeriod(%q, %q) = %v, %v, want %v, %v",
+					tt.from, tt.to, start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestMaskAccountNumber(t *testing.T) {
+	tests := []struct {
+		number string
+		want   string
+	}{
+		{"0123456789", "******6789"},
+		{"12345", "*2345"},
+		{"1234", "1234"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := maskAccountNumber(tt.number); got != tt.want {
+			t.Errorf("maskAccountNumber(%q) = %q, want %q", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestWalletStatement(t *testing.T) {
+	t.Setenv("STATEMENT_SIGNING_KEY", "statement-key")
+	db := dbtest.Open(t, append([]interface{}{&database.AccountStatement{}}, walletModels...)...)
+	sender, senderWallet := createWalletUser(t, db, "ada", 100000)
+	receiver, receiverWallet := createWalletUser(t, db, "tunde", 0)
+	// Funded before the period, so it only shows in the opening balance
+	db.Model(&database.LedgerEntry{}).Where("reference LIKE ?", "fund-%").
+		Update("created_at", time.Now().AddDate(0, 0, -3))
+	transaction, err := TransferToUser(sender, receiver, senderWallet, receiverWallet, 400, "rent", ChannelApp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	today := StartOfLagosDay(time.Now())
+	statement, err := WalletStatement(sender.UserID, today.AddDate(0, 0, -1), today, AccountStatementCSV)
+	if err != nil {
+		t.Fatalf("WalletStatement() error = %v", err)
+	}
+	record := statement.Record
+	if record.OpeningBalance != 1000 || record.TotalDebits != 400 || record.TotalCredits != 0 || record.ClosingBalance != 600 {
+		t.Errorf("statement = opening %v, debits %v, credits %v, closing %v, want 1000, 400, 0, 600",
+			record.OpeningBalance, record.TotalDebits, record.TotalCredits, record.ClosingBalance)
+	}
+	if len(statement.Entries) != 1 {
+		t.Fatalf("%d entries, want 1: %+v", len(statement.Entries), statement.Entries)
+	}
+	entry := statement.Entries[0]
+	if entry.Reference != transaction.Reference || entry.Counterparty != receiver.Tag || entry.Debit != 400 || entry.Balance != 600 {
+		t.Errorf("entry = %+v, want a debit of 400 to %s under %s", entry, receiver.Tag, transaction.Reference)
+	}
+
+	// The statement can be verified by its code and hash
+	verification, err := VerifyStatement(strings.ToLower(record.Code), record.Hash)
+	if err != nil {
+		t.Fatalf("VerifyStatement() error = %v", err)
+	}
+	if verification.HashMatches == nil || !*verification.HashMatches {
+		t.Errorf("VerifyStatement() hash matches = %v, want true", verification.HashMatches)
+	}
+}
+
+func TestGroupWalletStatementAdminOnly(t *testing.T) {
+	t.Setenv("STATEMENT_SIGNING_KEY", "statement-key")
+	db := dbtest.Open(t, append([]interface{}{&database.AccountStatement{}, &database.Group{},
+		&database.GroupVirtualAccount{}}, walletModels...)...)
+	admin, _ := createWalletUser(t, db, "ada", 0)
+	member, _ := createWalletUser(t, db, "tunde", 0)
+	group := database.Group{GroupName: "Savers", GroupTag: "savers", CreatedBy: admin.UserID, AdminID: admin.UserID}
+	db.Create(&group)
+	db.Create(&database.GroupVirtualAccount{GroupID: group.GroupID, GroupVirtualAccountBank: "Wema Bank",
+		GroupVirtualAccountNumber: "9100000001", GroupVirtualAccountName: "Savers"})
+
+	today := StartOfLagosDay(time.Now())
+	if _, err := GroupWalletStatement(member.UserID, group.GroupID, today, today, AccountStatementPDF); !errors.Is(err, ErrNotGroupAdmin) {
+		t.Errorf("GroupWalletStatement() by a member error = %v, want %v", err, ErrNotGroupAdmin)
+	}
+	if _, err := GroupWalletStatement(admin.UserID, group.GroupID, today, today, AccountStatementPDF); err != nil {
+		t.Errorf("GroupWalletStatement() by the admin error = %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/templates"
+	helpers "olra-v1/utils"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("Insufficient balance to send")
+	ErrInvalidAmount       = errors.New("amount must be greater than zero")
+)
+
+// lockWallets re-reads the wallets inside tx and holds them until it ends so
+// concurrent transfers cannot spend the same balance twice. Wallets are locked
+// in id order so two opposite transfers cannot deadlock.
+func lockWallets(tx *gorm.DB, wallets ...*database.VirtualAccount) error {
+	if len(wallets) == 2 && wallets[0].VirtualAccountID > wallets[1].VirtualAccountID {
+		wallets = []*database.VirtualAccount{wallets[1], wallets[0]}
+	}
+	for _, wallet := range wallets {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(wallet, wallet.VirtualAccountID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withFee adds the fee to the payer's leg, the first one, and credits it to
+// fee income so the charge posts in the same journal as the payment
+func withFee(quote *FeeQuote, payer Leg, legs ...Leg) []Leg {
+	fee := helpers.ToKobo(quote.Fee)
+	if fee == 0 {
+		return append([]Leg{payer}, legs...)
+	}
+	payer.Amount -= fee
+	return append(append([]Leg{payer}, legs...), Leg{Account: SystemFeeIncomeAccount, Amount: fee})
+}
+
+// TransferToUser moves funds between two Olra wallets. The ledger journal, the
+// fee, the transaction record and both notifications are committed together;
+// delivery of the notifications happens later and cannot affect the transfer.
+func TransferToUser(
+	sender, receiver *database.User,
+	senderWallet, receiverWallet *database.VirtualAccount,
+	amount float64,
+	description, channel string,
+) (*database.Transaction, error) {
+	// A negative amount would flip the journal legs and pay the sender
+	if helpers.ToKobo(amount) <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	transaction := database.Transaction{
+		UserID:             sender.UserID,
+		Reference:          helpers.GenerateTransactionReference(),
+		TransactionEnviron: "withinOlra",
+		TransactionType:    "olraTransfer-out",
+		Amount:             amount,
+		Description:        description,
+		Receiver:           receiver.Tag,
+		Sender:             sender.Tag,
+		Status:             "completed",
+		TransactionDate:    time.Now(),
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockWallets(tx, senderWallet, receiverWallet); err != nil {
+			return err
+		}
+		// Closure locks the wallet too, so this sees any closure committed
+		// before the lock was taken
+		var recipient database.User
+		if err := tx.Select("status").Where("user_id = ?", receiver.UserID).First(&recipient).Error; err != nil {
+			return err
+		}
+		if recipient.Status == "closed" {
+			return ErrRecipientClosed
+		}
+		quote, err := QuoteFee(tx, sender, PricedOlraTransfer, channel, amount)
+		if err != nil {
+			return err
+		}
+		if err := checkAvailable(tx, senderWallet, quote.Total); err != nil {
+			return err
+		}
+		transaction.Fee = quote.Fee
+		kobo := helpers.ToKobo(amount)
+		if err := PostJournal(tx, transaction.Reference, "Transfer to "+receiver.Tag,
+			withFee(quote,
+				Leg{Account: WalletAccount(senderWallet.VirtualAccountID), Amount: -kobo},
+				Leg{Account: WalletAccount(receiverWallet.VirtualAccountID), Amount: kobo},
+			)...,
+		); err != nil {
+			return err
+		}
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+		// Re-read so the notifications carry the balances after the journal
+		balanceBefore := senderWallet.Balance
+		if err := lockWallets(tx, senderWallet, receiverWallet); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := Dispatch(tx, receiver, Notice{
+			Type:         NotificationCredit,
+			Event:        templates.EventCredit,
+			ResourceType: ResourceTransaction,
+			ResourceID:   transaction.Reference,
+			Data: templates.Data{
+				"Tag":     sender.Tag,
+				"Amount":  amount,
+				"Balance": receiverWallet.Balance,
+				"Date":    now,
+			},
+		}); err != nil {
+			return err
+		}
+		if err := Dispatch(tx, sender, Notice{
+			Type:         NotificationDebit,
+			Event:        templates.EventDebit,
+			ResourceType: ResourceTransaction,
+			ResourceID:   transaction.Reference,
+			Data: templates.Data{
+				"Amount":  quote.Total,
+				"Balance": senderWallet.Balance,
+				"Date":    now,
+			},
+		}); err != nil {
+			return err
+		}
+		return dispatchLowBalance(tx, sender, balanceBefore, senderWallet.Balance)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// TransferToGroup moves funds from a wallet into a group wallet with the same
+// guarantees as TransferToUser
+func TransferToGroup(
+	sender *database.User,
+	senderWallet *database.VirtualAccount,
+	group *database.Group,
+	groupWallet *database.GroupVirtualAccount,
+	amount float64,
+	description, channel string,
+) (*database.Transaction, error) {
+	// A negative amount would flip the journal legs and pay the sender
+	if helpers.ToKobo(amount) <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	transaction := database.Transaction{
+		UserID:             sender.UserID,
+		Reference:          helpers.GenerateTransactionReference(),
+		TransactionEnviron: "withinOlra",
+		TransactionType:    "group-payment",
+		Amount:             amount,
+		Description:        description,
+		Receiver:           group.GroupTag,
+		Sender:             sender.Tag,
+		Status:             "completed",
+		TransactionDate:    time.Now(),
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockWallets(tx, senderWallet); err != nil {
+			return err
+		}
+		quote, err := QuoteFee(tx, sender, PricedGroupPayment, channel, amount)
+		if err != nil {
+			return err
+		}
+		if err := checkAvailable(tx, senderWallet, quote.Total); err != nil {
+			return err
+		}
+		transaction.Fee = quote.Fee
+		kobo := helpers.ToKobo(amount)
+		if err := PostJournal(tx, transaction.Reference, "Transfer to group "+group.GroupName,
+			withFee(quote,
+				Leg{Account: WalletAccount(senderWallet.VirtualAccountID), Amount: -kobo},
+				Leg{Account: GroupWalletAccount(groupWallet.GroupVirtualAccountID), Amount: kobo},
+			)...,
+		); err != nil {
+			return err
+		}
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+		balanceBefore := senderWallet.Balance
+		if err := lockWallets(tx, senderWallet); err != nil {
+			return err
+		}
+		if err := Dispatch(tx, sender, Notice{
+			Type:         NotificationDebit,
+			Event:        templates.EventGroupDebit,
+			ResourceType: ResourceTransaction,
+			ResourceID:   transaction.Reference,
+			Data: templates.Data{
+				"Group":   group.GroupName,
+				"Amount":  quote.Total,
+				"Balance": senderWallet.Balance,
+			},
+		}); err != nil {
+			return err
+		}
+		if err := DispatchGroupContribution(tx, group, sender, amount); err != nil {
+			return err
+		}
+		return dispatchLowBalance(tx, sender, balanceBefore, senderWallet.Balance)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+func TestTransferToUser(t *testing.T) {
+	db := dbtest.Open(t, walletModels...)
+	sender, senderWallet := createWalletUser(t, db, "ada", 100000)
+	receiver, receiverWallet := createWalletUser(t, db, "tunde", 0)
+
+	transaction, err := TransferToUser(sender, receiver, senderWallet, receiverWallet, 400, "lunch", ChannelApp)
+	if err != nil {
+		t.Fatalf("TransferToUser() error = %v", err)
+	}
+	assertLedgerBalance(t, WalletAccount(senderWallet.VirtualAccountID), 60000)
+	assertLedgerBalance(t, WalletAccount(receiverWallet.VirtualAccountID), 40000)
+	if senderWallet.Balance != 600 || receiverWallet.Balance != 400 {
+		t.Errorf("cached balances = %v and %v, want 600 and 400", senderWallet.Balance, receiverWallet.Balance)
+	}
+	var recorded database.Transaction
+	if err := db.Where("reference = ?", transaction.Reference).First(&recorded).Error; err != nil {
+		t.Fatalf("transaction not recorded: %v", err)
+	}
+	if recorded.UserID != sender.UserID || recorded.Receiver != receiver.Tag || recorded.Status != "completed" {
+		t.Errorf("transaction = %+v, want a completed transfer from %s to %s", recorded, sender.Tag, receiver.Tag)
+	}
+}
+
+func TestTransferToUserRefused(t *testing.T) {
+	db := dbtest.Open(t, walletModels...)
+	sender, senderWallet := createWalletUser(t, db, "ada", 100000)
+	receiver, receiverWallet := createWalletUser(t, db, "tunde", 0)
+
+	tests := []struct {
+		name   string
+		amount float64
+		err    error
+	}{
+		{"zero", 0, ErrInvalidAmount},
+		{"negative", -500, ErrInvalidAmount},
+		{"less than a kobo", 0.004, ErrInvalidAmount},
+		{"more than the wallet holds", 1000.01, ErrInsufficientBalance},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := TransferToUser(sender, receiver, senderWallet, receiverWallet, tt.amount, "", ChannelApp); !errors.Is(err, tt.err) {
+				t.Fatalf("TransferToUser() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+	assertLedgerBalance(t, WalletAccount(senderWallet.VirtualAccountID), 100000)
+	assertLedgerBalance(t, WalletAccount(receiverWallet.VirtualAccountID), 0)
+	var transactions int64
+	db.Model(&database.Transaction{}).Count(&transactions)
+	if transactions != 0 {
+		t.Errorf("%d transactions recorded, want none", transactions)
+	}
+}
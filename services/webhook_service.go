@@ -0,0 +1,298 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/structs"
+)
+
+// Callback request types sent by Wema in Data.Request
+const (
+	WebhookWalletCreation  = 1
+	WebhookAccountCreation = 2
+	WebhookPinValidation   = 3
+	WebhookPaymentResponse = 4
+	WebhookInboundCredit   = 5
+)
+
+const (
+	WebhookReceived  = "received"
+	WebhookProcessed = "processed"
+	WebhookFailed    = "failed"
+	WebhookInvalid   = "invalid" // the body could not be parsed
+)
+
+// Callbacks signed more than this long ago, or this far in the future, are
+// treated as replays
+const webhookTolerance = 5 * time.Minute
+
+var (
+	ErrWebhookSignature     = errors.New("invalid webhook signature")
+	ErrWebhookTimestamp     = errors.New("webhook timestamp outside the allowed window")
+	ErrWebhookNotConfigured = errors.New("webhook secret not configured")
+	ErrWebhookDuplicate     = errors.New("webhook event already processed")
+	ErrWebhookUnknown       = errors.New("unknown webhook request type")
+	ErrWebhookNoMatch       = errors.New("no matching record for webhook")
+	ErrWebhookNotFound      = errors.New("webhook event not found")
+	ErrWebhookInvalid       = errors.New("invalid webhook body")
+)
+
+// WebhookHeaders are the values the partner sends alongside the body
+type WebhookHeaders struct {
+	Signature string
+	Secret    string
+	Timestamp string
+	EventID   string
+}
+
+// VerifyWebhook authenticates a callback. With WEMA_WEBHOOK_MODE=secret the
+// partner sends the shared secret itself; otherwise it sends an HMAC-SHA256
+// of "<timestamp>.<body>" keyed by the secret. Either way the timestamp must
+// be recent so a captured request cannot be replayed later.
+func VerifyWebhook(body []byte, headers WebhookHeaders, now time.Time) error {
+	secret := os.Getenv("WEMA_WEBHOOK_SECRET")
+	if secret == "" {
+		return ErrWebhookNotConfigured
+	}
+	timestamp, err := strconv.ParseInt(headers.Timestamp, 10, 64)
+	if err != nil {
+		return ErrWebhookTimestamp
+	}
+	if math.Abs(now.Sub(time.Unix(timestamp, 0)).Seconds()) > webhookTolerance.Seconds() {
+		return ErrWebhookTimestamp
+	}
+
+	if os.Getenv("WEMA_WEBHOOK_MODE") == "secret" {
+		if !hmac.Equal([]byte(headers.Secret), []byte(secret)) {
+			return ErrWebhookSignature
+		}
+		return nil
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(headers.Timestamp + "."))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(headers.Signature), []byte(expected)) {
+		return ErrWebhookSignature
+	}
+	return nil
+}
+
+// webhookEventID is the partner's event id, or a hash of the body when it
+// sends none, so a redelivered callback is recognised either way
+func webhookEventID(body []byte, headerID string) string {
+	if headerID != "" {
+		return headerID
+	}
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+type webhookHandler func(tx *gorm.DB, data structs.Data) error
+
+var webhookHandlers = map[int]webhookHandler{
+	WebhookWalletCreation:  handleNubanCreated,
+	WebhookAccountCreation: handleNubanCreated,
+	WebhookPinValidation:   handlePinValidation,
+	WebhookPaymentResponse: handlePaymentResponse,
+	WebhookInboundCredit:   handleInboundCredit,
+}
+
+// ReceiveWebhook stores the raw callback and, unless it has already been
+// processed, hands it to the handler for its request type. A body that cannot
+// be parsed is stored as invalid. A failed event is kept and processed again
+// when the partner redelivers it.
+func ReceiveWebhook(provider string, body []byte, eventID string) (*database.WebhookEvent, error) {
+	event := database.WebhookEvent{
+		Provider: provider,
+		EventID:  webhookEventID(body, eventID),
+		Payload:  string(body),
+		Status:   WebhookReceived,
+	}
+	var callback structs.CallbackData
+	parseErr := json.Unmarshal(body, &callback)
+	if parseErr != nil {
+		event.Status = WebhookInvalid
+		event.Error = parseErr.Error()
+	} else {
+		event.Request = callback.Data.Request
+	}
+	// Concurrent deliveries of one event insert a single row between them
+	if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&event).Error; err != nil {
+		return nil, err
+	}
+	if event.ID == 0 {
+		if err := database.DB.Where("provider = ? AND event_id = ?", event.Provider, event.EventID).
+			First(&event).Error; err != nil {
+			return nil, err
+		}
+	}
+	if parseErr != nil {
+		return &event, fmt.Errorf("%w: %v", ErrWebhookInvalid, parseErr)
+	}
+	return &event, processWebhook(&event, callback.Data)
+}
+
+// ReplayWebhook runs a stored event through its handler again, for events
+// that failed because our records were not ready yet
+func ReplayWebhook(id uint) (*database.WebhookEvent, error) {
+	var event database.WebhookEvent
+	if err := database.DB.First(&event, id).Error; err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	if event.Status == WebhookProcessed {
+		return &event, ErrWebhookDuplicate
+	}
+	var callback structs.CallbackData
+	if err := json.Unmarshal([]byte(event.Payload), &callback); err != nil {
+		return &event, fmt.Errorf("%w: %v", ErrWebhookInvalid, err)
+	}
+	return &event, processWebhook(&event, callback.Data)
+}
+
+// processWebhook runs the handler with the event row locked, so a redelivery
+// arriving meanwhile waits and then finds the event processed. The handler
+// runs in a savepoint: if it fails its writes are undone but the failure is
+// still recorded on the event.
+func processWebhook(event *database.WebhookEvent, data structs.Data) error {
+	var handlerErr error
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(event, event.ID).Error; err != nil {
+			return err
+		}
+		if event.Status == WebhookProcessed {
+			return ErrWebhookDuplicate
+		}
+		handler, ok := webhookHandlers[data.Request]
+		if ok {
+			handlerErr = tx.Transaction(func(tx *gorm.DB) error {
+				return handler(tx, data)
+			})
+		} else {
+			handlerErr = fmt.Errorf("%w: %d", ErrWebhookUnknown, data.Request)
+		}
+		return markWebhook(tx, event, handlerErr)
+	})
+	if err != nil {
+		return err
+	}
+	return handlerErr
+}
+
+// markWebhook records the outcome of handling an event. It only fails if the
+// update does.
+func markWebhook(tx *gorm.DB, event *database.WebhookEvent, err error) error {
+	now := time.Now()
+	updates := map[string]interface{}{"status": WebhookProcessed, "error": "", "processed_at": now}
+	if err != nil {
+		log.Printf("Webhook %s/%s failed: %v", event.Provider, event.EventID, err)
+		updates = map[string]interface{}{"status": WebhookFailed, "error": err.Error()}
+	}
+	if dbErr := tx.Model(&database.WebhookEvent{}).Where("id = ?", event.ID).Updates(updates).Error; dbErr != nil {
+		return dbErr
+	}
+	event.Status = updates["status"].(string)
+	event.Error = updates["error"].(string)
+	if err == nil {
+		event.ProcessedAt = &now
+	}
+	return nil
+}
+
+// handleNubanCreated completes the wallet provisioning request the bank has
+// issued an account number for
+func handleNubanCreated(tx *gorm.DB, data structs.Data) error {
+	if data.NUBAN == "" {
+		return fmt.Errorf("%w: callback has no NUBAN", ErrWebhookNoMatch)
+	}
+	_, err := CompleteProvisioning(tx, ProvisioningCallback{
+		Reference:     data.Reference,
+		PhoneNumber:   data.PhoneNumber,
+		Email:         data.Email,
+		AccountNumber: data.NUBAN,
+		AccountName:   data.NUBANName,
+		Bank:          wemaBankName,
+	})
+	if errors.Is(err, ErrProvisioningNotFound) {
+		return fmt.Errorf("%w: provisioning for NUBAN %s", ErrWebhookNoMatch, data.NUBAN)
+	}
+	return err
+}
+
+// handlePinValidation has nothing to update; the event is stored for audit
+func handlePinValidation(tx *gorm.DB, data structs.Data) error {
+	log.Printf("Pin validation callback for NUBAN %s: %s", data.NUBAN, data.Status)
+	return nil
+}
+
+// handlePaymentResponse settles the transaction the bank is reporting on.
+// Outbound transfers are settled through their hold; a status that is not
+// clearly final leaves them, and any other transaction, for a later update.
+func handlePaymentResponse(tx *gorm.DB, data structs.Data) error {
+	if data.Reference == "" {
+		return fmt.Errorf("%w: callback has no reference", ErrWebhookNoMatch)
+	}
+	var transfers int64
+	if err := tx.Model(&database.BankTransfer{}).Where("reference = ?", data.Reference).
+		Count(&transfers).Error; err != nil {
+		return err
+	}
+	if transfers > 0 {
+		return SettleBankTransfer(tx, data.Reference, wemaTransferOutcome(data.Status), data.Status)
+	}
+
+	// Only a final answer changes the transaction; anything else, such as
+	// pending or processing, is acknowledged and waits for the next callback
+	var status string
+	switch wemaTransferOutcome(data.Status) {
+	case TransferSuccessful:
+		status = "completed"
+	case TransferFailed:
+		status = "failed"
+	default:
+		return nil
+	}
+	result := tx.Model(&database.Transaction{}).
+		Where("reference = ?", data.Reference).
+		Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: transaction %s", ErrWebhookNoMatch, data.Reference)
+	}
+	return nil
+}
+
+// handleInboundCredit credits money sent to a NUBAN from another bank. A
+// session the bank has already told us about is acknowledged and ignored.
+func handleInboundCredit(tx *gorm.DB, data structs.Data) error {
+	_, err := CreditInboundTransfer(tx, InboundTransfer{
+		Provider:      "wema",
+		SessionID:     data.SessionID,
+		AccountNumber: data.NUBAN,
+		Amount:        data.Amount,
+		SenderName:    data.SenderName,
+		SenderBank:    data.SenderBank,
+		SenderAccount: data.SenderAccountNumber,
+		Narration:     data.Narration,
+	})
+	if errors.Is(err, ErrDuplicateInboundCredit) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"olra-v1/internal/database"
+	"olra-v1/internal/dbtest"
+)
+
+var webhookModels = []interface{}{
+	&database.WebhookEvent{}, &database.Transaction{}, &database.BankTransfer{},
+}
+
+func signTestWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	body := []byte(`{"Data":{"Request":3}}`)
+	now := time.Unix(1700000000, 0)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	stale := strconv.FormatInt(now.Add(-webhookTolerance-time.Second).Unix(), 10)
+	t.Setenv("WEMA_WEBHOOK_SECRET", "whsec")
+
+	tests := []struct {
+		name    string
+		mode    string
+		headers WebhookHeaders
+		body    []byte
+		err     error
+	}{
+		{"signed", "", WebhookHeaders{Signature: signTestWebhook("whsec", timestamp, body), Timestamp: timestamp}, body, nil},
+		{"wrong key", "", WebhookHeaders{Signature: signTestWebhook("other", timestamp, body), Timestamp: timestamp}, body, ErrWebhookSignature},
+		{"tampered body", "", WebhookHeaders{Signature: signTestWebhook("whsec", timestamp, body), Timestamp: timestamp}, []byte(`{"Data":{"Request":4}}`), ErrWebhookSignature},
+		{"replayed", "", WebhookHeaders{Signature: signTestWebhook("whsec", stale, body), Timestamp: stale}, body, ErrWebhookTimestamp},
+		{"no timestamp", "", WebhookHeaders{Signature: signTestWebhook("whsec", "", body)}, body, ErrWebhookTimestamp},
+		{"shared secret", "secret", WebhookHeaders{Secret: "whsec", Timestamp: timestamp}, body, nil},
+		{"wrong shared secret", "secret", WebhookHeaders{Secret: "nope", Timestamp: timestamp}, body, ErrWebhookSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WEMA_WEBHOOK_MODE", tt.mode)
+			if err := VerifyWebhook(tt.body, tt.headers, now); !errors.Is(err, tt.err) {
+				t.Errorf("VerifyWebhook() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	t.Setenv("WEMA_WEBHOOK_SECRET", "")
+	headers := WebhookHeaders{Signature: signTestWebhook("", timestamp, body), Timestamp: timestamp}
+	if err := VerifyWebhook(body, headers, now); !errors.Is(err, ErrWebhookNotConfigured) {
+		t.Errorf("VerifyWebhook() without a secret error = %v, want %v", err, ErrWebhookNotConfigured)
+	}
+}
+
+func TestReceiveWebhook(t *testing.T) {
+	db := dbtest.Open(t, webhookModels...)
+	transaction := database.Transaction{Reference: "TX1", TransactionType: "olraTransfer-out", Status: "pending", TransactionDate: time.Now()}
+	if err := db.Create(&transaction).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	pending := []byte(`{"Data":{"Request":4,"Reference":"TX1","Status":"Pending"}}`)
+	event, err := ReceiveWebhook("wema", pending, "evt-1")
+	if err != nil || event.Status != WebhookProcessed {
+		t.Fatalf("pending callback = %+v, %v, want processed", event, err)
+	}
+	db.First(&transaction, transaction.TransactionID)
+	if transaction.Status != "pending" {
+		t.Errorf("status after a pending callback = %q, want pending", transaction.Status)
+	}
+
+	successful := []byte(`{"Data":{"Request":4,"Reference":"TX1","Status":"Successful"}}`)
+	if _, err := ReceiveWebhook("wema", successful, "evt-2"); err != nil {
+		t.Fatalf("ReceiveWebhook() error = %v", err)
+	}
+	db.First(&transaction, transaction.TransactionID)
+	if transaction.Status != "completed" {
+		t.Errorf("status after a successful callback = %q, want completed", transaction.Status)
+	}
+	if _, err := ReceiveWebhook("wema", successful, "evt-2"); !errors.Is(err, ErrWebhookDuplicate) {
+		t.Errorf("redelivered callback error = %v, want %v", err, ErrWebhookDuplicate)
+	}
+	var events int64
+	db.Model(&database.WebhookEvent{}).Count(&events)
+	if events != 2 {
+		t.Errorf("stored %d events, want 2", events)
+	}
+}
+
+func TestReceiveWebhookInvalid(t *testing.T) {
+	db := dbtest.Open(t, webhookModels...)
+
+	event, err := ReceiveWebhook("wema", []byte(`not json`), "")
+	if !errors.Is(err, ErrWebhookInvalid) {
+		t.Fatalf("ReceiveWebhook() error = %v, want %v", err, ErrWebhookInvalid)
+	}
+	var stored database.WebhookEvent
+	if err := db.First(&stored, event.ID).Error; err != nil {
+		t.Fatalf("invalid callback not stored: %v", err)
+	}
+	if stored.Status != WebhookInvalid || stored.Payload != "not json" || stored.EventID == "" {
+		t.Errorf("stored event = %+v, want the invalid body kept under a body hash", stored)
+	}
+}
+
+func TestReplayWebhook(t *testing.T) {
+	db := dbtest.Open(t, webhookModels...)
+
+	body := []byte(`{"Data":{"Request":4,"Reference":"TX1","Status":"Successful"}}`)
+	event, err := ReceiveWebhook("wema", body, "evt-1")
+	if !errors.Is(err, ErrWebhookNoMatch) || event.Status != WebhookFailed {
+		t.Fatalf("callback before the transaction = %+v, %v, want failed with %v", event, err, ErrWebhookNoMatch)
+	}
+
+	transaction := database.Transaction{Reference: "TX1", TransactionType: "olraTransfer-out", Status: "pending", TransactionDate: time.Now()}
+	if err := db.Create(&transaction).Error; err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := ReplayWebhook(event.ID)
+	if err != nil || replayed.Status != WebhookProcessed || replayed.Error != "" {
+		t.Fatalf("ReplayWebhook() = %+v, %v, want processed", replayed, err)
+	}
+	db.First(&transaction, transaction.TransactionID)
+	if transaction.Status != "completed" {
+		t.Errorf("status after replay = %q, want completed", transaction.Status)
+	}
+	if _, err := ReplayWebhook(event.ID); !errors.Is(err, ErrWebhookDuplicate) {
+		t.Errorf("second replay error = %v, want %v", err, ErrWebhookDuplicate)
+	}
+}
@@ -6,10 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"math/big"
 	mathrand "math/rand"
 	"reflect"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -87,21 +88,41 @@ func GenerateRandomPassword(length int) (string, error) {
 	return password, nil
 }
 
-func GenerateVerificationCode() string {
-	code := mathrand.Intn(900000) + 100000
-	return strconv.Itoa(code)
+// GenerateSecureOTP returns a numeric code of the given length from crypto/rand
+func GenerateSecureOTP(length int) (string, error) {
+	code := make([]byte, length)
+	for i := range code {
+		n, err := cryptorand.Int(cryptorand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
 }
 
-func GenerateRandomAccountNumber() string {
-	// Generate the first two digits starting with "00"
-	firstTwoDigits := "00"
-
-	// Generate the remaining eight digits randomly
-	var remainingDigits string
-	for i := 0; i < 8; i++ {
-		remainingDigits += fmt.Sprintf("%d", mathrand.Intn(10))
+func GenerateReferralCode() (string, error) {
+	// Ambiguous characters (0/O, 1/I/L) are left out so codes can be read aloud
+	const alphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	code := make([]byte, 8)
+	for i := range code {
+		n, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = alphabet[n.Int64()]
 	}
-	return firstTwoDigits + remainingDigits
+	return string(code), nil
+}
+
+// ToKobo converts a naira amount to kobo, rounding to the nearest kobo
+func ToKobo(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// FromKobo converts a kobo amount back to naira
+func FromKobo(amount int64) float64 {
+	return float64(amount) / 100
 }
 
 func ValidateTagRequest(tagRequest string) error {